@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// commandDiff is what has to change for Discord's registered commands to match the ones defined here.
+type commandDiff struct {
+	Create    []*discordgo.ApplicationCommand
+	Update    []*discordgo.ApplicationCommand // Desired definitions, with ID set to the registered command's
+	Delete    []*discordgo.ApplicationCommand // Registered commands that are no longer defined
+	Unchanged []string
+}
+
+// diffCommands compares the commands Discord has registered with the desired set, matching them by name.
+func diffCommands(existing, desired []*discordgo.ApplicationCommand) commandDiff {
+	var diff commandDiff
+
+	registered := make(map[string]*discordgo.ApplicationCommand, len(existing))
+	for _, cmd := range existing {
+		registered[cmd.Name] = cmd
+	}
+
+	defined := make(map[string]bool, len(desired))
+	for _, cmd := range desired {
+		defined[cmd.Name] = true
+		current, ok := registered[cmd.Name]
+		switch {
+		case !ok:
+			diff.Create = append(diff.Create, cmd)
+		case commandsEqual(current, cmd):
+			diff.Unchanged = append(diff.Unchanged, cmd.Name)
+		default:
+			update := *cmd
+			update.ID = current.ID
+			diff.Update = append(diff.Update, &update)
+		}
+	}
+
+	for _, cmd := range existing {
+		if !defined[cmd.Name] {
+			diff.Delete = append(diff.Delete, cmd)
+		}
+	}
+
+	return diff
+}
+
+// commandShape holds the parts of a command we define. Discord adds IDs, versions and defaults to
+// what it returns, so registered commands are compared through this rather than field by field.
+type commandShape struct {
+	Type                     discordgo.ApplicationCommandType       `json:"type"`
+	Description              string                                 `json:"description"`
+	DefaultMemberPermissions *int64                                 `json:"default_member_permissions"`
+	Contexts                 []discordgo.InteractionContextType     `json:"contexts,omitempty"`
+	IntegrationTypes         []discordgo.ApplicationIntegrationType `json:"integration_types,omitempty"`
+	Options                  []*discordgo.ApplicationCommandOption  `json:"options"`
+}
+
+// commandsEqual reports whether the registered command already matches the desired definition.
+// Contexts and integration types are only compared when the definition sets them, since Discord
+// fills in its own defaults otherwise.
+func commandsEqual(registered, desired *discordgo.ApplicationCommand) bool {
+	a, errA := json.Marshal(shapeOf(registered, desired))
+	b, errB := json.Marshal(shapeOf(desired, desired))
+	return errA == nil && errB == nil && string(a) == string(b)
+}
+
+func shapeOf(cmd, desired *discordgo.ApplicationCommand) commandShape {
+	shape := commandShape{
+		Type:                     cmd.Type,
+		Description:              cmd.Description,
+		DefaultMemberPermissions: cmd.DefaultMemberPermissions,
+		Options:                  normalizeOptions(cmd.Options),
+	}
+	if shape.Type == 0 {
+		shape.Type = discordgo.ChatApplicationCommand // What Discord reports when none was given
+	}
+	if desired.Contexts != nil && cmd.Contexts != nil {
+		shape.Contexts = *cmd.Contexts
+	}
+	if desired.IntegrationTypes != nil && cmd.IntegrationTypes != nil {
+		shape.IntegrationTypes = *cmd.IntegrationTypes
+	}
+	return shape
+}
+
+// normalizeOptions returns a copy of options with empty lists set to nil, so an option Discord
+// returns with `"choices": []` compares equal to one we defined without any.
+func normalizeOptions(options []*discordgo.ApplicationCommandOption) []*discordgo.ApplicationCommandOption {
+	if len(options) == 0 {
+		return nil
+	}
+	out := make([]*discordgo.ApplicationCommandOption, len(options))
+	for i, opt := range options {
+		o := *opt
+		if len(o.ChannelTypes) == 0 {
+			o.ChannelTypes = nil
+		}
+		if len(o.Choices) == 0 {
+			o.Choices = nil
+		}
+		o.Options = normalizeOptions(o.Options)
+		out[i] = &o
+	}
+	return out
+}
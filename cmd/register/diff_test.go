@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestDiffCommands(t *testing.T) {
+	minScore := 0.0
+	guildOnly := []discordgo.InteractionContextType{discordgo.InteractionContextGuild}
+	everywhere := []discordgo.InteractionContextType{
+		discordgo.InteractionContextGuild,
+		discordgo.InteractionContextBotDM,
+		discordgo.InteractionContextPrivateChannel,
+	}
+
+	desired := []*discordgo.ApplicationCommand{
+		{
+			Name:        "setup",
+			Description: "Configure the bot for this server (Admin Only)",
+			Options: []*discordgo.ApplicationCommandOption{
+				{Type: discordgo.ApplicationCommandOptionInteger, Name: "min_score", Description: "Minimum score", MinValue: &minScore},
+			},
+		},
+		{Name: "help", Description: "Learn how to use the bot and set up alerts"},
+		{Name: "alert", Description: "Manage your hardware alerts", Contexts: &everywhere},
+		{Name: "timezone", Description: "Set your timezone"},
+	}
+
+	// What Discord returns: IDs and versions added, type filled in, empty lists instead of nil, and
+	// default contexts on commands that didn't set any.
+	existing := []*discordgo.ApplicationCommand{
+		{
+			ID:          "1",
+			Version:     "100",
+			Type:        discordgo.ChatApplicationCommand,
+			Name:        "setup",
+			Description: "Configure the bot for this server (Admin Only)",
+			Contexts:    &everywhere,
+			Options: []*discordgo.ApplicationCommandOption{
+				{Type: discordgo.ApplicationCommandOptionInteger, Name: "min_score", Description: "Minimum score", MinValue: &minScore, Choices: []*discordgo.ApplicationCommandOptionChoice{}, ChannelTypes: []discordgo.ChannelType{}},
+			},
+		},
+		{ID: "2", Type: discordgo.ChatApplicationCommand, Name: "help", Description: "Old help text", Options: []*discordgo.ApplicationCommandOption{}},
+		{ID: "3", Type: discordgo.ChatApplicationCommand, Name: "alert", Description: "Manage your hardware alerts", Contexts: &guildOnly},
+		{ID: "4", Type: discordgo.ChatApplicationCommand, Name: "legacy", Description: "No longer defined"},
+	}
+
+	diff := diffCommands(existing, desired)
+
+	if len(diff.Unchanged) != 1 || diff.Unchanged[0] != "setup" {
+		t.Errorf("expected only setup to be unchanged, got %v", diff.Unchanged)
+	}
+	if len(diff.Create) != 1 || diff.Create[0].Name != "timezone" {
+		t.Errorf("expected timezone to be created, got %v", names(diff.Create))
+	}
+	if len(diff.Update) != 2 || diff.Update[0].Name != "help" || diff.Update[1].Name != "alert" {
+		t.Fatalf("expected help and alert to be updated, got %v", names(diff.Update))
+	}
+	if diff.Update[0].ID != "2" || diff.Update[0].Description != "Learn how to use the bot and set up alerts" {
+		t.Errorf("expected the update to carry the registered ID and new definition, got %+v", diff.Update[0])
+	}
+	if desired[1].ID != "" {
+		t.Error("expected the desired definition not to be modified")
+	}
+	if len(diff.Delete) != 1 || diff.Delete[0].ID != "4" {
+		t.Errorf("expected legacy to be removed, got %v", names(diff.Delete))
+	}
+}
+
+func TestDiffCommands_NothingChanged(t *testing.T) {
+	desired := []*discordgo.ApplicationCommand{{Name: "help", Description: "Help"}}
+	existing := []*discordgo.ApplicationCommand{{ID: "1", Type: discordgo.ChatApplicationCommand, Name: "help", Description: "Help"}}
+
+	diff := diffCommands(existing, desired)
+
+	if len(diff.Create)+len(diff.Update)+len(diff.Delete) != 0 {
+		t.Errorf("expected no changes, got %+v", diff)
+	}
+}
+
+func names(cmds []*discordgo.ApplicationCommand) []string {
+	var out []string
+	for _, c := range cmds {
+		out = append(out, c.Name)
+	}
+	return out
+}
@@ -1,14 +1,19 @@
 package main
 
 import (
+	"flag"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/joho/godotenv"
 )
 
 func main() {
+	deleteRemoved := flag.Bool("delete-removed", false, "Delete registered commands that are no longer defined here")
+	flag.Parse()
+
 	_ = godotenv.Load() // Load .env file if it exists (for local testing)
 
 	token := os.Getenv("DISCORD_BOT_TOKEN")
@@ -18,6 +23,9 @@ func main() {
 		log.Fatal("DISCORD_BOT_TOKEN and DISCORD_APP_ID must be set")
 	}
 
+	guildID, scope := registrationScope(os.Getenv("GUILD_ID"))
+	log.Printf("Registering commands %s", scope)
+
 	// Create a new Discord session using the bot token
 	dg, err := discordgo.New("Bot " + token)
 	if err != nil {
@@ -27,22 +35,117 @@ func main() {
 	// We only need to register commands, we don't need to open a websocket connection
 	// because this is an HTTP interactions bot.
 
+	// Server-wide settings like the blocklist default to members who can manage the server.
+	manageServer := int64(discordgo.PermissionManageServer)
+
+	// Reddit scores can go negative, but a negative threshold would never filter anything.
+	minScoreFloor := 0.0
+
+	// Trending alerts with a tiny threshold would fire on nearly every deal.
+	minTrendingComments := 5.0
+
+	// A rate limit bypass of 0 minutes revokes it.
+	bypassMinutesFloor := 0.0
+
+	// /runs shows at least one run.
+	minRuns := 1.0
+
+	// Alerts are numbered from 1 in /alert list.
+	firstAlert := 1.0
+
+	// Quiet hours are whole hours on a 24-hour clock.
+	firstHour, lastHour := 0.0, 23.0
+
+	// /alert can also be used from DMs when the app is installed to a user, so alerts work without a server.
+	alertIntegrationTypes := []discordgo.ApplicationIntegrationType{
+		discordgo.ApplicationIntegrationGuildInstall,
+		discordgo.ApplicationIntegrationUserInstall,
+	}
+	alertContexts := []discordgo.InteractionContextType{
+		discordgo.InteractionContextGuild,
+		discordgo.InteractionContextBotDM,
+		discordgo.InteractionContextPrivateChannel,
+	}
+
 	commands := []*discordgo.ApplicationCommand{
 		{
-			Name:        "setup",
-			Description: "Configure the bot for this server (Admin Only)",
+			Name:                     "setup",
+			Description:              "Configure the bot for this server (Admin Only)",
+			DefaultMemberPermissions: &manageServer,
 			Options: []*discordgo.ApplicationCommandOption{
 				{
 					Type:        discordgo.ApplicationCommandOptionChannel,
 					Name:        "feed_channel",
-					Description: "The channel where new deals will be posted",
-					Required:    true,
+					Description: "The channel where new deals will be posted (leave all options empty to pick from menus)",
+					Required:    false,
 				},
 				{
 					Type:        discordgo.ApplicationCommandOptionChannel,
 					Name:        "ping_channel",
 					Description: "The channel where users will be pinged when their alerts match",
-					Required:    true,
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionChannel,
+					Name:        "archive_channel",
+					Description: "A channel that logs what sold deals went for, for price reference",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "feed_mode",
+					Description: "Which deals to post in the feed channel (default: only deals matching an alert)",
+					Required:    false,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "Only deals matching an alert", Value: "alerts_only"},
+						{Name: "Every new deal", Value: "all_deals"},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "allow_nsfw",
+					Description: "Post deals marked NSFW on Reddit (default: suppressed)",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "min_score",
+					Description: "Leave deals below this Reddit score out of the feed unless they match an alert",
+					Required:    false,
+					MinValue:    &minScoreFloor,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "language",
+					Description: "Language for cleaned deals and the help text (default: English)",
+					Required:    false,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "English", Value: "en"},
+						{Name: "Français", Value: "fr"},
+					},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "feed_webhook",
+					Description: "Post the feed through this channel webhook instead of as the bot",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "raw_preview",
+					Description: "Also show the start of the original Reddit post under each deal (default: off)",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "clear",
+					Description: "Remove an optional setting",
+					Required:    false,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "Archive channel (stop logging sold deals)", Value: "archive_channel"},
+						{Name: "Feed webhook (post as the bot again)", Value: "feed_webhook"},
+						{Name: "Minimum score (post deals of any score)", Value: "min_score"},
+					},
 				},
 			},
 		},
@@ -51,8 +154,10 @@ func main() {
 			Description: "Learn how to use the bot and set up alerts",
 		},
 		{
-			Name:        "alert",
-			Description: "Manage your hardware alerts",
+			Name:             "alert",
+			Description:      "Manage your hardware alerts",
+			IntegrationTypes: &alertIntegrationTypes,
+			Contexts:         &alertContexts,
 			Options: []*discordgo.ApplicationCommandOption{
 				{
 					Name:        "add",
@@ -64,18 +169,368 @@ func main() {
 					Description: "List and manage your active alerts",
 					Type:        discordgo.ApplicationCommandOptionSubCommand,
 				},
+				{
+					Name:        "preview",
+					Description: "See the rule the AI would build from a description, without saving it",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "text",
+							Description: "What you're looking for, e.g. a 30-series GPU in Vancouver under $400",
+							Required:    true,
+							MaxLength:   300,
+						},
+					},
+				},
+				{
+					Name:        "trending",
+					Description: "Get pinged when a deal in the feed gets lots of comments fast",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "comments",
+							Description: "Ping me when a deal reaches this many comments",
+							Required:    true,
+							MinValue:    &minTrendingComments,
+						},
+					},
+				},
+				{
+					Name:        "pause-all",
+					Description: "Pause all of your alerts, e.g. while you're on vacation",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
+				{
+					Name:        "resume-all",
+					Description: "Resume all of your paused alerts",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
+				{
+					Name:        "bundles",
+					Description: "Choose whether your alerts match items only sold as part of a bundle",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionBoolean,
+							Name:        "exclude",
+							Description: "Skip bundles, e.g. a GPU that only comes with a full PC",
+							Required:    true,
+						},
+					},
+				},
+			},
+		},
+		{
+			Name:             "explain",
+			Description:      "Show why a deal did or didn't match one of your alerts",
+			IntegrationTypes: &alertIntegrationTypes,
+			Contexts:         &alertContexts,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "post",
+					Description: "Link to the Reddit post",
+					Required:    true,
+					MaxLength:   300,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "alert",
+					Description: "The alert's number in /alert list",
+					Required:    true,
+					MinValue:    &firstAlert,
+				},
+			},
+		},
+		{
+			Name:        "find",
+			Description: "Search recently posted deals",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "query",
+					Description: "Keywords to look for, e.g. 3080 toronto -broken",
+					Required:    true,
+					MaxLength:   80,
+				},
+			},
+		},
+		{
+			Name:                     "blocklist",
+			Description:              "Block terms from ever being posted in this server (Admin Only)",
+			DefaultMemberPermissions: &manageServer,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "add",
+					Description: "Block deals mentioning a term",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "term",
+							Description: "The word or phrase to block, e.g. giveaway",
+							Required:    true,
+							MaxLength:   50,
+						},
+					},
+				},
+				{
+					Name:        "remove",
+					Description: "Unblock a term",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "term",
+							Description: "The blocked word or phrase to remove",
+							Required:    true,
+							MaxLength:   50,
+						},
+					},
+				},
+				{
+					Name:        "list",
+					Description: "Show the blocked terms",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
 			},
 		},
+		{
+			Name:                     "inspect",
+			Description:              "Show a user's alerts exactly as stored, to debug alerts that don't fire (Admin Only)",
+			DefaultMemberPermissions: &manageServer,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Name:        "user",
+					Description: "The member whose alerts to inspect",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:             "quiethours",
+			Description:      "Hold your alert pings overnight and get them as one digest",
+			IntegrationTypes: &alertIntegrationTypes,
+			Contexts:         &alertContexts,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "set",
+					Description: "Choose when you don't want to be pinged",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "start",
+							Description: "Hour quiet hours begin (0-23), e.g. 22",
+							Required:    true,
+							MinValue:    &firstHour,
+							MaxValue:    lastHour,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionInteger,
+							Name:        "end",
+							Description: "Hour quiet hours end (0-23), e.g. 7",
+							Required:    true,
+							MinValue:    &firstHour,
+							MaxValue:    lastHour,
+						},
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "timezone",
+							Description: "Your timezone, e.g. America/Toronto (defaults to UTC)",
+							Required:    false,
+						},
+					},
+				},
+				{
+					Name:        "off",
+					Description: "Get pinged right away again",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
+			},
+		},
+		{
+			Name:             "timezone",
+			Description:      "Set your timezone for quiet hours and the times I show you",
+			IntegrationTypes: &alertIntegrationTypes,
+			Contexts:         &alertContexts,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "name",
+					Description: "An IANA timezone, e.g. America/Toronto or America/Vancouver",
+					Required:    true,
+				},
+			},
+		},
+		{
+			Name:        "servers",
+			Description: "List every server the bot is configured in (Bot Owner Only)",
+		},
+		{
+			Name:        "bypass",
+			Description: "Let a user skip the rate limit for bulk edits (Bot Owner Only)",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Name:        "user",
+					Description: "The user to exempt",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "minutes",
+					Description: "How long the bypass lasts (0 revokes it)",
+					Required:    true,
+					MinValue:    &bypassMinutesFloor,
+					MaxValue:    120,
+				},
+			},
+		},
+		{
+			Name:        "scammers",
+			Description: "Manage the global blocklist of scam sellers (Bot Owner Only)",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "add",
+					Description: "Drop every post by a Reddit user, in every server",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "username",
+							Description: "The Reddit username, e.g. u/scammer",
+							Required:    true,
+							MaxLength:   23,
+						},
+					},
+				},
+				{
+					Name:        "remove",
+					Description: "Let a Reddit user's posts through again",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "username",
+							Description: "The Reddit username to remove",
+							Required:    true,
+							MaxLength:   23,
+						},
+					},
+				},
+				{
+					Name:        "list",
+					Description: "Show every blocklisted seller",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+				},
+			},
+		},
+		{
+			Name:        "stats",
+			Description: "Compare how AI-built and manual alerts perform (Bot Owner Only)",
+		},
+		{
+			Name:        "runs",
+			Description: "Show the most recent Reddit scrape runs (Bot Owner Only)",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "count",
+					Description: "How many runs to show (default 10)",
+					MinValue:    &minRuns,
+					MaxValue:    25,
+				},
+			},
+		},
+		{
+			Name:        "prompt",
+			Description: "Inspect or reset the AI system prompts (Bot Owner Only)",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Name:        "show",
+					Description: "Show the prompt currently live for a flow",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "flow",
+							Description: "Which prompt to show",
+							Required:    true,
+							Choices: []*discordgo.ApplicationCommandOptionChoice{
+								{Name: "AI wizard", Value: "wizard"},
+								{Name: "Manual query validation", Value: "manual"},
+								{Name: "Post cleaning", Value: "clean"},
+							},
+						},
+					},
+				},
+				{
+					Name:        "reset",
+					Description: "Put a flow back on its built-in default prompt",
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandOption{
+						{
+							Type:        discordgo.ApplicationCommandOptionString,
+							Name:        "flow",
+							Description: "Which prompt to reset",
+							Required:    true,
+							Choices: []*discordgo.ApplicationCommandOptionChoice{
+								{Name: "AI wizard", Value: "wizard"},
+								{Name: "Manual query validation", Value: "manual"},
+								{Name: "Post cleaning", Value: "clean"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	existing, err := dg.ApplicationCommands(appID, guildID)
+	if err != nil {
+		log.Fatalf("Cannot fetch registered commands: %v", err)
 	}
 
-	log.Println("Registering commands globally...")
-	for _, v := range commands {
-		_, err := dg.ApplicationCommandCreate(appID, "", v)
-		if err != nil {
+	diff := diffCommands(existing, commands)
+	log.Printf("Commands: %d to create, %d to update, %d removed, %d unchanged", len(diff.Create), len(diff.Update), len(diff.Delete), len(diff.Unchanged))
+
+	for _, v := range diff.Create {
+		if _, err := dg.ApplicationCommandCreate(appID, guildID, v); err != nil {
 			log.Panicf("Cannot create '%v' command: %v", v.Name, err)
 		}
-		log.Printf("Successfully registered command /%s", v.Name)
+		log.Printf("Created command /%s", v.Name)
+	}
+	for _, v := range diff.Update {
+		if _, err := dg.ApplicationCommandEdit(appID, guildID, v.ID, v); err != nil {
+			log.Panicf("Cannot update '%v' command: %v", v.Name, err)
+		}
+		log.Printf("Updated command /%s", v.Name)
 	}
+	for _, v := range diff.Delete {
+		if !*deleteRemoved {
+			log.Printf("Command /%s is no longer defined; run with --delete-removed to delete it", v.Name)
+			continue
+		}
+		if err := dg.ApplicationCommandDelete(appID, guildID, v.ID); err != nil {
+			log.Panicf("Cannot delete '%v' command: %v", v.Name, err)
+		}
+		log.Printf("Deleted command /%s", v.Name)
+	}
+
+	log.Println("Commands are up to date!")
+}
 
-	log.Println("All commands registered successfully!")
+// registrationScope picks where commands are registered. Global commands can take up to an hour to
+// reach every client, so setting GUILD_ID registers them to that one server instead, where they show
+// up immediately. It returns the guild ID to pass to the API ("" for global) and a description for logs.
+func registrationScope(guildID string) (string, string) {
+	guildID = strings.TrimSpace(guildID)
+	if guildID == "" {
+		return "", "globally"
+	}
+	return guildID, "to guild " + guildID + " (development mode)"
 }
@@ -0,0 +1,25 @@
+package main
+
+import "testing"
+
+func TestRegistrationScope(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       string
+		wantGuild string
+		wantScope string
+	}{
+		{"Unset registers globally", "", "", "globally"},
+		{"Blank registers globally", "  ", "", "globally"},
+		{"Guild ID registers to that guild", " 123456789 ", "123456789", "to guild 123456789 (development mode)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			guildID, scope := registrationScope(tt.env)
+			if guildID != tt.wantGuild || scope != tt.wantScope {
+				t.Errorf("registrationScope(%q) = %q, %q; want %q, %q", tt.env, guildID, scope, tt.wantGuild, tt.wantScope)
+			}
+		})
+	}
+}
@@ -1,29 +1,89 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/pauljones0/betterHardwareSwap/internal/ai"
+	"github.com/pauljones0/betterHardwareSwap/internal/config"
 	"github.com/pauljones0/betterHardwareSwap/internal/discord"
 	"github.com/pauljones0/betterHardwareSwap/internal/processor"
+	"github.com/pauljones0/betterHardwareSwap/internal/reddit"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
 )
 
 func main() {
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
-		log.Printf("Defaulting to port %s", port)
+	// Load the environment once and fail fast on a broken deployment instead of erroring deep inside the first request.
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Refusing to start: %v", err)
 	}
 
+	// Dial Firestore and Gemini once; every request shares these connections.
+	ctx := context.Background()
+	db, err := store.Default(ctx, cfg.ProjectID)
+	if err != nil {
+		log.Fatalf("Failed to init db: %v", err)
+	}
+	defer store.CloseDefault()
+
+	aiSvc, err := ai.NewAIClient(ctx, cfg.GeminiAPIKey, ai.Settings{
+		Clean:  ai.GenerationSettings{Temperature: cfg.CleanTemperature, MaxOutputTokens: cfg.CleanMaxTokens},
+		Wizard: ai.GenerationSettings{Temperature: cfg.WizardTemperature, MaxOutputTokens: cfg.WizardMaxTokens},
+	})
+	if err != nil {
+		log.Fatalf("Failed to init ai: %v", err)
+	}
+	defer aiSvc.Close()
+
+	discordClient := discord.NewClient(cfg.DiscordBotToken)
+	interactions := discord.NewHandler(cfg, db, aiSvc, discordClient)
+	scraper := reddit.NewScraper()
+	scraper.Limit = cfg.RedditFetchLimit
+	if cfg.RedditUserAgent != "" {
+		scraper.UserAgent = cfg.RedditUserAgent
+	}
+	crons := processor.NewHandler(db, aiSvc, scraper, discordClient, cfg.AdminUserID, cfg.PingMaxPostAge)
+
 	// Setup Discord Interactions webhook handler
-	http.HandleFunc("/interactions", discord.HandleInteraction)
+	http.HandleFunc("/interactions", interactions.HandleInteraction)
 
 	// Setup Cloud Scheduler endpoint for scraping
-	http.HandleFunc("/cron/scrape", processor.HandleCronScrape)
+	http.HandleFunc("/cron/scrape", crons.HandleCronScrape)
+
+	// Setup Cloud Scheduler endpoint for re-attempting dead-lettered feed posts
+	http.HandleFunc("/cron/retry", crons.HandleCronRetry)
+
+	// Setup Cloud Scheduler endpoint for delivering pings held back during users' quiet hours
+	http.HandleFunc("/cron/digest", crons.HandleCronDigest)
+
+	// Setup Cloud Scheduler endpoint for compacting AI prompts independently of alert activity
+	http.HandleFunc("/cron/compact", interactions.HandleCronCompact)
+
+	srv := &http.Server{Addr: ":" + cfg.Port}
+	go func() {
+		log.Printf("Listening on port %s", cfg.Port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Fatal: %v", err)
+		}
+	}()
+
+	// Cloud Run sends SIGTERM before stopping an instance. Drain in-flight requests so the shared
+	// clients are closed by the deferred calls above instead of being dropped mid-request.
+	sigCtx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	<-sigCtx.Done()
 
-	log.Printf("Listening on port %s", port)
-	if err := http.ListenAndServe(":"+port, nil); err != nil {
-		log.Fatalf("Fatal: %v", err)
+	log.Println("Shutting down")
+	shutdownCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Shutdown did not finish cleanly: %v", err)
 	}
 }
@@ -3,6 +3,7 @@ package ai
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/google/generative-ai-go/genai"
 	"github.com/pauljones0/betterHardwareSwap/internal/store"
@@ -25,17 +26,31 @@ func (c *AIClient) RunCompaction(ctx context.Context, records []store.AnalyticsR
 	var ids []string
 	for i, r := range records {
 		ids = append(ids, r.ID)
-		recordDetails += fmt.Sprintf("Record %d:\n- Original Prompt: %s\n- Final Stored Query: %s\n- Outcome: %s\n\n",
+		if flowType == "clean" {
+			recordDetails += fmt.Sprintf("Record %d:\n- Reddit Post: %s\n- Cleaned Title: %s\n- Cleaned Text: %s\n- Outcome: %s\n\n",
+				i+1, r.RawPost, r.CleanedTitle, r.CleanedText, r.Outcome)
+			continue
+		}
+		recordDetails += fmt.Sprintf("Record %d:\n- Original Prompt: %s\n- Final Stored Query: %s\n- Outcome: %s\n",
 			i+1, r.OriginalUserPrompt, r.FinalSavedQuery, r.Outcome)
+		if len(r.StrippedKeywords) > 0 {
+			recordDetails += fmt.Sprintf("- Banned generic keywords the bot returned anyway: %s\n", strings.Join(r.StrippedKeywords, ", "))
+		}
+		recordDetails += "\n"
 	}
 
 	roleDesc := "a query-building bot"
-	if flowType == "manual" {
+	taskDesc := "convert natural language or validate manually typed Boolean queries"
+	switch flowType {
+	case "manual":
 		roleDesc = "a manual boolean syntax validator bot"
+	case "clean":
+		roleDesc = "a Reddit hardware listing summarizer bot"
+		taskDesc = "turn raw r/CanadianHardwareSwap posts into a clean title, description, price and location. Each record below is a summary a user flagged as wrong"
 	}
 
 	metaPrompt := fmt.Sprintf(`You are a senior AI prompt engineer improving %s.
-The bot uses a system prompt to convert natural language or validate manually typed Boolean queries.
+The bot uses a system prompt to %s.
 
 Currently, the bot is using this system prompt:
 """
@@ -55,7 +70,7 @@ CRITICAL RULES:
 2. DO NOT change the core structure or purpose of the prompt, only add examples or tweak keywords to dodge failures.
 3. ONLY output the raw, plaintext updated prompt. Do NOT include markdown blocks like `+"```...```"+`.
 
-New Prompt:`, roleDesc, currentPrompt, len(records), recordDetails)
+New Prompt:`, roleDesc, taskDesc, currentPrompt, len(records), recordDetails)
 
 	resp, err := c.model.GenerateContent(ctx, genai.Text(metaPrompt))
 	if err != nil {
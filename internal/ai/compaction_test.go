@@ -0,0 +1,98 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/google/generative-ai-go/genai"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+)
+
+func TestRunCompaction_CleanFlow(t *testing.T) {
+	ctx := context.Background()
+
+	var metaPrompt string
+	mock := &MockModel{
+		GenerateContentFn: func(ctx context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+			metaPrompt = string(parts[0].(genai.Text))
+			return &genai.GenerateContentResponse{
+				Candidates: []*genai.Candidate{
+					{
+						Content: &genai.Content{
+							Parts: []genai.Part{genai.Text("Improved cleaning prompt")},
+						},
+					},
+				},
+			}, nil
+		},
+	}
+
+	records := []store.AnalyticsRecord{
+		{
+			ID:           "a1",
+			FlowType:     "clean",
+			RawPost:      "https://reddit.com/r/CanadianHardwareSwap/comments/abc123",
+			CleanedText:  "RTX 3080 Great condition Toronto",
+			CleanedTitle: "RTX 3080",
+			Outcome:      "Flagged_Bad_Parse",
+		},
+		{ID: "a2", FlowType: "clean", Outcome: "Flagged_Bad_Parse"},
+	}
+
+	client := &AIClient{model: mock}
+	result, err := client.RunCompaction(ctx, records, CleanPostSystemInstruction, "clean")
+	if err != nil {
+		t.Fatalf("RunCompaction failed: %v", err)
+	}
+
+	if result.NewPrompt != "Improved cleaning prompt" {
+		t.Errorf("unexpected new prompt %q", result.NewPrompt)
+	}
+	if len(result.AnalyticsIDs) != 2 || result.AnalyticsIDs[0] != "a1" || result.AnalyticsIDs[1] != "a2" {
+		t.Errorf("unexpected analytics IDs %v", result.AnalyticsIDs)
+	}
+
+	for _, want := range []string{
+		"a Reddit hardware listing summarizer bot",
+		CleanPostSystemInstruction,
+		"- Reddit Post: https://reddit.com/r/CanadianHardwareSwap/comments/abc123",
+		"- Cleaned Title: RTX 3080",
+		"- Cleaned Text: RTX 3080 Great condition Toronto",
+	} {
+		if !strings.Contains(metaPrompt, want) {
+			t.Errorf("expected meta prompt to contain %q", want)
+		}
+	}
+	if strings.Contains(metaPrompt, "query-building") {
+		t.Error("clean flow should not use the query-building role description")
+	}
+}
+
+func TestRunCompaction_StrippedKeywords(t *testing.T) {
+	var metaPrompt string
+	mock := &MockModel{
+		GenerateContentFn: func(ctx context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+			metaPrompt = string(parts[0].(genai.Text))
+			return &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{
+				Content: &genai.Content{Parts: []genai.Part{genai.Text("Improved wizard prompt")}},
+			}}}, nil
+		},
+	}
+
+	records := []store.AnalyticsRecord{
+		{ID: "a1", FlowType: "wizard", OriginalUserPrompt: "any gpu", Outcome: "Stripped_Banned_Keywords", StrippedKeywords: []string{"gpu", "pc parts"}},
+		{ID: "a2", FlowType: "wizard", OriginalUserPrompt: "a 3080", Outcome: "Accepted_As_Is"},
+	}
+
+	client := &AIClient{model: mock}
+	if _, err := client.RunCompaction(context.Background(), records, DefaultWizardPrompt, "wizard"); err != nil {
+		t.Fatalf("RunCompaction failed: %v", err)
+	}
+	if !strings.Contains(metaPrompt, "- Banned generic keywords the bot returned anyway: gpu, pc parts") {
+		t.Error("expected the meta prompt to list the stripped keywords")
+	}
+	if strings.Count(metaPrompt, "Banned generic keywords") != 1 {
+		t.Error("expected only the record with stripped keywords to list them")
+	}
+}
@@ -4,16 +4,26 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/google/generative-ai-go/genai"
+	"github.com/pauljones0/betterHardwareSwap/internal/logger"
+	"github.com/pauljones0/betterHardwareSwap/internal/query"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
 	"google.golang.org/api/option"
 )
 
 // GenerativeModel defines the subset of genai.GenerativeModel methods we use.
 type GenerativeModel interface {
 	GenerateContent(ctx context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error)
-	SetSystemInstruction(parts ...genai.Part)
+	// WithSystemInstruction returns a copy of the model using the given system instruction. The receiver
+	// is left untouched, so one AIClient can serve concurrent calls that use different prompts.
+	WithSystemInstruction(parts ...genai.Part) GenerativeModel
+	// WithGenerationSettings returns a copy of the model sampling with the given settings, leaving the
+	// receiver untouched like WithSystemInstruction.
+	WithGenerationSettings(settings GenerationSettings) GenerativeModel
 }
 
 // ModelWrapper wraps the real genai.GenerativeModel to satisfy our interface.
@@ -25,23 +35,49 @@ func (m *ModelWrapper) GenerateContent(ctx context.Context, parts ...genai.Part)
 	return m.model.GenerateContent(ctx, parts...)
 }
 
-func (m *ModelWrapper) SetSystemInstruction(parts ...genai.Part) {
-	m.model.SystemInstruction = &genai.Content{Parts: parts}
+func (m *ModelWrapper) WithSystemInstruction(parts ...genai.Part) GenerativeModel {
+	model := *m.model
+	model.SystemInstruction = &genai.Content{Parts: parts}
+	return &ModelWrapper{model: &model}
 }
 
-// AIClient wraps the Gemini API.
+func (m *ModelWrapper) WithGenerationSettings(settings GenerationSettings) GenerativeModel {
+	model := *m.model
+	model.SetTemperature(settings.Temperature)
+	if settings.MaxOutputTokens > 0 {
+		model.SetMaxOutputTokens(settings.MaxOutputTokens)
+	}
+	return &ModelWrapper{model: &model}
+}
+
+// GenerationSettings tunes how the model samples for one kind of call.
+type GenerationSettings struct {
+	Temperature     float32 // 0 to 2; lower is more deterministic
+	MaxOutputTokens int32   // Caps the response length; 0 keeps the model's default
+}
+
+// Settings holds the generation settings for each kind of call the client makes.
+type Settings struct {
+	Clean  GenerationSettings // CleanRedditPost
+	Wizard GenerationSettings // RunKeywordWizard and ValidateManualQuery, which must return exact keywords
+}
+
+// AIClient wraps the Gemini API. It is safe for concurrent use.
 type AIClient struct {
-	client *genai.Client
-	model  GenerativeModel
+	client   *genai.Client
+	model    GenerativeModel
+	settings Settings
 }
 
 // CleanedPost is the structured response we want from Gemini when parsing a Reddit Deal.
 type CleanedPost struct {
 	Title       string `json:"title"`
+	Summary     string `json:"summary,omitempty"` // One-line tl;dr shown bolded above the description
 	Description string `json:"description"`
 	Price       string `json:"price,omitempty"`
 	Location    string `json:"location,omitempty"`
 	Condition   string `json:"condition,omitempty"`
+	IsBundle    bool   `json:"is_bundle,omitempty"` // Items are only sold together, e.g. a GPU in a full PC
 }
 
 // KeywordWizardResponse is the structured response for compiling a Boolean query.
@@ -56,8 +92,8 @@ type KeywordWizardResponse struct {
 	ErrorMessage     string   `json:"error_message,omitempty"`     // Explanation of why the syntax is invalid
 }
 
-// NewAIClient initializes the Gemini client.
-func NewAIClient(ctx context.Context, apiKey string) (*AIClient, error) {
+// NewAIClient initializes the Gemini client. settings picks the sampling for each kind of call.
+func NewAIClient(ctx context.Context, apiKey string, settings Settings) (*AIClient, error) {
 	client, err := genai.NewClient(ctx, option.WithAPIKey(apiKey))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create genai client: %v", err)
@@ -72,8 +108,9 @@ func NewAIClient(ctx context.Context, apiKey string) (*AIClient, error) {
 	model.ResponseSchema = schema
 
 	return &AIClient{
-		client: client,
-		model:  &ModelWrapper{model: model},
+		client:   client,
+		model:    &ModelWrapper{model: model},
+		settings: settings,
 	}, nil
 }
 
@@ -85,12 +122,16 @@ func (c *AIClient) Close() {
 }
 
 // CleanRedditPost takes the raw messy Reddit title and body, and returns a concise, mobile-friendly summary.
-func (c *AIClient) CleanRedditPost(ctx context.Context, rawTitle, rawBody string) (*CleanedPost, error) {
-	c.model.SetSystemInstruction(genai.Text(CleanPostSystemInstruction))
+func (c *AIClient) CleanRedditPost(ctx context.Context, rawTitle, rawBody, promptOverride string) (*CleanedPost, error) {
+	basePrompt := promptOverride
+	if basePrompt == "" {
+		basePrompt = CleanPostSystemInstruction
+	}
+	model := c.model.WithSystemInstruction(genai.Text(basePrompt)).WithGenerationSettings(c.settings.Clean)
 	prompt := fmt.Sprintf(CleanPostUserPromptTemplate, rawTitle, rawBody)
 
 	var cleaned CleanedPost
-	err := c.callWithRetry(ctx, prompt, &cleaned)
+	err := callWithRetry(ctx, model, prompt, &cleaned)
 	if err != nil {
 		return nil, err
 	}
@@ -103,41 +144,152 @@ func (c *AIClient) RunKeywordWizard(ctx context.Context, userRequest, promptOver
 	if basePrompt == "" {
 		basePrompt = DefaultWizardPrompt
 	}
-	c.model.SetSystemInstruction(genai.Text(basePrompt))
+	model := c.model.WithSystemInstruction(genai.Text(basePrompt)).WithGenerationSettings(c.settings.Wizard)
 	prompt := fmt.Sprintf(WizardUserPromptTemplate, userRequest)
 
 	var wizard KeywordWizardResponse
-	err := c.callWithRetry(ctx, prompt, &wizard)
+	err := callWithRetry(ctx, model, prompt, &wizard)
 	if err != nil {
 		return nil, err
 	}
+	sanitizeWizardResponse(&wizard)
 	return &wizard, nil
 }
 
-// ValidateManualQuery securely validates a user's manually typed Boolean-like query.
+// ValidateManualQuery securely validates a user's manually typed Boolean-like query. Well-formed queries
+// are parsed locally; Gemini is only asked about ones the parser rejects or can't read with certainty.
 func (c *AIClient) ValidateManualQuery(ctx context.Context, userQuery, promptOverride string) (*KeywordWizardResponse, error) {
+	q, err := query.Parse(userQuery)
+	if err == nil {
+		wizard := KeywordWizardResponse{MustHave: q.MustHave, AnyOf: q.AnyOf, MustNot: q.MustNot, IsValid: true}
+		sanitizeWizardResponse(&wizard)
+		return &wizard, nil
+	}
+	logger.Debug(ctx, "Manual query left to Gemini", "reason", err)
+
 	basePrompt := promptOverride
 	if basePrompt == "" {
 		basePrompt = DefaultManualPrompt
 	}
-	c.model.SetSystemInstruction(genai.Text(basePrompt))
+	model := c.model.WithSystemInstruction(genai.Text(basePrompt)).WithGenerationSettings(c.settings.Wizard)
 	prompt := fmt.Sprintf(ManualUserPromptTemplate, userQuery)
 
 	var wizard KeywordWizardResponse
-	err := c.callWithRetry(ctx, prompt, &wizard)
-	if err != nil {
+	if err := callWithRetry(ctx, model, prompt, &wizard); err != nil {
 		return nil, err
 	}
+	sanitizeWizardResponse(&wizard)
 	return &wizard, nil
 }
 
+// StripBannedKeywords removes banned generic terms from the keywords a post must match. The wizard prompt
+// forbids them, but the model sometimes uses them anyway and the rule then matches almost every post. If
+// nothing is left to search for, the response is marked too broad. Terms are compared case-insensitively.
+// It returns the terms it removed.
+func (w *KeywordWizardResponse) StripBannedKeywords(banned []string) []string {
+	bannedSet := make(map[string]bool, len(banned))
+	for _, term := range banned {
+		bannedSet[strings.ToLower(strings.TrimSpace(term))] = true
+	}
+	var stripped []string
+	keep := func(terms []string) []string {
+		var kept []string
+		for _, term := range terms {
+			if bannedSet[strings.ToLower(strings.TrimSpace(term))] {
+				stripped = append(stripped, term)
+				continue
+			}
+			kept = append(kept, term)
+		}
+		return kept
+	}
+	w.MustHave = keep(w.MustHave)
+	w.AnyOf = keep(w.AnyOf)
+
+	if len(stripped) > 0 && len(w.MustHave) == 0 && len(w.AnyOf) == 0 {
+		w.TooBroad = true
+		if w.BroadReason == "" {
+			w.BroadReason = fmt.Sprintf("Every post here is computer hardware, so `%s` would match almost all of them.", strings.Join(stripped, "`, `"))
+		}
+	}
+	return stripped
+}
+
+// Caps on the free-text parts of a wizard response, which are shown to the user but never saved.
+const (
+	maxWizardMessageLen    = 300
+	maxWizardSuggestions   = 5
+	maxWizardSuggestionLen = 100
+)
+
+// sanitizeWizardResponse treats the model's output as untrusted. Keywords are interpolated into
+// `...` blocks in embeds and saved as alert terms, so each one loses backticks and control characters
+// and is capped at the alert limits; the explanatory text is capped so it can't blow up the embed.
+func sanitizeWizardResponse(w *KeywordWizardResponse) {
+	w.MustHave = sanitizeKeywords(w.MustHave)
+	w.AnyOf = sanitizeKeywords(w.AnyOf)
+	w.MustNot = sanitizeKeywords(w.MustNot)
+
+	w.BroadReason = truncateRunes(w.BroadReason, maxWizardMessageLen)
+	w.ErrorMessage = truncateRunes(w.ErrorMessage, maxWizardMessageLen)
+	if len(w.BroadSuggestions) > maxWizardSuggestions {
+		w.BroadSuggestions = w.BroadSuggestions[:maxWizardSuggestions]
+	}
+	for i, s := range w.BroadSuggestions {
+		w.BroadSuggestions[i] = truncateRunes(s, maxWizardSuggestionLen)
+	}
+}
+
+func sanitizeKeywords(keywords []string) []string {
+	if keywords == nil {
+		return nil
+	}
+	out := make([]string, 0, len(keywords))
+	for _, k := range keywords {
+		k = strings.Map(func(r rune) rune {
+			if r == '`' || unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+				return ' '
+			}
+			return r
+		}, k)
+		k = strings.TrimSpace(truncateRunes(strings.Join(strings.Fields(k), " "), store.MaxAlertTermLength))
+		if k == "" {
+			continue
+		}
+		out = append(out, k)
+		if len(out) == store.MaxAlertTerms {
+			break
+		}
+	}
+	return out
+}
+
+func truncateRunes(s string, max int) string {
+	if r := []rune(s); len(r) > max {
+		return string(r[:max])
+	}
+	return s
+}
+
+// MaxAttempts is how many times a Gemini call is tried before giving up.
+const MaxAttempts = 3
+
+type retryNotifierKey struct{}
+
+// WithRetryNotifier returns a context whose Gemini calls report each retry to notify, with the number
+// of the attempt about to be made, so interactive callers can tell the user why they're still waiting.
+func WithRetryNotifier(ctx context.Context, notify func(attempt int)) context.Context {
+	return context.WithValue(ctx, retryNotifierKey{}, notify)
+}
+
 // callWithRetry handles the actual AI generation with exponential backoff on transient errors.
-func (c *AIClient) callWithRetry(ctx context.Context, prompt string, v interface{}) error {
+func callWithRetry(ctx context.Context, model GenerativeModel, prompt string, v interface{}) error {
 	var lastErr error
-	maxRetries := 3
+	maxRetries := MaxAttempts
+	notify, _ := ctx.Value(retryNotifierKey{}).(func(attempt int))
 
 	for i := 0; i < maxRetries; i++ {
-		resp, err := c.model.GenerateContent(ctx, genai.Text(prompt))
+		resp, err := model.GenerateContent(ctx, genai.Text(prompt))
 		if err == nil {
 			if parseErr := parseJSONResponse(resp, v); parseErr == nil {
 				return nil
@@ -152,6 +304,10 @@ func (c *AIClient) callWithRetry(ctx context.Context, prompt string, v interface
 			lastErr = err
 		}
 
+		if notify != nil && i+1 < maxRetries {
+			notify(i + 2)
+		}
+
 		backoff := time.Duration(i+1) * time.Second
 		select {
 		case <-time.After(backoff):
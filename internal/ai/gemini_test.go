@@ -4,25 +4,161 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"reflect"
+	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/google/generative-ai-go/genai"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
 )
 
 // MockModel satisfies the GenerativeModel interface for testing.
 type MockModel struct {
 	GenerateContentFn      func(ctx context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error)
 	SetSystemInstructionFn func(parts ...genai.Part)
+	Settings               []GenerationSettings // Every WithGenerationSettings call, in order
 }
 
 func (m *MockModel) GenerateContent(ctx context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
 	return m.GenerateContentFn(ctx, parts...)
 }
 
-func (m *MockModel) SetSystemInstruction(parts ...genai.Part) {
+func (m *MockModel) WithSystemInstruction(parts ...genai.Part) GenerativeModel {
 	if m.SetSystemInstructionFn != nil {
 		m.SetSystemInstructionFn(parts...)
 	}
+	return m
+}
+
+func (m *MockModel) WithGenerationSettings(settings GenerationSettings) GenerativeModel {
+	m.Settings = append(m.Settings, settings)
+	return m
+}
+
+func TestModelWrapper_WithGenerationSettings(t *testing.T) {
+	base := &ModelWrapper{model: &genai.GenerativeModel{}}
+
+	tuned := base.WithGenerationSettings(GenerationSettings{Temperature: 0.1, MaxOutputTokens: 512}).(*ModelWrapper)
+	if tuned.model.Temperature == nil || *tuned.model.Temperature != 0.1 {
+		t.Errorf("expected temperature 0.1, got %v", tuned.model.Temperature)
+	}
+	if tuned.model.MaxOutputTokens == nil || *tuned.model.MaxOutputTokens != 512 {
+		t.Errorf("expected a 512 token cap, got %v", tuned.model.MaxOutputTokens)
+	}
+	if base.model.Temperature != nil || base.model.MaxOutputTokens != nil {
+		t.Error("WithGenerationSettings should not modify the receiver")
+	}
+
+	uncapped := base.WithGenerationSettings(GenerationSettings{Temperature: 0}).(*ModelWrapper)
+	if uncapped.model.Temperature == nil || *uncapped.model.Temperature != 0 {
+		t.Errorf("expected a temperature of 0 to be sent, got %v", uncapped.model.Temperature)
+	}
+	if uncapped.model.MaxOutputTokens != nil {
+		t.Errorf("expected no token cap, got %v", *uncapped.model.MaxOutputTokens)
+	}
+}
+
+func TestAIClient_UsesSettingsPerCall(t *testing.T) {
+	ctx := context.Background()
+	settings := Settings{
+		Clean:  GenerationSettings{Temperature: 0.4, MaxOutputTokens: 2048},
+		Wizard: GenerationSettings{Temperature: 0.1, MaxOutputTokens: 1024},
+	}
+	mock := &MockModel{
+		GenerateContentFn: func(ctx context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+			return &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{
+				Content: &genai.Content{Parts: []genai.Part{genai.Text(`{"title": "RTX 3080", "must_have": ["3080"], "is_valid": true}`)}},
+			}}}, nil
+		},
+	}
+	client := &AIClient{model: mock, settings: settings}
+
+	if _, err := client.CleanRedditPost(ctx, "Selling 3080", "", ""); err != nil {
+		t.Fatalf("CleanRedditPost failed: %v", err)
+	}
+	if _, err := client.RunKeywordWizard(ctx, "a 3080", ""); err != nil {
+		t.Fatalf("RunKeywordWizard failed: %v", err)
+	}
+	if _, err := client.ValidateManualQuery(ctx, "3080 or 3090", ""); err != nil {
+		t.Fatalf("ValidateManualQuery failed: %v", err)
+	}
+
+	want := []GenerationSettings{settings.Clean, settings.Wizard, settings.Wizard}
+	if len(mock.Settings) != len(want) {
+		t.Fatalf("expected %d settings applied, got %v", len(want), mock.Settings)
+	}
+	for n := range want {
+		if mock.Settings[n] != want[n] {
+			t.Errorf("call %d used %+v, want %+v", n, mock.Settings[n], want[n])
+		}
+	}
+}
+
+func TestValidateManualQuery_ParsesLocally(t *testing.T) {
+	ctx := context.Background()
+	calls := 0
+	mock := &MockModel{
+		GenerateContentFn: func(ctx context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+			calls++
+			return &genai.GenerateContentResponse{Candidates: []*genai.Candidate{{
+				Content: &genai.Content{Parts: []genai.Part{genai.Text(`{"is_valid": false, "error_message": "Unclosed parenthesis."}`)}},
+			}}}, nil
+		},
+	}
+	client := &AIClient{model: mock}
+
+	got, err := client.ValidateManualQuery(ctx, "RTX AND (4080 OR 4090) NOT broken", "")
+	if err != nil {
+		t.Fatalf("ValidateManualQuery failed: %v", err)
+	}
+	want := &KeywordWizardResponse{MustHave: []string{"rtx"}, AnyOf: []string{"4080", "4090"}, MustNot: []string{"broken"}, IsValid: true}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	if calls != 0 {
+		t.Errorf("expected a well-formed query not to call Gemini, got %d calls", calls)
+	}
+
+	got, err = client.ValidateManualQuery(ctx, "(rtx AND 4090", "")
+	if err != nil {
+		t.Fatalf("ValidateManualQuery failed: %v", err)
+	}
+	if got.IsValid || calls != 1 {
+		t.Errorf("expected a malformed query to be checked by Gemini, got %+v after %d calls", got, calls)
+	}
+}
+
+func TestStripBannedKeywords(t *testing.T) {
+	banned := []string{"gpu", " PC Parts "}
+
+	t.Run("Specific terms are kept", func(t *testing.T) {
+		w := &KeywordWizardResponse{MustHave: []string{"GPU", "3080"}, AnyOf: []string{"pc parts", "toronto"}, MustNot: []string{"gpu"}}
+		w.StripBannedKeywords(banned)
+		want := &KeywordWizardResponse{MustHave: []string{"3080"}, AnyOf: []string{"toronto"}, MustNot: []string{"gpu"}}
+		if !reflect.DeepEqual(w, want) {
+			t.Errorf("got %+v, want %+v", w, want)
+		}
+	})
+
+	t.Run("Only generic terms", func(t *testing.T) {
+		w := &KeywordWizardResponse{MustHave: []string{"gpu"}, IsValid: true}
+		if stripped := w.StripBannedKeywords(banned); !reflect.DeepEqual(stripped, []string{"gpu"}) {
+			t.Errorf("expected gpu to be reported as stripped, got %v", stripped)
+		}
+		if len(w.MustHave) != 0 || !w.TooBroad || !strings.Contains(w.BroadReason, "`gpu`") {
+			t.Errorf("expected gpu to be stripped and the rule marked too broad, got %+v", w)
+		}
+	})
+
+	t.Run("Empty rule without banned terms is left alone", func(t *testing.T) {
+		w := &KeywordWizardResponse{}
+		w.StripBannedKeywords(banned)
+		if w.TooBroad || w.BroadReason != "" {
+			t.Errorf("expected no change, got %+v", w)
+		}
+	})
 }
 
 func TestCleanRedditPost(t *testing.T) {
@@ -31,6 +167,7 @@ func TestCleanRedditPost(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		expected := &CleanedPost{
 			Title:       "[WTS] RTX 3080",
+			Summary:     "RTX 3080, great condition, $500 in Toronto",
 			Description: "Great condition",
 			Price:       "$500",
 			Location:    "Toronto",
@@ -52,7 +189,7 @@ func TestCleanRedditPost(t *testing.T) {
 		}
 
 		client := &AIClient{model: mock}
-		got, err := client.CleanRedditPost(ctx, "Selling 3080", "Used but works well")
+		got, err := client.CleanRedditPost(ctx, "Selling 3080", "Used but works well", "")
 
 		if err != nil {
 			t.Fatalf("CleanRedditPost failed: %v", err)
@@ -60,6 +197,36 @@ func TestCleanRedditPost(t *testing.T) {
 		if got.Title != expected.Title {
 			t.Errorf("got title %q, want %q", got.Title, expected.Title)
 		}
+		if got.Summary != expected.Summary {
+			t.Errorf("got summary %q, want %q", got.Summary, expected.Summary)
+		}
+	})
+
+	t.Run("Bundle", func(t *testing.T) {
+		// A GPU only sold as part of a full PC, as the model is asked to flag it.
+		respJSON := `{"title": "[WTS] Full Gaming PC w/ RTX 3080", "summary": "Full PC bundle with RTX 3080, $2000 in Ottawa", "description": "5800X, RTX 3080, 32GB DDR4. Not parting out.", "price": "$2000", "location": "Ottawa, ON", "is_bundle": true}`
+		mock := &MockModel{
+			GenerateContentFn: func(ctx context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+				return &genai.GenerateContentResponse{
+					Candidates: []*genai.Candidate{
+						{
+							Content: &genai.Content{
+								Parts: []genai.Part{genai.Text(respJSON)},
+							},
+						},
+					},
+				}, nil
+			},
+		}
+
+		client := &AIClient{model: mock}
+		got, err := client.CleanRedditPost(ctx, "[H] RTX 3080 + full PC bundle [W] $2000", "Not parting out, everything goes together.", "")
+		if err != nil {
+			t.Fatalf("CleanRedditPost failed: %v", err)
+		}
+		if !got.IsBundle {
+			t.Error("expected the bundle post to be flagged")
+		}
 	})
 
 	t.Run("Retry on failure", func(t *testing.T) {
@@ -83,7 +250,7 @@ func TestCleanRedditPost(t *testing.T) {
 		}
 
 		client := &AIClient{model: mock}
-		_, err := client.CleanRedditPost(ctx, "title", "body")
+		_, err := client.CleanRedditPost(ctx, "title", "body", "")
 
 		if err != nil {
 			t.Errorf("expected success after retry, got error: %v", err)
@@ -93,6 +260,37 @@ func TestCleanRedditPost(t *testing.T) {
 		}
 	})
 
+	t.Run("Retry notifies the caller", func(t *testing.T) {
+		calls := 0
+		mock := &MockModel{
+			GenerateContentFn: func(ctx context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+				calls++
+				if calls < 2 {
+					return nil, errors.New("transient error")
+				}
+				return &genai.GenerateContentResponse{
+					Candidates: []*genai.Candidate{
+						{
+							Content: &genai.Content{
+								Parts: []genai.Part{genai.Text(`{"title":"Success"}`)},
+							},
+						},
+					},
+				}, nil
+			},
+		}
+
+		var attempts []int
+		notifyCtx := WithRetryNotifier(ctx, func(attempt int) { attempts = append(attempts, attempt) })
+		client := &AIClient{model: mock}
+		if _, err := client.CleanRedditPost(notifyCtx, "title", "body", ""); err != nil {
+			t.Fatalf("expected success after retry, got error: %v", err)
+		}
+		if len(attempts) != 1 || attempts[0] != 2 {
+			t.Errorf("expected one notification for attempt 2, got %v", attempts)
+		}
+	})
+
 	t.Run("JSON Parse Error", func(t *testing.T) {
 		mock := &MockModel{
 			GenerateContentFn: func(ctx context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
@@ -109,12 +307,47 @@ func TestCleanRedditPost(t *testing.T) {
 		}
 
 		client := &AIClient{model: mock}
-		_, err := client.CleanRedditPost(ctx, "title", "body")
+		_, err := client.CleanRedditPost(ctx, "title", "body", "")
 
 		if err == nil {
 			t.Error("expected error for invalid JSON, got nil")
 		}
 	})
+
+	t.Run("Prompt override replaces the default instruction", func(t *testing.T) {
+		var instructions []string
+		mock := &MockModel{
+			GenerateContentFn: func(ctx context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+				return &genai.GenerateContentResponse{
+					Candidates: []*genai.Candidate{
+						{
+							Content: &genai.Content{
+								Parts: []genai.Part{genai.Text(`{"title":"Success"}`)},
+							},
+						},
+					},
+				}, nil
+			},
+			SetSystemInstructionFn: func(parts ...genai.Part) {
+				instructions = append(instructions, string(parts[0].(genai.Text)))
+			},
+		}
+
+		client := &AIClient{model: mock}
+		if _, err := client.CleanRedditPost(ctx, "title", "body", "Prices are in USD."); err != nil {
+			t.Fatalf("CleanRedditPost failed: %v", err)
+		}
+		if _, err := client.CleanRedditPost(ctx, "title", "body", ""); err != nil {
+			t.Fatalf("CleanRedditPost failed: %v", err)
+		}
+
+		if len(instructions) != 2 || instructions[0] != "Prices are in USD." {
+			t.Errorf("expected the override to be used as the system instruction, got %v", instructions)
+		}
+		if instructions[1] != CleanPostSystemInstruction {
+			t.Error("expected an empty override to fall back to CleanPostSystemInstruction")
+		}
+	})
 }
 
 func TestRunKeywordWizard(t *testing.T) {
@@ -152,4 +385,52 @@ func TestRunKeywordWizard(t *testing.T) {
 			t.Errorf("unexpected must_have: %v", got.MustHave)
 		}
 	})
+
+	t.Run("Malicious Keywords", func(t *testing.T) {
+		resp := KeywordWizardResponse{
+			MustHave: []string{"3080` **@everyone** `" + strings.Repeat("x", 500), "``", "rtx\n\tfe"},
+			AnyOf:    make([]string, store.MaxAlertTerms+5),
+			IsValid:  true,
+		}
+		for i := range resp.AnyOf {
+			resp.AnyOf[i] = fmt.Sprintf("city%d", i)
+		}
+		respJSON, _ := json.Marshal(resp)
+
+		mock := &MockModel{
+			GenerateContentFn: func(ctx context.Context, parts ...genai.Part) (*genai.GenerateContentResponse, error) {
+				return &genai.GenerateContentResponse{
+					Candidates: []*genai.Candidate{
+						{Content: &genai.Content{Parts: []genai.Part{genai.Text(respJSON)}}},
+					},
+				}, nil
+			},
+		}
+
+		client := &AIClient{model: mock}
+		got, err := client.RunKeywordWizard(ctx, "I want a 3080", "")
+		if err != nil {
+			t.Fatalf("RunKeywordWizard failed: %v", err)
+		}
+
+		if len(got.MustHave) != 2 {
+			t.Fatalf("expected the backtick-only keyword to be dropped, got %q", got.MustHave)
+		}
+		first := got.MustHave[0]
+		if strings.Contains(first, "`") {
+			t.Errorf("expected backticks to be stripped, got %q", first)
+		}
+		if n := utf8.RuneCountInString(first); n != store.MaxAlertTermLength {
+			t.Errorf("expected keyword truncated to %d runes, got %d", store.MaxAlertTermLength, n)
+		}
+		if !strings.HasPrefix(first, "3080 **@everyone** xxx") {
+			t.Errorf("unexpected sanitized keyword %q", first)
+		}
+		if got.MustHave[1] != "rtx fe" {
+			t.Errorf("expected control characters collapsed to spaces, got %q", got.MustHave[1])
+		}
+		if len(got.AnyOf) != store.MaxAlertTerms {
+			t.Errorf("expected any_of capped at %d keywords, got %d", store.MaxAlertTerms, len(got.AnyOf))
+		}
+	})
 }
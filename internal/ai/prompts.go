@@ -1,5 +1,7 @@
 package ai
 
+import "github.com/pauljones0/betterHardwareSwap/internal/store"
+
 const CleanPostSystemInstruction = `You are a concise, highly efficient deal summarizer for a Canadian Hardware Swap Discord feed. 
 Your goal is to make the post readable on a mobile device at a glance.
 
@@ -10,6 +12,8 @@ Instructions:
 4. Extract the Price and Location if mentioned.
 5. Identify the condition (e.g., BNIB, Mint, Used, For Parts).
 6. Provide a succinct 'Description' summarizing the actual hardware specs or known issues.
+7. Provide a one-line 'Summary' of at most 80 characters that lets a reader scan the deal without opening it. Plain text only, no markdown.
+8. Set 'is_bundle' to true when the items are only sold together (e.g. "bundle", "full system", "full PC", "+ everything", "not parting out"). A post listing several items priced separately is not a bundle.
 
 Respond ONLY with a valid JSON object.`
 
@@ -19,10 +23,12 @@ Raw Body: %s
 Respond with JSON matching this schema:
 {
   "title": "Cleaned up title (e.g., [WTS] RTX 3080 FE)",
+  "summary": "One line, max 80 characters (e.g., RTX 3080 FE, lightly used, $500 OBO in Toronto)",
   "description": "Short summary of specs and key details.",
   "price": "$500 OBO",
   "location": "Toronto, ON",
-  "condition": "BNIB"
+  "condition": "BNIB",
+  "is_bundle": false
 }
 `
 
@@ -94,3 +100,26 @@ Respond ONLY with a valid JSON object matching this schema:
   "too_broad": false
 }
 `
+
+// cleanPostLanguageInstructions are appended to the cleaning instruction for servers that read their
+// feed in a language other than English.
+var cleanPostLanguageInstructions = map[store.Language]string{
+	store.LanguageFrench: `
+
+LANGUAGE:
+Write the title, summary, description and condition in Canadian French, translating the post if it is written in English.
+Keep model names, prices, locations and the standard hardware swap abbreviations exactly as they are.`,
+}
+
+// CleanPromptForLanguage returns the cleaning prompt override to use for a server language. English
+// returns promptOverride unchanged, so an empty override still means the built-in instruction.
+func CleanPromptForLanguage(promptOverride string, lang store.Language) string {
+	extra, ok := cleanPostLanguageInstructions[lang]
+	if !ok {
+		return promptOverride
+	}
+	if promptOverride == "" {
+		promptOverride = CleanPostSystemInstruction
+	}
+	return promptOverride + extra
+}
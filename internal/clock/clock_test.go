@@ -0,0 +1,25 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFake(t *testing.T) {
+	start := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+
+	f.Advance(time.Hour)
+	if got := f.Now(); !got.Equal(start.Add(time.Hour)) {
+		t.Errorf("expected Advance to move the clock an hour, got %v", got)
+	}
+
+	select {
+	case fired := <-f.After(30 * time.Second):
+		if want := start.Add(time.Hour + 30*time.Second); !fired.Equal(want) || !f.Now().Equal(want) {
+			t.Errorf("expected After to fire at %v and move the clock there, got %v (now %v)", want, fired, f.Now())
+		}
+	default:
+		t.Error("expected After to fire without waiting")
+	}
+}
@@ -0,0 +1,241 @@
+// Package config loads and checks the server's environment configuration once at startup.
+package config
+
+import (
+	"crypto/ed25519"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultPort is used when PORT is unset (Cloud Run always sets it).
+const defaultPort = "8080"
+
+// DefaultPingMaxPostAge is used when PING_MAX_POST_AGE is unset.
+const DefaultPingMaxPostAge = 3 * time.Hour
+
+// DefaultRedditFetchLimit is used when REDDIT_FETCH_LIMIT is unset: one full page of Reddit's feed.
+const DefaultRedditFetchLimit = 100
+
+// maxRedditFetchLimit is as far back as Reddit lets a listing be paged.
+const maxRedditFetchLimit = 1000
+
+// Default Gemini generation settings. The keyword wizard and manual query checks must return exact
+// keywords, so they sample almost deterministically; cleaning posts gets a little more freedom to write
+// a readable summary. Both are capped so a runaway response can't run up the bill.
+const (
+	DefaultCleanTemperature  = 0.4  // GEMINI_CLEAN_TEMPERATURE
+	DefaultCleanMaxTokens    = 2048 // GEMINI_CLEAN_MAX_TOKENS
+	DefaultWizardTemperature = 0.1  // GEMINI_WIZARD_TEMPERATURE
+	DefaultWizardMaxTokens   = 1024 // GEMINI_WIZARD_MAX_TOKENS
+)
+
+// maxTemperature and maxOutputTokens are the highest settings Gemini accepts.
+const (
+	maxTemperature  = 2.0
+	maxOutputTokens = 65536
+)
+
+// Rate limit backends selectable with RATE_LIMIT_BACKEND.
+const (
+	// RateLimitMemory keeps the interaction rate limit in each instance's memory. This is the default.
+	RateLimitMemory = "memory"
+	// RateLimitFirestore shares the interaction rate limit across instances through Firestore.
+	RateLimitFirestore = "firestore"
+)
+
+// Config is the server's environment configuration. Load it once at startup and hand it to the
+// packages that serve requests rather than reading the environment per request.
+type Config struct {
+	Port             string            // PORT
+	ProjectID        string            // GCP_PROJECT_ID
+	DiscordPublicKey ed25519.PublicKey // DISCORD_PUBLIC_KEY, decoded from hex
+	DiscordBotToken  string            // DISCORD_BOT_TOKEN
+	GeminiAPIKey     string            // GEMINI_API_KEY
+	AdminUserID      string            // ADMIN_USER_ID, optional: enables owner commands, prompt approvals and pipeline failure DMs
+	EncryptionKey    []byte            // BACKEND_API_ENCRYPTION_KEY_HEX, decoded from hex
+	RateLimitBackend string            // RATE_LIMIT_BACKEND, optional: RateLimitMemory (default) or RateLimitFirestore
+	PingMaxPostAge   time.Duration     // PING_MAX_POST_AGE, optional: older posts reach feeds without pinging; 0 disables
+	RedditFetchLimit int               // REDDIT_FETCH_LIMIT, optional: newest posts fetched per scrape, paged 100 at a time
+	RedditUserAgent  string            // REDDIT_USER_AGENT, optional: empty keeps reddit.DefaultUserAgent
+
+	CleanTemperature  float32 // GEMINI_CLEAN_TEMPERATURE, optional: sampling temperature for cleaning posts
+	CleanMaxTokens    int32   // GEMINI_CLEAN_MAX_TOKENS, optional: output cap for cleaning posts
+	WizardTemperature float32 // GEMINI_WIZARD_TEMPERATURE, optional: sampling temperature for the keyword wizard and manual queries
+	WizardMaxTokens   int32   // GEMINI_WIZARD_MAX_TOKENS, optional: output cap for the keyword wizard and manual queries
+}
+
+// Load reads the environment, validates it and returns the resulting Config.
+// The error is a *ValidationError listing every problem found.
+func Load() (*Config, error) {
+	return load(os.Getenv)
+}
+
+func load(getenv func(string) string) (*Config, error) {
+	if err := validate(getenv); err != nil {
+		return nil, err
+	}
+
+	// Both keys were checked by validate, so decoding can't fail here.
+	publicKey, _ := hex.DecodeString(getenv("DISCORD_PUBLIC_KEY"))
+	encryptionKey, _ := hex.DecodeString(getenv("BACKEND_API_ENCRYPTION_KEY_HEX"))
+
+	port := getenv("PORT")
+	if port == "" {
+		port = defaultPort
+	}
+	rateLimitBackend := getenv("RATE_LIMIT_BACKEND")
+	if rateLimitBackend == "" {
+		rateLimitBackend = RateLimitMemory
+	}
+	pingMaxPostAge := DefaultPingMaxPostAge
+	if v := getenv("PING_MAX_POST_AGE"); v != "" {
+		// Checked by validate.
+		pingMaxPostAge, _ = time.ParseDuration(v)
+	}
+	redditFetchLimit := DefaultRedditFetchLimit
+	if v := getenv("REDDIT_FETCH_LIMIT"); v != "" {
+		// Checked by validate.
+		redditFetchLimit, _ = strconv.Atoi(v)
+	}
+
+	return &Config{
+		CleanTemperature:  temperatureOr(getenv("GEMINI_CLEAN_TEMPERATURE"), DefaultCleanTemperature),
+		CleanMaxTokens:    maxTokensOr(getenv("GEMINI_CLEAN_MAX_TOKENS"), DefaultCleanMaxTokens),
+		WizardTemperature: temperatureOr(getenv("GEMINI_WIZARD_TEMPERATURE"), DefaultWizardTemperature),
+		WizardMaxTokens:   maxTokensOr(getenv("GEMINI_WIZARD_MAX_TOKENS"), DefaultWizardMaxTokens),
+		Port:              port,
+		ProjectID:         getenv("GCP_PROJECT_ID"),
+		DiscordPublicKey:  ed25519.PublicKey(publicKey),
+		DiscordBotToken:   getenv("DISCORD_BOT_TOKEN"),
+		GeminiAPIKey:      getenv("GEMINI_API_KEY"),
+		AdminUserID:       getenv("ADMIN_USER_ID"),
+		EncryptionKey:     encryptionKey,
+		RateLimitBackend:  rateLimitBackend,
+		PingMaxPostAge:    pingMaxPostAge,
+		RedditFetchLimit:  redditFetchLimit,
+		RedditUserAgent:   strings.TrimSpace(getenv("REDDIT_USER_AGENT")),
+	}, nil
+}
+
+// temperatureOr parses a temperature checked by validate, or returns def if v is unset.
+func temperatureOr(v string, def float32) float32 {
+	if v == "" {
+		return def
+	}
+	f, _ := strconv.ParseFloat(v, 32)
+	return float32(f)
+}
+
+// maxTokensOr parses an output token cap checked by validate, or returns def if v is unset.
+func maxTokensOr(v string, def int32) int32 {
+	if v == "" {
+		return def
+	}
+	n, _ := strconv.ParseInt(v, 10, 32)
+	return int32(n)
+}
+
+// encryptionKeySize is the length in bytes of BACKEND_API_ENCRYPTION_KEY_HEX once decoded (AES-256).
+const encryptionKeySize = 32
+
+// required lists the environment variables the server can't handle requests without.
+var required = []string{
+	"GCP_PROJECT_ID",
+	"DISCORD_PUBLIC_KEY",
+	"DISCORD_BOT_TOKEN",
+	"GEMINI_API_KEY",
+	"BACKEND_API_ENCRYPTION_KEY_HEX",
+}
+
+// ValidationError lists every problem found with the environment, so they can all be fixed in one go.
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// Validate checks that every required environment variable is set and well-formed.
+// It returns a *ValidationError describing all problems, or nil.
+func Validate() error {
+	return validate(os.Getenv)
+}
+
+func validate(getenv func(string) string) error {
+	var problems []string
+	for _, key := range required {
+		if strings.TrimSpace(getenv(key)) == "" {
+			problems = append(problems, key+" is not set")
+		}
+	}
+
+	if v := getenv("DISCORD_PUBLIC_KEY"); v != "" {
+		if err := checkHexKey(v, ed25519.PublicKeySize); err != nil {
+			problems = append(problems, "DISCORD_PUBLIC_KEY "+err.Error())
+		}
+	}
+	if v := getenv("BACKEND_API_ENCRYPTION_KEY_HEX"); v != "" {
+		if err := checkHexKey(v, encryptionKeySize); err != nil {
+			problems = append(problems, "BACKEND_API_ENCRYPTION_KEY_HEX "+err.Error())
+		}
+	}
+
+	switch getenv("RATE_LIMIT_BACKEND") {
+	case "", RateLimitMemory, RateLimitFirestore:
+	default:
+		problems = append(problems, fmt.Sprintf("RATE_LIMIT_BACKEND must be %q or %q", RateLimitMemory, RateLimitFirestore))
+	}
+
+	if v := getenv("PING_MAX_POST_AGE"); v != "" {
+		if d, err := time.ParseDuration(v); err != nil || d < 0 {
+			problems = append(problems, "PING_MAX_POST_AGE must be a non-negative duration such as 3h or 90m (0 disables it)")
+		}
+	}
+
+	if v := getenv("REDDIT_FETCH_LIMIT"); v != "" {
+		if n, err := strconv.Atoi(v); err != nil || n < 1 || n > maxRedditFetchLimit {
+			problems = append(problems, fmt.Sprintf("REDDIT_FETCH_LIMIT must be a whole number from 1 to %d", maxRedditFetchLimit))
+		}
+	}
+
+	if strings.ContainsAny(getenv("REDDIT_USER_AGENT"), "\r\n") {
+		problems = append(problems, "REDDIT_USER_AGENT must be a single line")
+	}
+
+	for _, key := range []string{"GEMINI_CLEAN_TEMPERATURE", "GEMINI_WIZARD_TEMPERATURE"} {
+		if v := getenv(key); v != "" {
+			if f, err := strconv.ParseFloat(v, 32); err != nil || f < 0 || f > maxTemperature {
+				problems = append(problems, fmt.Sprintf("%s must be a number from 0 to %g", key, maxTemperature))
+			}
+		}
+	}
+	for _, key := range []string{"GEMINI_CLEAN_MAX_TOKENS", "GEMINI_WIZARD_MAX_TOKENS"} {
+		if v := getenv(key); v != "" {
+			if n, err := strconv.Atoi(v); err != nil || n < 1 || n > maxOutputTokens {
+				problems = append(problems, fmt.Sprintf("%s must be a whole number from 1 to %d", key, maxOutputTokens))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &ValidationError{Problems: problems}
+	}
+	return nil
+}
+
+// checkHexKey reports whether v is hex encoding exactly size bytes.
+func checkHexKey(v string, size int) error {
+	b, err := hex.DecodeString(v)
+	if err != nil {
+		return fmt.Errorf("is not valid hex")
+	}
+	if len(b) != size {
+		return fmt.Errorf("must decode to %d bytes, got %d", size, len(b))
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+var validEnv = map[string]string{
+	"GCP_PROJECT_ID":                 "my-project",
+	"DISCORD_PUBLIC_KEY":             strings.Repeat("ab", 32),
+	"DISCORD_BOT_TOKEN":              "token",
+	"GEMINI_API_KEY":                 "gemini",
+	"BACKEND_API_ENCRYPTION_KEY_HEX": strings.Repeat("01", 32),
+}
+
+func TestValidate(t *testing.T) {
+	valid := validEnv
+
+	tests := []struct {
+		name      string
+		overrides map[string]string
+		want      []string // Substrings expected among the problems; empty means valid
+	}{
+		{
+			name: "All set",
+		},
+		{
+			name:      "Missing vars are all reported",
+			overrides: map[string]string{"GCP_PROJECT_ID": "", "GEMINI_API_KEY": "  "},
+			want:      []string{"GCP_PROJECT_ID is not set", "GEMINI_API_KEY is not set"},
+		},
+		{
+			name:      "Public key not hex",
+			overrides: map[string]string{"DISCORD_PUBLIC_KEY": "not-hex"},
+			want:      []string{"DISCORD_PUBLIC_KEY is not valid hex"},
+		},
+		{
+			name:      "Public key wrong length",
+			overrides: map[string]string{"DISCORD_PUBLIC_KEY": "abcd"},
+			want:      []string{"DISCORD_PUBLIC_KEY must decode to 32 bytes, got 2"},
+		},
+		{
+			name:      "Encryption key wrong length",
+			overrides: map[string]string{"BACKEND_API_ENCRYPTION_KEY_HEX": strings.Repeat("01", 16)},
+			want:      []string{"BACKEND_API_ENCRYPTION_KEY_HEX must decode to 32 bytes, got 16"},
+		},
+		{
+			name:      "Firestore rate limit backend",
+			overrides: map[string]string{"RATE_LIMIT_BACKEND": RateLimitFirestore},
+		},
+		{
+			name:      "Unknown rate limit backend",
+			overrides: map[string]string{"RATE_LIMIT_BACKEND": "redis"},
+			want:      []string{"RATE_LIMIT_BACKEND must be"},
+		},
+		{
+			name:      "Ping age limit disabled",
+			overrides: map[string]string{"PING_MAX_POST_AGE": "0"},
+		},
+		{
+			name:      "Malformed ping age limit",
+			overrides: map[string]string{"PING_MAX_POST_AGE": "3 hours"},
+			want:      []string{"PING_MAX_POST_AGE must be"},
+		},
+		{
+			name:      "Negative ping age limit",
+			overrides: map[string]string{"PING_MAX_POST_AGE": "-1h"},
+			want:      []string{"PING_MAX_POST_AGE must be"},
+		},
+		{
+			name:      "Small fetch limit",
+			overrides: map[string]string{"REDDIT_FETCH_LIMIT": "25"},
+		},
+		{
+			name:      "Zero fetch limit",
+			overrides: map[string]string{"REDDIT_FETCH_LIMIT": "0"},
+			want:      []string{"REDDIT_FETCH_LIMIT must be"},
+		},
+		{
+			name:      "Fetch limit past Reddit's listing depth",
+			overrides: map[string]string{"REDDIT_FETCH_LIMIT": "1001"},
+			want:      []string{"REDDIT_FETCH_LIMIT must be"},
+		},
+		{
+			name:      "Custom user agent",
+			overrides: map[string]string{"REDDIT_USER_AGENT": "script:mydealbot:v1.0 (by u/someone)"},
+		},
+		{
+			name:      "Multi-line user agent",
+			overrides: map[string]string{"REDDIT_USER_AGENT": "mydealbot\r\nX-Injected: 1"},
+			want:      []string{"REDDIT_USER_AGENT must be"},
+		},
+		{
+			name:      "Custom generation settings",
+			overrides: map[string]string{"GEMINI_CLEAN_TEMPERATURE": "0", "GEMINI_WIZARD_TEMPERATURE": "1.5", "GEMINI_CLEAN_MAX_TOKENS": "4096"},
+		},
+		{
+			name:      "Temperature out of range",
+			overrides: map[string]string{"GEMINI_CLEAN_TEMPERATURE": "2.5", "GEMINI_WIZARD_TEMPERATURE": "cold"},
+			want:      []string{"GEMINI_CLEAN_TEMPERATURE must be", "GEMINI_WIZARD_TEMPERATURE must be"},
+		},
+		{
+			name:      "Zero token cap",
+			overrides: map[string]string{"GEMINI_WIZARD_MAX_TOKENS": "0"},
+			want:      []string{"GEMINI_WIZARD_MAX_TOKENS must be"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := make(map[string]string)
+			for k, v := range valid {
+				env[k] = v
+			}
+			for k, v := range tt.overrides {
+				env[k] = v
+			}
+
+			err := validate(func(key string) string { return env[key] })
+
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("expected a valid config, got %v", err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("expected a *ValidationError, got %v", err)
+			}
+			if len(verr.Problems) != len(tt.want) {
+				t.Errorf("expected %d problems, got %v", len(tt.want), verr.Problems)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(err.Error(), w) {
+					t.Errorf("expected %q in %q", w, err.Error())
+				}
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	env := map[string]string{"ADMIN_USER_ID": "owner1"}
+	for k, v := range validEnv {
+		env[k] = v
+	}
+
+	cfg, err := load(func(key string) string { return env[key] })
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+
+	if cfg.Port != defaultPort {
+		t.Errorf("expected the default port, got %q", cfg.Port)
+	}
+	if cfg.ProjectID != "my-project" || cfg.DiscordBotToken != "token" || cfg.GeminiAPIKey != "gemini" || cfg.AdminUserID != "owner1" {
+		t.Errorf("unexpected string fields %+v", cfg)
+	}
+	if !bytes.Equal(cfg.DiscordPublicKey, bytes.Repeat([]byte{0xab}, 32)) {
+		t.Errorf("expected the public key to be decoded, got %x", cfg.DiscordPublicKey)
+	}
+	if len(cfg.EncryptionKey) != encryptionKeySize {
+		t.Errorf("expected a %d byte encryption key, got %d", encryptionKeySize, len(cfg.EncryptionKey))
+	}
+
+	if cfg.RateLimitBackend != RateLimitMemory {
+		t.Errorf("expected the in-memory rate limiter by default, got %q", cfg.RateLimitBackend)
+	}
+	if cfg.PingMaxPostAge != DefaultPingMaxPostAge {
+		t.Errorf("expected the default ping age limit, got %v", cfg.PingMaxPostAge)
+	}
+
+	env["PING_MAX_POST_AGE"] = "90m"
+	if cfg, _ := load(func(key string) string { return env[key] }); cfg == nil || cfg.PingMaxPostAge != 90*time.Minute {
+		t.Errorf("expected PING_MAX_POST_AGE to override the default, got %+v", cfg)
+	}
+
+	if cfg, _ := load(func(key string) string { return env[key] }); cfg == nil || cfg.RedditFetchLimit != DefaultRedditFetchLimit {
+		t.Errorf("expected the default fetch limit, got %+v", cfg)
+	}
+	env["REDDIT_FETCH_LIMIT"] = "300"
+	if cfg, _ := load(func(key string) string { return env[key] }); cfg == nil || cfg.RedditFetchLimit != 300 {
+		t.Errorf("expected REDDIT_FETCH_LIMIT to override the default, got %+v", cfg)
+	}
+
+	if cfg, _ := load(func(key string) string { return env[key] }); cfg == nil || cfg.RedditUserAgent != "" {
+		t.Errorf("expected no user agent override by default, got %+v", cfg)
+	}
+	env["REDDIT_USER_AGENT"] = " script:mydealbot:v1.0 (by u/someone) "
+	if cfg, _ := load(func(key string) string { return env[key] }); cfg == nil || cfg.RedditUserAgent != "script:mydealbot:v1.0 (by u/someone)" {
+		t.Errorf("expected REDDIT_USER_AGENT to be used, got %+v", cfg)
+	}
+
+	if cfg, _ := load(func(key string) string { return env[key] }); cfg == nil ||
+		cfg.CleanTemperature != DefaultCleanTemperature || cfg.CleanMaxTokens != DefaultCleanMaxTokens ||
+		cfg.WizardTemperature != DefaultWizardTemperature || cfg.WizardMaxTokens != DefaultWizardMaxTokens {
+		t.Errorf("expected the default generation settings, got %+v", cfg)
+	}
+	env["GEMINI_CLEAN_TEMPERATURE"] = "0.7"
+	env["GEMINI_WIZARD_MAX_TOKENS"] = "256"
+	if cfg, _ := load(func(key string) string { return env[key] }); cfg == nil ||
+		cfg.CleanTemperature != 0.7 || cfg.WizardMaxTokens != 256 || cfg.CleanMaxTokens != DefaultCleanMaxTokens {
+		t.Errorf("expected the generation settings to override the defaults, got %+v", cfg)
+	}
+
+	env["PORT"] = "9090"
+	if cfg, _ := load(func(key string) string { return env[key] }); cfg == nil || cfg.Port != "9090" {
+		t.Errorf("expected PORT to override the default, got %+v", cfg)
+	}
+
+	env["GEMINI_API_KEY"] = ""
+	if _, err := load(func(key string) string { return env[key] }); err == nil {
+		t.Error("expected load to fail when a required variable is missing")
+	}
+}
@@ -0,0 +1,311 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+)
+
+// serversPageSize is how many servers are listed per page of `/servers`.
+const serversPageSize = 10
+
+// isBotOwner reports whether the given user is the bot operator configured via ADMIN_USER_ID.
+func (h *Handler) isBotOwner(userID string) bool {
+	adminID := h.cfg.AdminUserID
+	return adminID != "" && userID == adminID
+}
+
+// memberIsAdmin double-checks that the member running a server-wide command can manage the server.
+// Discord enforces the command's default permissions, but a server can override them, so the handler
+// doesn't rely on that alone. Interactions outside a server (no Member) are never admin.
+func memberIsAdmin(i *discordgo.Interaction) bool {
+	if i.Member == nil {
+		return false
+	}
+	return i.Member.Permissions&(discordgo.PermissionAdministrator|discordgo.PermissionManageServer) != 0
+}
+
+// handleServers lists every server the bot is configured in. Restricted to the bot owner.
+func (h *Handler) handleServers(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
+	if !h.isBotOwner(interactionUserID(i)) {
+		respondError(w, "This command is restricted to the bot owner.")
+		return
+	}
+
+	embed, components, err := buildServersPage(ctx, h.db, 0)
+	if err != nil {
+		log.Printf("Failed to list servers: %v", err)
+		respondError(w, "Failed to load servers.")
+		return
+	}
+
+	writeJSON(w, discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: components,
+			Flags:      discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// buildServersPage renders one page of the configured-servers list along with its pagination buttons.
+func buildServersPage(ctx context.Context, db Storer, page int) (*discordgo.MessageEmbed, []discordgo.MessageComponent, error) {
+	servers, err := db.GetAllServers(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	totalPages := (len(servers) + serversPageSize - 1) / serversPageSize
+	if totalPages == 0 {
+		totalPages = 1
+	}
+	if page < 0 {
+		page = 0
+	}
+	if page >= totalPages {
+		page = totalPages - 1
+	}
+
+	start := page * serversPageSize
+	end := start + serversPageSize
+	if end > len(servers) {
+		end = len(servers)
+	}
+
+	desc := ""
+	if len(servers) == 0 {
+		desc = "No servers have run `/setup` yet."
+	}
+	for idx, cfg := range servers[start:end] {
+		count, err := db.CountServerAlerts(ctx, cfg.ServerID)
+		countText := fmt.Sprintf("%d alerts", count)
+		if err != nil {
+			log.Printf("Failed to count alerts for server %s: %v", cfg.ServerID, err)
+			countText = "? alerts"
+		}
+		desc += fmt.Sprintf("**%d.** `%s` • %s • feed <#%s>\n", start+idx+1, cfg.ServerID, countText, cfg.FeedChannelID)
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("🌐 Configured Servers (%d)", len(servers)),
+		Description: desc,
+		Color:       0x00B0F4,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("Page %d of %d", page+1, totalPages),
+		},
+	}
+
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "◀ Prev",
+					Style:    discordgo.SecondaryButton,
+					CustomID: fmt.Sprintf("servers_page|%d", page-1),
+					Disabled: page == 0,
+				},
+				discordgo.Button{
+					Label:    "Next ▶",
+					Style:    discordgo.SecondaryButton,
+					CustomID: fmt.Sprintf("servers_page|%d", page+1),
+					Disabled: page >= totalPages-1,
+				},
+			},
+		},
+	}
+
+	return embed, components, nil
+}
+
+// maxBypassMinutes caps how long `/bypass` can lift a user's rate limit.
+const maxBypassMinutes = 120
+
+// handleBypass lifts the interaction rate limit for a user for a few minutes via `/bypass @user <minutes>`,
+// so they can bulk import or edit alerts. Minutes of 0 revokes it. Restricted to the bot owner. The limiter
+// is per instance, so the bypass only applies to the instance that served the command.
+func (h *Handler) handleBypass(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
+	if !h.isBotOwner(interactionUserID(i)) {
+		respondError(w, "This command is restricted to the bot owner.")
+		return
+	}
+
+	userID, minutes := "", 0
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "user":
+			userID, _ = opt.Value.(string)
+		case "minutes":
+			minutes = int(opt.IntValue())
+		}
+	}
+	if userID == "" {
+		respondError(w, "Pick a user to exempt.")
+		return
+	}
+	if minutes < 0 || minutes > maxBypassMinutes {
+		respondError(w, fmt.Sprintf("Pick between 0 and %d minutes.", maxBypassMinutes))
+		return
+	}
+
+	until := time.Now().Add(time.Duration(minutes) * time.Minute)
+	h.limiter.GrantBypass(userID, until)
+	log.Printf("Rate limit bypass for user %s set to %d minutes", userID, minutes)
+
+	content := fmt.Sprintf("✅ <@%s> is back under the normal rate limit.", userID)
+	if minutes > 0 {
+		content = fmt.Sprintf("⏩ <@%s> can skip the rate limit until <t:%d:t>.", userID, until.Unix())
+	}
+	writeJSON(w, discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// handleStats compares how alerts built by the AI wizard perform against manually written ones.
+// Restricted to the bot owner.
+func (h *Handler) handleStats(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
+	if !h.isBotOwner(interactionUserID(i)) {
+		respondError(w, "This command is restricted to the bot owner.")
+		return
+	}
+
+	perf, err := h.db.GetAlertPerformance(ctx)
+	if err != nil {
+		log.Printf("Failed to load alert performance: %v", err)
+		respondError(w, "Failed to load stats.")
+		return
+	}
+
+	writeJSON(w, discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{buildStatsEmbed(perf)},
+			Flags:  discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// buildStatsEmbed renders one field per creation flow with its alert match summary.
+func buildStatsEmbed(perf []store.AlertPerformance) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title:       "📈 Alert Performance",
+		Description: "How often confirmed alerts have matched a deal, by how they were created.",
+		Color:       0x00B0F4,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: "Only alerts whose analytics haven't been compacted yet are counted.",
+		},
+	}
+	if len(perf) == 0 {
+		embed.Description = "No confirmed alerts have been recorded yet."
+		return embed
+	}
+
+	for _, p := range perf {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name: flowLabel(p.FlowType),
+			Value: fmt.Sprintf("**%d** active (%d deleted) • **%d** have fired • **%d** matches (%.1f per alert)",
+				p.Alerts, p.Deleted, p.FiringAlerts, p.Matches, p.MatchesPerAlert()),
+			Inline: false,
+		})
+	}
+	return embed
+}
+
+// flowLabel names an alert creation flow for display.
+func flowLabel(flowType string) string {
+	switch flowType {
+	case "wizard":
+		return "✨ AI Wizard Alerts"
+	case "manual":
+		return "⌨️ Manual Alerts"
+	default:
+		return flowType + " alerts"
+	}
+}
+
+// Bounds on how many runs `/runs` lists.
+const (
+	defaultRunsShown = 10
+	maxRunsShown     = 25
+)
+
+// maxRunsDescriptionLen keeps the `/runs` embed under Discord's 4096 character description limit.
+const maxRunsDescriptionLen = 4000
+
+// handleRuns lists the most recent scrape pipeline runs via `/runs [count]`. Restricted to the bot owner.
+func (h *Handler) handleRuns(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
+	if !h.isBotOwner(interactionUserID(i)) {
+		respondError(w, "This command is restricted to the bot owner.")
+		return
+	}
+
+	count := defaultRunsShown
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == "count" {
+			count = int(opt.IntValue())
+		}
+	}
+	if count < 1 || count > maxRunsShown {
+		respondError(w, fmt.Sprintf("Pick between 1 and %d runs.", maxRunsShown))
+		return
+	}
+
+	runs, err := h.db.GetRecentPipelineRuns(ctx, count)
+	if err != nil {
+		log.Printf("Failed to load pipeline runs: %v", err)
+		respondError(w, "Failed to load pipeline runs.")
+		return
+	}
+
+	writeJSON(w, discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{buildRunsEmbed(runs)},
+			Flags:  discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// buildRunsEmbed renders one line per pipeline run, newest first, with failed runs marked and their error shown.
+func buildRunsEmbed(runs []store.PipelineRun) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title: fmt.Sprintf("🛰️ Recent Pipeline Runs (%d)", len(runs)),
+		Color: 0x00B0F4,
+	}
+	if len(runs) == 0 {
+		embed.Description = "No pipeline runs have been recorded yet."
+		return embed
+	}
+
+	var b strings.Builder
+	for _, run := range runs {
+		status := "✅"
+		if run.Error != "" {
+			status = "❌"
+		} else if run.Errors > 0 {
+			status = "⚠️"
+		}
+		line := fmt.Sprintf("%s <t:%d:R> • %d fetched • %d new • %d matches • %d errors • %s\n",
+			status, run.StartedAt.Unix(), run.PostsFetched, run.NewPosts, run.Matches, run.Errors, run.Duration.Round(time.Millisecond))
+		if run.Error != "" {
+			line += fmt.Sprintf("-# %s\n", truncateField(run.Error, 150))
+		}
+		if b.Len()+len(line) > maxRunsDescriptionLen {
+			b.WriteString("…")
+			break
+		}
+		b.WriteString(line)
+	}
+	embed.Description = b.String()
+	return embed
+}
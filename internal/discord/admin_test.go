@@ -0,0 +1,498 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pauljones0/betterHardwareSwap/internal/config"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+	"github.com/pauljones0/betterHardwareSwap/internal/testutils"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestIsBotOwner(t *testing.T) {
+	h := NewHandler(&config.Config{AdminUserID: "owner1"}, nil, nil, nil)
+
+	if !h.isBotOwner("owner1") {
+		t.Error("expected owner1 to be recognised as the bot owner")
+	}
+	if h.isBotOwner("user2") {
+		t.Error("expected user2 to be rejected")
+	}
+	if h.isBotOwner("") {
+		t.Error("expected an empty user ID to be rejected")
+	}
+}
+
+func TestMemberIsAdmin(t *testing.T) {
+	tests := []struct {
+		name string
+		i    *discordgo.Interaction
+		want bool
+	}{
+		{"Administrator", &discordgo.Interaction{Member: &discordgo.Member{Permissions: discordgo.PermissionAdministrator}}, true},
+		{"Manage Server", &discordgo.Interaction{Member: &discordgo.Member{Permissions: discordgo.PermissionManageServer | discordgo.PermissionSendMessages}}, true},
+		{"Regular member", &discordgo.Interaction{Member: &discordgo.Member{Permissions: discordgo.PermissionSendMessages}}, false},
+		{"DM", &discordgo.Interaction{User: &discordgo.User{ID: "user1"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := memberIsAdmin(tt.i); got != tt.want {
+				t.Errorf("memberIsAdmin() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleInteraction_SetupRejectsNonAdmin(t *testing.T) {
+	th := newInteractionHarness(t)
+
+	resp := th.serve(t, discordgo.Interaction{
+		ID:      "interaction_setup",
+		Type:    discordgo.InteractionApplicationCommand,
+		GuildID: "guild1",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "setup_user"}, Permissions: discordgo.PermissionSendMessages},
+		Data: discordgo.ApplicationCommandInteractionData{
+			Name: "setup",
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Name: "feed_channel", Type: discordgo.ApplicationCommandOptionChannel, Value: "feed1"},
+				{Name: "ping_channel", Type: discordgo.ApplicationCommandOptionChannel, Value: "ping1"},
+			},
+		},
+	})
+
+	if resp.Data == nil || resp.Data.Flags != discordgo.MessageFlagsEphemeral || !strings.Contains(resp.Data.Content, "Only server admins") {
+		t.Errorf("expected an ephemeral admin-only rejection, got %+v", resp.Data)
+	}
+	th.db.AssertNotCalled(t, "SaveServerConfig", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestBuildServersPage(t *testing.T) {
+	ctx := context.Background()
+
+	var servers []store.ServerConfig
+	for n := 1; n <= 12; n++ {
+		servers = append(servers, store.ServerConfig{ServerID: fmt.Sprintf("guild%02d", n), FeedChannelID: "feed"})
+	}
+
+	mockDB := new(testutils.MockStore)
+	mockDB.On("GetAllServers", mock.Anything).Return(servers, nil)
+	mockDB.On("CountServerAlerts", mock.Anything, mock.Anything).Return(int64(4), nil)
+
+	embed, components, err := buildServersPage(ctx, mockDB, 1)
+	if err != nil {
+		t.Fatalf("buildServersPage failed: %v", err)
+	}
+
+	if !strings.Contains(embed.Description, "guild11") || !strings.Contains(embed.Description, "guild12") {
+		t.Errorf("expected the second page to list guild11 and guild12, got %q", embed.Description)
+	}
+	if strings.Contains(embed.Description, "guild01") {
+		t.Errorf("expected the second page to exclude first-page servers, got %q", embed.Description)
+	}
+	if embed.Footer.Text != "Page 2 of 2" {
+		t.Errorf("unexpected footer %q", embed.Footer.Text)
+	}
+	mockDB.AssertNumberOfCalls(t, "CountServerAlerts", 2)
+
+	buttons := components[0].(discordgo.ActionsRow).Components
+	if buttons[0].(discordgo.Button).Disabled {
+		t.Error("expected Prev to be enabled on the last page")
+	}
+	if !buttons[1].(discordgo.Button).Disabled {
+		t.Error("expected Next to be disabled on the last page")
+	}
+}
+
+func TestBuildStatsEmbed(t *testing.T) {
+	embed := buildStatsEmbed([]store.AlertPerformance{
+		{FlowType: "manual", Alerts: 2, FiringAlerts: 1, Matches: 3, Deleted: 1},
+		{FlowType: "wizard", Alerts: 4, FiringAlerts: 3, Matches: 10},
+	})
+
+	if len(embed.Fields) != 2 {
+		t.Fatalf("expected one field per flow, got %d", len(embed.Fields))
+	}
+	if embed.Fields[1].Name != "✨ AI Wizard Alerts" || !strings.Contains(embed.Fields[1].Value, "2.5 per alert") {
+		t.Errorf("unexpected wizard field %q: %q", embed.Fields[1].Name, embed.Fields[1].Value)
+	}
+
+	if empty := buildStatsEmbed(nil); len(empty.Fields) != 0 || !strings.Contains(empty.Description, "No confirmed alerts") {
+		t.Errorf("expected an empty-state embed, got %+v", empty)
+	}
+}
+
+func TestBuildRunsEmbed(t *testing.T) {
+	now := time.Now()
+	embed := buildRunsEmbed([]store.PipelineRun{
+		{StartedAt: now, Duration: 1500 * time.Millisecond, PostsFetched: 25, NewPosts: 3, Matches: 4},
+		{StartedAt: now.Add(-time.Minute), PostsFetched: 25, NewPosts: 2, Errors: 1},
+		{StartedAt: now.Add(-2 * time.Minute), Error: "failed to fetch reddit: reddit down"},
+	})
+
+	lines := strings.Split(strings.TrimSpace(embed.Description), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected a line per run plus the error, got %q", embed.Description)
+	}
+	if !strings.HasPrefix(lines[0], "✅") || !strings.Contains(lines[0], "25 fetched • 3 new • 4 matches • 0 errors • 1.5s") {
+		t.Errorf("unexpected first line %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "⚠️") || !strings.HasPrefix(lines[2], "❌") {
+		t.Errorf("expected partial and failed runs to be marked, got %q", embed.Description)
+	}
+	if !strings.Contains(lines[3], "reddit down") {
+		t.Errorf("expected the failed run's error, got %q", lines[3])
+	}
+
+	if embed := buildRunsEmbed(nil); embed.Description != "No pipeline runs have been recorded yet." {
+		t.Errorf("unexpected empty description %q", embed.Description)
+	}
+}
+
+func TestHandleInteraction_RunsCommand(t *testing.T) {
+	th := newInteractionHarness(t)
+	th.handler.cfg.AdminUserID = "runs_owner"
+	th.db.On("GetRecentPipelineRuns", mock.Anything, 5).Return([]store.PipelineRun{{StartedAt: time.Now(), PostsFetched: 25}}, nil)
+
+	resp := th.serve(t, discordgo.Interaction{
+		ID:   "interaction_runs",
+		Type: discordgo.InteractionApplicationCommand,
+		User: &discordgo.User{ID: "runs_owner"},
+		Data: discordgo.ApplicationCommandInteractionData{
+			Name:    "runs",
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{{Name: "count", Type: discordgo.ApplicationCommandOptionInteger, Value: float64(5)}},
+		},
+	})
+
+	if resp.Data == nil || len(resp.Data.Embeds) != 1 || !strings.Contains(resp.Data.Embeds[0].Description, "25 fetched") {
+		t.Errorf("expected the runs embed, got %+v", resp.Data)
+	}
+	th.db.AssertExpectations(t)
+}
+
+func TestSetupPicker(t *testing.T) {
+	data := setupPicker("feed1", "")
+	if len(data.Components) != 2 {
+		t.Fatalf("expected a feed and a ping menu, got %d rows", len(data.Components))
+	}
+	feed := data.Components[0].(discordgo.ActionsRow).Components[0].(discordgo.SelectMenu)
+	ping := data.Components[1].(discordgo.ActionsRow).Components[0].(discordgo.SelectMenu)
+
+	if feed.MenuType != discordgo.ChannelSelectMenu || feed.CustomID != "setup_pick|feed|feed1|" || ping.CustomID != "setup_pick|ping|feed1|" {
+		t.Errorf("expected channel menus carrying the feed pick, got %+v and %+v", feed, ping)
+	}
+	if len(feed.DefaultValues) != 1 || feed.DefaultValues[0].ID != "feed1" || len(ping.DefaultValues) != 0 {
+		t.Errorf("expected only the picked feed channel to be preselected, got %+v and %+v", feed.DefaultValues, ping.DefaultValues)
+	}
+}
+
+func TestHandleInteraction_SetupPicker(t *testing.T) {
+	th := newInteractionHarness(t)
+	// Interactions are rate limited per user, so each step comes from a different admin. The picks so far
+	// travel in the menus' custom IDs, so it doesn't matter who makes them.
+	admin := func(id string) *discordgo.Member {
+		return &discordgo.Member{User: &discordgo.User{ID: id}, Permissions: discordgo.PermissionManageServer}
+	}
+	pick := func(id, customID, channelID string) discordgo.Interaction {
+		return discordgo.Interaction{
+			ID:      id,
+			Type:    discordgo.InteractionMessageComponent,
+			GuildID: "guild1",
+			Member:  admin(id),
+			Data: discordgo.MessageComponentInteractionData{
+				CustomID:      customID,
+				ComponentType: discordgo.ChannelSelectMenuComponent,
+				Values:        []string{channelID},
+			},
+		}
+	}
+	// Components are interfaces that don't unmarshal, so only count them.
+	type menuResponse struct {
+		Type discordgo.InteractionResponseType `json:"type"`
+		Data *struct {
+			Components []json.RawMessage `json:"components"`
+		} `json:"data"`
+	}
+
+	th.db.On("GetServerConfig", mock.Anything, "guild1").Return(nil, store.ErrNotFound)
+
+	var bare menuResponse
+	th.serveInto(t, discordgo.Interaction{
+		ID:      "interaction_setup_bare",
+		Type:    discordgo.InteractionApplicationCommand,
+		GuildID: "guild1",
+		Member:  admin("picker_admin"),
+		Data:    discordgo.ApplicationCommandInteractionData{Name: "setup"},
+	}, &bare)
+	if bare.Data == nil || len(bare.Data.Components) != 2 {
+		t.Fatalf("expected bare /setup to show two channel menus, got %+v", bare.Data)
+	}
+
+	// Picking only the feed channel redraws the menus.
+	var redrawn menuResponse
+	th.serveInto(t, pick("interaction_pick_feed", "setup_pick|feed||", "feed1"), &redrawn)
+	if redrawn.Type != discordgo.InteractionResponseUpdateMessage || redrawn.Data == nil || len(redrawn.Data.Components) != 2 {
+		t.Fatalf("expected the menus to be redrawn, got %+v", redrawn)
+	}
+	th.db.AssertNotCalled(t, "SaveServerConfig", mock.Anything, mock.Anything, mock.Anything)
+
+	th.db.On("SaveServerConfig", mock.Anything, "guild1", mock.MatchedBy(func(cfg store.ServerConfig) bool {
+		return cfg.FeedChannelID == "feed1" && cfg.PingChannelID == "ping1" &&
+			cfg.FeedMode == store.FeedModeAlertsOnly && cfg.Language == store.LanguageEnglish
+	})).Return(nil)
+	welcomed := make(chan struct{})
+	th.client.On("SendMessage", "ping1", mock.Anything).Return(nil).Run(func(mock.Arguments) { close(welcomed) })
+
+	// The second pick completes and saves the setup.
+	resp := th.serve(t, pick("interaction_pick_ping", "setup_pick|ping|feed1|", "ping1"))
+	if resp.Type != discordgo.InteractionResponseUpdateMessage || resp.Data == nil || !strings.Contains(resp.Data.Content, "Setup Complete") {
+		t.Errorf("expected the menus to be replaced by the setup summary, got %+v", resp)
+	}
+	select {
+	case <-welcomed:
+	case <-time.After(2 * time.Second):
+		t.Fatal("welcome message was never sent")
+	}
+	th.db.AssertExpectations(t)
+}
+
+func TestHandleInteraction_SetupUpdatesOneChannel(t *testing.T) {
+	th := newInteractionHarness(t)
+	existing := &store.ServerConfig{
+		ServerID:      "guild1",
+		FeedChannelID: "feed1",
+		PingChannelID: "ping1",
+		FeedMode:      store.FeedModeAllDeals,
+		Language:      store.LanguageFrench,
+		MinScore:      5,
+		GlobalMustNot: []string{"scam"},
+	}
+	th.db.On("GetServerConfig", mock.Anything, "guild1").Return(existing, nil)
+	th.db.On("SaveServerConfig", mock.Anything, "guild1", mock.MatchedBy(func(cfg store.ServerConfig) bool {
+		return cfg.FeedChannelID == "feed1" && cfg.PingChannelID == "ping2" && cfg.FeedMode == store.FeedModeAllDeals &&
+			cfg.Language == store.LanguageFrench && cfg.MinScore == 5 && len(cfg.GlobalMustNot) == 1
+	})).Return(nil)
+
+	resp := th.serve(t, discordgo.Interaction{
+		ID:      "interaction_setup_ping",
+		Type:    discordgo.InteractionApplicationCommand,
+		GuildID: "guild1",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "ping_admin"}, Permissions: discordgo.PermissionManageServer},
+		Data: discordgo.ApplicationCommandInteractionData{
+			Name: "setup",
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Name: "ping_channel", Type: discordgo.ApplicationCommandOptionChannel, Value: "ping2"},
+			},
+		},
+	})
+
+	if resp.Data == nil || !strings.Contains(resp.Data.Content, "<#feed1>") || !strings.Contains(resp.Data.Content, "<#ping2>") {
+		t.Errorf("expected the summary to show the kept feed and new ping channel, got %+v", resp.Data)
+	}
+	if !strings.Contains(resp.Data.Content, "Configuration mise à jour") {
+		t.Errorf("expected a reconfigure to say the configuration was updated, got %q", resp.Data.Content)
+	}
+	// The welcome is sent from a goroutine, so give a stray one time to land before checking.
+	time.Sleep(50 * time.Millisecond)
+	th.client.AssertNotCalled(t, "SendMessage", mock.Anything, mock.Anything)
+	th.db.AssertExpectations(t)
+}
+
+func TestHandleInteraction_SetupNeedsBothChannelsFirstTime(t *testing.T) {
+	th := newInteractionHarness(t)
+	th.db.On("GetServerConfig", mock.Anything, "guild_new").Return(nil, store.ErrNotFound)
+
+	resp := th.serve(t, discordgo.Interaction{
+		ID:      "interaction_setup_first",
+		Type:    discordgo.InteractionApplicationCommand,
+		GuildID: "guild_new",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "first_admin"}, Permissions: discordgo.PermissionManageServer},
+		Data: discordgo.ApplicationCommandInteractionData{
+			Name: "setup",
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Name: "ping_channel", Type: discordgo.ApplicationCommandOptionChannel, Value: "ping1"},
+			},
+		},
+	})
+
+	if resp.Data == nil || !strings.Contains(resp.Data.Content, "required the first time") {
+		t.Errorf("expected a new server to need both channels, got %+v", resp.Data)
+	}
+	th.db.AssertNotCalled(t, "SaveServerConfig", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandleInteraction_SetupKeepsConfigWhenUnreadable(t *testing.T) {
+	th := newInteractionHarness(t)
+	th.db.On("GetServerConfig", mock.Anything, "guild1").Return(nil, errors.New("unavailable"))
+
+	resp := th.serve(t, discordgo.Interaction{
+		ID:      "interaction_setup_outage",
+		Type:    discordgo.InteractionApplicationCommand,
+		GuildID: "guild1",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "outage_admin"}, Permissions: discordgo.PermissionManageServer},
+		Data: discordgo.ApplicationCommandInteractionData{
+			Name: "setup",
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Name: "feed_channel", Type: discordgo.ApplicationCommandOptionChannel, Value: "feed2"},
+				{Name: "ping_channel", Type: discordgo.ApplicationCommandOptionChannel, Value: "ping2"},
+			},
+		},
+	})
+
+	if resp.Data == nil || !strings.Contains(resp.Data.Content, "Failed to load the current configuration") {
+		t.Errorf("expected the admin to be told to retry, got %+v", resp.Data)
+	}
+	th.db.AssertNotCalled(t, "SaveServerConfig", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandleInteraction_SetupPickRejectsNonAdmin(t *testing.T) {
+	th := newInteractionHarness(t)
+
+	resp := th.serve(t, discordgo.Interaction{
+		ID:      "interaction_pick_forged",
+		Type:    discordgo.InteractionMessageComponent,
+		GuildID: "guild1",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "picker_user"}, Permissions: discordgo.PermissionSendMessages},
+		Data: discordgo.MessageComponentInteractionData{
+			CustomID:      "setup_pick|ping|feed1|",
+			ComponentType: discordgo.ChannelSelectMenuComponent,
+			Values:        []string{"ping1"},
+		},
+	})
+
+	if resp.Data == nil || !strings.Contains(resp.Data.Content, "Only server admins") {
+		t.Errorf("expected a non-admin pick to be rejected, got %+v", resp.Data)
+	}
+	th.db.AssertNotCalled(t, "SaveServerConfig", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandleInteraction_SetupChecksWebhookChannel(t *testing.T) {
+	const hookURL = "https://discord.com/api/webhooks/123/tok"
+	existing := &store.ServerConfig{ServerID: "guild1", FeedChannelID: "feed1", PingChannelID: "ping1"}
+	setup := func(th *interactionHarness, userID string) discordgo.InteractionResponse {
+		return th.serve(t, discordgo.Interaction{
+			ID:      "interaction_setup_webhook",
+			Type:    discordgo.InteractionApplicationCommand,
+			GuildID: "guild1",
+			Member:  &discordgo.Member{User: &discordgo.User{ID: userID}, Permissions: discordgo.PermissionManageServer},
+			Data: discordgo.ApplicationCommandInteractionData{
+				Name: "setup",
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{
+					{Name: "feed_webhook", Type: discordgo.ApplicationCommandOptionString, Value: hookURL},
+				},
+			},
+		})
+	}
+
+	t.Run("Other channel is refused", func(t *testing.T) {
+		th := newInteractionHarness(t)
+		th.db.On("GetServerConfig", mock.Anything, "guild1").Return(existing, nil)
+		th.client.On("GetWebhookChannel", hookURL).Return("other1", nil)
+
+		resp := setup(th, "webhook_admin1")
+
+		if resp.Data == nil || !strings.Contains(resp.Data.Content, "posts to <#other1>, not the feed channel <#feed1>") {
+			t.Errorf("expected a webhook in another channel to be refused, got %+v", resp.Data)
+		}
+		th.db.AssertNotCalled(t, "SaveServerConfig", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Feed channel is saved", func(t *testing.T) {
+		th := newInteractionHarness(t)
+		th.db.On("GetServerConfig", mock.Anything, "guild1").Return(existing, nil)
+		th.client.On("GetWebhookChannel", hookURL).Return("feed1", nil)
+		th.db.On("SaveServerConfig", mock.Anything, "guild1", mock.MatchedBy(func(cfg store.ServerConfig) bool {
+			return cfg.FeedWebhookURL == hookURL
+		})).Return(nil)
+
+		setup(th, "webhook_admin2")
+
+		th.db.AssertExpectations(t)
+	})
+}
+
+func TestHandleInteraction_SetupClearsWebhook(t *testing.T) {
+	th := newInteractionHarness(t)
+	const hookURL = "https://discord.com/api/webhooks/123/tok"
+	th.db.On("GetServerConfig", mock.Anything, "guild1").Return(&store.ServerConfig{
+		ServerID: "guild1", FeedChannelID: "feed1", PingChannelID: "ping1", FeedWebhookURL: hookURL,
+	}, nil)
+	th.db.On("SaveServerConfig", mock.Anything, "guild1", mock.MatchedBy(func(cfg store.ServerConfig) bool {
+		return cfg.FeedWebhookURL == "" && cfg.FeedChannelID == "feed2" && cfg.PingChannelID == "ping1"
+	})).Return(nil)
+
+	th.serve(t, discordgo.Interaction{
+		ID:      "interaction_setup_clear_webhook",
+		Type:    discordgo.InteractionApplicationCommand,
+		GuildID: "guild1",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "clear_webhook_admin"}, Permissions: discordgo.PermissionManageServer},
+		Data: discordgo.ApplicationCommandInteractionData{
+			Name: "setup",
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Name: "clear", Type: discordgo.ApplicationCommandOptionString, Value: "feed_webhook"},
+				{Name: "feed_channel", Type: discordgo.ApplicationCommandOptionChannel, Value: "feed2"},
+			},
+		},
+	})
+
+	th.db.AssertExpectations(t)
+	th.client.AssertNotCalled(t, "GetWebhookChannel", mock.Anything)
+}
+
+func TestHandleInteraction_SetupClearsArchiveChannel(t *testing.T) {
+	th := newInteractionHarness(t)
+	th.db.On("GetServerConfig", mock.Anything, "guild1").Return(&store.ServerConfig{
+		ServerID: "guild1", FeedChannelID: "feed1", PingChannelID: "ping1", ArchiveChannelID: "archive1", MinScore: 5,
+	}, nil)
+	th.db.On("SaveServerConfig", mock.Anything, "guild1", mock.MatchedBy(func(cfg store.ServerConfig) bool {
+		return cfg.ArchiveChannelID == "" && cfg.FeedChannelID == "feed1" && cfg.PingChannelID == "ping1" && cfg.MinScore == 5
+	})).Return(nil)
+
+	th.serve(t, discordgo.Interaction{
+		ID:      "interaction_setup_clear_archive",
+		Type:    discordgo.InteractionApplicationCommand,
+		GuildID: "guild1",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "clear_archive_admin"}, Permissions: discordgo.PermissionManageServer},
+		Data: discordgo.ApplicationCommandInteractionData{
+			Name: "setup",
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				{Name: "clear", Type: discordgo.ApplicationCommandOptionString, Value: "archive_channel"},
+			},
+		},
+	})
+
+	th.db.AssertExpectations(t)
+}
+
+func TestHandleInteraction_SetupPickChecksWebhookChannel(t *testing.T) {
+	th := newInteractionHarness(t)
+	const hookURL = "https://discord.com/api/webhooks/123/tok"
+	th.db.On("GetServerConfig", mock.Anything, "guild1").Return(&store.ServerConfig{
+		ServerID: "guild1", FeedChannelID: "feed1", PingChannelID: "ping1", FeedWebhookURL: hookURL,
+	}, nil)
+	th.client.On("GetWebhookChannel", hookURL).Return("feed1", nil)
+
+	resp := th.serve(t, discordgo.Interaction{
+		ID:      "interaction_pick_webhook",
+		Type:    discordgo.InteractionMessageComponent,
+		GuildID: "guild1",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "pick_webhook_admin"}, Permissions: discordgo.PermissionManageServer},
+		Data: discordgo.MessageComponentInteractionData{
+			CustomID:      "setup_pick|feed|feed1|ping1",
+			ComponentType: discordgo.ChannelSelectMenuComponent,
+			Values:        []string{"feed2"},
+		},
+	})
+
+	if resp.Data == nil || !strings.Contains(resp.Data.Content, "not the feed channel <#feed2>") {
+		t.Errorf("expected moving the feed away from its webhook to be refused, got %+v", resp.Data)
+	}
+	th.db.AssertNotCalled(t, "SaveServerConfig", mock.Anything, mock.Anything, mock.Anything)
+}
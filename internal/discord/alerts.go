@@ -5,29 +5,31 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"os"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
-	"github.com/pauljones0/betterHardwareSwap/internal/ai"
 	"github.com/pauljones0/betterHardwareSwap/internal/store"
 )
 
+// minTrendingComments is the lowest comment threshold a trending alert accepts.
+const minTrendingComments = 5
+
 // handleAlertList fetches a user's alerts and displays them with inline delete buttons.
-func handleAlertList(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
-	db, err := store.NewStore(ctx, os.Getenv("GCP_PROJECT_ID"))
-	if err != nil {
-		respondError(w, "Database connection error.")
-		return
-	}
-	defer db.Close()
+func (h *Handler) handleAlertList(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
+	listAlerts(ctx, w, h.db, i)
+}
 
-	userID := i.Member.User.ID
+// listAlerts writes the alert list for the interaction's scope: the current server, or the
+// user's DM scope when the command is used from a DM.
+func listAlerts(ctx context.Context, w http.ResponseWriter, db Storer, i *discordgo.Interaction) {
+	userID := interactionUserID(i)
 	if userID == "" {
 		respondError(w, "Could not identify user.")
 		return
 	}
+	scope := alertScope(i)
 
-	alerts, err := db.GetUserAlerts(ctx, i.GuildID, userID)
+	alerts, err := db.GetUserAlerts(ctx, scope, userID)
 	if err != nil {
 		log.Printf("Error fetching user alerts for user %s: %v", userID, err)
 		respondError(w, "Failed to load alerts.")
@@ -38,7 +40,7 @@ func handleAlertList(ctx context.Context, w http.ResponseWriter, i *discordgo.In
 		writeJSON(w, discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseChannelMessageWithSource,
 			Data: &discordgo.InteractionResponseData{
-				Content: "You don't have any active alerts setup for this server.",
+				Content: "You don't have any active alerts setup " + scopeNoun(scope) + ".",
 				Flags:   discordgo.MessageFlagsEphemeral,
 			},
 		})
@@ -47,20 +49,34 @@ func handleAlertList(ctx context.Context, w http.ResponseWriter, i *discordgo.In
 
 	var rows []discordgo.MessageComponent
 	desc := ""
+	now := time.Now()
 	for idx, a := range alerts {
 		if idx >= 4 {
 			desc += "\n*...and more.*"
 			break
 		}
-		desc += fmt.Sprintf("**Alert #%d:** \"%s\"\n", idx+1, a.RawQuery)
+		bodyNote := ""
+		if a.SearchBody {
+			bodyNote = " *(searches full post)*"
+		}
+		if a.ExcludeBundles {
+			bodyNote += " *(skips bundles)*"
+		}
+		if a.Paused {
+			bodyNote += " *(paused)*"
+		} else if a.Snoozed(now) {
+			bodyNote += fmt.Sprintf(" *(snoozed until <t:%d:d>)*", a.SnoozeUntil.Unix())
+		}
+		desc += fmt.Sprintf("**Alert #%d:** \"%s\"%s\n", idx+1, a.RawQuery, bodyNote)
 		btnRow := discordgo.ActionsRow{
-			Components: []discordgo.MessageComponent{
+			Components: append([]discordgo.MessageComponent{
 				discordgo.Button{
 					Label:    fmt.Sprintf("🗑️ Delete #%d", idx+1),
 					Style:    discordgo.SecondaryButton,
 					CustomID: "delete_alert|" + a.ID,
 				},
-			},
+				searchBodyButton(idx+1, a),
+			}, snoozeButtons(idx+1, a, now)...),
 		}
 		rows = append(rows, btnRow)
 	}
@@ -91,55 +107,214 @@ func handleAlertList(ctx context.Context, w http.ResponseWriter, i *discordgo.In
 	})
 }
 
-func triggerCompaction(serverID string) {
-	ctx := context.Background()
-	db, err := store.NewStore(ctx, os.Getenv("GCP_PROJECT_ID"))
+// handleAlertTrending saves a trending alert via `/alert trending <comments>`, replacing the user's previous
+// one in this scope. Trending alerts ping once when a deal already in the feed reaches that many comments.
+func (h *Handler) handleAlertTrending(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
+	threshold := 0
+	for _, opt := range i.ApplicationCommandData().Options[0].Options {
+		if opt.Name == "comments" {
+			threshold = int(opt.IntValue())
+		}
+	}
+
+	content, err := runAlertTrending(ctx, h.db, alertScope(i), interactionUserID(i), threshold)
 	if err != nil {
+		log.Printf("Failed to save trending alert for user %s: %v", interactionUserID(i), err)
+		respondError(w, "Failed to save the trending alert.")
 		return
 	}
-	defer db.Close()
 
-	aiSvc, err := ai.NewAIClient(ctx, os.Getenv("GEMINI_API_KEY"))
+	writeJSON(w, discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// runAlertTrending replaces the user's trending alert in scope and returns the confirmation to show them.
+func runAlertTrending(ctx context.Context, db Storer, scope, userID string, threshold int) (string, error) {
+	if threshold < minTrendingComments {
+		return fmt.Sprintf("⚠️ Pick at least %d comments, or every deal will count as trending.", minTrendingComments), nil
+	}
+
+	existing, err := db.GetUserAlerts(ctx, scope, userID)
 	if err != nil {
+		return "", err
+	}
+	for _, a := range existing {
+		if a.TrendingComments > 0 {
+			if err := db.DeleteAlert(ctx, a.ID); err != nil {
+				return "", err
+			}
+		}
+	}
+
+	rule := store.AlertRule{
+		UserID:           userID,
+		ServerID:         scope,
+		RawQuery:         fmt.Sprintf("Trending: %d+ comments", threshold),
+		TrendingComments: threshold,
+	}
+	if err := db.AddAlert(ctx, rule); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("🔥 You'll be pinged once whenever a deal reaches **%d** comments. Remove it from `/alert list`.", threshold), nil
+}
+
+// handleAlertSetAllEnabled handles `/alert pause-all` and `/alert resume-all`, e.g. for going on vacation.
+func (h *Handler) handleAlertSetAllEnabled(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction, enabled bool) {
+	content, err := runAlertSetAllEnabled(ctx, h.db, alertScope(i), interactionUserID(i), enabled)
+	if err != nil {
+		log.Printf("Failed to update alerts for user %s: %v", interactionUserID(i), err)
+		respondError(w, "Failed to update your alerts.")
 		return
 	}
-	defer aiSvc.Close()
 
-	client := NewClient(os.Getenv("DISCORD_BOT_TOKEN"))
-	adminID := os.Getenv("ADMIN_USER_ID")
+	writeJSON(w, discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
 
-	flows := []string{"wizard", "manual"}
-	for _, flowType := range flows {
-		records, err := db.GetUnprocessedAnalyticsByFlow(ctx, flowType, 20)
-		if err != nil || len(records) < 20 {
-			continue
-		}
+// runAlertSetAllEnabled pauses or resumes all of the user's alerts in scope and returns the confirmation
+// to show them. Snoozes are left alone, so a resumed alert that is also snoozed stays quiet until it wakes.
+func runAlertSetAllEnabled(ctx context.Context, db Storer, scope, userID string, enabled bool) (string, error) {
+	changed, err := db.SetAllAlertsEnabled(ctx, scope, userID, enabled)
+	if err != nil && changed == 0 {
+		return "", err
+	}
 
-		sysPrompt, _ := db.GetSystemPrompt(ctx, flowType+"_prompt")
-		if sysPrompt == "" {
-			if flowType == "wizard" {
-				sysPrompt = ai.DefaultWizardPrompt
-			} else {
-				sysPrompt = ai.DefaultManualPrompt
-			}
+	noun := "alerts"
+	if changed == 1 {
+		noun = "alert"
+	}
+	if err != nil {
+		// Some batches committed before the failure, so say how far it got rather than that nothing changed.
+		log.Printf("Updated %d alerts for user %s before failing: %v", changed, userID, err)
+		verb := "paused"
+		if enabled {
+			verb = "resumed"
 		}
+		return fmt.Sprintf("⚠️ Only %s **%d** %s %s before an error. Run the command again to finish.", verb, changed, noun, scopeNoun(scope)), nil
+	}
+	switch {
+	case changed == 0 && enabled:
+		return "None of your alerts " + scopeNoun(scope) + " are paused.", nil
+	case changed == 0:
+		return "You have no alerts " + scopeNoun(scope) + " left to pause.", nil
+	case enabled:
+		return fmt.Sprintf("▶️ Resumed **%d** %s %s. They'll match new deals again.", changed, noun, scopeNoun(scope)), nil
+	default:
+		return fmt.Sprintf("⏸️ Paused **%d** %s %s. Use `/alert resume-all` when you're back.", changed, noun, scopeNoun(scope)), nil
+	}
+}
 
-		result, err := aiSvc.RunCompaction(ctx, records, sysPrompt, flowType)
-		if err != nil || result == nil {
-			log.Printf("Compaction failed for %s: %v", flowType, err)
-			continue
+// handleAlertBundles handles `/alert bundles exclude:<bool>`, which sets whether the user's alerts skip
+// bundle listings (items only sold together, like a GPU in a full PC).
+func (h *Handler) handleAlertBundles(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
+	exclude := false
+	for _, opt := range i.ApplicationCommandData().Options[0].Options {
+		if opt.Name == "exclude" {
+			exclude = opt.BoolValue()
 		}
+	}
+
+	content, err := runAlertExcludeBundles(ctx, h.db, alertScope(i), interactionUserID(i), exclude)
+	if err != nil {
+		log.Printf("Failed to update bundle setting for user %s: %v", interactionUserID(i), err)
+		respondError(w, "Failed to update your alerts.")
+		return
+	}
+
+	writeJSON(w, discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// runAlertExcludeBundles sets whether all of the user's alerts in scope skip bundle listings and returns
+// the confirmation to show them.
+func runAlertExcludeBundles(ctx context.Context, db Storer, scope, userID string, exclude bool) (string, error) {
+	changed, err := db.SetAllAlertsExcludeBundles(ctx, scope, userID, exclude)
+	if err != nil && changed == 0 {
+		return "", err
+	}
+
+	noun := "alerts"
+	if changed == 1 {
+		noun = "alert"
+	}
+	if err != nil {
+		log.Printf("Updated bundle setting on %d alerts for user %s before failing: %v", changed, userID, err)
+		return fmt.Sprintf("⚠️ Only updated **%d** %s %s before an error. Run the command again to finish.", changed, noun, scopeNoun(scope)), nil
+	}
+	switch {
+	case changed == 0 && exclude:
+		return "All of your alerts " + scopeNoun(scope) + " already skip bundles.", nil
+	case changed == 0:
+		return "All of your alerts " + scopeNoun(scope) + " already include bundles.", nil
+	case exclude:
+		return fmt.Sprintf("📦 **%d** %s %s will now skip bundles, like a GPU only sold as part of a full PC.", changed, noun, scopeNoun(scope)), nil
+	default:
+		return fmt.Sprintf("📦 **%d** %s %s will match bundles again.", changed, noun, scopeNoun(scope)), nil
+	}
+}
+
+// scopeNoun describes where a scope's alerts live, for user-facing messages.
+func scopeNoun(scope string) string {
+	if _, ok := store.DMScopeUser(scope); ok {
+		return "in your DMs"
+	}
+	return "on this server"
+}
 
-		if adminID == "" {
-			continue
+// searchBodyButton toggles whether an alert also matches the raw Reddit body, not just the AI summary.
+// The custom ID carries the value to switch to.
+func searchBodyButton(n int, a store.AlertRule) discordgo.Button {
+	if a.SearchBody {
+		return discordgo.Button{
+			Label:    fmt.Sprintf("📝 #%d: Summary Only", n),
+			Style:    discordgo.SecondaryButton,
+			CustomID: "search_body|" + a.ID + "|0",
 		}
+	}
+	return discordgo.Button{
+		Label:    fmt.Sprintf("🔎 #%d: Search Full Post", n),
+		Style:    discordgo.SecondaryButton,
+		CustomID: "search_body|" + a.ID + "|1",
+	}
+}
 
-		err = client.SendAdminApprovalDM(adminID, result.NewPrompt, flowType)
-		if err != nil && serverID != "" {
-			cfg, _ := db.GetServerConfig(ctx, serverID)
-			if cfg != nil && cfg.PingChannelID != "" {
-				_ = client.SendFallbackAdminApproval(cfg.PingChannelID, adminID, result.NewPrompt, flowType)
-			}
+// snoozeDays are the snooze lengths offered on each alert in `/alert list`.
+var snoozeDays = []int{1, 7, 30}
+
+// snoozeButtons offers to snooze an active alert for each of snoozeDays, or to wake a snoozed one.
+// The custom ID carries the number of days, with 0 meaning wake.
+func snoozeButtons(n int, a store.AlertRule, now time.Time) []discordgo.MessageComponent {
+	if a.Snoozed(now) {
+		return []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    fmt.Sprintf("🔔 #%d: Wake", n),
+				Style:    discordgo.SecondaryButton,
+				CustomID: "snooze_alert|" + a.ID + "|0",
+			},
 		}
 	}
+	buttons := make([]discordgo.MessageComponent, 0, len(snoozeDays))
+	for _, days := range snoozeDays {
+		buttons = append(buttons, discordgo.Button{
+			Label:    fmt.Sprintf("💤 #%d: %dd", n, days),
+			Style:    discordgo.SecondaryButton,
+			CustomID: fmt.Sprintf("snooze_alert|%s|%d", a.ID, days),
+		})
+	}
+	return buttons
 }
@@ -0,0 +1,182 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+	"github.com/pauljones0/betterHardwareSwap/internal/testutils"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestListAlerts_DMScope(t *testing.T) {
+	i := &discordgo.Interaction{User: &discordgo.User{ID: "user1"}}
+
+	mockDB := new(testutils.MockStore)
+	mockDB.On("GetUserAlerts", mock.Anything, "dm:user1", "user1").Return([]store.AlertRule{
+		{ID: "alert1", UserID: "user1", ServerID: "dm:user1", RawQuery: "Cheap 4090"},
+	}, nil)
+
+	rr := httptest.NewRecorder()
+	listAlerts(context.Background(), rr, mockDB, i)
+
+	mockDB.AssertExpectations(t)
+
+	// Components are interfaces that don't unmarshal, so only decode the embeds.
+	var resp struct {
+		Data *struct {
+			Embeds []*discordgo.MessageEmbed `json:"embeds"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Data == nil || len(resp.Data.Embeds) != 1 {
+		t.Fatalf("expected the alert list embed, got %+v", resp.Data)
+	}
+	if !strings.Contains(resp.Data.Embeds[0].Description, "Cheap 4090") {
+		t.Errorf("expected the DM-scoped alert to be listed, got %q", resp.Data.Embeds[0].Description)
+	}
+}
+
+func TestListAlerts_DMScopeEmpty(t *testing.T) {
+	i := &discordgo.Interaction{User: &discordgo.User{ID: "user1"}}
+
+	mockDB := new(testutils.MockStore)
+	mockDB.On("GetUserAlerts", mock.Anything, "dm:user1", "user1").Return(nil, nil)
+
+	rr := httptest.NewRecorder()
+	listAlerts(context.Background(), rr, mockDB, i)
+
+	var resp discordgo.InteractionResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Data == nil || !strings.Contains(resp.Data.Content, "in your DMs") {
+		t.Errorf("expected the empty DM list message, got %+v", resp.Data)
+	}
+}
+
+func TestRunAlertTrending(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Replaces the previous trending alert", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+		mockDB.On("GetUserAlerts", mock.Anything, "guild1", "user1").Return([]store.AlertRule{
+			{ID: "old_trending", TrendingComments: 50},
+			{ID: "keywords", MustHave: []string{"3080"}},
+		}, nil)
+		mockDB.On("DeleteAlert", mock.Anything, "old_trending").Return(nil)
+		mockDB.On("AddAlert", mock.Anything, mock.MatchedBy(func(rule store.AlertRule) bool {
+			return rule.UserID == "user1" && rule.ServerID == "guild1" && rule.TrendingComments == 20 && store.ValidateAlertRule(rule) == nil
+		})).Return(nil)
+
+		content, err := runAlertTrending(ctx, mockDB, "guild1", "user1", 20)
+		if err != nil {
+			t.Fatalf("runAlertTrending failed: %v", err)
+		}
+		if !strings.Contains(content, "**20** comments") {
+			t.Errorf("unexpected confirmation %q", content)
+		}
+		mockDB.AssertExpectations(t)
+		mockDB.AssertNotCalled(t, "DeleteAlert", mock.Anything, "keywords")
+	})
+
+	t.Run("Rejects tiny thresholds", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+
+		content, err := runAlertTrending(ctx, mockDB, "guild1", "user1", 1)
+		if err != nil || !strings.Contains(content, "at least 5") {
+			t.Errorf("expected a threshold warning, got %q, %v", content, err)
+		}
+		mockDB.AssertNotCalled(t, "AddAlert", mock.Anything, mock.Anything)
+	})
+}
+
+func TestRunAlertSetAllEnabled(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		enabled bool
+		changed int
+		want    string
+	}{
+		{"Pause reports the count", false, 3, "Paused **3** alerts on this server"},
+		{"Resume reports the count", true, 1, "Resumed **1** alert on this server"},
+		{"Nothing to pause", false, 0, "no alerts on this server left to pause"},
+		{"Nothing to resume", true, 0, "None of your alerts on this server are paused"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := new(testutils.MockStore)
+			mockDB.On("SetAllAlertsEnabled", mock.Anything, "guild1", "user1", tt.enabled).Return(tt.changed, nil)
+
+			content, err := runAlertSetAllEnabled(ctx, mockDB, "guild1", "user1", tt.enabled)
+			if err != nil {
+				t.Fatalf("runAlertSetAllEnabled failed: %v", err)
+			}
+			if !strings.Contains(content, tt.want) {
+				t.Errorf("expected %q in %q", tt.want, content)
+			}
+			mockDB.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRunAlertSetAllEnabled_PartialFailure(t *testing.T) {
+	ctx := context.Background()
+	mockDB := new(testutils.MockStore)
+	mockDB.On("SetAllAlertsEnabled", mock.Anything, "guild1", "user1", false).Return(500, errors.New("batch failed"))
+
+	content, err := runAlertSetAllEnabled(ctx, mockDB, "guild1", "user1", false)
+	if err != nil {
+		t.Fatalf("expected a partial write to be reported, not an error: %v", err)
+	}
+	if !strings.Contains(content, "Only paused **500** alerts") {
+		t.Errorf("expected the committed count in %q", content)
+	}
+
+	failed := new(testutils.MockStore)
+	failed.On("SetAllAlertsEnabled", mock.Anything, "guild1", "user1", false).Return(0, errors.New("batch failed"))
+	if _, err := runAlertSetAllEnabled(ctx, failed, "guild1", "user1", false); err == nil {
+		t.Error("expected an error when nothing was written")
+	}
+}
+
+func TestRunAlertExcludeBundles(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		exclude bool
+		changed int
+		want    string
+	}{
+		{"Exclude reports the count", true, 2, "**2** alerts on this server will now skip bundles"},
+		{"Include reports the count", false, 1, "**1** alert on this server will match bundles again"},
+		{"Already excluded", true, 0, "already skip bundles"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := new(testutils.MockStore)
+			mockDB.On("SetAllAlertsExcludeBundles", mock.Anything, "guild1", "user1", tt.exclude).Return(tt.changed, nil)
+
+			content, err := runAlertExcludeBundles(ctx, mockDB, "guild1", "user1", tt.exclude)
+			if err != nil {
+				t.Fatalf("runAlertExcludeBundles failed: %v", err)
+			}
+			if !strings.Contains(content, tt.want) {
+				t.Errorf("expected %q in %q", tt.want, content)
+			}
+			mockDB.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+)
+
+const (
+	// maxBlocklistTerms caps how many terms a server can block.
+	maxBlocklistTerms = 50
+	// maxBlocklistTermLength caps the length of a single blocked term.
+	maxBlocklistTermLength = 50
+)
+
+// handleBlocklist manages the server-wide blocklist via `/blocklist add|remove|list`.
+func (h *Handler) handleBlocklist(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
+	// Discord hides the command from non-admins by default, but servers can override that, so check again.
+	if i.GuildID == "" || !memberIsAdmin(i) {
+		respondError(w, "Only server admins (Manage Server permission) can manage the blocklist.")
+		return
+	}
+
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		respondError(w, "Unknown subcommand")
+		return
+	}
+
+	subCommand := options[0].Name
+	term := ""
+	for _, opt := range options[0].Options {
+		if opt.Name == "term" {
+			term = opt.StringValue()
+		}
+	}
+
+	content, err := runBlocklistCommand(ctx, h.db, i.GuildID, subCommand, term)
+	if err != nil {
+		log.Printf("Blocklist %s failed for server %s: %v", subCommand, i.GuildID, err)
+		respondError(w, "Failed to update the blocklist.")
+		return
+	}
+
+	writeJSON(w, discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// runBlocklistCommand applies a blocklist subcommand and returns the message to show the admin.
+// User mistakes are reported in the message; only storage failures are returned as errors.
+func runBlocklistCommand(ctx context.Context, db Storer, serverID, subCommand, term string) (string, error) {
+	cfg, err := db.GetServerConfig(ctx, serverID)
+	if errors.Is(err, store.ErrNotFound) {
+		return "⚠️ This server isn't set up yet. Run `/setup` first.", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	term = strings.ToLower(strings.TrimSpace(term))
+
+	switch subCommand {
+	case "add":
+		if term == "" {
+			return "⚠️ Please provide a term to block.", nil
+		}
+		if len(term) > maxBlocklistTermLength {
+			return fmt.Sprintf("⚠️ Blocked terms can be at most %d characters.", maxBlocklistTermLength), nil
+		}
+		for _, existing := range cfg.GlobalMustNot {
+			if existing == term {
+				return fmt.Sprintf("`%s` is already blocked.", term), nil
+			}
+		}
+		if len(cfg.GlobalMustNot) >= maxBlocklistTerms {
+			return fmt.Sprintf("⚠️ The blocklist is full (%d terms). Remove a term before adding another.", maxBlocklistTerms), nil
+		}
+		if err := db.AddBlocklistTerm(ctx, serverID, term); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("🚫 Deals mentioning `%s` will no longer be posted in this server.", term), nil
+
+	case "remove":
+		found := false
+		for _, existing := range cfg.GlobalMustNot {
+			if existing == term {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Sprintf("`%s` isn't on the blocklist.", term), nil
+		}
+		if err := db.RemoveBlocklistTerm(ctx, serverID, term); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("✅ `%s` removed from the blocklist.", term), nil
+
+	case "list":
+		if len(cfg.GlobalMustNot) == 0 {
+			return "The blocklist is empty. Use `/blocklist add` to block spammy terms.", nil
+		}
+		return fmt.Sprintf("🚫 **Blocked terms (%d):**\n`%s`", len(cfg.GlobalMustNot), strings.Join(cfg.GlobalMustNot, "`, `")), nil
+
+	default:
+		return "⚠️ Unknown subcommand.", nil
+	}
+}
@@ -0,0 +1,123 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+	"github.com/pauljones0/betterHardwareSwap/internal/testutils"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRunBlocklistCommand(t *testing.T) {
+	ctx := context.Background()
+	cfg := &store.ServerConfig{ServerID: "guild1", FeedChannelID: "feed1", GlobalMustNot: []string{"giveaway"}}
+
+	tests := []struct {
+		name       string
+		subCommand string
+		term       string
+		setup      func(m *testutils.MockStore)
+		want       string
+	}{
+		{
+			name:       "Add normalizes and stores the term",
+			subCommand: "add",
+			term:       "  Mining Rig ",
+			setup: func(m *testutils.MockStore) {
+				m.On("AddBlocklistTerm", mock.Anything, "guild1", "mining rig").Return(nil)
+			},
+			want: "`mining rig` will no longer be posted",
+		},
+		{
+			name:       "Add skips duplicates",
+			subCommand: "add",
+			term:       "Giveaway",
+			want:       "already blocked",
+		},
+		{
+			name:       "Remove deletes a blocked term",
+			subCommand: "remove",
+			term:       "giveaway",
+			setup: func(m *testutils.MockStore) {
+				m.On("RemoveBlocklistTerm", mock.Anything, "guild1", "giveaway").Return(nil)
+			},
+			want: "removed from the blocklist",
+		},
+		{
+			name:       "Remove reports unknown terms",
+			subCommand: "remove",
+			term:       "crypto",
+			want:       "isn't on the blocklist",
+		},
+		{
+			name:       "List shows blocked terms",
+			subCommand: "list",
+			want:       "`giveaway`",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := new(testutils.MockStore)
+			mockDB.On("GetServerConfig", mock.Anything, "guild1").Return(cfg, nil)
+			if tt.setup != nil {
+				tt.setup(mockDB)
+			}
+
+			got, err := runBlocklistCommand(ctx, mockDB, "guild1", tt.subCommand, tt.term)
+			if err != nil {
+				t.Fatalf("runBlocklistCommand failed: %v", err)
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("expected response to contain %q, got %q", tt.want, got)
+			}
+			mockDB.AssertExpectations(t)
+		})
+	}
+}
+
+func TestRunBlocklistCommand_ConfigErrors(t *testing.T) {
+	ctx := context.Background()
+
+	mockDB := new(testutils.MockStore)
+	mockDB.On("GetServerConfig", mock.Anything, "guild_new").Return(nil, store.ErrNotFound)
+	got, err := runBlocklistCommand(ctx, mockDB, "guild_new", "list", "")
+	if err != nil || !strings.Contains(got, "isn't set up yet") {
+		t.Errorf("expected a server that was never set up to be told to run /setup, got %q, %v", got, err)
+	}
+
+	mockDB = new(testutils.MockStore)
+	mockDB.On("GetServerConfig", mock.Anything, "guild1").Return(nil, errors.New("unavailable"))
+	if _, err := runBlocklistCommand(ctx, mockDB, "guild1", "list", ""); err == nil {
+		t.Error("expected a storage failure to be returned rather than reported as not set up")
+	}
+}
+
+func TestHandleInteraction_BlocklistRequiresAdmin(t *testing.T) {
+	th := newInteractionHarness(t)
+
+	resp := th.serve(t, discordgo.Interaction{
+		ID:      "interaction_blocklist",
+		Type:    discordgo.InteractionApplicationCommand,
+		GuildID: "guild1",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "blocklist_user"}, Permissions: discordgo.PermissionSendMessages},
+		Data: discordgo.ApplicationCommandInteractionData{
+			Name: "blocklist",
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{{
+				Name:    "add",
+				Type:    discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandInteractionDataOption{{Name: "term", Type: discordgo.ApplicationCommandOptionString, Value: "mining"}},
+			}},
+		},
+	})
+
+	if resp.Data == nil || !strings.Contains(resp.Data.Content, "Only server admins") {
+		t.Errorf("expected an admin-only rejection, got %+v", resp.Data)
+	}
+	th.db.AssertNotCalled(t, "GetServerConfig", mock.Anything, mock.Anything)
+	th.db.AssertNotCalled(t, "AddBlocklistTerm", mock.Anything, mock.Anything, mock.Anything)
+}
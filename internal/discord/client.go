@@ -3,26 +3,59 @@ package discord
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 )
 
 const discordAPI = "https://discord.com/api/v10"
 
+// maxWebhookRetryWait caps how long a rate-limited webhook request waits before its one retry.
+const maxWebhookRetryWait = 5 * time.Second
+
 // Client is a wrapper around the Discord REST API to perform actions the Interaction webhook cannot
 // (e.g. sending proactive messages to channels, editing messages, adding reactions).
 type Client struct {
 	token      string
+	baseURL    string
 	httpClient *http.Client
 }
 
+// APIError is returned when Discord responds with a non-2xx status.
+type APIError struct {
+	StatusCode int
+	Body       string
+	// RetryAfter is how long Discord asked us to wait before retrying. Only set on 429 responses.
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("discord API error %d: %s", e.StatusCode, e.Body)
+}
+
+// IsRateLimited reports whether Discord rejected the request with a 429.
+func (e *APIError) IsRateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
 // NewClient initializes a new Discord REST client.
 func NewClient(token string) *Client {
+	return NewClientWithBaseURL(token, discordAPI)
+}
+
+// NewClientWithBaseURL initializes a Discord REST client against a custom API root, e.g. an httptest server.
+func NewClientWithBaseURL(token, baseURL string) *Client {
 	return &Client{
 		token:      token,
+		baseURL:    baseURL,
 		httpClient: &http.Client{},
 	}
 }
@@ -37,13 +70,54 @@ func (c *Client) doRequest(method, endpoint string, body interface{}) ([]byte, e
 		bodyReader = bytes.NewReader(b)
 	}
 
-	req, err := http.NewRequest(method, discordAPI+endpoint, bodyReader)
+	req, err := http.NewRequest(method, c.baseURL+endpoint, bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.send(req)
+}
+
+// doFileRequest sends payload together with one file attachment, as Discord's multipart upload expects.
+func (c *Client) doFileRequest(method, endpoint string, payload interface{}, filename string, data []byte) ([]byte, error) {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	if err := mw.WriteField("payload_json", string(payloadJSON)); err != nil {
+		return nil, err
+	}
+	fw, err := mw.CreateFormFile("files[0]", filename)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fw.Write(data); err != nil {
+		return nil, err
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, c.baseURL+endpoint, &buf)
 	if err != nil {
 		return nil, err
 	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return c.send(req)
+}
 
+// send authenticates req, performs it, and turns non-2xx responses into an *APIError.
+func (c *Client) send(req *http.Request) ([]byte, error) {
 	req.Header.Set("Authorization", "Bot "+c.token)
-	req.Header.Set("Content-Type", "application/json")
+	return c.do(req)
+}
+
+// do performs req as-is and turns non-2xx responses into an *APIError. Webhook requests go through
+// here directly since the webhook URL carries its own credentials.
+func (c *Client) do(req *http.Request) ([]byte, error) {
 	req.Header.Set("User-Agent", "DiscordBot (https://github.com/pauljones0/betterHardwareSwap, 1.0.0)")
 
 	resp, err := c.httpClient.Do(req)
@@ -55,12 +129,30 @@ func (c *Client) doRequest(method, endpoint string, body interface{}) ([]byte, e
 	respBody, _ := io.ReadAll(resp.Body)
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return nil, fmt.Errorf("discord API error %d: %s", resp.StatusCode, string(respBody))
+		apiErr := &APIError{StatusCode: resp.StatusCode, Body: string(respBody)}
+		if apiErr.IsRateLimited() {
+			apiErr.RetryAfter = parseRetryAfter(resp, respBody)
+		}
+		return nil, apiErr
 	}
 
 	return respBody, nil
 }
 
+// parseRetryAfter reads the rate limit wait from a 429 body, falling back to the Retry-After header.
+func parseRetryAfter(resp *http.Response, body []byte) time.Duration {
+	var rl struct {
+		RetryAfter float64 `json:"retry_after"`
+	}
+	if err := json.Unmarshal(body, &rl); err == nil && rl.RetryAfter > 0 {
+		return time.Duration(rl.RetryAfter * float64(time.Second))
+	}
+	if secs, err := strconv.ParseFloat(resp.Header.Get("Retry-After"), 64); err == nil {
+		return time.Duration(secs * float64(time.Second))
+	}
+	return 0
+}
+
 // SendMessage sends a plain text message to a channel.
 func (c *Client) SendMessage(channelID, content string) error {
 	payload := map[string]string{"content": content}
@@ -117,6 +209,179 @@ func (c *Client) EditEmbed(channelID, messageID, content string, embed *discordg
 	return err
 }
 
+// SendEmbedViaWebhook posts an embed and UI components through a channel webhook and returns the created
+// Message ID. Unlike the channels endpoint, a webhook answers 204 with no body unless asked to wait, and
+// ignores components unless with_components is set. Even then, only webhooks owned by an application may
+// send interactive components; feed webhooks are created by admins in channel settings, so only link
+// buttons are sent and everything else is dropped.
+func (c *Client) SendEmbedViaWebhook(webhookURL string, embed *discordgo.MessageEmbed, components []discordgo.MessageComponent) (string, error) {
+	payload := map[string]interface{}{
+		"embeds":     []*discordgo.MessageEmbed{embed},
+		"components": linkButtonsOnly(components),
+	}
+	endpoint, err := webhookEndpoint(webhookURL, "", url.Values{"wait": {"true"}, "with_components": {"true"}})
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := c.doWebhookRequest("POST", endpoint, payload)
+	if err != nil {
+		return "", err
+	}
+
+	var msg discordgo.Message
+	if err := json.Unmarshal(resp, &msg); err != nil {
+		return "", err
+	}
+	return msg.ID, nil
+}
+
+// GetWebhookChannel returns the ID of the channel a webhook posts to. A webhook URL carries its own
+// token, so the lookup needs no bot authorization.
+func (c *Client) GetWebhookChannel(webhookURL string) (string, error) {
+	endpoint, err := webhookEndpoint(webhookURL, "", nil)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := c.do(req)
+	if err != nil {
+		return "", err
+	}
+
+	var hook discordgo.Webhook
+	if err := json.Unmarshal(resp, &hook); err != nil {
+		return "", err
+	}
+	return hook.ChannelID, nil
+}
+
+// linkButtonsOnly returns the action rows in components with every component but link buttons removed,
+// dropping rows left empty.
+func linkButtonsOnly(components []discordgo.MessageComponent) []discordgo.MessageComponent {
+	rows := []discordgo.MessageComponent{}
+	for _, c := range components {
+		var row discordgo.ActionsRow
+		switch r := c.(type) {
+		case discordgo.ActionsRow:
+			row = r
+		case *discordgo.ActionsRow:
+			row = *r
+		default:
+			continue
+		}
+		var links []discordgo.MessageComponent
+		for _, rc := range row.Components {
+			switch b := rc.(type) {
+			case discordgo.Button:
+				if b.Style == discordgo.LinkButton {
+					links = append(links, b)
+				}
+			case *discordgo.Button:
+				if b.Style == discordgo.LinkButton {
+					links = append(links, b)
+				}
+			}
+		}
+		if len(links) > 0 {
+			rows = append(rows, discordgo.ActionsRow{Components: links})
+		}
+	}
+	return rows
+}
+
+// EditEmbedViaWebhook updates a message previously sent through the same webhook. The bot itself
+// cannot edit webhook-authored messages, so edits have to go back through the webhook.
+func (c *Client) EditEmbedViaWebhook(webhookURL, messageID string, embed *discordgo.MessageEmbed) error {
+	payload := discordgo.WebhookEdit{
+		Embeds: &[]*discordgo.MessageEmbed{embed},
+	}
+	endpoint, err := webhookEndpoint(webhookURL, "/messages/"+messageID, nil)
+	if err != nil {
+		return err
+	}
+	_, err = c.doWebhookRequest("PATCH", endpoint, payload)
+	return err
+}
+
+// doWebhookRequest sends body to a webhook URL. A 429 is retried once after the wait Discord asks for,
+// since webhooks share a tight per-channel bucket and a single deal burst often trips it.
+func (c *Client) doWebhookRequest(method, endpoint string, body interface{}) ([]byte, error) {
+	b, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(method, endpoint, bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		resp, err := c.do(req)
+		var apiErr *APIError
+		if attempt > 0 || !errors.As(err, &apiErr) || !apiErr.IsRateLimited() {
+			return resp, err
+		}
+		time.Sleep(min(apiErr.RetryAfter, maxWebhookRetryWait))
+	}
+}
+
+// webhookEndpoint appends suffix and query to a webhook URL, keeping any query it already carries
+// (e.g. thread_id).
+func webhookEndpoint(webhookURL, suffix string, query url.Values) (string, error) {
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return "", fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + suffix
+	q := u.Query()
+	for k, vs := range query {
+		q[k] = vs
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// IsDiscordWebhookURL reports whether raw looks like a Discord channel webhook URL. Feed webhooks are
+// entered by server admins, so anything else is rejected before deal posts are sent to it.
+func IsDiscordWebhookURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || u.Scheme != "https" {
+		return false
+	}
+	switch u.Host {
+	case "discord.com", "discordapp.com", "ptb.discord.com", "canary.discord.com":
+	default:
+		return false
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	// api/webhooks/{id}/{token}, optionally with a version segment after api.
+	if len(parts) == 5 && strings.HasPrefix(parts[1], "v") {
+		parts = append(parts[:1], parts[2:]...)
+	}
+	return len(parts) == 4 && parts[0] == "api" && parts[1] == "webhooks" && parts[2] != "" && parts[3] != ""
+}
+
+// WebhookID returns the ID in a webhook URL (the segment after "webhooks"), or "" if there is none.
+func WebhookID(webhookURL string) string {
+	u, err := url.Parse(webhookURL)
+	if err != nil {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	for n := 0; n+1 < len(parts); n++ {
+		if parts[n] == "webhooks" {
+			return parts[n+1]
+		}
+	}
+	return ""
+}
+
 // AddReaction adds a unicode emoji reaction to a message.
 func (c *Client) AddReaction(channelID, messageID, emoji string) error {
 	// Emoji needs to be URL encoded if it's custom, but standard unicode works directly in the path if properly escaped.
@@ -149,6 +414,29 @@ func (c *Client) SendFollowupEmbedWithComponents(i *discordgo.Interaction, embed
 	return err
 }
 
+// EditOriginalInteractionResponse replaces the deferred response of an Interaction (the "thinking..."
+// placeholder) with an embed and UI components. The response keeps the ephemeral flag it was deferred with.
+func (c *Client) EditOriginalInteractionResponse(i *discordgo.Interaction, embed *discordgo.MessageEmbed, components []discordgo.MessageComponent) error {
+	payload := map[string]interface{}{
+		"embeds":     []*discordgo.MessageEmbed{embed},
+		"components": components,
+	}
+	endpoint := fmt.Sprintf("/webhooks/%s/%s/messages/@original", i.AppID, i.Token)
+	_, err := c.doRequest("PATCH", endpoint, payload)
+	return err
+}
+
+// SendFollowupFile sends an ephemeral followup with a text file attached, for content too long for a message.
+func (c *Client) SendFollowupFile(i *discordgo.Interaction, content, filename string, data []byte) error {
+	payload := discordgo.WebhookParams{
+		Content: content,
+		Flags:   discordgo.MessageFlagsEphemeral,
+	}
+	endpoint := fmt.Sprintf("/webhooks/%s/%s", i.AppID, i.Token)
+	_, err := c.doFileRequest("POST", endpoint, payload, filename, data)
+	return err
+}
+
 // CreateDM opens a DM channel with a specific user.
 func (c *Client) CreateDM(userID string) (string, error) {
 	payload := map[string]string{"recipient_id": userID}
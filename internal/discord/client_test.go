@@ -0,0 +1,373 @@
+package discord
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// capturedRequest records what the client sent to the mock Discord API.
+type capturedRequest struct {
+	Method string
+	Path   string
+	Auth   string
+	Body   map[string]interface{}
+}
+
+func newTestServer(t *testing.T, status int, respBody string, captured *capturedRequest) *httptest.Server {
+	t.Helper()
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured.Method = r.Method
+		captured.Path = r.URL.EscapedPath()
+		captured.Auth = r.Header.Get("Authorization")
+		b, _ := io.ReadAll(r.Body)
+		if len(b) > 0 {
+			_ = json.Unmarshal(b, &captured.Body)
+		}
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(respBody))
+	}))
+}
+
+func TestClient_RequestShapes(t *testing.T) {
+	interaction := &discordgo.Interaction{AppID: "app1", Token: "tok1"}
+
+	tests := []struct {
+		name       string
+		respBody   string
+		call       func(c *Client) error
+		wantMethod string
+		wantPath   string
+		checkBody  func(t *testing.T, body map[string]interface{})
+	}{
+		{
+			name:       "SendMessage",
+			call:       func(c *Client) error { return c.SendMessage("chan1", "hello") },
+			wantMethod: "POST",
+			wantPath:   "/channels/chan1/messages",
+			checkBody: func(t *testing.T, body map[string]interface{}) {
+				if body["content"] != "hello" {
+					t.Errorf("unexpected content %v", body["content"])
+				}
+			},
+		},
+		{
+			name:     "SendEmbedWithComponents",
+			respBody: `{"id": "msg1"}`,
+			call: func(c *Client) error {
+				id, err := c.SendEmbedWithComponents("chan1", "", &discordgo.MessageEmbed{Title: "Deal"}, []discordgo.MessageComponent{})
+				if err == nil && id != "msg1" {
+					return errors.New("unexpected message ID " + id)
+				}
+				return err
+			},
+			wantMethod: "POST",
+			wantPath:   "/channels/chan1/messages",
+			checkBody: func(t *testing.T, body map[string]interface{}) {
+				embeds, _ := body["embeds"].([]interface{})
+				if len(embeds) != 1 || embeds[0].(map[string]interface{})["title"] != "Deal" {
+					t.Errorf("unexpected embeds %v", body["embeds"])
+				}
+				if _, ok := body["components"]; !ok {
+					t.Error("expected components to be sent")
+				}
+			},
+		},
+		{
+			name:       "EditEmbed",
+			call:       func(c *Client) error { return c.EditEmbed("chan1", "msg1", "", &discordgo.MessageEmbed{Title: "Sold"}) },
+			wantMethod: "PATCH",
+			wantPath:   "/channels/chan1/messages/msg1",
+			checkBody: func(t *testing.T, body map[string]interface{}) {
+				if _, ok := body["embeds"]; !ok {
+					t.Error("expected embeds to be sent")
+				}
+			},
+		},
+		{
+			name:       "AddReaction",
+			call:       func(c *Client) error { return c.AddReaction("chan1", "msg1", "%F0%9F%91%8D") },
+			wantMethod: "PUT",
+			wantPath:   "/channels/chan1/messages/msg1/reactions/%F0%9F%91%8D/@me",
+		},
+		{
+			name:       "SendFollowupMessage",
+			call:       func(c *Client) error { return c.SendFollowupMessage(interaction, "done") },
+			wantMethod: "POST",
+			wantPath:   "/webhooks/app1/tok1",
+			checkBody: func(t *testing.T, body map[string]interface{}) {
+				if body["content"] != "done" || body["flags"] != float64(discordgo.MessageFlagsEphemeral) {
+					t.Errorf("expected an ephemeral followup, got %v", body)
+				}
+			},
+		},
+		{
+			name: "EditOriginalInteractionResponse",
+			call: func(c *Client) error {
+				return c.EditOriginalInteractionResponse(interaction, &discordgo.MessageEmbed{Title: "🎯 Match Rule Created"}, []discordgo.MessageComponent{})
+			},
+			wantMethod: "PATCH",
+			wantPath:   "/webhooks/app1/tok1/messages/@original",
+			checkBody: func(t *testing.T, body map[string]interface{}) {
+				if _, ok := body["embeds"]; !ok {
+					t.Error("expected embeds to be sent")
+				}
+				if _, ok := body["components"]; !ok {
+					t.Error("expected components to be sent")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got capturedRequest
+			server := newTestServer(t, http.StatusOK, tt.respBody, &got)
+			defer server.Close()
+
+			if err := tt.call(NewClientWithBaseURL("secret", server.URL)); err != nil {
+				t.Fatalf("call failed: %v", err)
+			}
+
+			if got.Method != tt.wantMethod || got.Path != tt.wantPath {
+				t.Errorf("got %s %s, want %s %s", got.Method, got.Path, tt.wantMethod, tt.wantPath)
+			}
+			if got.Auth != "Bot secret" {
+				t.Errorf("unexpected Authorization header %q", got.Auth)
+			}
+			if tt.checkBody != nil {
+				tt.checkBody(t, got.Body)
+			}
+		})
+	}
+}
+
+func TestClient_RateLimitError(t *testing.T) {
+	var got capturedRequest
+	server := newTestServer(t, http.StatusTooManyRequests, `{"message": "You are being rate limited.", "retry_after": 0.5}`, &got)
+	defer server.Close()
+
+	err := NewClientWithBaseURL("secret", server.URL).AddReaction("chan1", "msg1", "%F0%9F%91%8D")
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected an *APIError, got %v", err)
+	}
+	if !apiErr.IsRateLimited() {
+		t.Errorf("expected a rate limit error, got status %d", apiErr.StatusCode)
+	}
+	if apiErr.RetryAfter != 500*time.Millisecond {
+		t.Errorf("expected retry_after of 500ms, got %v", apiErr.RetryAfter)
+	}
+}
+
+func TestClient_SendFollowupFile(t *testing.T) {
+	var path, auth, payload, filename, fileBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		auth = r.Header.Get("Authorization")
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("expected a multipart body: %v", err)
+			return
+		}
+		payload = r.FormValue("payload_json")
+		f, header, err := r.FormFile("files[0]")
+		if err != nil {
+			t.Errorf("expected an attached file: %v", err)
+			return
+		}
+		defer f.Close()
+		b, _ := io.ReadAll(f)
+		filename, fileBody = header.Filename, string(b)
+	}))
+	defer server.Close()
+
+	i := &discordgo.Interaction{AppID: "app1", Token: "tok1"}
+	if err := NewClientWithBaseURL("secret", server.URL).SendFollowupFile(i, "see attached", "wizard_prompt.txt", []byte("prompt text")); err != nil {
+		t.Fatalf("SendFollowupFile failed: %v", err)
+	}
+
+	if path != "/webhooks/app1/tok1" || auth != "Bot secret" {
+		t.Errorf("unexpected request to %s with auth %q", path, auth)
+	}
+	var params map[string]interface{}
+	if err := json.Unmarshal([]byte(payload), &params); err != nil || params["content"] != "see attached" {
+		t.Errorf("unexpected payload_json %q", payload)
+	}
+	if filename != "wizard_prompt.txt" || fileBody != "prompt text" {
+		t.Errorf("unexpected attachment %q: %q", filename, fileBody)
+	}
+}
+
+func TestClient_SendEmbedViaWebhook(t *testing.T) {
+	var got capturedRequest
+	var query url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got.Method, got.Path, got.Auth = r.Method, r.URL.Path, r.Header.Get("Authorization")
+		query = r.URL.Query()
+		b, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(b, &got.Body)
+		_, _ = w.Write([]byte(`{"id": "msg1", "webhook_id": "hook1"}`))
+	}))
+	defer server.Close()
+
+	components := []discordgo.MessageComponent{discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+		discordgo.Button{Label: "View", Style: discordgo.LinkButton, URL: "https://example.com"},
+	}}}
+	id, err := NewClientWithBaseURL("secret", server.URL).SendEmbedViaWebhook(server.URL+"/api/webhooks/hook1/hooktok?thread_id=t1", &discordgo.MessageEmbed{Title: "Deal"}, components)
+	if err != nil {
+		t.Fatalf("SendEmbedViaWebhook failed: %v", err)
+	}
+	if id != "msg1" {
+		t.Errorf("expected message ID msg1, got %q", id)
+	}
+
+	if got.Method != "POST" || got.Path != "/api/webhooks/hook1/hooktok" {
+		t.Errorf("unexpected request %s %s", got.Method, got.Path)
+	}
+	if got.Auth != "" {
+		t.Errorf("webhook requests must not carry the bot token, got %q", got.Auth)
+	}
+	if query.Get("wait") != "true" || query.Get("with_components") != "true" || query.Get("thread_id") != "t1" {
+		t.Errorf("unexpected query %v", query)
+	}
+	embeds, _ := got.Body["embeds"].([]interface{})
+	if len(embeds) != 1 || embeds[0].(map[string]interface{})["title"] != "Deal" {
+		t.Errorf("unexpected embeds %v", got.Body["embeds"])
+	}
+	if rows, _ := got.Body["components"].([]interface{}); len(rows) != 1 {
+		t.Errorf("expected one component row, got %v", got.Body["components"])
+	}
+	if _, ok := got.Body["content"]; ok {
+		t.Errorf("expected no content field, got %v", got.Body["content"])
+	}
+}
+
+// TestClient_SendEmbedViaWebhook_NotAppOwned sends deal buttons through a webhook created in channel
+// settings, which Discord answers with a 400 if the post carries any interactive component.
+func TestClient_SendEmbedViaWebhook_NotAppOwned(t *testing.T) {
+	var sent []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Components []struct {
+				Components []map[string]interface{} `json:"components"`
+			} `json:"components"`
+		}
+		b, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(b, &body)
+		for _, row := range body.Components {
+			for _, c := range row.Components {
+				if _, ok := c["custom_id"]; ok {
+					w.WriteHeader(http.StatusBadRequest)
+					_, _ = w.Write([]byte(`{"message": "Invalid Form Body", "code": 50035}`))
+					return
+				}
+				sent = append(sent, c)
+			}
+		}
+		_, _ = w.Write([]byte(`{"id": "msg1"}`))
+	}))
+	defer server.Close()
+
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{Label: "Open in Reddit", Style: discordgo.LinkButton, URL: "https://reddit.com/r/x"},
+			discordgo.Button{Label: "Bad Summary", Style: discordgo.SecondaryButton, CustomID: "flag_parse|t3_x"},
+		}},
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{Label: "Mute Seller", Style: discordgo.SecondaryButton, CustomID: "mute_author|seller"},
+		}},
+	}
+	id, err := NewClientWithBaseURL("secret", server.URL).SendEmbedViaWebhook(server.URL+"/api/webhooks/hook1/hooktok", &discordgo.MessageEmbed{Title: "Deal"}, components)
+	if err != nil {
+		t.Fatalf("SendEmbedViaWebhook failed: %v", err)
+	}
+	if id != "msg1" {
+		t.Errorf("expected message ID msg1, got %q", id)
+	}
+	if len(sent) != 1 || sent[0]["label"] != "Open in Reddit" {
+		t.Errorf("expected only the link button to be sent, got %v", sent)
+	}
+}
+
+func TestClient_GetWebhookChannel(t *testing.T) {
+	var got capturedRequest
+	server := newTestServer(t, http.StatusOK, `{"id": "hook1", "channel_id": "feed1"}`, &got)
+	defer server.Close()
+
+	channelID, err := NewClientWithBaseURL("secret", server.URL).GetWebhookChannel(server.URL + "/api/webhooks/hook1/hooktok")
+	if err != nil {
+		t.Fatalf("GetWebhookChannel failed: %v", err)
+	}
+	if channelID != "feed1" {
+		t.Errorf("expected channel feed1, got %q", channelID)
+	}
+	if got.Method != "GET" || got.Path != "/api/webhooks/hook1/hooktok" || got.Auth != "" {
+		t.Errorf("unexpected request %s %s (auth %q)", got.Method, got.Path, got.Auth)
+	}
+}
+
+func TestClient_WebhookRetriesRateLimit(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"message": "You are being rate limited.", "retry_after": 0.01}`))
+			return
+		}
+		if r.Method != "PATCH" || r.URL.Path != "/api/webhooks/hook1/hooktok/messages/msg1" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"id": "msg1"}`))
+	}))
+	defer server.Close()
+
+	err := NewClientWithBaseURL("secret", server.URL).EditEmbedViaWebhook(server.URL+"/api/webhooks/hook1/hooktok", "msg1", &discordgo.MessageEmbed{Title: "Sold"})
+	if err != nil {
+		t.Fatalf("expected the retry to succeed, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected 2 requests, got %d", calls)
+	}
+}
+
+func TestIsDiscordWebhookURL(t *testing.T) {
+	tests := map[string]bool{
+		"https://discord.com/api/webhooks/123/abc":            true,
+		"https://discord.com/api/v10/webhooks/123/abc":        true,
+		"https://canary.discord.com/api/webhooks/123/abc":     true,
+		"http://discord.com/api/webhooks/123/abc":             false,
+		"https://evil.example.com/api/webhooks/123/abc":       false,
+		"https://discord.com/api/webhooks/123":                false,
+		"https://discord.com/api/channels/123/messages":       false,
+		"https://discord.com.evil.example/api/webhooks/1/abc": false,
+		"not a url": false,
+	}
+	for raw, want := range tests {
+		if got := IsDiscordWebhookURL(raw); got != want {
+			t.Errorf("IsDiscordWebhookURL(%q) = %v, want %v", raw, got, want)
+		}
+	}
+}
+
+func TestWebhookID(t *testing.T) {
+	tests := map[string]string{
+		"https://discord.com/api/webhooks/123/abc":                 "123",
+		"https://discord.com/api/v10/webhooks/456/abc?thread_id=1": "456",
+		"https://discord.com/api/channels/123/messages":            "",
+		"not a url": "",
+	}
+	for raw, want := range tests {
+		if got := WebhookID(raw); got != want {
+			t.Errorf("WebhookID(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
@@ -2,138 +2,403 @@ package discord
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
-	"os"
+	"slices"
+	"strings"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/pauljones0/betterHardwareSwap/internal/store"
 )
 
-func routeSlashCommand(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
+func (h *Handler) routeSlashCommand(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
 	data := i.ApplicationCommandData()
 	switch data.Name {
 	case "setup":
-		handleSetup(ctx, w, i)
+		h.handleSetup(ctx, w, i)
 	case "help":
-		handleHelp(ctx, w, i)
+		h.handleHelp(ctx, w, i)
 	case "alert":
-		handleAlertGroup(ctx, w, i)
+		h.handleAlertGroup(ctx, w, i)
+	case "servers":
+		h.handleServers(ctx, w, i)
+	case "bypass":
+		h.handleBypass(ctx, w, i)
+	case "stats":
+		h.handleStats(ctx, w, i)
+	case "runs":
+		h.handleRuns(ctx, w, i)
+	case "find":
+		h.handleFind(ctx, w, i)
+	case "explain":
+		h.handleExplain(ctx, w, i)
+	case "blocklist":
+		h.handleBlocklist(ctx, w, i)
+	case "scammers":
+		h.handleScammers(ctx, w, i)
+	case "inspect":
+		h.handleInspect(ctx, w, i)
+	case "quiethours":
+		h.handleQuietHours(ctx, w, i)
+	case "timezone":
+		h.handleTimezone(ctx, w, i)
+	case "prompt":
+		h.handlePrompt(ctx, w, i)
 	default:
 		respondError(w, "Unknown command")
 	}
 }
 
-func handleSetup(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
+func (h *Handler) handleSetup(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
 	// Only allow admins to run this (Discord permissions can enforce this, but double check)
-	var feedChannelID, pingChannelID string
+	if !memberIsAdmin(i) {
+		respondError(w, "Only server admins (Manage Server permission) can run `/setup`.")
+		return
+	}
+
 	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		// Bare /setup: let the admin pick the channels from menus instead, starting from the current ones.
+		current, _, err := h.currentSetup(ctx, i.GuildID)
+		if err != nil {
+			log.Printf("Failed to load config for guild %s: %v", i.GuildID, err)
+			respondError(w, "Failed to load the current configuration. Please try again.")
+			return
+		}
+		data := setupPicker(current.FeedChannelID, current.PingChannelID)
+		data.Flags = discordgo.MessageFlagsEphemeral
+		writeJSON(w, discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: data,
+		})
+		return
+	}
+
+	// Options left out keep their current values, so one channel or setting can be changed on its own.
+	cfg, existed, err := h.currentSetup(ctx, i.GuildID)
+	if err != nil {
+		log.Printf("Failed to load config for guild %s: %v", i.GuildID, err)
+		respondError(w, "Failed to load the current configuration. Please try again.")
+		return
+	}
+	toClear := ""
 	for _, opt := range options {
 		if opt.Name == "feed_channel" {
-			feedChannelID = opt.Value.(string)
+			cfg.FeedChannelID = opt.Value.(string)
 		} else if opt.Name == "ping_channel" {
-			pingChannelID = opt.Value.(string)
+			cfg.PingChannelID = opt.Value.(string)
+		} else if opt.Name == "archive_channel" {
+			cfg.ArchiveChannelID = opt.Value.(string)
+		} else if opt.Name == "feed_mode" {
+			cfg.FeedMode = store.FeedMode(opt.StringValue())
+		} else if opt.Name == "allow_nsfw" {
+			cfg.AllowNSFW = opt.BoolValue()
+		} else if opt.Name == "min_score" {
+			cfg.MinScore = int(opt.IntValue())
+		} else if opt.Name == "language" {
+			cfg.Language = store.Language(opt.StringValue())
+		} else if opt.Name == "raw_preview" {
+			cfg.ShowRawPreview = opt.BoolValue()
+		} else if opt.Name == "feed_webhook" {
+			cfg.FeedWebhookURL = strings.TrimSpace(opt.StringValue())
+		} else if opt.Name == "clear" {
+			toClear = opt.StringValue()
 		}
 	}
 
-	if feedChannelID == "" || pingChannelID == "" {
-		respondError(w, "Both feed_channel and ping_channel are required.")
+	// Discord never sends an empty option, so optional settings are removed with clear instead.
+	switch toClear {
+	case "archive_channel":
+		cfg.ArchiveChannelID = ""
+	case "feed_webhook":
+		cfg.FeedWebhookURL = ""
+	case "min_score":
+		cfg.MinScore = 0
+	}
+
+	if cfg.FeedChannelID == "" || cfg.PingChannelID == "" {
+		respondError(w, "Both feed_channel and ping_channel are required the first time, or run `/setup` with no options to pick them from menus.")
+		return
+	}
+	if cfg.FeedWebhookURL != "" && !IsDiscordWebhookURL(cfg.FeedWebhookURL) {
+		respondError(w, "feed_webhook must be a Discord webhook URL (https://discord.com/api/webhooks/...).")
+		return
+	}
+	if problem := h.checkFeedWebhook(cfg); problem != "" {
+		respondError(w, problem)
 		return
 	}
 
-	projectID := os.Getenv("GCP_PROJECT_ID")
-	db, err := store.NewStore(ctx, projectID)
+	h.saveSetup(ctx, w, i, cfg, existed, discordgo.InteractionResponseChannelMessageWithSource)
+}
+
+// checkFeedWebhook returns why cfg's feed webhook can't be used, or "" if none is set or it posts to the
+// feed channel. Reactions and ping links point at the feed channel, so a webhook posting anywhere else
+// would leave them pointing at a message that isn't there.
+func (h *Handler) checkFeedWebhook(cfg store.ServerConfig) string {
+	if cfg.FeedWebhookURL == "" {
+		return ""
+	}
+	channelID, err := h.client.GetWebhookChannel(cfg.FeedWebhookURL)
 	if err != nil {
-		respondError(w, "Database connection failed.")
-		return
+		log.Printf("Failed to look up feed webhook: %v", err)
+		return "Couldn't look up feed_webhook. Check that the webhook still exists and try again."
 	}
-	defer db.Close()
+	if channelID != cfg.FeedChannelID {
+		return fmt.Sprintf("feed_webhook posts to <#%s>, not the feed channel <#%s>. Use a webhook created in the feed channel, or remove it with `clear:feed_webhook`.", channelID, cfg.FeedChannelID)
+	}
+	return ""
+}
 
-	cfg := store.ServerConfig{
-		FeedChannelID: feedChannelID,
-		PingChannelID: pingChannelID,
+// currentSetup returns the server's saved configuration and true, or the defaults and false if it hasn't
+// been set up. Setup changes are applied on top of it, which also keeps the blocklist the admins already built.
+// Any other failure to load it is returned, so a Firestore outage can't reset the server to the defaults.
+func (h *Handler) currentSetup(ctx context.Context, guildID string) (store.ServerConfig, bool, error) {
+	existing, err := h.db.GetServerConfig(ctx, guildID)
+	if errors.Is(err, store.ErrNotFound) {
+		return store.ServerConfig{FeedMode: store.FeedModeAlertsOnly, Language: store.LanguageEnglish}, false, nil
 	}
+	if err != nil {
+		return store.ServerConfig{}, false, err
+	}
+	return *existing, true, nil
+}
 
-	if err := db.SaveServerConfig(ctx, i.GuildID, cfg); err != nil {
+// saveSetup saves a server's new configuration and answers with the setup summary, using respType so the
+// interactive flow can replace its channel menus with it. A first-time setup then greets the server in the
+// ping channel; reconfiguring an existing one only tells the admin, so re-running /setup doesn't spam it.
+func (h *Handler) saveSetup(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction, cfg store.ServerConfig, existed bool, respType discordgo.InteractionResponseType) {
+	if err := h.db.SaveServerConfig(ctx, i.GuildID, cfg); err != nil {
 		log.Printf("Failed to save config: %v", err)
 		respondError(w, "Failed to completely save configuration.")
 		return
 	}
 
+	msgs := messagesFor(cfg.Language)
+	feedDesc := msgs.setupFeedMatches
+	if cfg.FeedMode == store.FeedModeAllDeals {
+		feedDesc = msgs.setupFeedAll
+	}
+
+	summary := msgs.setupComplete
+	if existed {
+		summary = msgs.setupUpdated
+	}
+
 	// Say hello! Keep it simple and visible only to the person running the setup.
 	// We'll let the client internally handle sending a "public" welcome message later if needed.
 	writeJSON(w, discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Type: respType,
 		Data: &discordgo.InteractionResponseData{
-			Content: fmt.Sprintf("✅ **Setup Complete!**\n\nDeals will be posted to <#%s>.\nUser Alerts will ping in <#%s>.\n\nUsers can now run `/alert add` to get started!", feedChannelID, pingChannelID),
-			Flags:   discordgo.MessageFlagsEphemeral,
+			Content:    fmt.Sprintf(summary, feedDesc, cfg.FeedChannelID, cfg.PingChannelID),
+			Flags:      discordgo.MessageFlagsEphemeral,
+			Components: []discordgo.MessageComponent{},
 		},
 	})
+	if existed {
+		return
+	}
 
 	// Send public welcome message via REST Client
 	go func() {
-		client := NewClient(os.Getenv("DISCORD_BOT_TOKEN"))
-		client.SendMessage(pingChannelID, "👋 **Hello! Hardware Swap Bot is now online!**\nRun `/help` to see how to set up alerts for specific gear.")
+		h.client.SendMessage(cfg.PingChannelID, msgs.setupWelcome)
 	}()
 }
 
-func handleHelp(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
-	embed := &discordgo.MessageEmbed{
-		Title:       "🛡️ Better Hardware Swap Help",
-		Description: "I'm your agentic companion for tracking PC gear in Canada. I scan `r/CanadianHardwareSwap` in real-time.",
+// handleHelp acknowledges immediately and builds the help embed in the background,
+// since tailoring it to the server and user requires Firestore lookups.
+func (h *Handler) handleHelp(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
+	writeJSON(w, discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral, // Only the user who asked sees it
+		},
+	})
+
+	go h.sendHelp(context.Background(), i)
+}
+
+func (h *Handler) sendHelp(ctx context.Context, i *discordgo.Interaction) {
+	var cfg *store.ServerConfig
+	cfgLoaded := i.GuildID != ""
+	if cfgLoaded {
+		var err error
+		cfg, err = h.db.GetServerConfig(ctx, i.GuildID)
+		if err != nil && !errors.Is(err, store.ErrNotFound) {
+			// Without the config we can't tell whether the server is set up, so skip the status
+			// field rather than wrongly telling admins to run /setup.
+			log.Printf("Help: failed to load config for guild %s: %v", i.GuildID, err)
+			cfg, cfgLoaded = nil, false
+		}
+	}
+
+	embed := helpEmbed(store.ServerLanguage(cfg))
+	if cfgLoaded {
+		if field := helpStatusField(ctx, h.db, cfg, i.GuildID, interactionUserID(i)); field != nil {
+			embed.Fields = append([]*discordgo.MessageEmbedField{field}, embed.Fields...)
+		}
+	}
+
+	if err := h.client.SendFollowupEmbedWithComponents(i, embed, []discordgo.MessageComponent{}); err != nil {
+		log.Printf("Failed to send help followup: %v", err)
+	}
+}
+
+// helpEmbeds holds the generic help embed for each language, built once since it never changes.
+var helpEmbeds = func() map[store.Language]*discordgo.MessageEmbed {
+	embeds := make(map[store.Language]*discordgo.MessageEmbed, len(localizedMessages))
+	for lang := range localizedMessages {
+		embeds[lang] = buildHelpEmbed(lang)
+	}
+	return embeds
+}()
+
+// helpEmbed returns a copy of the precomputed help embed for lang, falling back to English. The
+// copy has its own Fields slice, so callers can add per-user fields without touching the shared one.
+func helpEmbed(lang store.Language) *discordgo.MessageEmbed {
+	shared, ok := helpEmbeds[lang]
+	if !ok {
+		shared = helpEmbeds[store.LanguageEnglish]
+	}
+	embed := *shared
+	embed.Fields = slices.Clone(shared.Fields)
+	return &embed
+}
+
+// buildHelpEmbed returns the generic help embed shared by every server and user, in the given language.
+func buildHelpEmbed(lang store.Language) *discordgo.MessageEmbed {
+	msgs := messagesFor(lang)
+	return &discordgo.MessageEmbed{
+		Title:       msgs.helpTitle,
+		Description: msgs.helpDescription,
 		Color:       0x00FF00, // Green
 		Fields: []*discordgo.MessageEmbedField{
 			{
-				Name:   "✨ AI-Powered Alerts",
-				Value:  "Run `/alert add` and select **'Help Me Write It'**. Just describe your goal (e.g., *\"A 30-series GPU in Vancouver under $400\"*) and my AI handles the logic.",
+				Name:   msgs.helpAIName,
+				Value:  msgs.helpAIValue,
 				Inline: false,
 			},
 			{
-				Name:   "🔧 Technical Querying",
-				Value:  "Select **'I'll Type It Myself'** to use Boolean logic like `(rtx AND 4090) NOT broken`.",
+				Name:   msgs.helpManualName,
+				Value:  msgs.helpManualValue,
 				Inline: false,
 			},
 			{
-				Name:  "📋 Management",
-				Value: "Use `/alert list` to view or delete your current subscriptions.",
+				Name:  msgs.helpManageName,
+				Value: msgs.helpManageValue,
 			},
 		},
 		Thumbnail: &discordgo.MessageEmbedThumbnail{
 			URL: "https://em-content.zobj.net/source/microsoft-teams/363/shield_1f6e1-fe0f.png",
 		},
 		Footer: &discordgo.MessageEmbedFooter{
-			Text: "Agentic • Serverless • Open Source",
+			Text: msgs.helpFooter,
 		},
 	}
+}
 
-	writeJSON(w, discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Embeds: []*discordgo.MessageEmbed{embed},
-			Flags:  discordgo.MessageFlagsEphemeral, // Only the user who asked sees it
-		},
-	})
+// helpStatusField tells the user what to do next based on whether the server is configured
+// and how many alerts they already have, in the server's language. cfg is guildID's config as
+// already loaded by the caller, or nil if the server has none. Returns nil when there is no
+// server context.
+func helpStatusField(ctx context.Context, db Storer, cfg *store.ServerConfig, guildID, userID string) *discordgo.MessageEmbedField {
+	if guildID == "" {
+		return nil
+	}
+
+	msgs := messagesFor(store.ServerLanguage(cfg))
+	if cfg == nil || cfg.FeedChannelID == "" {
+		return &discordgo.MessageEmbedField{
+			Name:  msgs.notSetUpName,
+			Value: msgs.notSetUpValue,
+		}
+	}
+
+	if userID == "" {
+		return nil
+	}
+
+	alerts, err := db.GetUserAlerts(ctx, guildID, userID)
+	if err != nil {
+		log.Printf("Help: failed to load alerts for user %s: %v", userID, err)
+		return nil
+	}
+
+	if len(alerts) == 0 {
+		return &discordgo.MessageEmbedField{
+			Name:  msgs.getStartedName,
+			Value: fmt.Sprintf(msgs.getStartedValue, cfg.FeedChannelID),
+		}
+	}
+
+	noun := msgs.alertsNoun
+	if len(alerts) == 1 {
+		noun = msgs.alertNoun
+	}
+	return &discordgo.MessageEmbedField{
+		Name:  msgs.statusName,
+		Value: fmt.Sprintf(msgs.statusValue, len(alerts), noun),
+	}
 }
 
 // handleAlertGroup routes the subcommands of `/alert`
-func handleAlertGroup(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
+func (h *Handler) handleAlertGroup(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
 	options := i.ApplicationCommandData().Options
 	if len(options) == 0 {
 		return
 	}
+	if alertScope(i) == "" {
+		respondError(w, "Could not identify user.")
+		return
+	}
 
 	subCommand := options[0].Name
 	switch subCommand {
 	case "add":
 		handleAlertAddStart(ctx, w, i)
 	case "list":
-		handleAlertList(ctx, w, i)
+		h.handleAlertList(ctx, w, i)
+	case "preview":
+		h.handleAlertPreview(ctx, w, i)
+	case "trending":
+		h.handleAlertTrending(ctx, w, i)
+	case "pause-all":
+		h.handleAlertSetAllEnabled(ctx, w, i, false)
+	case "resume-all":
+		h.handleAlertSetAllEnabled(ctx, w, i, true)
+	case "bundles":
+		h.handleAlertBundles(ctx, w, i)
 	default:
 		respondError(w, "Unknown subcommand")
 	}
 }
 
+// handleAlertPreview runs the AI wizard on `/alert preview <text>` and shows the result without saving anything.
+func (h *Handler) handleAlertPreview(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
+	text := ""
+	for _, opt := range i.ApplicationCommandData().Options[0].Options {
+		if opt.Name == "text" {
+			text = Sanitize(opt.StringValue())
+		}
+	}
+	if strings.TrimSpace(text) == "" {
+		respondError(w, "Describe what you're looking for, e.g. `a 30-series GPU in Vancouver under $400`.")
+		return
+	}
+
+	// Acknowledge immediately so Discord doesn't time out while Gemini thinks.
+	writeJSON(w, discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Flags: discordgo.MessageFlagsEphemeral,
+		},
+	})
+
+	go h.processWizardPreview(context.Background(), i, text)
+}
+
 // handleAlertAddStart gives the user the choice between AI assistance and manual entry.
 func handleAlertAddStart(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
 	embed := &discordgo.MessageEmbed{
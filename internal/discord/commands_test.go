@@ -0,0 +1,81 @@
+package discord
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+	"github.com/pauljones0/betterHardwareSwap/internal/testutils"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHelpStatusField(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Unconfigured server", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+
+		field := helpStatusField(ctx, mockDB, nil, "guild1", "user1")
+
+		if field == nil {
+			t.Fatal("expected a status field for an unconfigured server, got nil")
+		}
+		if !strings.Contains(field.Value, "/setup") {
+			t.Errorf("expected guidance to run /setup, got %q", field.Value)
+		}
+		mockDB.AssertNotCalled(t, "GetUserAlerts", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unconfigured French server", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+		cfg := &store.ServerConfig{Language: store.LanguageFrench}
+
+		field := helpStatusField(ctx, mockDB, cfg, "guild1", "user1")
+
+		if field == nil || field.Name != messagesFor(store.LanguageFrench).notSetUpName || !strings.Contains(field.Value, "/setup") {
+			t.Errorf("expected the French setup guidance, got %+v", field)
+		}
+	})
+
+	t.Run("Configured server with alerts", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+		mockDB.On("GetUserAlerts", mock.Anything, "guild1", "user1").Return([]store.AlertRule{{ID: "a1"}, {ID: "a2"}, {ID: "a3"}}, nil)
+		cfg := &store.ServerConfig{FeedChannelID: "feed1", PingChannelID: "ping1"}
+
+		field := helpStatusField(ctx, mockDB, cfg, "guild1", "user1")
+
+		if field == nil || !strings.Contains(field.Value, "**3**") || !strings.Contains(field.Value, "/alert list") {
+			t.Errorf("expected alert count guidance, got %+v", field)
+		}
+	})
+
+	t.Run("No server context", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+
+		if field := helpStatusField(ctx, mockDB, nil, "", "user1"); field != nil {
+			t.Errorf("expected nil field outside a server, got %+v", field)
+		}
+	})
+}
+
+func TestHelpEmbed_Precomputed(t *testing.T) {
+	got := helpEmbed(store.LanguageEnglish)
+	if !reflect.DeepEqual(got, buildHelpEmbed(store.LanguageEnglish)) {
+		t.Errorf("expected the precomputed embed to match a fresh build, got %+v", got)
+	}
+	if fr := helpEmbed(store.LanguageFrench); fr.Title != messagesFor(store.LanguageFrench).helpTitle {
+		t.Errorf("expected the French help embed, got title %q", fr.Title)
+	}
+	if unknown := helpEmbed(store.Language("de")); unknown.Title != got.Title {
+		t.Errorf("expected unknown languages to fall back to English, got title %q", unknown.Title)
+	}
+
+	// Adding the per-user status field must not leak into the shared embed.
+	got.Fields = append([]*discordgo.MessageEmbedField{{Name: "status"}}, got.Fields...)
+	if again := helpEmbed(store.LanguageEnglish); len(again.Fields) != len(got.Fields)-1 {
+		t.Errorf("expected the shared embed to be left unchanged, got %d fields", len(again.Fields))
+	}
+}
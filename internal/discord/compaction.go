@@ -0,0 +1,96 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/ai"
+	"github.com/pauljones0/betterHardwareSwap/internal/logger"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+)
+
+// compactionThreshold is how many unprocessed analytics records a flow needs before its prompt is compacted.
+const compactionThreshold = 20
+
+// compactionFlows are the analytics flows whose system prompts can be compacted.
+var compactionFlows = []string{"wizard", "manual", "clean"}
+
+// Compactor defines the Gemini operation that rewrites a system prompt from analytics feedback.
+type Compactor interface {
+	RunCompaction(ctx context.Context, records []store.AnalyticsRecord, currentPrompt, flowType string) (*ai.CompactionResult, error)
+}
+
+// AdminNotifier defines the Discord operations used to send a compacted prompt to the bot owner for approval.
+type AdminNotifier interface {
+	SendAdminApprovalDM(adminID, newPrompt, flowType string) error
+	SendFallbackAdminApproval(channelID, adminID, newPrompt, flowType string) error
+}
+
+// triggerCompaction runs compaction in the background after alert activity. serverID is used as the
+// fallback channel for approvals when the admin can't be DMed.
+func (h *Handler) triggerCompaction(serverID string) {
+	runCompaction(context.Background(), h.db, h.ai, h.client, h.cfg.AdminUserID, serverID)
+}
+
+// HandleCronCompact is the HTTP handler invoked by Cloud Scheduler so analytics are compacted
+// even on days without any alert activity to trigger it.
+func (h *Handler) HandleCronCompact(w http.ResponseWriter, r *http.Request) {
+	requestID := fmt.Sprintf("compact-%d", time.Now().UnixNano())
+	ctx := logger.WithRequestID(r.Context(), requestID)
+
+	logger.Info(ctx, "Starting scheduled compaction")
+
+	compacted := runCompaction(ctx, h.db, h.ai, h.client, h.cfg.AdminUserID, "")
+
+	logger.Info(ctx, "Scheduled compaction finished", "flows_compacted", compacted)
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "✅ Compaction complete (%d flows).", compacted)
+}
+
+// runCompaction compacts every flow with at least compactionThreshold unprocessed analytics records and
+// sends each new prompt to the admin for approval, falling back to serverID's ping channel if the DM fails.
+// It returns how many flows were compacted.
+func runCompaction(ctx context.Context, db Storer, aiSvc Compactor, client AdminNotifier, adminID, serverID string) int {
+	// Nobody could approve the result, so don't spend Gemini calls on it.
+	if adminID == "" {
+		return 0
+	}
+
+	compacted := 0
+	for _, flowType := range compactionFlows {
+		records, err := db.GetUnprocessedAnalyticsByFlow(ctx, flowType, compactionThreshold)
+		if err != nil || len(records) < compactionThreshold {
+			continue
+		}
+
+		sysPrompt, _ := db.GetSystemPrompt(ctx, flowType+"_prompt")
+		if sysPrompt == "" {
+			switch flowType {
+			case "wizard":
+				sysPrompt = ai.DefaultWizardPrompt
+			case "clean":
+				sysPrompt = ai.CleanPostSystemInstruction
+			default:
+				sysPrompt = ai.DefaultManualPrompt
+			}
+		}
+
+		result, err := aiSvc.RunCompaction(ctx, records, sysPrompt, flowType)
+		if err != nil || result == nil {
+			logger.Error(ctx, "Compaction failed", "flow_type", flowType, "error", err)
+			continue
+		}
+		compacted++
+
+		err = client.SendAdminApprovalDM(adminID, result.NewPrompt, flowType)
+		if err != nil && serverID != "" {
+			cfg, _ := db.GetServerConfig(ctx, serverID)
+			if cfg != nil && cfg.PingChannelID != "" {
+				_ = client.SendFallbackAdminApproval(cfg.PingChannelID, adminID, result.NewPrompt, flowType)
+			}
+		}
+	}
+	return compacted
+}
@@ -0,0 +1,78 @@
+package discord
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/ai"
+	"github.com/pauljones0/betterHardwareSwap/internal/config"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+	"github.com/pauljones0/betterHardwareSwap/internal/testutils"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRunCompaction(t *testing.T) {
+	ctx := context.Background()
+	full := make([]store.AnalyticsRecord, compactionThreshold)
+	partial := make([]store.AnalyticsRecord, compactionThreshold-1)
+
+	mockDB := new(testutils.MockStore)
+	mockAI := new(testutils.MockAI)
+	mockDiscord := new(testutils.MockDiscord)
+
+	mockDB.On("GetUnprocessedAnalyticsByFlow", mock.Anything, "wizard", compactionThreshold).Return(full, nil)
+	mockDB.On("GetUnprocessedAnalyticsByFlow", mock.Anything, "manual", compactionThreshold).Return(partial, nil)
+	mockDB.On("GetUnprocessedAnalyticsByFlow", mock.Anything, "clean", compactionThreshold).Return(nil, nil)
+	mockDB.On("GetSystemPrompt", mock.Anything, "wizard_prompt").Return("", nil)
+	mockAI.On("RunCompaction", mock.Anything, full, ai.DefaultWizardPrompt, "wizard").Return(&ai.CompactionResult{NewPrompt: "better prompt"}, nil)
+	mockDiscord.On("SendAdminApprovalDM", "admin1", "better prompt", "wizard").Return(nil)
+
+	compacted := runCompaction(ctx, mockDB, mockAI, mockDiscord, "admin1", "")
+
+	if compacted != 1 {
+		t.Errorf("expected only the wizard flow to be compacted, got %d", compacted)
+	}
+	mockDB.AssertExpectations(t)
+	mockAI.AssertExpectations(t)
+	mockDiscord.AssertExpectations(t)
+	mockAI.AssertNumberOfCalls(t, "RunCompaction", 1)
+}
+
+func TestRunCompaction_NoAdmin(t *testing.T) {
+	mockDB := new(testutils.MockStore)
+	mockAI := new(testutils.MockAI)
+	mockDiscord := new(testutils.MockDiscord)
+
+	if compacted := runCompaction(context.Background(), mockDB, mockAI, mockDiscord, "", ""); compacted != 0 {
+		t.Errorf("expected no compaction without an admin to approve it, got %d", compacted)
+	}
+	mockAI.AssertNotCalled(t, "RunCompaction", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandleCronCompact_UsesInjectedClients(t *testing.T) {
+	full := make([]store.AnalyticsRecord, compactionThreshold)
+
+	mockDB := new(testutils.MockStore)
+	mockAI := new(testutils.MockAI)
+	mockDiscord := new(testutils.MockDiscord)
+
+	mockDB.On("GetUnprocessedAnalyticsByFlow", mock.Anything, "wizard", compactionThreshold).Return(nil, nil)
+	mockDB.On("GetUnprocessedAnalyticsByFlow", mock.Anything, "manual", compactionThreshold).Return(full, nil)
+	mockDB.On("GetUnprocessedAnalyticsByFlow", mock.Anything, "clean", compactionThreshold).Return(nil, nil)
+	mockDB.On("GetSystemPrompt", mock.Anything, "manual_prompt").Return("current prompt", nil)
+	mockAI.On("RunCompaction", mock.Anything, full, "current prompt", "manual").Return(&ai.CompactionResult{NewPrompt: "better prompt"}, nil)
+	mockDiscord.On("SendAdminApprovalDM", "admin1", "better prompt", "manual").Return(nil)
+
+	h := NewHandler(&config.Config{AdminUserID: "admin1"}, mockDB, mockAI, mockDiscord)
+	rr := httptest.NewRecorder()
+	h.HandleCronCompact(rr, httptest.NewRequest("POST", "/cron/compact", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rr.Code)
+	}
+	mockDB.AssertExpectations(t)
+	mockAI.AssertExpectations(t)
+	mockDiscord.AssertExpectations(t)
+}
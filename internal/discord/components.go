@@ -2,26 +2,25 @@ package discord
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
-	"os"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/pauljones0/betterHardwareSwap/internal/store"
 )
 
 // routeComponentInteraction handles Button Clicks and select menu interactions (Confirm/Cancel AI rules, Delete Alerts).
-func routeComponentInteraction(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
+func (h *Handler) routeComponentInteraction(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
 	data := i.MessageComponentData()
 	parts := strings.Split(data.CustomID, "|")
 	action := parts[0]
-
-	db, err := store.NewStore(ctx, os.Getenv("GCP_PROJECT_ID"))
-	if err != nil {
-		respondError(w, "Database connection failed")
-		return
-	}
-	defer db.Close()
+	db := h.db
 
 	switch action {
 	case "wizard_ai":
@@ -50,54 +49,18 @@ func routeComponentInteraction(ctx context.Context, w http.ResponseWriter, i *di
 	case "wizard_manual":
 		writeJSON(w, discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseModal,
-			Data: &discordgo.InteractionResponseData{
-				CustomID: "modal_alert_wizard_manual",
-				Title:    "Manual Alert Entry",
-				Components: []discordgo.MessageComponent{
-					discordgo.ActionsRow{
-						Components: []discordgo.MessageComponent{
-							discordgo.TextInput{
-								CustomID:  "text_title",
-								Label:     "Name your alert (e.g., Cheap 4090)",
-								Style:     discordgo.TextInputShort,
-								Required:  true,
-								MaxLength: 50,
-							},
-						},
-					},
-					discordgo.ActionsRow{
-						Components: []discordgo.MessageComponent{
-							discordgo.TextInput{
-								CustomID:    "text_query",
-								Label:       "Query Syntax",
-								Style:       discordgo.TextInputParagraph,
-								Placeholder: "(rtx AND 4090) NOT (broken)",
-								Required:    true,
-								MaxLength:   150,
-							},
-						},
-					},
-				},
-			},
+			Data: manualAlertModal("modal_alert_wizard_manual"),
 		})
 
 	case "confirm_alert":
-		flow := "wizard"
-		if len(parts) > 2 {
-			if parts[2] == "Manual" {
-				flow = "manual"
-			}
+		content, confirmed := confirmAlert(ctx, db, parts, interactionUserID(i), alertScope(i))
+		if confirmed {
+			go h.triggerCompaction(i.GuildID)
 		}
-		_ = db.SaveAnalytics(ctx, store.AnalyticsRecord{
-			FlowType:  flow,
-			Outcome:   "Accepted_" + flow,
-			EditCount: 0,
-		})
-		go triggerCompaction(i.GuildID)
 		writeJSON(w, discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseUpdateMessage,
 			Data: &discordgo.InteractionResponseData{
-				Content:    "✨ **Alert Saved Successfully!**",
+				Content:    content,
 				Embeds:     nil,
 				Components: []discordgo.MessageComponent{},
 			},
@@ -112,9 +75,42 @@ func routeComponentInteraction(ctx context.Context, w http.ResponseWriter, i *di
 			},
 		})
 
+	case "mute_author", "unmute_author":
+		if len(parts) < 2 || parts[1] == "" {
+			respondError(w, "This seller can no longer be muted.")
+			return
+		}
+		h.handleMuteAuthor(ctx, w, i, parts[1], action == "mute_author")
+
+	case "flag_parse":
+		content := "⚠️ **Thanks for the report!** We'll use it to improve how deals are summarized."
+		if len(parts) < 2 {
+			content = "❌ This deal can no longer be reported."
+		} else if created, err := recordParseFlag(ctx, db, parts[1], interactionUserID(i)); err != nil {
+			log.Printf("Failed to record parse flag for %s: %v", parts[1], err)
+			content = "❌ This deal can no longer be reported."
+		} else if created {
+			go h.triggerCompaction(i.GuildID)
+		} else {
+			content = "⚠️ You've already reported this deal. Thanks!"
+		}
+		writeJSON(w, discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: content,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+
 	case "cancel_alert":
-		if len(parts) > 1 {
-			db.DeleteAlert(ctx, parts[1])
+		if len(parts) < 2 || !authorizeAlertAction(ctx, db, parts[1], interactionUserID(i), alertScope(i)) {
+			respondError(w, "That alert no longer exists or isn't yours to change here.")
+			return
+		}
+		if err := db.DeleteAlert(ctx, parts[1]); err != nil {
+			log.Printf("Failed to cancel alert %s: %v", parts[1], err)
+			respondError(w, "Failed to cancel the alert. Please try again.")
+			return
 		}
 		flow := "wizard"
 		if len(parts) > 2 {
@@ -127,7 +123,7 @@ func routeComponentInteraction(ctx context.Context, w http.ResponseWriter, i *di
 			Outcome:   "Cancelled_" + flow,
 			EditCount: 0,
 		})
-		go triggerCompaction(i.GuildID)
+		go h.triggerCompaction(i.GuildID)
 		writeJSON(w, discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseUpdateMessage,
 			Data: &discordgo.InteractionResponseData{
@@ -143,7 +139,7 @@ func routeComponentInteraction(ctx context.Context, w http.ResponseWriter, i *di
 			Outcome:   "Cancelled_Manual_Syntax_Error",
 			EditCount: 0,
 		})
-		go triggerCompaction(i.GuildID)
+		go h.triggerCompaction(i.GuildID)
 		writeJSON(w, discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseUpdateMessage,
 			Data: &discordgo.InteractionResponseData{
@@ -154,6 +150,10 @@ func routeComponentInteraction(ctx context.Context, w http.ResponseWriter, i *di
 		})
 
 	case "approve_prompt":
+		if !h.isBotOwner(interactionUserID(i)) {
+			respondError(w, "This action is restricted to the bot owner.")
+			return
+		}
 		flowType := "wizard"
 		if len(parts) > 1 {
 			flowType = parts[1]
@@ -162,7 +162,9 @@ func routeComponentInteraction(ctx context.Context, w http.ResponseWriter, i *di
 		promptParts := strings.Split(embedDesc, "```text\n")
 		if len(promptParts) > 1 {
 			newPrompt := strings.TrimSuffix(promptParts[1], "\n```")
-			_ = db.SetSystemPrompt(ctx, flowType+"_prompt", newPrompt)
+			if err := db.SetSystemPrompt(ctx, flowType+"_prompt", newPrompt); err == nil {
+				_ = db.AddPromptHistory(ctx, store.PromptHistoryEntry{PromptKey: flowType + "_prompt", Action: store.PromptActionApproved, PromptText: newPrompt})
+			}
 		}
 		records, _ := db.GetUnprocessedAnalyticsByFlow(ctx, flowType, 20)
 		var ids []string
@@ -180,6 +182,10 @@ func routeComponentInteraction(ctx context.Context, w http.ResponseWriter, i *di
 		})
 
 	case "reject_prompt":
+		if !h.isBotOwner(interactionUserID(i)) {
+			respondError(w, "This action is restricted to the bot owner.")
+			return
+		}
 		flowType := "wizard"
 		if len(parts) > 1 {
 			flowType = parts[1]
@@ -199,6 +205,37 @@ func routeComponentInteraction(ctx context.Context, w http.ResponseWriter, i *di
 			},
 		})
 
+	case "reset_prompt":
+		if !h.isBotOwner(interactionUserID(i)) {
+			respondError(w, "This action is restricted to the bot owner.")
+			return
+		}
+		flow := ""
+		if len(parts) > 1 {
+			flow = parts[1]
+		}
+		content := fmt.Sprintf("♻️ **The %s prompt is back on the built-in default.**", flow)
+		if err := resetPrompt(ctx, db, flow); err != nil {
+			log.Printf("Failed to reset %s prompt: %v", flow, err)
+			content = "⚠️ Failed to reset the prompt."
+		}
+		writeJSON(w, discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content:    content,
+				Components: []discordgo.MessageComponent{},
+			},
+		})
+
+	case "cancel_prompt_reset":
+		writeJSON(w, discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content:    "Kept the current prompt.",
+				Components: []discordgo.MessageComponent{},
+			},
+		})
+
 	case "edit_alert":
 		editCount := "1"
 		if len(parts) > 2 {
@@ -206,40 +243,18 @@ func routeComponentInteraction(ctx context.Context, w http.ResponseWriter, i *di
 		}
 		writeJSON(w, discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseModal,
-			Data: &discordgo.InteractionResponseData{
-				CustomID: "modal_alert_wizard_manual|" + editCount,
-				Title:    "Manual Alert Entry",
-				Components: []discordgo.MessageComponent{
-					discordgo.ActionsRow{
-						Components: []discordgo.MessageComponent{
-							discordgo.TextInput{
-								CustomID:  "text_title",
-								Label:     "Name your alert (e.g., Cheap 4090)",
-								Style:     discordgo.TextInputShort,
-								Required:  true,
-								MaxLength: 50,
-							},
-						},
-					},
-					discordgo.ActionsRow{
-						Components: []discordgo.MessageComponent{
-							discordgo.TextInput{
-								CustomID:    "text_query",
-								Label:       "Query Syntax",
-								Style:       discordgo.TextInputParagraph,
-								Placeholder: "(rtx AND 4090) NOT (broken)",
-								Required:    true,
-								MaxLength:   150,
-							},
-						},
-					},
-				},
-			},
+			Data: manualAlertModal("modal_alert_wizard_manual|" + editCount),
 		})
 
 	case "delete_alert":
-		if len(parts) > 1 {
-			db.DeleteAlert(ctx, parts[1])
+		if len(parts) < 2 || !authorizeAlertAction(ctx, db, parts[1], interactionUserID(i), alertScope(i)) {
+			respondError(w, "That alert no longer exists or isn't yours to change here.")
+			return
+		}
+		if err := db.DeleteAlert(ctx, parts[1]); err != nil {
+			log.Printf("Failed to delete alert %s: %v", parts[1], err)
+			respondError(w, "Failed to delete the alert. Please try again.")
+			return
 		}
 		writeJSON(w, discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseUpdateMessage,
@@ -251,17 +266,261 @@ func routeComponentInteraction(ctx context.Context, w http.ResponseWriter, i *di
 		})
 
 	case "delete_all_alerts":
-		db.DeleteAllUserAlerts(ctx, i.GuildID, i.Member.User.ID)
+		scope := alertScope(i)
+		if scope == "" {
+			respondError(w, "Could not identify user.")
+			return
+		}
+		db.DeleteAllUserAlerts(ctx, scope, interactionUserID(i))
 		writeJSON(w, discordgo.InteractionResponse{
 			Type: discordgo.InteractionResponseUpdateMessage,
 			Data: &discordgo.InteractionResponseData{
-				Content:    "🚨 **All your alerts on this server have been deleted.**",
+				Content:    "🚨 **All your alerts " + scopeNoun(scope) + " have been deleted.**",
 				Embeds:     nil,
 				Components: []discordgo.MessageComponent{},
 			},
 		})
 
+	case "servers_page":
+		if !h.isBotOwner(interactionUserID(i)) {
+			respondError(w, "This command is restricted to the bot owner.")
+			return
+		}
+		page := 0
+		if len(parts) > 1 {
+			page, _ = strconv.Atoi(parts[1])
+		}
+		embed, components, err := buildServersPage(ctx, db, page)
+		if err != nil {
+			respondError(w, "Failed to load servers.")
+			return
+		}
+		writeJSON(w, discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Embeds:     []*discordgo.MessageEmbed{embed},
+				Components: components,
+			},
+		})
+
+	case "search_body":
+		if len(parts) < 3 {
+			respondError(w, "Invalid alert.")
+			return
+		}
+		if !authorizeAlertAction(ctx, db, parts[1], interactionUserID(i), alertScope(i)) {
+			respondError(w, "That alert no longer exists or isn't yours to change here.")
+			return
+		}
+		enabled := parts[2] == "1"
+		if err := db.SetAlertSearchBody(ctx, parts[1], enabled); err != nil {
+			respondError(w, "Failed to update alert.")
+			return
+		}
+		content := "📝 This alert now only matches the AI summary of each post."
+		if enabled {
+			content = "🔎 This alert now also matches the full Reddit post body. Expect a few more (noisier) matches."
+		}
+		writeJSON(w, discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: content,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+
+	case "snooze_alert":
+		if len(parts) < 3 {
+			respondError(w, "Invalid alert.")
+			return
+		}
+		days, err := strconv.Atoi(parts[2])
+		if err != nil || (days != 0 && !slices.Contains(snoozeDays, days)) {
+			respondError(w, "Invalid snooze length.")
+			return
+		}
+		if !authorizeAlertAction(ctx, db, parts[1], interactionUserID(i), alertScope(i)) {
+			respondError(w, "That alert no longer exists or isn't yours to change here.")
+			return
+		}
+		var until time.Time
+		if days > 0 {
+			until = time.Now().AddDate(0, 0, days)
+		}
+		if err := db.SnoozeAlert(ctx, parts[1], until); err != nil {
+			respondError(w, "Failed to update alert.")
+			return
+		}
+		content := "🔔 This alert is awake and matching new deals again."
+		if days > 0 {
+			content = fmt.Sprintf("💤 This alert is snoozed until <t:%d:f>. It starts matching again on its own after that.", until.Unix())
+		}
+		writeJSON(w, discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: content,
+				Flags:   discordgo.MessageFlagsEphemeral,
+			},
+		})
+
+	case "find_page":
+		if len(parts) < 3 {
+			respondError(w, "Invalid search page.")
+			return
+		}
+		page, _ := strconv.Atoi(parts[1])
+		query := strings.Join(parts[2:], "|")
+		embed, components, err := buildFindPage(ctx, db, query, page)
+		if err != nil {
+			respondError(w, "Failed to search recent deals.")
+			return
+		}
+		writeJSON(w, discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Embeds:     []*discordgo.MessageEmbed{embed},
+				Components: components,
+			},
+		})
+
+	default:
+		respondError(w, "Unknown component action")
+	}
+}
+
+// isSelectMenu reports whether a component interaction came from a select menu rather than a button.
+func isSelectMenu(t discordgo.ComponentType) bool {
+	switch t {
+	case discordgo.SelectMenuComponent, discordgo.UserSelectMenuComponent, discordgo.RoleSelectMenuComponent,
+		discordgo.MentionableSelectMenuComponent, discordgo.ChannelSelectMenuComponent:
+		return true
+	}
+	return false
+}
+
+// selectedChannelIDs returns the channels picked in a channel select menu, in the order Discord sent them.
+// It returns nil for any other component, so a forged custom ID can't pass off user or role IDs as channels.
+func selectedChannelIDs(i *discordgo.Interaction) []string {
+	data := i.MessageComponentData()
+	if data.ComponentType != discordgo.ChannelSelectMenuComponent {
+		return nil
+	}
+	return data.Values
+}
+
+// routeSelectMenu handles picks from select menus, routed by custom ID like buttons are.
+func (h *Handler) routeSelectMenu(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
+	parts := strings.Split(i.MessageComponentData().CustomID, "|")
+	switch parts[0] {
+	case "setup_pick":
+		h.handleSetupPick(ctx, w, i, parts)
 	default:
 		respondError(w, "Unknown component action")
 	}
 }
+
+// confirmAlert checks that the staged alert named in a confirm_alert custom ID still exists and belongs to
+// userID in scope, since a cleanup sweep or a cancel from another device can delete it while the preview is
+// open. It records the confirmation and returns the message that replaces the preview, and whether the
+// alert was confirmed.
+func confirmAlert(ctx context.Context, db Storer, parts []string, userID, scope string) (string, bool) {
+	if len(parts) < 2 || parts[1] == "" {
+		return "❌ This alert can no longer be saved. Please set it up again with `/alert add`.", false
+	}
+	alert, err := db.GetAlert(ctx, parts[1])
+	if err != nil {
+		if errors.Is(err, store.ErrAlertNotFound) {
+			return "❌ **This alert no longer exists.** It was cancelled or expired before it was saved. Please set it up again with `/alert add`.", false
+		}
+		log.Printf("Failed to load staged alert %s: %v", parts[1], err)
+		return "❌ Couldn't confirm your alert right now. Please check `/alert list` before trying again.", false
+	}
+	if alert.UserID != userID || alert.ServerID != scope {
+		return "❌ This alert isn't yours to save.", false
+	}
+	recordAlertConfirmed(ctx, db, parts)
+	return "✨ **Alert Saved Successfully!**", true
+}
+
+// authorizeAlertAction reports whether docID is an existing alert that belongs to userID in scope (see
+// alertScope). Alert IDs travel in custom IDs, which a client can forge, so buttons that change an alert
+// check this before acting on it; the scope check also stops a user changing their alerts for one server
+// from another server or their DMs.
+func authorizeAlertAction(ctx context.Context, db Storer, docID, userID, scope string) bool {
+	if docID == "" || userID == "" || scope == "" {
+		return false
+	}
+	alert, err := db.GetAlert(ctx, docID)
+	if err != nil {
+		if !errors.Is(err, store.ErrAlertNotFound) {
+			log.Printf("Failed to load alert %s: %v", docID, err)
+		}
+		return false
+	}
+	return alert.UserID == userID && alert.ServerID == scope
+}
+
+// recordAlertConfirmed logs the accepted outcome of a wizard or manual flow, linked to the alert it saved
+// so /stats can compare how each flow's alerts perform. parts is the split confirm_alert custom ID.
+func recordAlertConfirmed(ctx context.Context, db Storer, parts []string) {
+	flow := "wizard"
+	if len(parts) > 2 && parts[2] == "Manual" {
+		flow = "manual"
+	}
+	alertID := ""
+	if len(parts) > 1 {
+		alertID = parts[1]
+	}
+	_ = db.SaveAnalytics(ctx, store.AnalyticsRecord{
+		FlowType:  flow,
+		Outcome:   "Accepted_" + flow,
+		EditCount: 0,
+		AlertID:   alertID,
+	})
+}
+
+// manualAlertModal builds the manual entry form. The query is split into keywords and optional
+// exclusions so longer queries fit; see manualQueryFromModal for how they are recombined.
+func manualAlertModal(customID string) *discordgo.InteractionResponseData {
+	return &discordgo.InteractionResponseData{
+		CustomID: customID,
+		Title:    "Manual Alert Entry",
+		Components: []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:  "text_title",
+						Label:     "Name your alert (e.g., Cheap 4090)",
+						Style:     discordgo.TextInputShort,
+						Required:  true,
+						MaxLength: 50,
+					},
+				},
+			},
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    "text_query",
+						Label:       "Keywords",
+						Style:       discordgo.TextInputParagraph,
+						Placeholder: manualKeywordsPlaceholder,
+						Required:    true,
+						MaxLength:   manualKeywordsMaxLength,
+					},
+				},
+			},
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    "text_exclude",
+						Label:       "Exclude (optional, comma separated)",
+						Style:       discordgo.TextInputParagraph,
+						Placeholder: "broken, for parts, mining",
+						Required:    false,
+						MaxLength:   manualExclusionsMaxLength,
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,153 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+	"github.com/pauljones0/betterHardwareSwap/internal/testutils"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRecordAlertConfirmed(t *testing.T) {
+	tests := []struct {
+		name     string
+		customID []string
+		wantFlow string
+	}{
+		{"Wizard", []string{"confirm_alert", "alert123"}, "wizard"},
+		{"Manual", []string{"confirm_alert", "alert123", "Manual"}, "manual"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := new(testutils.MockStore)
+			mockDB.On("SaveAnalytics", mock.Anything, mock.MatchedBy(func(rec store.AnalyticsRecord) bool {
+				return rec.AlertID == "alert123" && rec.FlowType == tt.wantFlow && rec.Outcome == "Accepted_"+tt.wantFlow
+			})).Return(nil)
+
+			recordAlertConfirmed(context.Background(), mockDB, tt.customID)
+
+			mockDB.AssertExpectations(t)
+		})
+	}
+}
+
+func TestConfirmAlert(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Staged alert exists", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+		mockDB.On("GetAlert", mock.Anything, "alert123").Return(&store.AlertRule{ID: "alert123", UserID: "user1", ServerID: "guild1"}, nil)
+		mockDB.On("SaveAnalytics", mock.Anything, mock.Anything).Return(nil)
+
+		content, confirmed := confirmAlert(ctx, mockDB, []string{"confirm_alert", "alert123"}, "user1", "guild1")
+		if !confirmed || !strings.Contains(content, "Saved Successfully") {
+			t.Errorf("expected success, got %q", content)
+		}
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("Staged alert deleted", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+		mockDB.On("GetAlert", mock.Anything, "alert123").Return(nil, store.ErrAlertNotFound)
+
+		content, confirmed := confirmAlert(ctx, mockDB, []string{"confirm_alert", "alert123"}, "user1", "guild1")
+		if confirmed || !strings.Contains(content, "no longer exists") {
+			t.Errorf("expected a clear error for a deleted alert, got %q", content)
+		}
+		mockDB.AssertNotCalled(t, "SaveAnalytics", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Another user's alert", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+		mockDB.On("GetAlert", mock.Anything, "alert123").Return(&store.AlertRule{ID: "alert123", UserID: "user2", ServerID: "guild1"}, nil)
+
+		content, confirmed := confirmAlert(ctx, mockDB, []string{"confirm_alert", "alert123"}, "user1", "guild1")
+		if confirmed || strings.Contains(content, "Saved Successfully") {
+			t.Errorf("expected confirming another user's alert to fail, got %q", content)
+		}
+		mockDB.AssertNotCalled(t, "SaveAnalytics", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Alert from another server", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+		mockDB.On("GetAlert", mock.Anything, "alert123").Return(&store.AlertRule{ID: "alert123", UserID: "user1", ServerID: "guild2"}, nil)
+
+		content, confirmed := confirmAlert(ctx, mockDB, []string{"confirm_alert", "alert123"}, "user1", "guild1")
+		if confirmed || strings.Contains(content, "Saved Successfully") {
+			t.Errorf("expected confirming an alert from another server to fail, got %q", content)
+		}
+	})
+
+	t.Run("Lookup fails", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+		mockDB.On("GetAlert", mock.Anything, "alert123").Return(nil, errors.New("unavailable"))
+
+		content, confirmed := confirmAlert(ctx, mockDB, []string{"confirm_alert", "alert123"}, "user1", "guild1")
+		if confirmed || strings.Contains(content, "Saved Successfully") {
+			t.Errorf("expected a failed lookup not to report success, got %q", content)
+		}
+		mockDB.AssertNotCalled(t, "SaveAnalytics", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAuthorizeAlertAction(t *testing.T) {
+	mockDB := new(testutils.MockStore)
+	mockDB.On("GetAlert", mock.Anything, "mine").Return(&store.AlertRule{ID: "mine", UserID: "user1", ServerID: "guild1"}, nil)
+	mockDB.On("GetAlert", mock.Anything, "theirs").Return(&store.AlertRule{ID: "theirs", UserID: "user2", ServerID: "guild1"}, nil)
+	mockDB.On("GetAlert", mock.Anything, "mine_dm").Return(&store.AlertRule{ID: "mine_dm", UserID: "user1", ServerID: store.DMScope("user1")}, nil)
+	mockDB.On("GetAlert", mock.Anything, "gone").Return(nil, store.ErrAlertNotFound)
+
+	tests := []struct {
+		name                 string
+		docID, userID, scope string
+		want                 bool
+	}{
+		{"Own alert", "mine", "user1", "guild1", true},
+		{"Own DM alert", "mine_dm", "user1", store.DMScope("user1"), true},
+		{"Another user's alert", "theirs", "user1", "guild1", false},
+		{"Own alert from another server", "mine", "user1", "guild2", false},
+		{"Own server alert from DMs", "mine", "user1", store.DMScope("user1"), false},
+		{"Own DM alert from a server", "mine_dm", "user1", "guild1", false},
+		{"Deleted alert", "gone", "user1", "guild1", false},
+		{"No user", "mine", "", "guild1", false},
+		{"No alert ID", "", "user1", "guild1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := authorizeAlertAction(context.Background(), mockDB, tt.docID, tt.userID, tt.scope); got != tt.want {
+				t.Errorf("authorizeAlertAction(%q, %q, %q) = %v, want %v", tt.docID, tt.userID, tt.scope, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSelectedChannelIDs(t *testing.T) {
+	var i discordgo.Interaction
+	if err := testutils.LoadFixture("discord_channel_select.json", &i); err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+
+	if i.Type != discordgo.InteractionMessageComponent || !isSelectMenu(i.MessageComponentData().ComponentType) {
+		t.Fatalf("expected a select menu interaction, got type %v", i.Type)
+	}
+	if got := selectedChannelIDs(&i); len(got) != 1 || got[0] != "777888999" {
+		t.Errorf("selectedChannelIDs() = %v, want [777888999]", got)
+	}
+	if !memberIsAdmin(&i) {
+		t.Error("expected the fixture's Manage Server permission to decode")
+	}
+
+	userMenu := &discordgo.Interaction{Type: discordgo.InteractionMessageComponent, Data: discordgo.MessageComponentInteractionData{
+		CustomID:      "setup_pick|ping||",
+		ComponentType: discordgo.UserSelectMenuComponent,
+		Values:        []string{"111122223333"},
+	}}
+	if got := selectedChannelIDs(userMenu); got != nil {
+		t.Errorf("expected picks from a user menu to be ignored, got %v", got)
+	}
+}
@@ -0,0 +1,123 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pauljones0/betterHardwareSwap/internal/matcher"
+)
+
+// explainMatcher runs `/explain` checks. It is separate from the pipeline's matcher but behaves the same.
+var explainMatcher = matcher.New()
+
+// redditPostIDPattern pulls the post ID out of a Reddit link, e.g. .../comments/1abc23d/some_title/.
+var redditPostIDPattern = regexp.MustCompile(`/comments/([a-z0-9]+)`)
+
+// bareRedditIDPattern matches a post ID given on its own, with or without the t3_ prefix.
+var bareRedditIDPattern = regexp.MustCompile(`^(?:t3_)?([a-z0-9]+)$`)
+
+// redditPostID returns the post ID in a Reddit link or bare ID, or "" if ref is neither.
+func redditPostID(ref string) string {
+	ref = strings.ToLower(strings.TrimSpace(ref))
+	if m := redditPostIDPattern.FindStringSubmatch(ref); m != nil {
+		return m[1]
+	}
+	if m := bareRedditIDPattern.FindStringSubmatch(ref); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// handleExplain handles `/explain <post> <alert>`, which shows why a deal the bot handled did or didn't
+// match one of the user's alerts.
+func (h *Handler) handleExplain(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
+	postRef := ""
+	alertNum := 0
+	for _, opt := range i.ApplicationCommandData().Options {
+		switch opt.Name {
+		case "post":
+			postRef = opt.StringValue()
+		case "alert":
+			alertNum = int(opt.IntValue())
+		}
+	}
+
+	content, err := runExplain(ctx, h.db, alertScope(i), interactionUserID(i), postRef, alertNum, time.Now())
+	if err != nil {
+		log.Printf("Failed to explain match for user %s: %v", interactionUserID(i), err)
+		respondError(w, "Failed to load your alerts.")
+		return
+	}
+
+	writeJSON(w, discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// runExplain matches alert number alertNum (as numbered in `/alert list`) against the stored cleaned text of
+// the referenced post and returns the explanation to show the user. Posts are only explained from their
+// stored record, so deals the bot never handled, or that were trimmed, can't be checked.
+func runExplain(ctx context.Context, db Storer, scope, userID, postRef string, alertNum int, now time.Time) (string, error) {
+	redditID := redditPostID(postRef)
+	if redditID == "" {
+		return "⚠️ That doesn't look like a Reddit post link. Paste the link to the post, e.g. `https://www.reddit.com/r/CanadianHardwareSwap/comments/1abc23d/...`.", nil
+	}
+
+	alerts, err := db.GetUserAlerts(ctx, scope, userID)
+	if err != nil {
+		return "", err
+	}
+	if alertNum < 1 || alertNum > len(alerts) {
+		return fmt.Sprintf("⚠️ You don't have an alert #%d %s. Check the numbers in `/alert list`.", alertNum, scopeNoun(scope)), nil
+	}
+	alert := alerts[alertNum-1]
+
+	record, err := db.GetPostRecord(ctx, redditID)
+	if err != nil {
+		log.Printf("Explain: no post record for %s: %v", redditID, err)
+		return "🤷 I don't have that post on record. Only recent deals the bot has posted can be explained.", nil
+	}
+	if record.Corpus == "" {
+		return "🤷 That post was saved before its cleaned text was kept, so it can't be explained.", nil
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "**Alert #%d:** \"%s\"\n**Post:** %s\n\n", alertNum, alert.RawQuery, record.CleanedTitle)
+
+	result := explainMatcher.Explain(record.Corpus, alert)
+	switch {
+	case result.Matched && result.MatchedAnyOf != "":
+		fmt.Fprintf(&b, "✅ The keywords match this post (it mentions `%s`).", result.MatchedAnyOf)
+	case result.Matched:
+		b.WriteString("✅ The keywords match this post.")
+	case result.FiredMustNot != "":
+		fmt.Fprintf(&b, "❌ Excluded: the post mentions `%s`, which this alert must not contain.", result.FiredMustNot)
+	case result.FailedMustHave != "":
+		fmt.Fprintf(&b, "❌ No match: the post doesn't mention `%s`, which this alert requires.", result.FailedMustHave)
+	default:
+		fmt.Fprintf(&b, "❌ No match: the post mentions none of `%s`.", strings.Join(alert.AnyOf, "`, `"))
+	}
+
+	if alert.TrendingComments > 0 {
+		fmt.Fprintf(&b, "\n• This is a trending alert: it only fires once a matching deal reaches %d comments.", alert.TrendingComments)
+	}
+	if alert.Paused {
+		b.WriteString("\n• This alert is paused, so it isn't matching anything right now.")
+	} else if alert.Snoozed(now) {
+		fmt.Fprintf(&b, "\n• This alert is snoozed until <t:%d:f>.", alert.SnoozeUntil.Unix())
+	}
+	if alert.SearchBody {
+		b.WriteString("\n• This alert also searches the full Reddit post, which isn't stored; only the cleaned text was checked.")
+	}
+	return b.String(), nil
+}
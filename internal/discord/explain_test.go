@@ -0,0 +1,84 @@
+package discord
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+	"github.com/pauljones0/betterHardwareSwap/internal/testutils"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRedditPostID(t *testing.T) {
+	tests := map[string]string{
+		"https://www.reddit.com/r/CanadianHardwareSwap/comments/1abc23d/h_rtx_3080_w_cash/": "1abc23d",
+		"https://old.reddit.com/r/CanadianHardwareSwap/comments/1ABC23D":                    "1abc23d",
+		"t3_1abc23d":                       "1abc23d",
+		" 1abc23d ":                        "1abc23d",
+		"https://discord.com/channels/1/2": "",
+	}
+	for ref, want := range tests {
+		if got := redditPostID(ref); got != want {
+			t.Errorf("redditPostID(%q) = %q, want %q", ref, got, want)
+		}
+	}
+}
+
+func TestRunExplain(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	postURL := "https://www.reddit.com/r/CanadianHardwareSwap/comments/1abc23d/h_rtx_3080/"
+	record := &store.PostRecord{RedditID: "1abc23d", CleanedTitle: "[WTS] RTX 3080 FE", Corpus: "[WTS] RTX 3080 FE Lightly used, never mined. Toronto, ON"}
+
+	tests := []struct {
+		name  string
+		alert store.AlertRule
+		want  string
+	}{
+		{"Matched", store.AlertRule{RawQuery: "3080 in toronto", MustHave: []string{"3080", "toronto"}}, "✅ The keywords match"},
+		{"MustNot", store.AlertRule{RawQuery: "3080 not used", MustHave: []string{"3080"}, MustNot: []string{"used"}}, "mentions `used`, which this alert must not contain"},
+		{"MustHave", store.AlertRule{RawQuery: "3090", MustHave: []string{"3090"}}, "doesn't mention `3090`"},
+		{"AnyOf", store.AlertRule{RawQuery: "3080 out west", MustHave: []string{"3080"}, AnyOf: []string{"vancouver", "calgary"}}, "mentions none of `vancouver`, `calgary`"},
+		{"Paused", store.AlertRule{RawQuery: "3080", MustHave: []string{"3080"}, Paused: true}, "This alert is paused"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := new(testutils.MockStore)
+			mockDB.On("GetUserAlerts", mock.Anything, "guild1", "user1").Return([]store.AlertRule{{RawQuery: "other"}, tt.alert}, nil)
+			mockDB.On("GetPostRecord", mock.Anything, "1abc23d").Return(record, nil)
+
+			content, err := runExplain(ctx, mockDB, "guild1", "user1", postURL, 2, now)
+			if err != nil {
+				t.Fatalf("runExplain failed: %v", err)
+			}
+			if !strings.Contains(content, tt.want) {
+				t.Errorf("expected %q in %q", tt.want, content)
+			}
+		})
+	}
+
+	t.Run("Unknown post", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+		mockDB.On("GetUserAlerts", mock.Anything, "guild1", "user1").Return([]store.AlertRule{{RawQuery: "3080"}}, nil)
+		mockDB.On("GetPostRecord", mock.Anything, "1abc23d").Return(nil, store.ErrNotFound)
+
+		content, _ := runExplain(ctx, mockDB, "guild1", "user1", postURL, 1, now)
+		if !strings.Contains(content, "don't have that post on record") {
+			t.Errorf("expected an unknown post to be explained as such, got %q", content)
+		}
+	})
+
+	t.Run("Unknown alert", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+		mockDB.On("GetUserAlerts", mock.Anything, "guild1", "user1").Return([]store.AlertRule{{RawQuery: "3080"}}, nil)
+
+		content, _ := runExplain(ctx, mockDB, "guild1", "user1", postURL, 3, now)
+		if !strings.Contains(content, "don't have an alert #3") {
+			t.Errorf("expected an out of range alert number to be rejected, got %q", content)
+		}
+		mockDB.AssertNotCalled(t, "GetPostRecord", mock.Anything, mock.Anything)
+	})
+}
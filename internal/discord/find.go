@@ -0,0 +1,214 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+)
+
+const (
+	// findSearchDepth is how many recent post records `/find` searches. It matches the retention kept by TrimOldPosts.
+	findSearchDepth = 500
+	// findMaxResults caps how many matches `/find` returns across all pages.
+	findMaxResults = 25
+	// findPageSize is how many matches are shown per page of `/find`.
+	findPageSize = 5
+	// findMaxQueryLength keeps `find_page|<page>|<query>` within Discord's 100 character custom ID limit.
+	findMaxQueryLength = 80
+)
+
+// findMatch is a stored post scored against a `/find` query.
+type findMatch struct {
+	post  store.PostRecord
+	score int
+}
+
+// handleFind searches recently posted deals for the given keywords.
+func (h *Handler) handleFind(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
+	query := ""
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == "query" {
+			query = strings.TrimSpace(opt.StringValue())
+		}
+	}
+	if query == "" {
+		respondError(w, "Please provide something to search for.")
+		return
+	}
+	query = truncateFindQuery(query)
+
+	embed, components, err := buildFindPage(ctx, h.db, query, 0)
+	if err != nil {
+		log.Printf("Failed to search posts for %q: %v", query, err)
+		respondError(w, "Failed to search recent deals.")
+		return
+	}
+
+	writeJSON(w, discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds:     []*discordgo.MessageEmbed{embed},
+			Components: components,
+			Flags:      discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// parseFindQuery splits a search into lowercase terms to include and exclude.
+// Terms prefixed with `-` or following NOT are excluded; AND/OR are ignored.
+func parseFindQuery(query string) (include, exclude []string) {
+	negateNext := false
+	for _, field := range strings.Fields(strings.ToLower(query)) {
+		switch field {
+		case "and", "or":
+			continue
+		case "not":
+			negateNext = true
+			continue
+		}
+
+		term := strings.Trim(field, "()\"'")
+		negate := negateNext
+		negateNext = false
+		if strings.HasPrefix(term, "-") {
+			negate = true
+			term = strings.TrimPrefix(term, "-")
+		}
+		if term == "" {
+			continue
+		}
+
+		if negate {
+			exclude = append(exclude, term)
+		} else {
+			include = append(include, term)
+		}
+	}
+	return include, exclude
+}
+
+// searchPosts scores posts by how many included terms they contain, dropping any with an excluded term.
+// Posts are expected newest first, so ties keep the most recent deal on top.
+func searchPosts(posts []store.PostRecord, include, exclude []string) []findMatch {
+	var matches []findMatch
+	for _, p := range posts {
+		corpus := strings.ToLower(p.CleanedTitle + " " + p.Corpus)
+
+		excluded := false
+		for _, term := range exclude {
+			if strings.Contains(corpus, term) {
+				excluded = true
+				break
+			}
+		}
+		if excluded {
+			continue
+		}
+
+		score := 0
+		for _, term := range include {
+			if strings.Contains(corpus, term) {
+				score++
+			}
+		}
+		if score > 0 {
+			matches = append(matches, findMatch{post: p, score: score})
+		}
+	}
+
+	sort.SliceStable(matches, func(a, b int) bool {
+		return matches[a].score > matches[b].score
+	})
+	if len(matches) > findMaxResults {
+		matches = matches[:findMaxResults]
+	}
+	return matches
+}
+
+// truncateFindQuery cuts query to findMaxQueryLength characters. Discord counts custom ID length in
+// characters, and cutting by bytes could split a multi-byte character.
+func truncateFindQuery(query string) string {
+	if r := []rune(query); len(r) > findMaxQueryLength {
+		return string(r[:findMaxQueryLength])
+	}
+	return query
+}
+
+// buildFindPage runs a search over recent posts and renders one page of results with pagination buttons.
+func buildFindPage(ctx context.Context, db Storer, query string, page int) (*discordgo.MessageEmbed, []discordgo.MessageComponent, error) {
+	include, exclude := parseFindQuery(query)
+
+	var matches []findMatch
+	if len(include) > 0 {
+		posts, err := db.GetRecentPosts(ctx, findSearchDepth)
+		if err != nil {
+			return nil, nil, err
+		}
+		matches = searchPosts(posts, include, exclude)
+	}
+
+	totalPages := (len(matches) + findPageSize - 1) / findPageSize
+	if totalPages == 0 {
+		totalPages = 1
+	}
+	if page < 0 {
+		page = 0
+	}
+	if page >= totalPages {
+		page = totalPages - 1
+	}
+
+	start := page * findPageSize
+	end := start + findPageSize
+	if end > len(matches) {
+		end = len(matches)
+	}
+
+	desc := ""
+	if len(matches) == 0 {
+		desc = "No recent deals matched your search. Try fewer or broader keywords."
+	}
+	for idx, m := range matches[start:end] {
+		title := m.post.CleanedTitle
+		if m.post.URL != "" {
+			title = fmt.Sprintf("[%s](%s)", title, m.post.URL)
+		}
+		desc += fmt.Sprintf("**%d.** %s • <t:%d:R>\n", start+idx+1, title, m.post.PostedAt.Unix())
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("🔎 Recent deals matching \"%s\"", query),
+		Description: desc,
+		Color:       0x00B0F4,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: fmt.Sprintf("Page %d of %d • %d matches", page+1, totalPages, len(matches)),
+		},
+	}
+
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{
+			Components: []discordgo.MessageComponent{
+				discordgo.Button{
+					Label:    "◀ Prev",
+					Style:    discordgo.SecondaryButton,
+					CustomID: fmt.Sprintf("find_page|%d|%s", page-1, query),
+					Disabled: page == 0,
+				},
+				discordgo.Button{
+					Label:    "Next ▶",
+					Style:    discordgo.SecondaryButton,
+					CustomID: fmt.Sprintf("find_page|%d|%s", page+1, query),
+					Disabled: page >= totalPages-1,
+				},
+			},
+		},
+	}
+
+	return embed, components, nil
+}
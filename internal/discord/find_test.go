@@ -0,0 +1,130 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+	"github.com/pauljones0/betterHardwareSwap/internal/testutils"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestParseFindQuery(t *testing.T) {
+	tests := []struct {
+		query       string
+		wantInclude []string
+		wantExclude []string
+	}{
+		{"RTX 3080", []string{"rtx", "3080"}, nil},
+		{"3080 -broken", []string{"3080"}, []string{"broken"}},
+		{"(rtx AND 4090) NOT broken", []string{"rtx", "4090"}, []string{"broken"}},
+		{"-", nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			include, exclude := parseFindQuery(tt.query)
+			if fmt.Sprint(include) != fmt.Sprint(tt.wantInclude) {
+				t.Errorf("include = %v, want %v", include, tt.wantInclude)
+			}
+			if fmt.Sprint(exclude) != fmt.Sprint(tt.wantExclude) {
+				t.Errorf("exclude = %v, want %v", exclude, tt.wantExclude)
+			}
+		})
+	}
+}
+
+func TestTruncateFindQuery(t *testing.T) {
+	if got := truncateFindQuery("rtx 3080"); got != "rtx 3080" {
+		t.Errorf("expected a short query unchanged, got %q", got)
+	}
+
+	// An odd ASCII prefix puts the byte limit in the middle of a two-byte character.
+	long := "a" + strings.Repeat("ñ", findMaxQueryLength)
+	got := truncateFindQuery(long)
+	if !utf8.ValidString(got) {
+		t.Fatalf("truncated query %q is not valid UTF-8", got)
+	}
+	if n := utf8.RuneCountInString(got); n != findMaxQueryLength {
+		t.Errorf("truncated query has %d characters, want %d", n, findMaxQueryLength)
+	}
+	if want := "a" + strings.Repeat("ñ", findMaxQueryLength-1); got != want {
+		t.Errorf("truncateFindQuery = %q, want %q", got, want)
+	}
+}
+
+func TestBuildFindPage(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+
+	posts := []store.PostRecord{
+		{RedditID: "t3_a", CleanedTitle: "RTX 3080 FE", Corpus: "Toronto local pickup", URL: "https://reddit.com/a", PostedAt: now},
+		{RedditID: "t3_b", CleanedTitle: "Broken RTX 3080", Corpus: "for parts", URL: "https://reddit.com/b", PostedAt: now},
+		{RedditID: "t3_c", CleanedTitle: "Mechanical Keyboard", Corpus: "Vancouver", URL: "https://reddit.com/c", PostedAt: now},
+		{RedditID: "t3_d", CleanedTitle: "RTX 3070", Corpus: "Toronto", URL: "https://reddit.com/d", PostedAt: now},
+	}
+	for n := 0; n < 6; n++ {
+		posts = append(posts, store.PostRecord{RedditID: fmt.Sprintf("t3_rtx%d", n), CleanedTitle: "RTX 4090", PostedAt: now})
+	}
+
+	t.Run("Ranks by matched terms and honours exclusions", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+		mockDB.On("GetRecentPosts", mock.Anything, findSearchDepth).Return(posts, nil)
+
+		embed, components, err := buildFindPage(ctx, mockDB, "3080 toronto -broken", 0)
+		if err != nil {
+			t.Fatalf("buildFindPage failed: %v", err)
+		}
+
+		lines := strings.Split(strings.TrimSpace(embed.Description), "\n")
+		if len(lines) != 2 {
+			t.Fatalf("expected 2 results, got %d: %q", len(lines), embed.Description)
+		}
+		if !strings.Contains(lines[0], "[RTX 3080 FE](https://reddit.com/a)") {
+			t.Errorf("expected the post matching both terms first, got %q", lines[0])
+		}
+		if strings.Contains(embed.Description, "reddit.com/b") {
+			t.Errorf("expected excluded post to be dropped, got %q", embed.Description)
+		}
+		if !components[0].(discordgo.ActionsRow).Components[1].(discordgo.Button).Disabled {
+			t.Error("expected Next to be disabled with a single page of results")
+		}
+	})
+
+	t.Run("Paginates results", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+		mockDB.On("GetRecentPosts", mock.Anything, findSearchDepth).Return(posts, nil)
+
+		embed, components, err := buildFindPage(ctx, mockDB, "rtx", 1)
+		if err != nil {
+			t.Fatalf("buildFindPage failed: %v", err)
+		}
+
+		if embed.Footer.Text != "Page 2 of 2 • 9 matches" {
+			t.Errorf("unexpected footer %q", embed.Footer.Text)
+		}
+		prev := components[0].(discordgo.ActionsRow).Components[0].(discordgo.Button)
+		if prev.Disabled || prev.CustomID != "find_page|0|rtx" {
+			t.Errorf("unexpected Prev button %+v", prev)
+		}
+	})
+
+	t.Run("Exclusion-only query skips the lookup", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+
+		embed, _, err := buildFindPage(ctx, mockDB, "-broken", 0)
+		if err != nil {
+			t.Fatalf("buildFindPage failed: %v", err)
+		}
+
+		mockDB.AssertNotCalled(t, "GetRecentPosts", mock.Anything, mock.Anything)
+		if !strings.Contains(embed.Description, "No recent deals") {
+			t.Errorf("expected an empty result message, got %q", embed.Description)
+		}
+	})
+}
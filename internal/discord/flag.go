@@ -0,0 +1,28 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+)
+
+// recordParseFlag saves a "clean" flow analytics record for a deal userID reported as badly summarized,
+// so the post cleaning prompt can be compacted against real failures. It returns false when userID had
+// already reported the deal, so repeated clicks count once.
+func recordParseFlag(ctx context.Context, db Storer, redditID, userID string) (bool, error) {
+	record, err := db.GetPostRecord(ctx, redditID)
+	if err != nil {
+		return false, fmt.Errorf("failed to load post record: %w", err)
+	}
+
+	return db.SaveParseFlag(ctx, store.AnalyticsRecord{
+		FlowType:     "clean",
+		RawPost:      record.RawText,
+		CleanedTitle: record.CleanedTitle,
+		CleanedText:  record.Corpus,
+		Outcome:      "Flagged_Bad_Parse",
+		RedditID:     redditID,
+		UserID:       userID,
+	})
+}
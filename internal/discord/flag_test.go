@@ -0,0 +1,81 @@
+package discord
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+	"github.com/pauljones0/betterHardwareSwap/internal/testutils"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRecordParseFlag(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Saves a clean flow record for the flagged post", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+		mockDB.On("GetPostRecord", mock.Anything, "abc123").Return(&store.PostRecord{
+			RedditID:     "abc123",
+			CleanedTitle: "RTX 3080",
+			Corpus:       "RTX 3080 Great condition Toronto",
+			RawText:      "[ON] [H] 3080 FE [W] Cash\n\nGreat condition, Toronto pickup",
+			URL:          "https://reddit.com/r/CanadianHardwareSwap/comments/abc123",
+		}, nil)
+		mockDB.On("SaveParseFlag", mock.Anything, mock.MatchedBy(func(r store.AnalyticsRecord) bool {
+			return r.FlowType == "clean" && r.Outcome == "Flagged_Bad_Parse" &&
+				r.RawPost == "[ON] [H] 3080 FE [W] Cash\n\nGreat condition, Toronto pickup" &&
+				r.CleanedTitle == "RTX 3080" && r.CleanedText == "RTX 3080 Great condition Toronto" &&
+				r.OriginalUserPrompt == "" && r.FinalSavedQuery == "" && r.AISuggestedQuery == "" &&
+				r.RedditID == "abc123" && r.UserID == "reporter1"
+		})).Return(true, nil)
+
+		created, err := recordParseFlag(ctx, mockDB, "abc123", "reporter1")
+		if err != nil {
+			t.Fatalf("recordParseFlag failed: %v", err)
+		}
+		if !created {
+			t.Error("expected the first report to be saved")
+		}
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("Missing record is an error", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+		mockDB.On("GetPostRecord", mock.Anything, "gone").Return(nil, store.ErrNotFound)
+
+		if _, err := recordParseFlag(ctx, mockDB, "gone", "reporter1"); err == nil {
+			t.Error("expected an error for a missing post record")
+		}
+		mockDB.AssertNotCalled(t, "SaveParseFlag", mock.Anything, mock.Anything)
+	})
+}
+
+func TestHandleInteraction_FlagParseCountsOncePerUser(t *testing.T) {
+	th := newInteractionHarness(t)
+	th.db.On("GetPostRecord", mock.Anything, "abc123").Return(&store.PostRecord{RedditID: "abc123", RawText: "3080 FE"}, nil)
+	th.db.On("SaveParseFlag", mock.Anything, mock.MatchedBy(func(r store.AnalyticsRecord) bool {
+		return r.UserID == "first_reporter"
+	})).Return(true, nil)
+	th.db.On("SaveParseFlag", mock.Anything, mock.MatchedBy(func(r store.AnalyticsRecord) bool {
+		return r.UserID == "repeat_reporter"
+	})).Return(false, nil)
+
+	click := func(userID string) discordgo.InteractionResponse {
+		return th.serve(t, discordgo.Interaction{
+			ID:   "interaction_flag",
+			Type: discordgo.InteractionMessageComponent,
+			User: &discordgo.User{ID: userID},
+			Data: discordgo.MessageComponentInteractionData{CustomID: "flag_parse|abc123", ComponentType: discordgo.ButtonComponent},
+		})
+	}
+
+	if resp := click("first_reporter"); resp.Data == nil || !strings.Contains(resp.Data.Content, "Thanks for the report") {
+		t.Errorf("expected the report to be acknowledged, got %+v", resp.Data)
+	}
+	if resp := click("repeat_reporter"); resp.Data == nil || !strings.Contains(resp.Data.Content, "already reported") {
+		t.Errorf("expected a repeat report to be acknowledged as a duplicate, got %+v", resp.Data)
+	}
+	th.db.AssertExpectations(t)
+}
@@ -0,0 +1,110 @@
+package discord
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+)
+
+// maxInspectFields is Discord's limit on fields per embed.
+const maxInspectFields = 25
+
+// handleInspect shows an admin every alert a member has on the server via `/inspect @user`, with the
+// keyword lists exactly as the matcher sees them, so "my alert isn't firing" reports can be debugged.
+func (h *Handler) handleInspect(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
+	if i.GuildID == "" || !memberIsAdmin(i) {
+		respondError(w, "Only server admins (Manage Server permission) can inspect alerts.")
+		return
+	}
+
+	userID := ""
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == "user" {
+			userID, _ = opt.Value.(string)
+		}
+	}
+	if userID == "" {
+		respondError(w, "Pick a member to inspect.")
+		return
+	}
+
+	alerts, err := h.db.GetUserAlerts(ctx, i.GuildID, userID)
+	if err != nil {
+		log.Printf("Inspect: failed to load alerts for user %s: %v", userID, err)
+		respondError(w, "Failed to load alerts.")
+		return
+	}
+
+	writeJSON(w, discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Embeds: []*discordgo.MessageEmbed{buildInspectEmbed(userID, alerts, time.Now())},
+			Flags:  discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// buildInspectEmbed renders one field per alert with its stored keyword arrays and matching settings.
+func buildInspectEmbed(userID string, alerts []store.AlertRule, now time.Time) *discordgo.MessageEmbed {
+	embed := &discordgo.MessageEmbed{
+		Title:       "🔍 Alert Inspector",
+		Description: fmt.Sprintf("<@%s> has **%d** alert(s) on this server.", userID, len(alerts)),
+		Color:       0x00B0F4,
+	}
+
+	for idx, a := range alerts {
+		if idx == maxInspectFields {
+			embed.Footer = &discordgo.MessageEmbedFooter{Text: fmt.Sprintf("Showing the first %d alerts.", maxInspectFields)}
+			break
+		}
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "**ID:** `%s`\n", a.ID)
+		fmt.Fprintf(&b, "**MustHave:** `%s`\n", inspectTerms(a.MustHave))
+		fmt.Fprintf(&b, "**AnyOf:** `%s`\n", inspectTerms(a.AnyOf))
+		fmt.Fprintf(&b, "**MustNot:** `%s`\n", inspectTerms(a.MustNot))
+		fmt.Fprintf(&b, "**Search full post:** %t • **Matches:** %d", a.SearchBody, a.MatchCount)
+		if a.ExcludeBundles {
+			b.WriteString(" • **Skips bundles**")
+		}
+		if a.Paused {
+			b.WriteString(" • **Paused**")
+		}
+		if a.Snoozed(now) {
+			fmt.Fprintf(&b, " • **Snoozed until** <t:%d:f>", a.SnoozeUntil.Unix())
+		}
+
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:  truncateField(fmt.Sprintf("#%d: %q", idx+1, a.RawQuery), 256),
+			Value: truncateField(b.String(), 1024),
+		})
+	}
+	return embed
+}
+
+// inspectTerms renders a keyword list as JSON so empty strings and stray spaces are visible.
+func inspectTerms(terms []string) string {
+	if len(terms) == 0 {
+		return "[]"
+	}
+	out, err := json.Marshal(terms)
+	if err != nil {
+		return fmt.Sprintf("%q", terms)
+	}
+	return strings.ReplaceAll(string(out), "`", "'")
+}
+
+// truncateField cuts s to Discord's max runes for an embed field part.
+func truncateField(s string, max int) string {
+	if r := []rune(s); len(r) > max {
+		return string(r[:max-1]) + "…"
+	}
+	return s
+}
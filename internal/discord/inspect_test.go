@@ -0,0 +1,64 @@
+package discord
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestBuildInspectEmbed(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	alerts := []store.AlertRule{
+		{ID: "a1", RawQuery: "3080 in toronto", MustHave: []string{"toronto"}, AnyOf: []string{"rtx 3080", "3080"}, MatchCount: 2},
+		{ID: "a2", RawQuery: "cheap ssd", AnyOf: []string{"ssd", "nvme "}, MustNot: []string{"sata"}, SearchBody: true, SnoozeUntil: now.Add(time.Hour)},
+	}
+
+	embed := buildInspectEmbed("user1", alerts, now)
+
+	if !strings.Contains(embed.Description, "<@user1>") || !strings.Contains(embed.Description, "**2**") {
+		t.Errorf("unexpected description %q", embed.Description)
+	}
+	if len(embed.Fields) != 2 {
+		t.Fatalf("expected one field per alert, got %d", len(embed.Fields))
+	}
+
+	first := embed.Fields[0].Value
+	for _, want := range []string{"`a1`", "**MustHave:** `[\"toronto\"]`", "**AnyOf:** `[\"rtx 3080\",\"3080\"]`", "**MustNot:** `[]`", "**Matches:** 2"} {
+		if !strings.Contains(first, want) {
+			t.Errorf("expected %q in the first alert, got %q", want, first)
+		}
+	}
+	second := embed.Fields[1].Value
+	for _, want := range []string{"`[\"ssd\",\"nvme \"]`", "`[\"sata\"]`", "**Search full post:** true", "Snoozed until"} {
+		if !strings.Contains(second, want) {
+			t.Errorf("expected %q in the second alert, got %q", want, second)
+		}
+	}
+	if embed.Fields[1].Name != `#2: "cheap ssd"` {
+		t.Errorf("unexpected field name %q", embed.Fields[1].Name)
+	}
+}
+
+func TestHandleInteraction_InspectRequiresAdmin(t *testing.T) {
+	th := newInteractionHarness(t)
+
+	resp := th.serve(t, discordgo.Interaction{
+		ID:      "interaction_inspect",
+		Type:    discordgo.InteractionApplicationCommand,
+		GuildID: "guild1",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "inspect_user"}, Permissions: discordgo.PermissionSendMessages},
+		Data: discordgo.ApplicationCommandInteractionData{
+			Name:    "inspect",
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{{Name: "user", Type: discordgo.ApplicationCommandOptionUser, Value: "user1"}},
+		},
+	})
+
+	if resp.Data == nil || !strings.Contains(resp.Data.Content, "Only server admins") {
+		t.Errorf("expected an admin-only rejection, got %+v", resp.Data)
+	}
+	th.db.AssertNotCalled(t, "GetUserAlerts", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -9,18 +9,104 @@ import (
 	"io"
 	"log"
 	"net/http"
-	"os"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/pauljones0/betterHardwareSwap/internal/ai"
+	"github.com/pauljones0/betterHardwareSwap/internal/config"
 	"github.com/pauljones0/betterHardwareSwap/internal/logger"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
 )
 
+// Storer defines the database operations needed by the interaction handlers.
+type Storer interface {
+	SaveServerConfig(ctx context.Context, serverID string, cfg store.ServerConfig) error
+	GetServerConfig(ctx context.Context, serverID string) (*store.ServerConfig, error)
+	GetAllServers(ctx context.Context) ([]store.ServerConfig, error)
+	AddBlocklistTerm(ctx context.Context, serverID, term string) error
+	RemoveBlocklistTerm(ctx context.Context, serverID, term string) error
+	CountServerAlerts(ctx context.Context, serverID string) (int64, error)
+	GetRecentPosts(ctx context.Context, limit int) ([]store.PostRecord, error)
+	GetPostRecord(ctx context.Context, redditID string) (*store.PostRecord, error)
+	AddAlert(ctx context.Context, rule store.AlertRule) error
+	GetAlert(ctx context.Context, docID string) (*store.AlertRule, error)
+	GetUserAlerts(ctx context.Context, serverID, userID string) ([]store.AlertRule, error)
+	DeleteAlert(ctx context.Context, docID string) error
+	SetAlertSearchBody(ctx context.Context, docID string, enabled bool) error
+	SnoozeAlert(ctx context.Context, docID string, until time.Time) error
+	SetAllAlertsEnabled(ctx context.Context, serverID, userID string, enabled bool) (int, error)
+	SetAllAlertsExcludeBundles(ctx context.Context, serverID, userID string, exclude bool) (int, error)
+	DeleteAllUserAlerts(ctx context.Context, serverID, userID string) error
+	SaveAnalytics(ctx context.Context, record store.AnalyticsRecord) error
+	SaveParseFlag(ctx context.Context, record store.AnalyticsRecord) (bool, error)
+	GetAlertPerformance(ctx context.Context) ([]store.AlertPerformance, error)
+	GetUnprocessedAnalyticsByFlow(ctx context.Context, flowType string, limit int) ([]store.AnalyticsRecord, error)
+	DeleteAnalyticsChunk(ctx context.Context, ids []string) error
+	GetSystemPrompt(ctx context.Context, key string) (string, error)
+	GetSystemPromptRecord(ctx context.Context, key string) (*store.SystemPrompt, error)
+	SetSystemPrompt(ctx context.Context, key, promptText string) error
+	AddPromptHistory(ctx context.Context, entry store.PromptHistoryEntry) error
+	SetQuietHours(ctx context.Context, userID string, start, end int, timezone string) error
+	ClearQuietHours(ctx context.Context, userID string) error
+	SetTimezone(ctx context.Context, userID, timezone string) error
+	MuteAuthor(ctx context.Context, userID, author string) error
+	UnmuteAuthor(ctx context.Context, userID, author string) error
+	AddScammer(ctx context.Context, username, addedBy string) error
+	RemoveScammer(ctx context.Context, username string) error
+	GetScammers(ctx context.Context) ([]store.Scammer, error)
+	GetBannedKeywords(ctx context.Context) ([]string, error)
+	RecordStrippedKeywords(ctx context.Context, terms []string) error
+	GetRecentPipelineRuns(ctx context.Context, limit int) ([]store.PipelineRun, error)
+	SharedRateLimitStore
+}
+
+// AIService defines the Gemini operations needed by the alert wizards and prompt compaction.
+type AIService interface {
+	RunKeywordWizard(ctx context.Context, userRequest, promptOverride string) (*ai.KeywordWizardResponse, error)
+	ValidateManualQuery(ctx context.Context, userQuery, promptOverride string) (*ai.KeywordWizardResponse, error)
+	Compactor
+}
+
+// Messenger defines the Discord REST operations needed by the interaction handlers.
+type Messenger interface {
+	SendMessage(channelID, content string) error
+	SendFollowupMessage(i *discordgo.Interaction, content string) error
+	SendFollowupEmbedWithComponents(i *discordgo.Interaction, embed *discordgo.MessageEmbed, components []discordgo.MessageComponent) error
+	SendFollowupFile(i *discordgo.Interaction, content, filename string, data []byte) error
+	EditOriginalInteractionResponse(i *discordgo.Interaction, embed *discordgo.MessageEmbed, components []discordgo.MessageComponent) error
+	GetWebhookChannel(webhookURL string) (string, error)
+}
+
+// BotClient is the full set of Discord REST operations the handlers send through the bot's client.
+type BotClient interface {
+	Messenger
+	AdminNotifier
+}
+
 // Global discord session for handling Webhook interaction payloads types.
 // We don't actually use this session to connect a websocket, just to utilize their struct definitions.
-var (
-	session       *discordgo.Session
-	globalLimiter = NewRateLimiter()
-)
+var session *discordgo.Session
+
+// Handler serves Discord interactions and the compaction cron. Its Firestore, Gemini and Discord clients
+// are created once at startup and shared by every request, so it must only hold clients that are safe
+// for concurrent use.
+type Handler struct {
+	cfg     *config.Config
+	db      Storer
+	ai      AIService
+	client  BotClient
+	limiter *RateLimiter
+}
+
+// NewHandler returns a Handler that serves requests with the given long-lived clients.
+// It rate limits through Firestore when cfg selects config.RateLimitFirestore, and in memory otherwise.
+func NewHandler(cfg *config.Config, db Storer, aiSvc AIService, client BotClient) *Handler {
+	limiter := NewRateLimiter()
+	if cfg != nil && cfg.RateLimitBackend == config.RateLimitFirestore {
+		limiter = NewSharedRateLimiter(db)
+	}
+	return &Handler{cfg: cfg, db: db, ai: aiSvc, client: client, limiter: limiter}
+}
 
 func init() {
 	var err error
@@ -32,52 +118,37 @@ func init() {
 
 // HandleInteraction is the main HTTP endpoint hit by Discord for every slash command, button click, and modal submit.
 // It verifies the cryptographic signature to ensure the request is actually from Discord.
-func HandleInteraction(w http.ResponseWriter, r *http.Request) {
-	pubKey := os.Getenv("DISCORD_PUBLIC_KEY")
-	if pubKey == "" {
-		log.Println("DISCORD_PUBLIC_KEY is not set")
+func (h *Handler) HandleInteraction(w http.ResponseWriter, r *http.Request) {
+	edKey := h.cfg.DiscordPublicKey
+	if len(edKey) != ed25519.PublicKeySize {
+		log.Println("DISCORD_PUBLIC_KEY is not configured")
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 		return
 	}
 
-	// The discord API expects the public key as an ed25519.PublicKey object.
-	decodedKey, err := hex.DecodeString(pubKey)
+	// 1. Read the body once. The signature is checked and the interaction parsed from this same
+	// buffer, so neither step depends on the other leaving r.Body readable.
+	body, err := io.ReadAll(r.Body)
+	r.Body.Close()
 	if err != nil {
-		log.Printf("Failed to decode public key: %v", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		logger.Warn(r.Context(), "Failed to read interaction body", "error", err)
+		writeHTTPError(w, http.StatusBadRequest, "could not read request body")
 		return
 	}
 
-	if len(decodedKey) != ed25519.PublicKeySize {
-		log.Printf("Invalid public key length")
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-		return
-	}
-
-	edKey := ed25519.PublicKey(decodedKey)
-
-	// 1. Verify the signature
-	verified := discordgo.VerifyInteraction(r, edKey)
+	// 2. Verify the signature
+	verified := verifyInteractionSignature(r.Header, body, edKey)
 	if !verified {
 		log.Println("Interaction verification failed")
 		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	// 2. Read the body
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		log.Printf("Error reading body: %v", err)
-		http.Error(w, "Bad Request", http.StatusBadRequest)
-		return
-	}
-	defer r.Body.Close()
-
-	// 3. Parse the Interaction
+	// 3. Parse the Interaction. Only the body's size is logged, never its content.
 	var interaction discordgo.Interaction
 	if err := json.Unmarshal(body, &interaction); err != nil {
-		log.Printf("Error unmarshaling interaction: %v", err)
-		http.Error(w, "Bad Request", http.StatusBadRequest)
+		logger.Warn(r.Context(), "Failed to decode interaction", "error", err, "body_bytes", len(body))
+		writeHTTPError(w, http.StatusBadRequest, "invalid interaction payload")
 		return
 	}
 
@@ -92,14 +163,9 @@ func HandleInteraction(w http.ResponseWriter, r *http.Request) {
 	ctx := logger.WithRequestID(r.Context(), interaction.ID)
 
 	// Rate limiting check
-	userID := ""
-	if interaction.Member != nil && interaction.Member.User != nil {
-		userID = interaction.Member.User.ID
-	} else if interaction.User != nil {
-		userID = interaction.User.ID
-	}
+	userID := interactionUserID(&interaction)
 
-	if userID != "" && !globalLimiter.Allow(userID) {
+	if userID != "" && !h.limiter.Allow(ctx, userID) {
 		logger.Warn(ctx, "Rate limit exceeded for user", "user_id", userID)
 		respondError(w, "You are doing that too fast! Please wait a few seconds.")
 		return
@@ -108,23 +174,52 @@ func HandleInteraction(w http.ResponseWriter, r *http.Request) {
 	logger.Info(ctx, "Handling Discord interaction", "type", interaction.Type, "user", userID)
 
 	// 5. Route to appropriate handler
-	handleInteractionEvent(ctx, w, &interaction)
+	h.handleInteractionEvent(ctx, w, &interaction)
 }
 
-func handleInteractionEvent(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
+func (h *Handler) handleInteractionEvent(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
 	switch i.Type {
 	case discordgo.InteractionApplicationCommand:
-		routeSlashCommand(ctx, w, i)
+		h.routeSlashCommand(ctx, w, i)
 	case discordgo.InteractionMessageComponent:
-		routeComponentInteraction(ctx, w, i)
+		if isSelectMenu(i.MessageComponentData().ComponentType) {
+			h.routeSelectMenu(ctx, w, i)
+		} else {
+			h.routeComponentInteraction(ctx, w, i)
+		}
 	case discordgo.InteractionModalSubmit:
-		routeModalSubmit(ctx, w, i)
+		h.routeModalSubmit(ctx, w, i)
 	default:
 		logger.Warn(ctx, "Unknown interaction type", "type", i.Type)
 		http.Error(w, "Unsupported Interaction Type", http.StatusBadRequest)
 	}
 }
 
+// interactionUserID returns the invoking user's ID. Guild interactions carry the user on Member,
+// while DM interactions leave Member nil and set User instead.
+func interactionUserID(i *discordgo.Interaction) string {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User.ID
+	}
+	if i.User != nil {
+		return i.User.ID
+	}
+	return ""
+}
+
+// alertScope returns the server ID alerts from this interaction are stored under. Interactions from
+// a DM (user-installed app) have no guild, so they use the user's synthetic DM scope instead.
+// It returns "" when the user can't be identified.
+func alertScope(i *discordgo.Interaction) string {
+	if i.GuildID != "" {
+		return i.GuildID
+	}
+	if userID := interactionUserID(i); userID != "" {
+		return store.DMScope(userID)
+	}
+	return ""
+}
+
 // Helper to write a JSON response quickly
 func writeJSON(w http.ResponseWriter, resp discordgo.InteractionResponse) {
 	w.Header().Set("Content-Type", "application/json")
@@ -133,6 +228,34 @@ func writeJSON(w http.ResponseWriter, resp discordgo.InteractionResponse) {
 	}
 }
 
+// verifyInteractionSignature checks Discord's Ed25519 signature over the request timestamp and body,
+// as discordgo.VerifyInteraction does, but against an already buffered body.
+func verifyInteractionSignature(header http.Header, body []byte, key ed25519.PublicKey) bool {
+	sig, err := hex.DecodeString(header.Get("X-Signature-Ed25519"))
+	if err != nil || len(sig) != ed25519.SignatureSize {
+		return false
+	}
+	timestamp := header.Get("X-Signature-Timestamp")
+	if timestamp == "" {
+		return false
+	}
+
+	msg := make([]byte, 0, len(timestamp)+len(body))
+	msg = append(msg, timestamp...)
+	msg = append(msg, body...)
+	return ed25519.Verify(key, msg, sig)
+}
+
+// writeHTTPError rejects a request Discord can't turn into an interaction with a JSON error body,
+// e.g. {"error": "invalid interaction payload"}.
+func writeHTTPError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(map[string]string{"error": msg}); err != nil {
+		log.Printf("Error encoding error response: %v", err)
+	}
+}
+
 // Helper to respond with an ephemeral error message
 func respondError(w http.ResponseWriter, msg string) {
 	writeJSON(w, discordgo.InteractionResponse{
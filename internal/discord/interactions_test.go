@@ -2,28 +2,61 @@ package discord
 
 import (
 	"bytes"
+	"context"
 	"crypto/ed25519"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
-	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/pauljones0/betterHardwareSwap/internal/config"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+	"github.com/pauljones0/betterHardwareSwap/internal/testutils"
+	"github.com/stretchr/testify/mock"
 )
 
 func TestHandleInteraction_Ping(t *testing.T) {
 	pub, priv, _ := ed25519.GenerateKey(nil)
-	pubHex := hex.EncodeToString(pub)
-	os.Setenv("DISCORD_PUBLIC_KEY", pubHex)
-	defer os.Unsetenv("DISCORD_PUBLIC_KEY")
+	h := NewHandler(&config.Config{DiscordPublicKey: pub}, nil, nil, nil)
 
 	interaction := discordgo.Interaction{
 		Type: discordgo.InteractionPing,
 	}
-	body, _ := json.Marshal(interaction)
 
+	rr := httptest.NewRecorder()
+	h.HandleInteraction(rr, signedRequest(t, priv, interaction))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rr.Code)
+	}
+
+	var resp discordgo.InteractionResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	if resp.Type != discordgo.InteractionResponsePong {
+		t.Errorf("expected response type pong, got %v", resp.Type)
+	}
+}
+
+// signedRequest builds an /interactions request signed the way Discord signs them.
+func signedRequest(t *testing.T, priv ed25519.PrivateKey, interaction discordgo.Interaction) *http.Request {
+	t.Helper()
+	body, err := json.Marshal(interaction)
+	if err != nil {
+		t.Fatalf("failed to marshal interaction: %v", err)
+	}
+	return signedRawRequest(priv, body)
+}
+
+// signedRawRequest signs body as-is, so tests can send payloads that aren't valid interactions.
+func signedRawRequest(priv ed25519.PrivateKey, body []byte) *http.Request {
 	timestamp := "123456789"
 	msg := append([]byte(timestamp), body...)
 	sig := ed25519.Sign(priv, msg)
@@ -31,36 +64,442 @@ func TestHandleInteraction_Ping(t *testing.T) {
 	req := httptest.NewRequest("POST", "/interactions", bytes.NewReader(body))
 	req.Header.Set("X-Signature-Ed25519", hex.EncodeToString(sig))
 	req.Header.Set("X-Signature-Timestamp", timestamp)
+	return req
+}
 
-	rr := httptest.NewRecorder()
-	HandleInteraction(rr, req)
+// interactionHarness serves signed interactions through a Handler backed by mocks, so tests can drive
+// slash commands, component clicks and modal submits end-to-end, from signature check to response.
+type interactionHarness struct {
+	handler *Handler
+	priv    ed25519.PrivateKey
+	db      *testutils.MockStore
+	ai      *testutils.MockAI
+	client  *testutils.MockDiscord
+}
 
-	if rr.Code != http.StatusOK {
-		t.Errorf("expected status 200, got %d", rr.Code)
+func newInteractionHarness(t *testing.T) *interactionHarness {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
 	}
+	th := &interactionHarness{
+		priv:   priv,
+		db:     new(testutils.MockStore),
+		ai:     new(testutils.MockAI),
+		client: new(testutils.MockDiscord),
+	}
+	th.handler = NewHandler(&config.Config{DiscordPublicKey: pub}, th.db, th.ai, th.client)
+	return th
+}
 
+// serve signs the interaction, sends it to HandleInteraction and decodes the response. Each harness has
+// its own rate limiter, but a test sending several interactions from one user will hit it.
+func (th *interactionHarness) serve(t *testing.T, interaction discordgo.Interaction) discordgo.InteractionResponse {
+	t.Helper()
 	var resp discordgo.InteractionResponse
-	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+	th.serveInto(t, interaction, &resp)
+	return resp
+}
+
+// serveInto is serve for responses with components, which are interfaces that don't unmarshal into
+// discordgo.InteractionResponse; resp is a struct decoding only the parts the test checks.
+func (th *interactionHarness) serveInto(t *testing.T, interaction discordgo.Interaction, resp any) {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	th.handler.HandleInteraction(rr, signedRequest(t, th.priv, interaction))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if err := json.NewDecoder(rr.Body).Decode(resp); err != nil {
 		t.Fatalf("failed to decode response: %v", err)
 	}
+}
 
-	if resp.Type != discordgo.InteractionResponsePong {
-		t.Errorf("expected response type pong, got %v", resp.Type)
+func TestHandleInteraction_MalformedJSON(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	h := NewHandler(&config.Config{DiscordPublicKey: pub}, nil, nil, nil)
+
+	rr := httptest.NewRecorder()
+	h.HandleInteraction(rr, signedRawRequest(priv, []byte(`{"type": 2, "data": {`)))
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400, got %d", rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("expected a JSON error body, got content type %q", ct)
+	}
+	var resp struct {
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil || resp.Error != "invalid interaction payload" {
+		t.Errorf("unexpected error body %+v (%v)", resp, err)
+	}
+}
+
+func TestHandleInteraction_SignedBodyIsParsed(t *testing.T) {
+	th := newInteractionHarness(t)
+	th.db.On("SetAllAlertsEnabled", mock.Anything, "guild1", "parsed_user", false).Return(2, nil)
+
+	resp := th.serve(t, discordgo.Interaction{
+		ID:      "interaction_parsed",
+		Type:    discordgo.InteractionApplicationCommand,
+		GuildID: "guild1",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "parsed_user"}},
+		Data: discordgo.ApplicationCommandInteractionData{
+			Name:    "alert",
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{{Name: "pause-all"}},
+		},
+	})
+
+	// The store is only reached with these arguments if the guild, user and subcommand all survived verification.
+	if resp.Data == nil || !strings.Contains(resp.Data.Content, "Paused **2** alerts") {
+		t.Errorf("expected the signed interaction to be parsed and routed, got %+v", resp.Data)
+	}
+	th.db.AssertExpectations(t)
+}
+
+func TestVerifyInteractionSignature(t *testing.T) {
+	pub, priv, _ := ed25519.GenerateKey(nil)
+	body := []byte(`{"type": 1}`)
+	req := signedRawRequest(priv, body)
+
+	if !verifyInteractionSignature(req.Header, body, pub) {
+		t.Error("expected a correctly signed body to verify")
+	}
+	if verifyInteractionSignature(req.Header, []byte(`{"type": 2}`), pub) {
+		t.Error("expected a tampered body to fail verification")
+	}
+	req.Header.Del("X-Signature-Timestamp")
+	if verifyInteractionSignature(req.Header, body, pub) {
+		t.Error("expected a missing timestamp to fail verification")
+	}
+}
+
+func TestHandleInteraction_UsesInjectedStore(t *testing.T) {
+	th := newInteractionHarness(t)
+	th.db.On("GetUserAlerts", mock.Anything, "guild1", "injected_user").Return([]store.AlertRule{}, nil)
+
+	resp := th.serve(t, discordgo.Interaction{
+		ID:      "interaction1",
+		Type:    discordgo.InteractionApplicationCommand,
+		GuildID: "guild1",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "injected_user"}},
+		Data: discordgo.ApplicationCommandInteractionData{
+			Name:    "alert",
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{{Name: "list"}},
+		},
+	})
+
+	if resp.Data == nil || !strings.Contains(resp.Data.Content, "don't have any active alerts") {
+		t.Errorf("expected the empty alert list from the injected store, got %+v", resp.Data)
+	}
+	th.db.AssertExpectations(t)
+}
+
+func TestHandleInteraction_HelpCommand(t *testing.T) {
+	th := newInteractionHarness(t)
+	th.db.On("GetServerConfig", mock.Anything, "guild1").Return(&store.ServerConfig{ServerID: "guild1", FeedChannelID: "feed1"}, nil)
+	th.db.On("GetUserAlerts", mock.Anything, "guild1", "help_user").Return([]store.AlertRule{{ID: "alert1"}}, nil)
+
+	// The help embed is sent as a followup from a goroutine once the command is acknowledged.
+	sent := make(chan *discordgo.MessageEmbed, 1)
+	th.client.On("SendFollowupEmbedWithComponents", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			sent <- args.Get(1).(*discordgo.MessageEmbed)
+		}).
+		Return(nil)
+
+	resp := th.serve(t, discordgo.Interaction{
+		ID:      "interaction2",
+		Type:    discordgo.InteractionApplicationCommand,
+		GuildID: "guild1",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "help_user"}},
+		Data:    discordgo.ApplicationCommandInteractionData{Name: "help"},
+	})
+
+	if resp.Type != discordgo.InteractionResponseDeferredChannelMessageWithSource {
+		t.Errorf("expected a deferred response, got %v", resp.Type)
+	}
+	if resp.Data == nil || resp.Data.Flags != discordgo.MessageFlagsEphemeral {
+		t.Errorf("expected the help reply to be ephemeral, got %+v", resp.Data)
+	}
+
+	select {
+	case embed := <-sent:
+		if embed.Title != "🛡️ Better Hardware Swap Help" {
+			t.Errorf("unexpected help title %q", embed.Title)
+		}
+		if len(embed.Fields) == 0 || !strings.Contains(embed.Fields[0].Value, "**1** active alert on this server") {
+			t.Errorf("expected the user's status first, got %+v", embed.Fields)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("help embed was never sent")
+	}
+	th.db.AssertExpectations(t)
+	th.db.AssertNumberOfCalls(t, "GetServerConfig", 1)
+}
+
+func TestHandleInteraction_HelpCommandConfigUnavailable(t *testing.T) {
+	th := newInteractionHarness(t)
+	th.db.On("GetServerConfig", mock.Anything, "guild_down").Return(nil, errors.New("unavailable"))
+
+	sent := make(chan *discordgo.MessageEmbed, 1)
+	th.client.On("SendFollowupEmbedWithComponents", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			sent <- args.Get(1).(*discordgo.MessageEmbed)
+		}).
+		Return(nil)
+
+	th.serve(t, discordgo.Interaction{
+		ID:      "interaction_help_down",
+		Type:    discordgo.InteractionApplicationCommand,
+		GuildID: "guild_down",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "help_user_down"}},
+		Data:    discordgo.ApplicationCommandInteractionData{Name: "help"},
+	})
+
+	select {
+	case embed := <-sent:
+		// No status field rather than a wrong "run /setup" prompt.
+		if len(embed.Fields) != len(helpEmbed(store.LanguageEnglish).Fields) {
+			t.Errorf("expected only the generic help fields, got %+v", embed.Fields)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("help embed was never sent")
+	}
+	th.db.AssertNotCalled(t, "GetUserAlerts", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandleInteraction_HelpCommandFrench(t *testing.T) {
+	th := newInteractionHarness(t)
+	th.db.On("GetServerConfig", mock.Anything, "guild_fr").Return(&store.ServerConfig{ServerID: "guild_fr", FeedChannelID: "feed1", Language: store.LanguageFrench}, nil)
+	th.db.On("GetUserAlerts", mock.Anything, "guild_fr", "help_user_fr").Return([]store.AlertRule{{ID: "alert1"}, {ID: "alert2"}}, nil)
+
+	sent := make(chan *discordgo.MessageEmbed, 1)
+	th.client.On("SendFollowupEmbedWithComponents", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			sent <- args.Get(1).(*discordgo.MessageEmbed)
+		}).
+		Return(nil)
+
+	th.serve(t, discordgo.Interaction{
+		ID:      "interaction_fr",
+		Type:    discordgo.InteractionApplicationCommand,
+		GuildID: "guild_fr",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "help_user_fr"}},
+		Data:    discordgo.ApplicationCommandInteractionData{Name: "help"},
+	})
+
+	select {
+	case embed := <-sent:
+		if embed.Title != "🛡️ Aide de Better Hardware Swap" {
+			t.Errorf("expected the French help title, got %q", embed.Title)
+		}
+		if len(embed.Fields) == 0 || !strings.Contains(embed.Fields[0].Value, "**2** alertes actives sur ce serveur") {
+			t.Errorf("expected the user's status in French first, got %+v", embed.Fields)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("help embed was never sent")
+	}
+	th.db.AssertExpectations(t)
+}
+
+func TestHandleInteraction_DeleteAlertButton(t *testing.T) {
+	th := newInteractionHarness(t)
+	th.db.On("GetAlert", mock.Anything, "alert42").Return(&store.AlertRule{ID: "alert42", UserID: "delete_user", ServerID: "guild1"}, nil)
+	th.db.On("DeleteAlert", mock.Anything, "alert42").Return(nil)
+
+	listEmbed := &discordgo.MessageEmbed{Title: "📋 Your Active Alerts"}
+	resp := th.serve(t, discordgo.Interaction{
+		ID:      "interaction3",
+		Type:    discordgo.InteractionMessageComponent,
+		GuildID: "guild1",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "delete_user"}},
+		Message: &discordgo.Message{Embeds: []*discordgo.MessageEmbed{listEmbed}},
+		Data: discordgo.MessageComponentInteractionData{
+			CustomID:      "delete_alert|alert42",
+			ComponentType: discordgo.ButtonComponent,
+		},
+	})
+
+	if resp.Type != discordgo.InteractionResponseUpdateMessage {
+		t.Errorf("expected the alert list to be updated in place, got %v", resp.Type)
+	}
+	if resp.Data == nil || !strings.Contains(resp.Data.Content, "Alert removed") {
+		t.Errorf("expected a removal confirmation, got %+v", resp.Data)
+	}
+	th.db.AssertExpectations(t)
+}
+
+func TestHandleInteraction_DeleteAlertButton_StoreError(t *testing.T) {
+	th := newInteractionHarness(t)
+	th.db.On("GetAlert", mock.Anything, "alert42").Return(&store.AlertRule{ID: "alert42", UserID: "delete_fail_user", ServerID: "guild1"}, nil)
+	th.db.On("DeleteAlert", mock.Anything, "alert42").Return(errors.New("firestore unavailable"))
+
+	resp := th.serve(t, discordgo.Interaction{
+		ID:      "interaction_delete_fail",
+		Type:    discordgo.InteractionMessageComponent,
+		GuildID: "guild1",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "delete_fail_user"}},
+		Message: &discordgo.Message{},
+		Data: discordgo.MessageComponentInteractionData{
+			CustomID:      "delete_alert|alert42",
+			ComponentType: discordgo.ButtonComponent,
+		},
+	})
+
+	if resp.Data == nil || !strings.Contains(resp.Data.Content, "Failed to delete the alert") {
+		t.Errorf("expected the failed delete to be reported, got %+v", resp.Data)
+	}
+	th.db.AssertExpectations(t)
+}
+
+func TestHandleInteraction_DeleteAlertButton_ForgedID(t *testing.T) {
+	th := newInteractionHarness(t)
+	th.db.On("GetAlert", mock.Anything, "alert42").Return(&store.AlertRule{ID: "alert42", UserID: "victim", ServerID: "guild1"}, nil)
+
+	resp := th.serve(t, discordgo.Interaction{
+		ID:      "interaction_forged",
+		Type:    discordgo.InteractionMessageComponent,
+		GuildID: "guild1",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "attacker"}},
+		Message: &discordgo.Message{},
+		Data: discordgo.MessageComponentInteractionData{
+			CustomID:      "delete_alert|alert42",
+			ComponentType: discordgo.ButtonComponent,
+		},
+	})
+
+	if resp.Data == nil || strings.Contains(resp.Data.Content, "Alert removed") {
+		t.Errorf("expected deleting another user's alert to be refused, got %+v", resp.Data)
+	}
+	th.db.AssertNotCalled(t, "DeleteAlert", mock.Anything, mock.Anything)
+}
+
+func TestHandleInteraction_CancelAlertButton_ForgedID(t *testing.T) {
+	th := newInteractionHarness(t)
+	th.db.On("GetAlert", mock.Anything, "alert42").Return(&store.AlertRule{ID: "alert42", UserID: "victim", ServerID: "guild1"}, nil)
+
+	resp := th.serve(t, discordgo.Interaction{
+		ID:      "interaction_cancel_forged",
+		Type:    discordgo.InteractionMessageComponent,
+		GuildID: "guild1",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "cancel_attacker"}},
+		Message: &discordgo.Message{},
+		Data: discordgo.MessageComponentInteractionData{
+			CustomID:      "cancel_alert|alert42|Manual",
+			ComponentType: discordgo.ButtonComponent,
+		},
+	})
+
+	if resp.Data == nil || strings.Contains(resp.Data.Content, "Alert Cancelled") {
+		t.Errorf("expected cancelling another user's alert to be refused, got %+v", resp.Data)
+	}
+	th.db.AssertNotCalled(t, "DeleteAlert", mock.Anything, mock.Anything)
+	th.db.AssertNotCalled(t, "SaveAnalytics", mock.Anything, mock.Anything)
+}
+
+func TestHandleInteraction_SnoozeAlertButton_OtherServer(t *testing.T) {
+	th := newInteractionHarness(t)
+	th.db.On("GetAlert", mock.Anything, "alert7").Return(&store.AlertRule{ID: "alert7", UserID: "cross_server_user", ServerID: "guild2"}, nil)
+
+	resp := th.serve(t, discordgo.Interaction{
+		ID:      "interaction_snooze_cross",
+		Type:    discordgo.InteractionMessageComponent,
+		GuildID: "guild1",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "cross_server_user"}},
+		Data: discordgo.MessageComponentInteractionData{
+			CustomID:      "snooze_alert|alert7|7",
+			ComponentType: discordgo.ButtonComponent,
+		},
+	})
+
+	if resp.Data == nil || strings.Contains(resp.Data.Content, "snoozed until") {
+		t.Errorf("expected snoozing an alert from another server to be refused, got %+v", resp.Data)
+	}
+	th.db.AssertNotCalled(t, "SnoozeAlert", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandleInteraction_SnoozeAlertButton(t *testing.T) {
+	th := newInteractionHarness(t)
+	th.db.On("GetAlert", mock.Anything, "alert7").Return(&store.AlertRule{ID: "alert7", UserID: "snooze_user", ServerID: "guild1"}, nil)
+	before := time.Now()
+	th.db.On("SnoozeAlert", mock.Anything, "alert7", mock.MatchedBy(func(until time.Time) bool {
+		return until.After(before.Add(6*24*time.Hour)) && until.Before(before.Add(8*24*time.Hour))
+	})).Return(nil)
+
+	resp := th.serve(t, discordgo.Interaction{
+		ID:      "interaction_snooze",
+		Type:    discordgo.InteractionMessageComponent,
+		GuildID: "guild1",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "snooze_user"}},
+		Data: discordgo.MessageComponentInteractionData{
+			CustomID:      "snooze_alert|alert7|7",
+			ComponentType: discordgo.ButtonComponent,
+		},
+	})
+
+	if resp.Data == nil || !strings.Contains(resp.Data.Content, "snoozed until") {
+		t.Errorf("expected a snooze confirmation, got %+v", resp.Data)
 	}
+	th.db.AssertExpectations(t)
 }
 
 func TestHandleInteraction_Unauthorized(t *testing.T) {
-	os.Setenv("DISCORD_PUBLIC_KEY", hex.EncodeToString(make([]byte, 32)))
-	defer os.Unsetenv("DISCORD_PUBLIC_KEY")
+	h := NewHandler(&config.Config{DiscordPublicKey: make([]byte, ed25519.PublicKeySize)}, nil, nil, nil)
 
 	req := httptest.NewRequest("POST", "/interactions", bytes.NewReader([]byte("{}")))
 	req.Header.Set("X-Signature-Ed25519", "invalid")
 	req.Header.Set("X-Signature-Timestamp", "invalid")
 
 	rr := httptest.NewRecorder()
-	HandleInteraction(rr, req)
+	h.HandleInteraction(rr, req)
 
 	if rr.Code != http.StatusUnauthorized {
 		t.Errorf("expected status 401, got %d", rr.Code)
 	}
 }
+
+func TestInteractionUserID(t *testing.T) {
+	tests := []struct {
+		name string
+		i    *discordgo.Interaction
+		want string
+	}{
+		{"Guild", &discordgo.Interaction{Member: &discordgo.Member{User: &discordgo.User{ID: "member1"}}}, "member1"},
+		{"DM", &discordgo.Interaction{User: &discordgo.User{ID: "dm1"}}, "dm1"},
+		{"Member without user", &discordgo.Interaction{Member: &discordgo.Member{}, User: &discordgo.User{ID: "dm1"}}, "dm1"},
+		{"Neither", &discordgo.Interaction{}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := interactionUserID(tt.i); got != tt.want {
+				t.Errorf("interactionUserID() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandleAlertGroup_NoUser(t *testing.T) {
+	i := &discordgo.Interaction{
+		Type: discordgo.InteractionApplicationCommand,
+		Data: discordgo.ApplicationCommandInteractionData{
+			Name:    "alert",
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{{Name: "list"}},
+		},
+	}
+
+	rr := httptest.NewRecorder()
+	NewHandler(&config.Config{}, nil, nil, nil).handleAlertGroup(context.Background(), rr, i)
+
+	var resp discordgo.InteractionResponse
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Data == nil || !strings.Contains(resp.Data.Content, "Could not identify user") {
+		t.Errorf("expected an unidentified user error, got %+v", resp.Data)
+	}
+}
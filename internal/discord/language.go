@@ -0,0 +1,88 @@
+package discord
+
+import "github.com/pauljones0/betterHardwareSwap/internal/store"
+
+// messages holds the static help and setup text shown in a server's configured language.
+type messages struct {
+	helpTitle        string
+	helpDescription  string
+	helpAIName       string
+	helpAIValue      string
+	helpManualName   string
+	helpManualValue  string
+	helpManageName   string
+	helpManageValue  string
+	helpFooter       string
+	notSetUpName     string
+	notSetUpValue    string
+	getStartedName   string
+	getStartedValue  string // %s: feed channel ID
+	statusName       string
+	statusValue      string // %d: alert count, %s: alertNoun or alertsNoun
+	alertNoun        string
+	alertsNoun       string
+	setupComplete    string // %s: feed description, %s: feed channel ID, %s: ping channel ID
+	setupUpdated     string // Same arguments as setupComplete, for a server that was already set up
+	setupFeedMatches string
+	setupFeedAll     string
+	setupWelcome     string
+}
+
+var localizedMessages = map[store.Language]messages{
+	store.LanguageEnglish: {
+		helpTitle:        "🛡️ Better Hardware Swap Help",
+		helpDescription:  "I'm your agentic companion for tracking PC gear in Canada. I scan `r/CanadianHardwareSwap` in real-time.",
+		helpAIName:       "✨ AI-Powered Alerts",
+		helpAIValue:      "Run `/alert add` and select **'Help Me Write It'**. Just describe your goal (e.g., *\"A 30-series GPU in Vancouver under $400\"*) and my AI handles the logic.",
+		helpManualName:   "🔧 Technical Querying",
+		helpManualValue:  "Select **'I'll Type It Myself'** to use Boolean logic like `(rtx AND 4090) NOT broken`.",
+		helpManageName:   "📋 Management",
+		helpManageValue:  "Use `/alert list` to view or delete your current subscriptions, and `/quiethours set` to hold pings overnight.",
+		helpFooter:       "Agentic • Serverless • Open Source",
+		notSetUpName:     "⚙️ Server Not Set Up",
+		notSetUpValue:    "This server hasn't been configured yet. **Admin:** run `/setup` first to choose the deal feed and ping channels.",
+		getStartedName:   "🚀 Get Started",
+		getStartedValue:  "You don't have any alerts yet. Run `/alert add` to create one. Deals are posted in <#%s>.",
+		statusName:       "📊 Your Status",
+		statusValue:      "You have **%d** %s on this server. Run `/alert list` to review them.",
+		alertNoun:        "active alert",
+		alertsNoun:       "active alerts",
+		setupComplete:    "✅ **Setup Complete!**\n\n%s will be posted to <#%s>.\nUser Alerts will ping in <#%s>.\n\nUsers can now run `/alert add` to get started!",
+		setupUpdated:     "✅ **Configuration updated.**\n\n%s will be posted to <#%s>.\nUser Alerts will ping in <#%s>.",
+		setupFeedMatches: "Deals matching someone's alert",
+		setupFeedAll:     "Every new deal",
+		setupWelcome:     "👋 **Hello! Hardware Swap Bot is now online!**\nRun `/help` to see how to set up alerts for specific gear.",
+	},
+	store.LanguageFrench: {
+		helpTitle:        "🛡️ Aide de Better Hardware Swap",
+		helpDescription:  "Je suis votre compagnon agentique pour suivre le matériel PC au Canada. Je surveille `r/CanadianHardwareSwap` en temps réel.",
+		helpAIName:       "✨ Alertes propulsées par l'IA",
+		helpAIValue:      "Lancez `/alert add` et choisissez **'Help Me Write It'**. Décrivez simplement ce que vous cherchez (p. ex. *\"Une carte graphique série 30 à Vancouver pour moins de 400 $\"*) et mon IA s'occupe de la logique.",
+		helpManualName:   "🔧 Requêtes techniques",
+		helpManualValue:  "Choisissez **'I'll Type It Myself'** pour utiliser la logique booléenne, comme `(rtx AND 4090) NOT broken`.",
+		helpManageName:   "📋 Gestion",
+		helpManageValue:  "Utilisez `/alert list` pour consulter ou supprimer vos abonnements, et `/quiethours set` pour retenir les mentions la nuit.",
+		helpFooter:       "Agentique • Sans serveur • Open source",
+		notSetUpName:     "⚙️ Serveur non configuré",
+		notSetUpValue:    "Ce serveur n'est pas encore configuré. **Admin:** lancez d'abord `/setup` pour choisir les salons des aubaines et des mentions.",
+		getStartedName:   "🚀 Pour commencer",
+		getStartedValue:  "Vous n'avez encore aucune alerte. Lancez `/alert add` pour en créer une. Les aubaines sont publiées dans <#%s>.",
+		statusName:       "📊 Votre statut",
+		statusValue:      "Vous avez **%d** %s sur ce serveur. Lancez `/alert list` pour les consulter.",
+		alertNoun:        "alerte active",
+		alertsNoun:       "alertes actives",
+		setupComplete:    "✅ **Configuration terminée!**\n\n%s seront publiées dans <#%s>.\nLes alertes des utilisateurs les mentionneront dans <#%s>.\n\nLes utilisateurs peuvent maintenant lancer `/alert add` pour commencer!",
+		setupUpdated:     "✅ **Configuration mise à jour.**\n\n%s seront publiées dans <#%s>.\nLes alertes des utilisateurs les mentionneront dans <#%s>.",
+		setupFeedMatches: "Les aubaines correspondant à une alerte",
+		setupFeedAll:     "Toutes les nouvelles aubaines",
+		setupWelcome:     "👋 **Bonjour! Le bot Hardware Swap est maintenant en ligne!**\nLancez `/help` pour voir comment créer des alertes pour du matériel précis.",
+	},
+}
+
+// messagesFor returns the text for lang, falling back to English for languages without a translation.
+func messagesFor(lang store.Language) messages {
+	if m, ok := localizedMessages[lang]; ok {
+		return m
+	}
+	return localizedMessages[store.LanguageEnglish]
+}
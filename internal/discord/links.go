@@ -0,0 +1,11 @@
+package discord
+
+import "fmt"
+
+// BuildMessageLink returns a jump link to a message. DM channels have no guild, so Discord expects `@me` in its place.
+func BuildMessageLink(guildID, channelID, msgID string) string {
+	if guildID == "" {
+		guildID = "@me"
+	}
+	return fmt.Sprintf("https://discord.com/channels/%s/%s/%s", guildID, channelID, msgID)
+}
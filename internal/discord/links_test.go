@@ -0,0 +1,22 @@
+package discord
+
+import "testing"
+
+func TestBuildMessageLink(t *testing.T) {
+	tests := []struct {
+		name    string
+		guildID string
+		want    string
+	}{
+		{"Guild channel", "guild1", "https://discord.com/channels/guild1/chan1/msg1"},
+		{"DM channel", "", "https://discord.com/channels/@me/chan1/msg1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BuildMessageLink(tt.guildID, "chan1", "msg1"); got != tt.want {
+				t.Errorf("BuildMessageLink() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
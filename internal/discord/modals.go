@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"log"
 	"net/http"
-	"os"
 	"strings"
 
 	"github.com/bwmarrin/discordgo"
@@ -14,7 +13,7 @@ import (
 )
 
 // routeModalSubmit handles the response when a user submits the wizard forms.
-func routeModalSubmit(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
+func (h *Handler) routeModalSubmit(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
 	data := i.ModalSubmitData()
 
 	// Immediately acknowledge the request so Discord doesn't timeout while Gemini thinks.
@@ -28,7 +27,7 @@ func routeModalSubmit(ctx context.Context, w http.ResponseWriter, i *discordgo.I
 	if data.CustomID == "modal_alert_wizard_ai" {
 		rawQuery := data.Components[0].(*discordgo.ActionsRow).Components[0].(*discordgo.TextInput).Value
 		sanitizedQuery := Sanitize(rawQuery)
-		go processAIWizard(context.Background(), i, sanitizedQuery)
+		go h.processAIWizard(context.Background(), i, sanitizedQuery)
 	} else if strings.HasPrefix(data.CustomID, "modal_alert_wizard_manual") {
 		editCount := 0
 		parts := strings.Split(data.CustomID, "|")
@@ -36,84 +35,110 @@ func routeModalSubmit(ctx context.Context, w http.ResponseWriter, i *discordgo.I
 			fmt.Sscanf(parts[1], "%d", &editCount)
 		}
 
-		title := data.Components[0].(*discordgo.ActionsRow).Components[0].(*discordgo.TextInput).Value
-		query := data.Components[1].(*discordgo.ActionsRow).Components[0].(*discordgo.TextInput).Value
-
-		sanitizedTitle := Sanitize(title)
-		sanitizedQuery := Sanitize(query)
-
-		go processManualWizard(context.Background(), i, sanitizedTitle, sanitizedQuery, editCount)
+		title, query := manualQueryFromModal(data)
+		go h.processManualWizard(context.Background(), i, title, query, editCount)
 	} else {
-		client := NewClient(os.Getenv("DISCORD_BOT_TOKEN"))
-		client.SendFollowupMessage(i, "⚠️ Unknown modal ID")
+		h.client.SendFollowupMessage(i, "⚠️ Unknown modal ID")
 	}
 }
 
-func processAIWizard(ctx context.Context, i *discordgo.Interaction, query string) {
-	client := NewClient(os.Getenv("DISCORD_BOT_TOKEN"))
+// The manual entry modal splits a query across two inputs so complex Boolean queries fit. Together they
+// allow up to manualKeywordsMaxLength+manualExclusionsMaxLength (300) characters, plus the " NOT (...)"
+// that joins them.
+const (
+	manualKeywordsMaxLength   = 150
+	manualExclusionsMaxLength = 150
+)
 
-	db, err := store.NewStore(ctx, os.Getenv("GCP_PROJECT_ID"))
-	if err != nil {
-		client.SendFollowupMessage(i, "⚠️ Database error.")
-		return
+// manualKeywordsPlaceholder is the example query shown in the manual entry modal. It only uses syntax
+// query.Parse reads locally, so copying it never costs a Gemini call.
+const manualKeywordsPlaceholder = "rtx AND (4090 OR 4080) AND toronto"
+
+// manualQueryFromModal returns the sanitized alert title and the query recombined from the keyword and
+// exclusion inputs, e.g. "(rtx AND 4090) NOT (broken OR mining)". Exclusions are optional and may be
+// separated by commas or line breaks. Both inputs are paragraphs, so line breaks in the keywords are
+// collapsed to spaces before the query is checked for injection.
+func manualQueryFromModal(data discordgo.ModalSubmitInteractionData) (title, query string) {
+	title = Sanitize(modalValue(data, "text_title"))
+	query = strings.Join(strings.Fields(sanitizeQuery(modalValue(data, "text_query"))), " ")
+
+	var excludes []string
+	for _, term := range strings.FieldsFunc(Sanitize(modalValue(data, "text_exclude")), func(r rune) bool {
+		return r == ',' || r == '\n' || r == '\r'
+	}) {
+		if term = strings.Join(strings.Fields(term), " "); term != "" {
+			excludes = append(excludes, term)
+		}
+	}
+	if len(excludes) > 0 {
+		query = fmt.Sprintf("(%s) NOT (%s)", query, strings.Join(excludes, " OR "))
+	}
+	return title, query
+}
+
+// modalValue returns the value of the text input with the given custom ID, or "" if the modal has none.
+func modalValue(data discordgo.ModalSubmitInteractionData, customID string) string {
+	for _, c := range data.Components {
+		row, ok := c.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, rc := range row.Components {
+			if input, ok := rc.(*discordgo.TextInput); ok && input.CustomID == customID {
+				return input.Value
+			}
+		}
 	}
-	defer db.Close()
+	return ""
+}
 
+func (h *Handler) processAIWizard(ctx context.Context, i *discordgo.Interaction, query string) {
+	runAIWizard(ctx, h.db, h.ai, h.client, i, query)
+}
+
+// runAIWizard asks Gemini to build a rule from the user's request, stages it, and asks the user to confirm.
+func runAIWizard(ctx context.Context, db Storer, aiSvc AIService, client Messenger, i *discordgo.Interaction, query string) {
+	scope := alertScope(i)
 	sysPrompt, _ := db.GetSystemPrompt(ctx, "wizard_prompt")
 
-	aiSvc, err := ai.NewAIClient(ctx, os.Getenv("GEMINI_API_KEY"))
-	if err != nil {
-		client.SendFollowupMessage(i, "⚠️ Could not connect to Gemini AI.")
-		return
-	}
-	defer aiSvc.Close()
+	// Replace Discord's bare "thinking..." state with a status that says what is happening, and keep it
+	// current if Gemini has to be retried.
+	setWizardStatus(client, i, "🤖 Analyzing your request...")
+	ctx = ai.WithRetryNotifier(ctx, func(attempt int) {
+		setWizardStatus(client, i, fmt.Sprintf("🤖 Gemini is taking longer than usual, retrying (attempt %d of %d)...", attempt, ai.MaxAttempts))
+	})
 
 	wizard, err := aiSvc.RunKeywordWizard(ctx, query, sysPrompt)
 	if err != nil {
 		log.Printf("Gemini Wizard Error: %v", err)
-		client.SendFollowupMessage(i, "⚠️ Gemini failed to parse your request. Try wording it differently.")
+		setWizardStatus(client, i, "⚠️ Gemini failed to parse your request. Try wording it differently.")
 		return
 	}
-
-	color := 0x5865F2 // Blurple
-	var fields []*discordgo.MessageEmbedField
-
-	if len(wizard.MustHave) > 0 {
-		fields = append(fields, &discordgo.MessageEmbedField{
-			Name:   "✅ Must Include",
-			Value:  fmt.Sprintf("`%s`", strings.Join(wizard.MustHave, "`, `")),
-			Inline: false,
+	stripBannedKeywords(ctx, db, query, wizard)
+
+	// An empty rule would match every post (this is also what the anti-injection guardrail returns),
+	// so never stage it. Explain and let the user try again instead.
+	if isEmptyQuery(wizard) {
+		_ = db.SaveAnalytics(ctx, store.AnalyticsRecord{
+			FlowType:           "wizard",
+			OriginalUserPrompt: query,
+			Outcome:            "Rejected_Empty_Result",
 		})
-	}
-	if len(wizard.AnyOf) > 0 {
-		fields = append(fields, &discordgo.MessageEmbedField{
-			Name:   "🔍 Match Any Of",
-			Value:  fmt.Sprintf("`%s`", strings.Join(wizard.AnyOf, "`, `")),
-			Inline: false,
-		})
-	}
-	if len(wizard.MustNot) > 0 {
-		fields = append(fields, &discordgo.MessageEmbedField{
-			Name:   "🚫 Exclude",
-			Value:  fmt.Sprintf("`%s`", strings.Join(wizard.MustNot, "`, `")),
-			Inline: false,
+		client.EditOriginalInteractionResponse(i, buildEmptyWizardEmbed(query, wizard), []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    "✏️ Try Again",
+						Style:    discordgo.PrimaryButton,
+						CustomID: "wizard_ai",
+					},
+				},
+			},
 		})
+		return
 	}
 
-	if wizard.TooBroad {
-		color = 0xFEE75C // Yellow
-		suggestions := ""
-		if len(wizard.BroadSuggestions) > 0 {
-			for _, s := range wizard.BroadSuggestions {
-				suggestions += fmt.Sprintf("• %s\n", s)
-			}
-		}
-		fields = append(fields, &discordgo.MessageEmbedField{
-			Name:   "⚠️ Search is Too Broad",
-			Value:  fmt.Sprintf("> %s\n\n**Suggestions:**\n%s", wizard.BroadReason, suggestions),
-			Inline: false,
-		})
-	}
+	color, fields := wizardRuleFields(wizard)
 
 	embed := &discordgo.MessageEmbed{
 		Title:       "🎯 Match Rule Created",
@@ -128,23 +153,27 @@ func processAIWizard(ctx context.Context, i *discordgo.Interaction, query string
 		},
 	}
 
-	tempRule := store.AlertRule{
-		UserID:   i.Member.User.ID,
-		ServerID: i.GuildID,
+	tempRule := store.NormalizeAlertRule(store.AlertRule{
+		UserID:   interactionUserID(i),
+		ServerID: scope,
 		MustHave: wizard.MustHave,
 		AnyOf:    wizard.AnyOf,
 		MustNot:  wizard.MustNot,
 		RawQuery: query,
+	})
+	if err := store.ValidateAlertRule(tempRule); err != nil {
+		setWizardStatus(client, i, fmt.Sprintf("⚠️ This alert can't be saved: %v.", err))
+		return
 	}
 
 	if err := db.AddAlert(ctx, tempRule); err != nil {
-		client.SendFollowupMessage(i, "⚠️ Failed to stage alert in database.")
+		setWizardStatus(client, i, "⚠️ Failed to stage alert in database.")
 		return
 	}
 
-	alerts, _ := db.GetUserAlerts(ctx, i.GuildID, i.Member.User.ID)
+	alerts, _ := db.GetUserAlerts(ctx, scope, interactionUserID(i))
 	if len(alerts) == 0 {
-		client.SendFollowupMessage(i, "⚠️ Failed to retrieve staged alert.")
+		setWizardStatus(client, i, "⚠️ Failed to retrieve staged alert.")
 		return
 	}
 	stagedAlertID := alerts[0].ID
@@ -166,33 +195,115 @@ func processAIWizard(ctx context.Context, i *discordgo.Interaction, query string
 		},
 	}
 
-	client.SendFollowupEmbedWithComponents(i, embed, components)
+	// Turn the deferred "thinking..." placeholder into the result rather than posting beside it.
+	client.EditOriginalInteractionResponse(i, embed, components)
 }
 
-func processManualWizard(ctx context.Context, i *discordgo.Interaction, title, query string, editCount int) {
-	client := NewClient(os.Getenv("DISCORD_BOT_TOKEN"))
+// setWizardStatus edits the deferred wizard response into a one-line status or error message.
+func setWizardStatus(client Messenger, i *discordgo.Interaction, text string) {
+	embed := &discordgo.MessageEmbed{Description: text, Color: 0x00B0F4}
+	if err := client.EditOriginalInteractionResponse(i, embed, []discordgo.MessageComponent{}); err != nil {
+		log.Printf("Failed to update wizard status: %v", err)
+	}
+}
 
-	if editCount >= 3 {
-		client.SendFollowupMessage(i, "⚠️ **Alert creation cancelled due to multiple invalid query attempts.** Please start over.")
+func (h *Handler) processWizardPreview(ctx context.Context, i *discordgo.Interaction, query string) {
+	runWizardPreview(ctx, h.db, h.ai, h.client, i, query)
+}
+
+// runWizardPreview shows the rule Gemini would build from the user's request without staging it,
+// so users can try out phrasings without creating and cancelling alerts.
+func runWizardPreview(ctx context.Context, db Storer, aiSvc AIService, client Messenger, i *discordgo.Interaction, query string) {
+	sysPrompt, _ := db.GetSystemPrompt(ctx, "wizard_prompt")
+
+	wizard, err := aiSvc.RunKeywordWizard(ctx, query, sysPrompt)
+	if err != nil {
+		log.Printf("Gemini Wizard Preview Error: %v", err)
+		client.SendFollowupMessage(i, "⚠️ Gemini failed to parse your request. Try wording it differently.")
 		return
 	}
+	stripBannedKeywords(ctx, db, query, wizard)
 
-	db, err := store.NewStore(ctx, os.Getenv("GCP_PROJECT_ID"))
-	if err == nil {
-		defer db.Close()
+	if isEmptyQuery(wizard) {
+		client.SendFollowupEmbedWithComponents(i, buildEmptyWizardEmbed(query, wizard), []discordgo.MessageComponent{})
+		return
 	}
 
-	sysPrompt := ""
-	if db != nil {
-		sysPrompt, _ = db.GetSystemPrompt(ctx, "manual_prompt")
+	color, fields := wizardRuleFields(wizard)
+	embed := &discordgo.MessageEmbed{
+		Title:       "👀 Rule Preview",
+		Description: fmt.Sprintf("This is the search rule I'd build for your request.\n\n**Intent:** *\"%s\"*", query),
+		Color:       color,
+		Fields:      fields,
+		Footer: &discordgo.MessageEmbedFooter{
+			Text: "Preview only, nothing was saved. Run /alert add to create an alert.",
+		},
 	}
 
-	aiSvc, err := ai.NewAIClient(ctx, os.Getenv("GEMINI_API_KEY"))
-	if err != nil {
-		client.SendFollowupMessage(i, "⚠️ Could not connect to Gemini AI.")
+	client.SendFollowupEmbedWithComponents(i, embed, []discordgo.MessageComponent{})
+}
+
+// wizardRuleFields renders the keyword arrays Gemini produced, plus its warning when the rule is too broad.
+// The color turns yellow for a too-broad rule.
+func wizardRuleFields(wizard *ai.KeywordWizardResponse) (color int, fields []*discordgo.MessageEmbedField) {
+	color = 0x5865F2 // Blurple
+
+	if len(wizard.MustHave) > 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   "✅ Must Include",
+			Value:  fmt.Sprintf("`%s`", strings.Join(wizard.MustHave, "`, `")),
+			Inline: false,
+		})
+	}
+	if len(wizard.AnyOf) > 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   "🔍 Match Any Of",
+			Value:  fmt.Sprintf("`%s`", strings.Join(wizard.AnyOf, "`, `")),
+			Inline: false,
+		})
+	}
+	if len(wizard.MustNot) > 0 {
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   "🚫 Exclude",
+			Value:  fmt.Sprintf("`%s`", strings.Join(wizard.MustNot, "`, `")),
+			Inline: false,
+		})
+	}
+
+	if wizard.TooBroad {
+		color = 0xFEE75C // Yellow
+		suggestions := ""
+		if len(wizard.BroadSuggestions) > 0 {
+			for _, s := range wizard.BroadSuggestions {
+				suggestions += fmt.Sprintf("• %s\n", s)
+			}
+		}
+		fields = append(fields, &discordgo.MessageEmbedField{
+			Name:   "⚠️ Search is Too Broad",
+			Value:  fmt.Sprintf("> %s\n\n**Suggestions:**\n%s", wizard.BroadReason, suggestions),
+			Inline: false,
+		})
+	}
+	return color, fields
+}
+
+func (h *Handler) processManualWizard(ctx context.Context, i *discordgo.Interaction, title, query string, editCount int) {
+	db, aiSvc, client := h.db, h.ai, h.client
+
+	scope := alertScope(i)
+	if editCount >= 3 {
+		client.SendFollowupMessage(i, "⚠️ **Alert creation cancelled due to multiple invalid query attempts.** Please start over.")
+		return
+	}
+
+	// Don't pay for a Gemini call, or rely on it catching the attempt, when the input is plainly not a query.
+	if marker := injectionMarker(title + " " + query); marker != "" {
+		log.Printf("SECURITY: Rejected manual query from user %s in %s before validation (%s): %q", interactionUserID(i), scope, marker, query)
+		client.SendFollowupMessage(i, "⚠️ That doesn't look like a search query. Enter keywords like `rtx AND 3080` and try `/alert add` again.")
 		return
 	}
-	defer aiSvc.Close()
+
+	sysPrompt, _ := db.GetSystemPrompt(ctx, "manual_prompt")
 
 	wizard, err := aiSvc.ValidateManualQuery(ctx, query, sysPrompt)
 	if err != nil {
@@ -202,13 +313,11 @@ func processManualWizard(ctx context.Context, i *discordgo.Interaction, title, q
 	}
 
 	if !wizard.IsValid {
-		if db != nil {
-			_ = db.SaveAnalytics(ctx, store.AnalyticsRecord{
-				OriginalUserPrompt: query,
-				Outcome:            "Rejected_Syntax_Error",
-				EditCount:          editCount,
-			})
-		}
+		_ = db.SaveAnalytics(ctx, store.AnalyticsRecord{
+			OriginalUserPrompt: query,
+			Outcome:            "Rejected_Syntax_Error",
+			EditCount:          editCount,
+		})
 
 		desc := fmt.Sprintf("**Query Syntax Error:**\n`%s`\n\n**Reason:** %s", query, wizard.ErrorMessage)
 		embed := &discordgo.MessageEmbed{
@@ -254,42 +363,96 @@ func processManualWizard(ctx context.Context, i *discordgo.Interaction, title, q
 		Color:       0x00FF00,
 	}
 
-	tempRule := store.AlertRule{
-		UserID:   i.Member.User.ID,
-		ServerID: i.GuildID,
+	tempRule := store.NormalizeAlertRule(store.AlertRule{
+		UserID:   interactionUserID(i),
+		ServerID: scope,
 		MustHave: wizard.MustHave,
 		AnyOf:    wizard.AnyOf,
 		MustNot:  wizard.MustNot,
 		RawQuery: title,
+	})
+	if err := store.ValidateAlertRule(tempRule); err != nil {
+		client.SendFollowupMessage(i, fmt.Sprintf("⚠️ This alert can't be saved: %v.", err))
+		return
 	}
 
-	if db != nil {
-		if err := db.AddAlert(ctx, tempRule); err != nil {
-			client.SendFollowupMessage(i, "⚠️ Failed to stage alert in database.")
-			return
-		}
-		alerts, _ := db.GetUserAlerts(ctx, i.GuildID, i.Member.User.ID)
-		if len(alerts) > 0 {
-			stagedAlertID := alerts[0].ID
-			components := []discordgo.MessageComponent{
-				discordgo.ActionsRow{
-					Components: []discordgo.MessageComponent{
-						discordgo.Button{
-							Label:    "💾 Save Alert",
-							Style:    discordgo.SuccessButton,
-							CustomID: "confirm_alert|" + stagedAlertID + "|Manual",
-						},
-						discordgo.Button{
-							Label:    "❌ Cancel",
-							Style:    discordgo.DangerButton,
-							CustomID: "cancel_alert|" + stagedAlertID + "|Manual",
-						},
+	if err := db.AddAlert(ctx, tempRule); err != nil {
+		client.SendFollowupMessage(i, "⚠️ Failed to stage alert in database.")
+		return
+	}
+	alerts, _ := db.GetUserAlerts(ctx, scope, interactionUserID(i))
+	if len(alerts) > 0 {
+		stagedAlertID := alerts[0].ID
+		components := []discordgo.MessageComponent{
+			discordgo.ActionsRow{
+				Components: []discordgo.MessageComponent{
+					discordgo.Button{
+						Label:    "💾 Save Alert",
+						Style:    discordgo.SuccessButton,
+						CustomID: "confirm_alert|" + stagedAlertID + "|Manual",
+					},
+					discordgo.Button{
+						Label:    "❌ Cancel",
+						Style:    discordgo.DangerButton,
+						CustomID: "cancel_alert|" + stagedAlertID + "|Manual",
 					},
 				},
-			}
-			client.SendFollowupEmbedWithComponents(i, embed, components)
-			return
+			},
 		}
+		client.SendFollowupEmbedWithComponents(i, embed, components)
+		return
 	}
 	client.SendFollowupMessage(i, "⚠️ System error while saving alert.")
 }
+
+// stripBannedKeywords removes the stored banned generic terms from a wizard rule, using the defaults if
+// the list can't be loaded. Every removal is counted per term and saved as a wizard analytics record,
+// so compaction sees the model breaking its prompt.
+func stripBannedKeywords(ctx context.Context, db Storer, query string, wizard *ai.KeywordWizardResponse) {
+	banned, err := db.GetBannedKeywords(ctx)
+	if err != nil {
+		log.Printf("Failed to load banned keywords, using the defaults: %v", err)
+		banned = store.DefaultBannedKeywords
+	}
+	stripped := wizard.StripBannedKeywords(banned)
+	if len(stripped) == 0 {
+		return
+	}
+
+	if err := db.RecordStrippedKeywords(ctx, stripped); err != nil {
+		log.Printf("Failed to count stripped keywords %v: %v", stripped, err)
+	}
+	_ = db.SaveAnalytics(ctx, store.AnalyticsRecord{
+		FlowType:           "wizard",
+		OriginalUserPrompt: query,
+		Outcome:            "Stripped_Banned_Keywords",
+		StrippedKeywords:   stripped,
+	})
+}
+
+// isEmptyQuery reports whether a parsed rule has no positive keywords, meaning it would match every post.
+func isEmptyQuery(wizard *ai.KeywordWizardResponse) bool {
+	return len(wizard.MustHave) == 0 && len(wizard.AnyOf) == 0
+}
+
+// buildEmptyWizardEmbed explains why no rule could be built from the user's request.
+func buildEmptyWizardEmbed(query string, wizard *ai.KeywordWizardResponse) *discordgo.MessageEmbed {
+	reason := wizard.BroadReason
+	if reason == "" {
+		reason = "I couldn't pick out any specific hardware, brand, or location to search for."
+	}
+
+	desc := fmt.Sprintf("**Intent:** *\"%s\"*\n\n> %s\n\nTry naming a specific model (e.g. `rtx 3080`, `5800x3d`) or a city.", query, reason)
+	if len(wizard.BroadSuggestions) > 0 {
+		desc += "\n\n**Suggestions:**\n"
+		for _, s := range wizard.BroadSuggestions {
+			desc += fmt.Sprintf("• %s\n", s)
+		}
+	}
+
+	return &discordgo.MessageEmbed{
+		Title:       "🤔 I Couldn't Build a Rule From That",
+		Description: desc,
+		Color:       0xFEE75C, // Yellow
+	}
+}
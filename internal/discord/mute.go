@@ -0,0 +1,60 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// handleMuteAuthor mutes or unmutes a Reddit seller for the user who clicked a deal's Mute Seller button
+// (or the Undo button on its confirmation). Muted sellers' posts never match the user's alerts.
+func (h *Handler) handleMuteAuthor(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction, author string, mute bool) {
+	userID := interactionUserID(i)
+	if userID == "" {
+		respondError(w, "Could not identify user.")
+		return
+	}
+
+	if !mute {
+		if err := h.db.UnmuteAuthor(ctx, userID, author); err != nil {
+			log.Printf("Failed to unmute author %s for user %s: %v", author, userID, err)
+			respondError(w, "Failed to unmute seller.")
+			return
+		}
+		writeJSON(w, discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: &discordgo.InteractionResponseData{
+				Content:    fmt.Sprintf("🔔 Posts from **u/%s** can match your alerts again.", author),
+				Components: []discordgo.MessageComponent{},
+			},
+		})
+		return
+	}
+
+	if err := h.db.MuteAuthor(ctx, userID, author); err != nil {
+		log.Printf("Failed to mute author %s for user %s: %v", author, userID, err)
+		respondError(w, "Failed to mute seller.")
+		return
+	}
+	writeJSON(w, discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: fmt.Sprintf("🚫 **u/%s** is muted. Their posts won't match any of your alerts.", author),
+			Flags:   discordgo.MessageFlagsEphemeral,
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{
+					Components: []discordgo.MessageComponent{
+						discordgo.Button{
+							Label:    "Undo",
+							Style:    discordgo.SecondaryButton,
+							CustomID: "unmute_author|" + author,
+						},
+					},
+				},
+			},
+		},
+	})
+}
@@ -0,0 +1,66 @@
+package discord
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandleInteraction_MuteSellerButton(t *testing.T) {
+	th := newInteractionHarness(t)
+	th.db.On("MuteAuthor", mock.Anything, "mute_user", "Spammer99").Return(nil)
+	th.db.On("UnmuteAuthor", mock.Anything, "unmute_user", "Spammer99").Return(nil)
+
+	// The confirmation carries a button, which discordgo can't decode back, so read the raw response.
+	rr := httptest.NewRecorder()
+	th.handler.HandleInteraction(rr, signedRequest(t, th.priv, discordgo.Interaction{
+		ID:      "interaction_mute",
+		Type:    discordgo.InteractionMessageComponent,
+		GuildID: "guild1",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "mute_user"}},
+		Data: discordgo.MessageComponentInteractionData{
+			CustomID:      "mute_author|Spammer99",
+			ComponentType: discordgo.ButtonComponent,
+		},
+	}))
+	var muted struct {
+		Data struct {
+			Content    string                 `json:"content"`
+			Flags      discordgo.MessageFlags `json:"flags"`
+			Components []struct {
+				Components []struct {
+					CustomID string `json:"custom_id"`
+				} `json:"components"`
+			} `json:"components"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&muted); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if muted.Data.Flags != discordgo.MessageFlagsEphemeral || !strings.Contains(muted.Data.Content, "u/Spammer99** is muted") {
+		t.Fatalf("expected an ephemeral mute confirmation, got %+v", muted.Data)
+	}
+	if len(muted.Data.Components) != 1 || muted.Data.Components[0].Components[0].CustomID != "unmute_author|Spammer99" {
+		t.Fatalf("expected an Undo button, got %+v", muted.Data.Components)
+	}
+
+	resp := th.serve(t, discordgo.Interaction{
+		ID:      "interaction_unmute",
+		Type:    discordgo.InteractionMessageComponent,
+		GuildID: "guild1",
+		Member:  &discordgo.Member{User: &discordgo.User{ID: "unmute_user"}},
+		Data: discordgo.MessageComponentInteractionData{
+			CustomID:      "unmute_author|Spammer99",
+			ComponentType: discordgo.ButtonComponent,
+		},
+	})
+
+	if resp.Type != discordgo.InteractionResponseUpdateMessage || !strings.Contains(resp.Data.Content, "can match your alerts again") {
+		t.Errorf("expected the confirmation to be replaced, got %+v", resp)
+	}
+	th.db.AssertExpectations(t)
+}
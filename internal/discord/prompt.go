@@ -0,0 +1,141 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pauljones0/betterHardwareSwap/internal/ai"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+)
+
+// maxInlinePromptLen is the longest prompt shown inside the embed. Embed descriptions are capped at
+// 4096 characters and the header and code fence need some of that; longer prompts are attached instead.
+const maxInlinePromptLen = 3800
+
+// defaultPrompts are the built-in system prompts each flow uses until an admin approves a compacted one.
+var defaultPrompts = map[string]string{
+	"wizard": ai.DefaultWizardPrompt,
+	"manual": ai.DefaultManualPrompt,
+	"clean":  ai.CleanPostSystemInstruction,
+}
+
+// handlePrompt routes `/prompt show|reset <flow>`. Restricted to the bot owner, who approves prompt changes.
+func (h *Handler) handlePrompt(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
+	if !h.isBotOwner(interactionUserID(i)) {
+		respondError(w, "This command is restricted to the bot owner.")
+		return
+	}
+
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		respondError(w, "Unknown subcommand")
+		return
+	}
+	flow := ""
+	for _, opt := range options[0].Options {
+		if opt.Name == "flow" {
+			flow = opt.StringValue()
+		}
+	}
+	if _, ok := defaultPrompts[flow]; !ok {
+		respondError(w, "Unknown flow. Choose wizard, manual or clean.")
+		return
+	}
+
+	switch options[0].Name {
+	case "show":
+		writeJSON(w, discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Flags: discordgo.MessageFlagsEphemeral,
+			},
+		})
+		go showPrompt(context.Background(), h.db, h.client, i, flow)
+
+	case "reset":
+		// Resetting throws away whatever compaction produced, so ask first.
+		writeJSON(w, discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: &discordgo.InteractionResponseData{
+				Content: fmt.Sprintf("♻️ Reset the **%s** prompt to the built-in default? The current prompt will be replaced for every server.", flow),
+				Components: []discordgo.MessageComponent{
+					discordgo.ActionsRow{
+						Components: []discordgo.MessageComponent{
+							discordgo.Button{
+								Label:    "♻️ Reset to Default",
+								Style:    discordgo.DangerButton,
+								CustomID: "reset_prompt|" + flow,
+							},
+							discordgo.Button{
+								Label:    "Keep Current",
+								Style:    discordgo.SecondaryButton,
+								CustomID: "cancel_prompt_reset",
+							},
+						},
+					},
+				},
+				Flags: discordgo.MessageFlagsEphemeral,
+			},
+		})
+
+	default:
+		respondError(w, "Unknown subcommand")
+	}
+}
+
+// resetPrompt puts a flow back on its built-in default prompt and records the reset in the prompt history.
+func resetPrompt(ctx context.Context, db Storer, flow string) error {
+	text, ok := defaultPrompts[flow]
+	if !ok {
+		return fmt.Errorf("unknown flow %q", flow)
+	}
+	key := flow + "_prompt"
+	if err := db.SetSystemPrompt(ctx, key, text); err != nil {
+		return err
+	}
+	if err := db.AddPromptHistory(ctx, store.PromptHistoryEntry{PromptKey: key, Action: store.PromptActionReset, PromptText: text}); err != nil {
+		log.Printf("Failed to record %s reset in the prompt history: %v", key, err)
+	}
+	return nil
+}
+
+// showPrompt sends the system prompt currently live for a flow, or the built-in default when none
+// has been approved. Prompts too long for an embed, or containing their own code fences, are attached
+// as a text file.
+func showPrompt(ctx context.Context, db Storer, client Messenger, i *discordgo.Interaction, flow string) {
+	key := flow + "_prompt"
+	record, err := db.GetSystemPromptRecord(ctx, key)
+	if err != nil {
+		log.Printf("Failed to load %s: %v", key, err)
+		client.SendFollowupMessage(i, "⚠️ Failed to load the prompt.")
+		return
+	}
+
+	text := defaultPrompts[flow]
+	source := "Built-in default. No compacted prompt has been approved yet."
+	if record != nil && record.PromptText != "" {
+		text = record.PromptText
+		source = fmt.Sprintf("Approved prompt, last updated <t:%d:f>.", record.UpdatedAt.Unix())
+	}
+
+	if len(text) > maxInlinePromptLen || strings.Contains(text, "```") {
+		content := fmt.Sprintf("🧠 **Active %s prompt**\n%s\nIt's too long to show here, so it's attached.", flow, source)
+		if err := client.SendFollowupFile(i, content, key+".txt", []byte(text)); err != nil {
+			log.Printf("Failed to send %s as a file: %v", key, err)
+		}
+		return
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       fmt.Sprintf("🧠 Active %s prompt", flow),
+		Description: source + "\n```text\n" + text + "\n```",
+		Color:       0xFFD700, // Gold, like the approval requests
+	}
+	if err := client.SendFollowupEmbedWithComponents(i, embed, []discordgo.MessageComponent{}); err != nil {
+		log.Printf("Failed to send %s: %v", key, err)
+	}
+}
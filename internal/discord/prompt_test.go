@@ -0,0 +1,156 @@
+package discord
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pauljones0/betterHardwareSwap/internal/ai"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+	"github.com/pauljones0/betterHardwareSwap/internal/testutils"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestShowPrompt(t *testing.T) {
+	ctx := context.Background()
+	i := &discordgo.Interaction{AppID: "app1", Token: "tok1"}
+	updated := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	t.Run("Wizard shows the approved prompt and when it changed", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+		mockDiscord := new(testutils.MockDiscord)
+		mockDB.On("GetSystemPromptRecord", mock.Anything, "wizard_prompt").Return(&store.SystemPrompt{PromptText: "Build tight rules.", UpdatedAt: updated}, nil)
+
+		var sent *discordgo.MessageEmbed
+		mockDiscord.On("SendFollowupEmbedWithComponents", i, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) { sent = args.Get(1).(*discordgo.MessageEmbed) }).
+			Return(nil)
+
+		showPrompt(ctx, mockDB, mockDiscord, i, "wizard")
+
+		mockDiscord.AssertExpectations(t)
+		if !strings.Contains(sent.Description, "Build tight rules.") {
+			t.Errorf("expected the stored prompt, got %q", sent.Description)
+		}
+		if !strings.Contains(sent.Description, "<t:1772366400:f>") {
+			t.Errorf("expected the last-updated timestamp, got %q", sent.Description)
+		}
+	})
+
+	t.Run("Manual falls back to the built-in default", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+		mockDiscord := new(testutils.MockDiscord)
+		mockDB.On("GetSystemPromptRecord", mock.Anything, "manual_prompt").Return(nil, nil)
+
+		var sent *discordgo.MessageEmbed
+		mockDiscord.On("SendFollowupEmbedWithComponents", i, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) { sent = args.Get(1).(*discordgo.MessageEmbed) }).
+			Return(nil)
+
+		showPrompt(ctx, mockDB, mockDiscord, i, "manual")
+
+		mockDiscord.AssertExpectations(t)
+		if !strings.Contains(sent.Description, "Built-in default") || !strings.Contains(sent.Description, ai.DefaultManualPrompt) {
+			t.Errorf("expected the default manual prompt, got %q", sent.Description)
+		}
+	})
+
+	t.Run("Long prompts are attached as a file", func(t *testing.T) {
+		long := strings.Repeat("Keep keywords short. ", 300)
+		mockDB := new(testutils.MockStore)
+		mockDiscord := new(testutils.MockDiscord)
+		mockDB.On("GetSystemPromptRecord", mock.Anything, "wizard_prompt").Return(&store.SystemPrompt{PromptText: long, UpdatedAt: updated}, nil)
+		mockDiscord.On("SendFollowupFile", i, mock.MatchedBy(func(content string) bool {
+			return strings.Contains(content, "Active wizard prompt") && strings.Contains(content, "<t:1772366400:f>")
+		}), "wizard_prompt.txt", []byte(long)).Return(nil)
+
+		showPrompt(ctx, mockDB, mockDiscord, i, "wizard")
+
+		mockDiscord.AssertExpectations(t)
+		mockDiscord.AssertNotCalled(t, "SendFollowupEmbedWithComponents", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Load failure is reported", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+		mockDiscord := new(testutils.MockDiscord)
+		mockDB.On("GetSystemPromptRecord", mock.Anything, "manual_prompt").Return(nil, errors.New("firestore down"))
+		mockDiscord.On("SendFollowupMessage", i, "⚠️ Failed to load the prompt.").Return(nil)
+
+		showPrompt(ctx, mockDB, mockDiscord, i, "manual")
+
+		mockDiscord.AssertExpectations(t)
+	})
+}
+
+func TestResetPrompt(t *testing.T) {
+	ctx := context.Background()
+
+	for flow, want := range map[string]string{"wizard": ai.DefaultWizardPrompt, "manual": ai.DefaultManualPrompt} {
+		t.Run(flow, func(t *testing.T) {
+			mockDB := new(testutils.MockStore)
+			var stored string
+			mockDB.On("SetSystemPrompt", mock.Anything, flow+"_prompt", mock.Anything).
+				Run(func(args mock.Arguments) { stored = args.String(2) }).
+				Return(nil)
+			mockDB.On("AddPromptHistory", mock.Anything, mock.MatchedBy(func(e store.PromptHistoryEntry) bool {
+				return e.PromptKey == flow+"_prompt" && e.Action == store.PromptActionReset && e.PromptText == want
+			})).Return(nil)
+
+			if err := resetPrompt(ctx, mockDB, flow); err != nil {
+				t.Fatalf("resetPrompt failed: %v", err)
+			}
+			if stored != want {
+				t.Errorf("expected the stored prompt to be the default, got %q", stored)
+			}
+			mockDB.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandleInteraction_ResetPromptButton(t *testing.T) {
+	th := newInteractionHarness(t)
+	th.handler.cfg.AdminUserID = "prompt_owner"
+	th.db.On("SetSystemPrompt", mock.Anything, "wizard_prompt", ai.DefaultWizardPrompt).Return(nil)
+	th.db.On("AddPromptHistory", mock.Anything, mock.Anything).Return(nil)
+
+	click := func(userID string) discordgo.InteractionResponse {
+		return th.serve(t, discordgo.Interaction{
+			ID:   "interaction_reset",
+			Type: discordgo.InteractionMessageComponent,
+			User: &discordgo.User{ID: userID},
+			Data: discordgo.MessageComponentInteractionData{CustomID: "reset_prompt|wizard", ComponentType: discordgo.ButtonComponent},
+		})
+	}
+
+	if resp := click("someone_else"); resp.Data == nil || !strings.Contains(resp.Data.Content, "restricted to the bot owner") {
+		t.Errorf("expected non-owners to be refused, got %+v", resp.Data)
+	}
+	if resp := click("prompt_owner"); resp.Data == nil || !strings.Contains(resp.Data.Content, "back on the built-in default") {
+		t.Errorf("expected the reset to be confirmed, got %+v", resp.Data)
+	}
+	th.db.AssertExpectations(t)
+	th.db.AssertNumberOfCalls(t, "SetSystemPrompt", 1)
+}
+
+func TestHandleInteraction_PromptReviewRequiresOwner(t *testing.T) {
+	th := newInteractionHarness(t)
+	th.handler.cfg.AdminUserID = "prompt_owner"
+
+	for _, customID := range []string{"approve_prompt|wizard", "reject_prompt|wizard"} {
+		resp := th.serve(t, discordgo.Interaction{
+			ID:      "interaction_review",
+			Type:    discordgo.InteractionMessageComponent,
+			User:    &discordgo.User{ID: "reviewer_" + customID},
+			Message: &discordgo.Message{Embeds: []*discordgo.MessageEmbed{{Description: "```text\nnew prompt\n```"}}},
+			Data:    discordgo.MessageComponentInteractionData{CustomID: customID, ComponentType: discordgo.ButtonComponent},
+		})
+		if resp.Data == nil || !strings.Contains(resp.Data.Content, "restricted to the bot owner") {
+			t.Errorf("%s: expected non-owners to be refused, got %+v", customID, resp.Data)
+		}
+	}
+	th.db.AssertNotCalled(t, "SetSystemPrompt", mock.Anything, mock.Anything, mock.Anything)
+	th.db.AssertNotCalled(t, "DeleteAnalyticsChunk", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,91 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// handleQuietHours manages a user's quiet hours via `/quiethours set|off`.
+func (h *Handler) handleQuietHours(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		respondError(w, "Unknown subcommand")
+		return
+	}
+
+	subCommand := options[0].Name
+	start, end := -1, -1
+	timezone := ""
+	for _, opt := range options[0].Options {
+		switch opt.Name {
+		case "start":
+			start = int(opt.IntValue())
+		case "end":
+			end = int(opt.IntValue())
+		case "timezone":
+			timezone = opt.StringValue()
+		}
+	}
+
+	userID := interactionUserID(i)
+	content, err := runQuietHoursCommand(ctx, h.db, userID, subCommand, start, end, timezone)
+	if err != nil {
+		log.Printf("Quiet hours %s failed for user %s: %v", subCommand, userID, err)
+		respondError(w, "Failed to update your quiet hours.")
+		return
+	}
+
+	writeJSON(w, discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// runQuietHoursCommand applies a quiet hours subcommand and returns the message to show the user.
+// User mistakes are reported in the message; only storage failures are returned as errors.
+func runQuietHoursCommand(ctx context.Context, db Storer, userID, subCommand string, start, end int, timezone string) (string, error) {
+	if userID == "" {
+		return "⚠️ Couldn't identify you. Please try again.", nil
+	}
+
+	switch subCommand {
+	case "set":
+		if start < 0 || start > 23 || end < 0 || end > 23 {
+			return "⚠️ Start and end must be hours from 0 to 23.", nil
+		}
+		if start == end {
+			return "⚠️ Start and end can't be the same hour.", nil
+		}
+		if strings.TrimSpace(timezone) != "" {
+			var problem string
+			if timezone, problem = validateTimezone(timezone); problem != "" {
+				return problem, nil
+			}
+		}
+		if err := db.SetQuietHours(ctx, userID, start, end, timezone); err != nil {
+			return "", err
+		}
+		zone := "your `/timezone` (UTC if you haven't set one)"
+		if timezone != "" {
+			zone = timezone
+		}
+		return fmt.Sprintf("🌙 Quiet hours set from **%02d:00** to **%02d:00** in %s. Matches in that window will be sent as one digest when it ends.", start, end, zone), nil
+
+	case "off":
+		if err := db.ClearQuietHours(ctx, userID); err != nil {
+			return "", err
+		}
+		return "🔔 Quiet hours are off. You'll be pinged as soon as a deal matches.", nil
+
+	default:
+		return "⚠️ Unknown subcommand.", nil
+	}
+}
@@ -0,0 +1,86 @@
+package discord
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/testutils"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRunQuietHoursCommand(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		subCommand string
+		start, end int
+		timezone   string
+		setup      func(m *testutils.MockStore)
+		want       string
+	}{
+		{
+			name:       "Set stores an overnight window",
+			subCommand: "set",
+			start:      22,
+			end:        7,
+			timezone:   " America/Toronto ",
+			setup: func(m *testutils.MockStore) {
+				m.On("SetQuietHours", mock.Anything, "user1", 22, 7, "America/Toronto").Return(nil)
+			},
+			want: "**22:00** to **07:00** in America/Toronto",
+		},
+		{
+			name:       "Set without a timezone keeps the saved one",
+			subCommand: "set",
+			start:      0,
+			end:        6,
+			setup: func(m *testutils.MockStore) {
+				m.On("SetQuietHours", mock.Anything, "user1", 0, 6, "").Return(nil)
+			},
+			want: "your `/timezone`",
+		},
+		{
+			name:       "Set rejects unknown timezones",
+			subCommand: "set",
+			start:      22,
+			end:        7,
+			timezone:   "Mars/Olympus",
+			want:       "isn't a timezone I know",
+		},
+		{
+			name:       "Set rejects an empty window",
+			subCommand: "set",
+			start:      8,
+			end:        8,
+			want:       "can't be the same hour",
+		},
+		{
+			name:       "Off clears quiet hours",
+			subCommand: "off",
+			setup: func(m *testutils.MockStore) {
+				m.On("ClearQuietHours", mock.Anything, "user1").Return(nil)
+			},
+			want: "Quiet hours are off",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := new(testutils.MockStore)
+			if tt.setup != nil {
+				tt.setup(mockDB)
+			}
+
+			got, err := runQuietHoursCommand(ctx, mockDB, "user1", tt.subCommand, tt.start, tt.end, tt.timezone)
+			if err != nil {
+				t.Fatalf("runQuietHoursCommand failed: %v", err)
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("expected response to contain %q, got %q", tt.want, got)
+			}
+			mockDB.AssertExpectations(t)
+		})
+	}
+}
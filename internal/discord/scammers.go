@@ -0,0 +1,101 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+)
+
+// handleScammers manages the global scam seller blocklist via `/scammers add|remove|list`. Restricted to
+// the bot owner, since the list applies to every server.
+func (h *Handler) handleScammers(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
+	userID := interactionUserID(i)
+	if !h.isBotOwner(userID) {
+		respondError(w, "This command is restricted to the bot owner.")
+		return
+	}
+
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		respondError(w, "Unknown subcommand")
+		return
+	}
+
+	subCommand := options[0].Name
+	username := ""
+	for _, opt := range options[0].Options {
+		if opt.Name == "username" {
+			username = opt.StringValue()
+		}
+	}
+
+	content, err := runScammersCommand(ctx, h.db, userID, subCommand, username)
+	if err != nil {
+		log.Printf("Scammers %s failed: %v", subCommand, err)
+		respondError(w, "Failed to update the scammer blocklist.")
+		return
+	}
+
+	writeJSON(w, discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// runScammersCommand applies a scammers subcommand and returns the message to show the owner.
+// Mistakes are reported in the message; only storage failures are returned as errors.
+func runScammersCommand(ctx context.Context, db Storer, ownerID, subCommand, username string) (string, error) {
+	scammers, err := db.GetScammers(ctx)
+	if err != nil {
+		return "", err
+	}
+	listed := func(name string) bool {
+		return slices.ContainsFunc(scammers, func(sc store.Scammer) bool { return sc.Username == name })
+	}
+
+	switch subCommand {
+	case "add", "remove":
+		name, ok := store.NormalizeRedditUsername(username)
+		if !ok {
+			return "⚠️ That isn't a valid Reddit username.", nil
+		}
+		if subCommand == "add" {
+			if listed(name) {
+				return fmt.Sprintf("u/%s is already blocklisted.", name), nil
+			}
+			if err := db.AddScammer(ctx, name, ownerID); err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("🚫 Posts by **u/%s** will be dropped in every server.", name), nil
+		}
+		if !listed(name) {
+			return fmt.Sprintf("u/%s isn't on the blocklist.", name), nil
+		}
+		if err := db.RemoveScammer(ctx, name); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("✅ u/%s removed from the blocklist.", name), nil
+
+	case "list":
+		if len(scammers) == 0 {
+			return "The scammer blocklist is empty. Use `/scammers add` to block a seller everywhere.", nil
+		}
+		names := make([]string, len(scammers))
+		for idx, sc := range scammers {
+			names[idx] = "u/" + sc.Username
+		}
+		return truncateField(fmt.Sprintf("🚫 **Blocklisted sellers (%d):**\n%s", len(names), strings.Join(names, ", ")), 2000), nil
+
+	default:
+		return "⚠️ Unknown subcommand.", nil
+	}
+}
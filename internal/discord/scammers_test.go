@@ -0,0 +1,85 @@
+package discord
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+	"github.com/pauljones0/betterHardwareSwap/internal/testutils"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRunScammersCommand(t *testing.T) {
+	ctx := context.Background()
+	scammers := []store.Scammer{{Username: "scammer_joe"}}
+
+	tests := []struct {
+		name       string
+		subCommand string
+		username   string
+		setup      func(m *testutils.MockStore)
+		want       string
+	}{
+		{
+			name:       "Add normalizes and stores the username",
+			subCommand: "add",
+			username:   " u/Bad_Seller ",
+			setup: func(m *testutils.MockStore) {
+				m.On("AddScammer", mock.Anything, "bad_seller", "owner1").Return(nil)
+			},
+			want: "**u/bad_seller** will be dropped in every server",
+		},
+		{
+			name:       "Add skips duplicates",
+			subCommand: "add",
+			username:   "Scammer_Joe",
+			want:       "already blocklisted",
+		},
+		{
+			name:       "Add rejects invalid usernames",
+			subCommand: "add",
+			username:   "not a user",
+			want:       "isn't a valid Reddit username",
+		},
+		{
+			name:       "Remove deletes a listed username",
+			subCommand: "remove",
+			username:   "/u/scammer_joe",
+			setup: func(m *testutils.MockStore) {
+				m.On("RemoveScammer", mock.Anything, "scammer_joe").Return(nil)
+			},
+			want: "removed from the blocklist",
+		},
+		{
+			name:       "Remove reports unknown usernames",
+			subCommand: "remove",
+			username:   "honest_seller",
+			want:       "isn't on the blocklist",
+		},
+		{
+			name:       "List shows blocklisted sellers",
+			subCommand: "list",
+			want:       "u/scammer_joe",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := new(testutils.MockStore)
+			mockDB.On("GetScammers", mock.Anything).Return(scammers, nil)
+			if tt.setup != nil {
+				tt.setup(mockDB)
+			}
+
+			got, err := runScammersCommand(ctx, mockDB, "owner1", tt.subCommand, tt.username)
+			if err != nil {
+				t.Fatalf("runScammersCommand failed: %v", err)
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("expected response to contain %q, got %q", tt.want, got)
+			}
+			mockDB.AssertExpectations(t)
+		})
+	}
+}
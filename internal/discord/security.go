@@ -1,53 +1,172 @@
 package discord
 
 import (
+	"context"
+	"log"
 	"regexp"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/clock"
 )
 
+// rateLimitInterval is the minimum time between two interactions from the same user.
+const rateLimitInterval = 2 * time.Second
+
+// SharedRateLimitStore records interactions in storage shared by every instance of the bot.
+type SharedRateLimitStore interface {
+	TryRateLimit(ctx context.Context, userID string, interval time.Duration, now time.Time) (bool, error)
+}
+
 // RateLimiter provides a simple in-memory token bucket rate limiter.
 type RateLimiter struct {
 	mu       sync.Mutex
 	lastSeen map[string]time.Time
+	bypass   map[string]time.Time // UserID -> when their temporary bypass expires
+	shared   SharedRateLimitStore // Optional; nil keeps the limit per instance
+	clock    clock.Clock
 }
 
 func NewRateLimiter() *RateLimiter {
 	return &RateLimiter{
 		lastSeen: make(map[string]time.Time),
+		bypass:   make(map[string]time.Time),
+		clock:    clock.Real{},
+	}
+}
+
+// NewSharedRateLimiter returns a RateLimiter that enforces the limit across instances through shared.
+// Bypasses are still kept in memory, so they only apply on the instance that granted them.
+func NewSharedRateLimiter(shared SharedRateLimitStore) *RateLimiter {
+	rl := NewRateLimiter()
+	rl.shared = shared
+	return rl
+}
+
+// GrantBypass exempts userID from the limit until the given time, for bulk imports and edits.
+// A zero or past time revokes an existing bypass.
+func (rl *RateLimiter) GrantBypass(userID string, until time.Time) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if !rl.clock.Now().Before(until) {
+		delete(rl.bypass, userID)
+		return
 	}
+	rl.bypass[userID] = until
 }
 
 // Allow checks if the given userID is allowed to perform an action (max 1 request per 2 seconds).
-func (rl *RateLimiter) Allow(userID string) bool {
+// Users with an active bypass are always allowed; expired bypasses are dropped on their next request.
+// A shared limiter falls back to the in-memory check if the shared store can't be reached, so a
+// Firestore outage doesn't lock everyone out.
+func (rl *RateLimiter) Allow(ctx context.Context, userID string) bool {
+	if rl.bypassed(userID) {
+		return true
+	}
+
+	if rl.shared != nil {
+		allowed, err := rl.shared.TryRateLimit(ctx, userID, rateLimitInterval, rl.clock.Now())
+		if err == nil {
+			return allowed
+		}
+		log.Printf("Shared rate limit check failed for user %s, using the local limit: %v", userID, err)
+	}
+
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
 
+	now := rl.clock.Now()
 	last, ok := rl.lastSeen[userID]
-	if ok && time.Since(last) < 2*time.Second {
+	if ok && now.Sub(last) < rateLimitInterval {
 		return false
 	}
 
-	rl.lastSeen[userID] = time.Now()
+	rl.lastSeen[userID] = now
 	return true
 }
 
+// bypassed reports whether userID has an active bypass, dropping it once it has expired.
+func (rl *RateLimiter) bypassed(userID string) bool {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	until, ok := rl.bypass[userID]
+	if !ok {
+		return false
+	}
+	if rl.clock.Now().Before(until) {
+		return true
+	}
+	delete(rl.bypass, userID)
+	return false
+}
+
 var (
 	// regex to strip potentially dangerous characters while allowing common hardware/location characters.
 	sanitizeRegex = regexp.MustCompile(`[^a-zA-Z0-9\s.,!?-]`)
+	// sanitizeQueryRegex also keeps the parentheses and double quotes that group Boolean queries.
+	sanitizeQueryRegex = regexp.MustCompile(`[^a-zA-Z0-9\s.,!?()"-]`)
 )
 
 // Sanitize cleans up user input strings to prevent basic injection or formatting abuse.
 func Sanitize(input string) string {
+	return sanitizeWith(sanitizeRegex, input)
+}
+
+// sanitizeQuery cleans up a manually typed Boolean query like Sanitize, but keeps its grouping, so
+// `rtx AND (4090 OR 4080)` can still be parsed without the AI.
+func sanitizeQuery(input string) string {
+	return sanitizeWith(sanitizeQueryRegex, input)
+}
+
+func sanitizeWith(re *regexp.Regexp, input string) string {
 	// 1. Limit length
 	if len(input) > 500 {
 		input = input[:500]
 	}
 
 	// 2. Strip dangerous characters
-	input = sanitizeRegex.ReplaceAllString(input, "")
+	input = re.ReplaceAllString(input, "")
 
 	// 3. Trim whitespace
 	return strings.TrimSpace(input)
 }
+
+// injectionPhrases are phrases that show up in prompt-injection attempts but never in a hardware search.
+// They are matched as whole words against lowercased input with punctuation removed, since Sanitize
+// strips most punctuation before queries reach us.
+var injectionPhrases = []string{
+	"ignore previous", "ignore all previous", "ignore prior", "ignore the above", "ignore your instructions",
+	"disregard previous", "disregard all", "disregard the above", "disregard your instructions",
+	"forget previous", "forget your instructions", "forget everything",
+	"system prompt", "system instruction", "system instructions", "new instructions",
+	"you are now", "act as", "pretend to be", "developer mode", "jailbreak",
+	"is valid true", "isvalid", "isvalid true", "respond with", "return json", "output json",
+}
+
+// injectionMarker returns the reason input looks like a prompt-injection attempt, or "" if it looks
+// like an ordinary query. It is deterministic and runs before Gemini sees the input, so obvious attempts
+// cost nothing and don't depend on the model noticing them.
+func injectionMarker(input string) string {
+	for _, r := range input {
+		// Newlines and other control characters let text pose as a separate prompt section, and format
+		// characters (zero-width, bidi overrides) hide text from whoever reads the query back.
+		if (unicode.IsControl(r) && r != ' ') || unicode.Is(unicode.Cf, r) {
+			return "control character"
+		}
+	}
+
+	words := strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	normalized := " " + strings.Join(words, " ") + " "
+	for _, phrase := range injectionPhrases {
+		if strings.Contains(normalized, " "+phrase+" ") {
+			return "phrase " + phrase
+		}
+	}
+	return ""
+}
@@ -0,0 +1,90 @@
+package discord
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// setupChannelTypes are the channels the interactive /setup menus offer: ones the bot can post deals in.
+var setupChannelTypes = []discordgo.ChannelType{discordgo.ChannelTypeGuildText, discordgo.ChannelTypeGuildNews}
+
+// setupPicker builds the interactive /setup message, with one channel menu for the feed and one for pings.
+// The channels picked so far travel in both menus' custom IDs ("setup_pick|<menu>|<feed>|<ping>"), so
+// the flow needs nothing stored between picks.
+func setupPicker(feedChannelID, pingChannelID string) *discordgo.InteractionResponseData {
+	menu := func(name, placeholder, picked string) discordgo.ActionsRow {
+		sm := discordgo.SelectMenu{
+			MenuType:     discordgo.ChannelSelectMenu,
+			CustomID:     strings.Join([]string{"setup_pick", name, feedChannelID, pingChannelID}, "|"),
+			Placeholder:  placeholder,
+			MaxValues:    1,
+			ChannelTypes: setupChannelTypes,
+		}
+		if picked != "" {
+			sm.DefaultValues = []discordgo.SelectMenuDefaultValue{{ID: picked, Type: discordgo.SelectMenuDefaultValueChannel}}
+		}
+		return discordgo.ActionsRow{Components: []discordgo.MessageComponent{sm}}
+	}
+
+	return &discordgo.InteractionResponseData{
+		Content: "⚙️ **Let's set up the bot.** Pick the channel new deals are posted in and the channel alert pings go to. " +
+			"The setup is saved as soon as both are chosen, so on a server that's already set up, changing either one saves it.\n\n" +
+			"*Run `/setup` with options instead for the archive channel, feed mode, language and other settings.*",
+		Components: []discordgo.MessageComponent{
+			menu("feed", "📰 Deal feed channel", feedChannelID),
+			menu("ping", "🔔 Alert ping channel", pingChannelID),
+		},
+	}
+}
+
+// handleSetupPick handles a pick from one of the interactive /setup channel menus. It shows the menus again
+// until both channels are chosen, then saves them, keeping the server's other settings.
+func (h *Handler) handleSetupPick(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction, parts []string) {
+	// The menus were only shown to an admin, but custom IDs can be forged, so check again.
+	if !memberIsAdmin(i) {
+		respondError(w, "Only server admins (Manage Server permission) can run `/setup`.")
+		return
+	}
+	values := selectedChannelIDs(i)
+	if len(parts) != 4 || len(values) != 1 {
+		respondError(w, "This setup has expired. Please run `/setup` again.")
+		return
+	}
+
+	feedChannelID, pingChannelID := parts[2], parts[3]
+	switch parts[1] {
+	case "feed":
+		feedChannelID = values[0]
+	case "ping":
+		pingChannelID = values[0]
+	default:
+		respondError(w, "This setup has expired. Please run `/setup` again.")
+		return
+	}
+
+	if feedChannelID == "" || pingChannelID == "" {
+		writeJSON(w, discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseUpdateMessage,
+			Data: setupPicker(feedChannelID, pingChannelID),
+		})
+		return
+	}
+
+	cfg, existed, err := h.currentSetup(ctx, i.GuildID)
+	if err != nil {
+		log.Printf("Failed to load config for guild %s: %v", i.GuildID, err)
+		respondError(w, "Failed to load the current configuration. Please try again.")
+		return
+	}
+	cfg.FeedChannelID = feedChannelID
+	cfg.PingChannelID = pingChannelID
+	if problem := h.checkFeedWebhook(cfg); problem != "" {
+		respondError(w, problem)
+		return
+	}
+	h.saveSetup(ctx, w, i, cfg, existed, discordgo.InteractionResponseUpdateMessage)
+}
@@ -0,0 +1,74 @@
+package discord
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// timezoneExamples is shown whenever a user enters a timezone we can't load.
+const timezoneExamples = "Use an IANA name like `America/Toronto`, `America/Vancouver`, `America/Halifax` or `UTC`."
+
+// validateTimezone trims name and checks it is an IANA timezone Go can load. It reports the
+// cleaned name, or a message explaining the problem to the user.
+func validateTimezone(name string) (string, string) {
+	name = strings.TrimSpace(name)
+	// LoadLocation treats "" as UTC and "Local" as the server's zone, neither of which is what the user typed.
+	if name == "" || name == "Local" {
+		return "", "⚠️ Please provide a timezone. " + timezoneExamples
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return "", fmt.Sprintf("⚠️ `%s` isn't a timezone I know. %s", name, timezoneExamples)
+	}
+	return name, ""
+}
+
+// handleTimezone saves the user's timezone via `/timezone <name>`.
+func (h *Handler) handleTimezone(ctx context.Context, w http.ResponseWriter, i *discordgo.Interaction) {
+	timezone := ""
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == "name" {
+			timezone = opt.StringValue()
+		}
+	}
+
+	userID := interactionUserID(i)
+	content, err := runTimezoneCommand(ctx, h.db, userID, timezone, time.Now())
+	if err != nil {
+		log.Printf("Setting timezone failed for user %s: %v", userID, err)
+		respondError(w, "Failed to save your timezone.")
+		return
+	}
+
+	writeJSON(w, discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{
+			Content: content,
+			Flags:   discordgo.MessageFlagsEphemeral,
+		},
+	})
+}
+
+// runTimezoneCommand validates and saves a user's timezone and returns the message to show them.
+// User mistakes are reported in the message; only storage failures are returned as errors.
+func runTimezoneCommand(ctx context.Context, db Storer, userID, timezone string, now time.Time) (string, error) {
+	if userID == "" {
+		return "⚠️ Couldn't identify you. Please try again.", nil
+	}
+
+	timezone, problem := validateTimezone(timezone)
+	if problem != "" {
+		return problem, nil
+	}
+	if err := db.SetTimezone(ctx, userID, timezone); err != nil {
+		return "", err
+	}
+
+	loc, _ := time.LoadLocation(timezone) // Already validated above.
+	return fmt.Sprintf("🕒 Timezone set to **%s**. It's %s there now; quiet hours and times I show you will use it.", timezone, now.In(loc).Format("3:04 PM")), nil
+}
@@ -0,0 +1,73 @@
+package discord
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/testutils"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRunTimezoneCommand(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 7, 1, 18, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		timezone string
+		setup    func(m *testutils.MockStore)
+		want     string
+	}{
+		{
+			name:     "Valid name is saved",
+			timezone: " America/Vancouver ",
+			setup: func(m *testutils.MockStore) {
+				m.On("SetTimezone", mock.Anything, "user1", "America/Vancouver").Return(nil)
+			},
+			want: "It's 11:30 AM there now",
+		},
+		{
+			name:     "UTC is accepted",
+			timezone: "UTC",
+			setup: func(m *testutils.MockStore) {
+				m.On("SetTimezone", mock.Anything, "user1", "UTC").Return(nil)
+			},
+			want: "**UTC**",
+		},
+		{
+			name:     "Unknown name is rejected with examples",
+			timezone: "Eastern",
+			want:     "`Eastern` isn't a timezone I know. Use an IANA name like `America/Toronto`",
+		},
+		{
+			name:     "Blank name is rejected",
+			timezone: "  ",
+			want:     "Please provide a timezone",
+		},
+		{
+			name:     "Server-local zone is rejected",
+			timezone: "Local",
+			want:     "Please provide a timezone",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := new(testutils.MockStore)
+			if tt.setup != nil {
+				tt.setup(mockDB)
+			}
+
+			got, err := runTimezoneCommand(ctx, mockDB, "user1", tt.timezone, now)
+			if err != nil {
+				t.Fatalf("runTimezoneCommand failed: %v", err)
+			}
+			if !strings.Contains(got, tt.want) {
+				t.Errorf("expected response to contain %q, got %q", tt.want, got)
+			}
+			mockDB.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,101 @@
+// Package hardware holds deterministic knowledge about PC hardware naming, so alert matching doesn't
+// depend on the AI wizard guessing every way a seller might spell a model.
+package hardware
+
+import (
+	"slices"
+	"strings"
+)
+
+// modelAliases lists, per model, the spellings sellers commonly use for it. The first entry is the
+// canonical name. Every spelling must be lowercase; add a line here to teach the bot a new model.
+//
+// The matcher works on word boundaries, so only spellings a plain "3080" would miss (glued prefixes
+// like "rtx3080", or "fe" suffixes) are worth listing.
+var modelAliases = [][]string{
+	// NVIDIA GPUs
+	{"3060", "rtx 3060", "rtx3060"},
+	{"3060 ti", "3060ti", "rtx 3060 ti", "rtx3060ti"},
+	{"3070", "rtx 3070", "rtx3070", "3070fe"},
+	{"3070 ti", "3070ti", "rtx 3070 ti", "rtx3070ti"},
+	{"3080", "rtx 3080", "rtx3080", "3080fe"},
+	{"3080 ti", "3080ti", "rtx 3080 ti", "rtx3080ti"},
+	{"3090", "rtx 3090", "rtx3090", "3090fe"},
+	{"4060", "rtx 4060", "rtx4060"},
+	{"4070", "rtx 4070", "rtx4070", "4070fe"},
+	{"4070 super", "4070s", "rtx 4070 super", "rtx4070super"},
+	{"4070 ti", "4070ti", "rtx 4070 ti", "rtx4070ti"},
+	{"4080", "rtx 4080", "rtx4080", "4080fe"},
+	{"4080 super", "4080s", "rtx 4080 super", "rtx4080super"},
+	{"4090", "rtx 4090", "rtx4090", "4090fe"},
+	{"5080", "rtx 5080", "rtx5080", "5080fe"},
+	{"5090", "rtx 5090", "rtx5090", "5090fe"},
+
+	// AMD GPUs
+	{"6700 xt", "6700xt", "rx 6700 xt", "rx6700xt"},
+	{"6800 xt", "6800xt", "rx 6800 xt", "rx6800xt"},
+	{"6900 xt", "6900xt", "rx 6900 xt", "rx6900xt"},
+	{"7800 xt", "7800xt", "rx 7800 xt", "rx7800xt"},
+	{"7900 xt", "7900xt", "rx 7900 xt", "rx7900xt"},
+	{"7900 xtx", "7900xtx", "rx 7900 xtx", "rx7900xtx"},
+
+	// AMD CPUs
+	{"5600x", "5600 x", "r5 5600x", "r55600x"},
+	{"5800x", "5800 x", "r7 5800x", "r75800x"},
+	{"5800x3d", "5800x 3d", "5800 x3d", "r7 5800x3d"},
+	{"5900x", "5900 x", "r9 5900x", "r95900x"},
+	{"7800x3d", "7800x 3d", "7800 x3d", "r7 7800x3d"},
+	{"9800x3d", "9800x 3d", "9800 x3d", "r7 9800x3d"},
+
+	// Intel CPUs
+	{"12600k", "12600 k", "i5 12600k", "i512600k"},
+	{"12700k", "12700 k", "i7 12700k", "i712700k"},
+	{"13600k", "13600 k", "i5 13600k", "i513600k"},
+	{"13700k", "13700 k", "i7 13700k", "i713700k"},
+	{"14700k", "14700 k", "i7 14700k", "i714700k"},
+}
+
+// aliasIndex maps every known spelling to its model's entry in modelAliases.
+var aliasIndex = buildAliasIndex(modelAliases)
+
+func buildAliasIndex(models [][]string) map[string][]string {
+	index := make(map[string][]string)
+	for _, spellings := range models {
+		for _, s := range spellings {
+			index[s] = spellings
+		}
+	}
+	return index
+}
+
+// Aliases returns every known spelling of the model term names, canonical name first, or nil if
+// term isn't a recognized model.
+func Aliases(term string) []string {
+	return aliasIndex[strings.ToLower(strings.TrimSpace(term))]
+}
+
+// ExpandAliases returns terms followed by the missing spellings of every recognized model among them,
+// stopping once the list holds max terms. Terms are expected to be normalized (trimmed and lowercase).
+func ExpandAliases(terms []string, max int) []string {
+	if len(terms) == 0 {
+		return terms
+	}
+	seen := make(map[string]bool, len(terms))
+	for _, t := range terms {
+		seen[t] = true
+	}
+
+	out := slices.Clip(terms)
+	for _, t := range terms {
+		for _, alias := range aliasIndex[t] {
+			if len(out) >= max {
+				return out
+			}
+			if !seen[alias] {
+				seen[alias] = true
+				out = append(out, alias)
+			}
+		}
+	}
+	return out
+}
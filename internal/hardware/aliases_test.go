@@ -0,0 +1,70 @@
+package hardware
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpandAliases_GPU(t *testing.T) {
+	got := ExpandAliases([]string{"toronto", "3080"}, 20)
+	want := []string{"toronto", "3080", "rtx 3080", "rtx3080", "3080fe"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExpandAliases() = %v, want %v", got, want)
+	}
+
+	// Any spelling of a model expands to the rest, and the 3080 Ti is a different card.
+	got = ExpandAliases([]string{"rtx3080ti"}, 20)
+	want = []string{"rtx3080ti", "3080 ti", "3080ti", "rtx 3080 ti"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExpandAliases() = %v, want %v", got, want)
+	}
+}
+
+func TestExpandAliases_CPU(t *testing.T) {
+	got := ExpandAliases([]string{"5800x3d", "13700k"}, 20)
+	want := []string{"5800x3d", "13700k", "5800x 3d", "5800 x3d", "r7 5800x3d", "13700 k", "i7 13700k", "i713700k"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExpandAliases() = %v, want %v", got, want)
+	}
+}
+
+func TestExpandAliases_Limits(t *testing.T) {
+	if got := ExpandAliases([]string{"4090", "5090"}, 4); len(got) != 4 {
+		t.Errorf("expected expansion to stop at the limit, got %v", got)
+	}
+	if got := ExpandAliases([]string{"keyboard"}, 20); !reflect.DeepEqual(got, []string{"keyboard"}) {
+		t.Errorf("expected unknown terms to be left alone, got %v", got)
+	}
+
+	terms := make([]string, 1, 10)
+	terms[0] = "4090"
+	ExpandAliases(terms, 20)
+	if terms[:cap(terms)][1] != "" {
+		t.Error("expected the caller's backing array to be left untouched")
+	}
+}
+
+func TestModelAliasesAreLowercaseAndUnique(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, spellings := range modelAliases {
+		for _, s := range spellings {
+			if s != strings.ToLower(strings.TrimSpace(s)) {
+				t.Errorf("alias %q must be trimmed and lowercase", s)
+			}
+			if seen[s] {
+				t.Errorf("alias %q is listed for more than one model", s)
+			}
+			seen[s] = true
+		}
+	}
+}
+
+func TestAliases(t *testing.T) {
+	if got := Aliases(" RTX3080 "); len(got) == 0 || got[0] != "3080" {
+		t.Errorf("expected the 3080 spellings with the canonical name first, got %v", got)
+	}
+	if got := Aliases("mouse"); got != nil {
+		t.Errorf("expected nil for an unknown model, got %v", got)
+	}
+}
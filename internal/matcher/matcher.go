@@ -0,0 +1,139 @@
+// Package matcher decides whether a post's text satisfies an alert's keyword lists, and can explain why not.
+package matcher
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+)
+
+// Matcher provides robust keyword matching with word boundary awareness.
+// It is safe for concurrent use.
+type Matcher struct {
+	mu       sync.RWMutex
+	patterns map[string]*regexp.Regexp
+}
+
+func New() *Matcher {
+	return &Matcher{
+		patterns: make(map[string]*regexp.Regexp),
+	}
+}
+
+// MatchResult is the outcome of matching one alert's keywords against a corpus. At most one of
+// FailedMustHave and FiredMustNot is set, since matching stops at the first failed check.
+type MatchResult struct {
+	Matched        bool
+	FailedMustHave string // The first MustHave term missing from the corpus
+	FiredMustNot   string // The first MustNot term found in the corpus
+	MatchedAnyOf   string // The AnyOf term that satisfied the alert; empty if it has no AnyOf terms
+}
+
+// Matches returns true if the corpus matches the criteria defined by mustHave, anyOf, and mustNot.
+func (m *Matcher) Matches(corpus string, mustHave, anyOf, mustNot []string) bool {
+	return m.MatchesWithReason(corpus, mustHave, anyOf, mustNot).Matched
+}
+
+// Explain is MatchesWithReason for rule's keyword lists. Only the keywords are considered; whether the
+// rule is paused, snoozed or skips bundles is up to the caller.
+func (m *Matcher) Explain(corpus string, rule store.AlertRule) MatchResult {
+	return m.MatchesWithReason(corpus, rule.MustHave, rule.AnyOf, rule.MustNot)
+}
+
+// MatchesWithReason matches like Matches and also reports which term decided the outcome. Checks run
+// MustNot, then MustHave, then AnyOf; a result with Matched false and no term set means no AnyOf term
+// was present.
+func (m *Matcher) MatchesWithReason(corpus string, mustHave, anyOf, mustNot []string) MatchResult {
+	corpus = strings.ToLower(corpus)
+
+	// 1. MustNot check (Fails if any are present)
+	for _, word := range mustNot {
+		if m.ContainsWord(corpus, word) {
+			return MatchResult{FiredMustNot: word}
+		}
+	}
+
+	// 2. MustHave check (Fails if any are missing)
+	for _, word := range mustHave {
+		if !m.ContainsWord(corpus, word) {
+			return MatchResult{FailedMustHave: word}
+		}
+	}
+
+	// 3. AnyOf check (Fails if none are present, but only if AnyOf is not empty)
+	if len(anyOf) > 0 {
+		for _, word := range anyOf {
+			if m.ContainsWord(corpus, word) {
+				return MatchResult{Matched: true, MatchedAnyOf: word}
+			}
+		}
+		return MatchResult{}
+	}
+
+	return MatchResult{Matched: true}
+}
+
+// ContainsWord checks if a word exists in the corpus with word boundary awareness.
+// Keywords are normalized when alerts are saved, so the cache is keyed by the word as given
+// and only a miss pays for trimming and lowercasing (which still covers legacy alerts).
+func (m *Matcher) ContainsWord(corpus, word string) bool {
+	m.mu.RLock()
+	re, ok := m.patterns[word]
+	m.mu.RUnlock()
+	if !ok {
+		re = m.compile(word)
+	}
+	return re != nil && re.MatchString(corpus)
+}
+
+// compile builds and caches the boundary-aware pattern for a word, under both the word as given and
+// its normalized form. Blank words cache a nil pattern, which never matches.
+func (m *Matcher) compile(word string) *regexp.Regexp {
+	key := word
+	word = strings.ToLower(strings.TrimSpace(word))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if word == "" {
+		m.patterns[key] = nil
+		return nil
+	}
+
+	// Cache the regex for performance
+	re, ok := m.patterns[word]
+	if !ok {
+		// Use a more flexible boundary check than \b to handle special characters (like $)
+		// \b only works if one character is word and the other is non-word.
+		// For $500, \b before $ fails if preceded by a space.
+
+		// We use a custom boundary that considers start/end of string or any whitespace/punctuation.
+		// However, Go's regexp doesn't support lookaround.
+		// A common trick is to use \b if the word starts/ends with a word character,
+		// and something else if it doesn't.
+
+		isWordStart := regexp.MustCompile(`^[a-zA-Z0-9]`).MatchString(word)
+		isWordEnd := regexp.MustCompile(`[a-zA-Z0-9]$`).MatchString(word)
+
+		pattern := regexp.QuoteMeta(word)
+		if isWordStart {
+			pattern = `\b` + pattern
+		} else {
+			// If it starts with a special character, we want it preceded by start of string or whitespace/non-word
+			pattern = `(?:^|[^\w])` + pattern
+		}
+
+		if isWordEnd {
+			pattern = pattern + `\b`
+		} else {
+			pattern = pattern + `(?:$|[^\w])`
+		}
+
+		re = regexp.MustCompile(`(?i)` + pattern)
+		m.patterns[word] = re
+	}
+	m.patterns[key] = re
+	return re
+}
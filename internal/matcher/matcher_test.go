@@ -0,0 +1,221 @@
+package matcher
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+)
+
+func TestMatcher(t *testing.T) {
+	m := New()
+	corpus := "Selling my RTX 3080ti for $500 in Toronto. BNIB."
+
+	tests := []struct {
+		name     string
+		mustHave []string
+		anyOf    []string
+		mustNot  []string
+		want     bool
+	}{
+		{
+			name:     "Direct Match",
+			mustHave: []string{"3080ti"},
+			want:     true,
+		},
+		{
+			name:     "Case Insensitive",
+			mustHave: []string{"rtx"},
+			want:     true,
+		},
+		{
+			name:     "Word Boundary - Prevent Partial Match",
+			mustHave: []string{"3080"},
+			want:     false, // "3080" should not match "3080ti"
+		},
+		{
+			name:    "MustNot Match",
+			mustNot: []string{"3080ti"},
+			want:    false,
+		},
+		{
+			name:  "AnyOf Match",
+			anyOf: []string{"toronto", "vancouver"},
+			want:  true,
+		},
+		{
+			name:     "MustHave and AnyOf Match",
+			mustHave: []string{"bnib"},
+			anyOf:    []string{"3080ti"},
+			want:     true,
+		},
+		{
+			name:     "Multiple MustHave - All required",
+			mustHave: []string{"3080ti", "toronto"},
+			want:     true,
+		},
+		{
+			name:     "Multiple MustHave - One missing",
+			mustHave: []string{"3080ti", "vancouver"},
+			want:     false,
+		},
+		{
+			name:     "Special Characters in Corpus",
+			mustHave: []string{"$500"},
+			want:     true,
+		},
+		{
+			name:  "Partial word match in AnyOf",
+			anyOf: []string{"3080"},
+			want:  false, // should not match 3080ti
+		},
+		{
+			name:     "MustNot takes precedence",
+			mustHave: []string{"3080ti"},
+			mustNot:  []string{"bnib"},
+			want:     false,
+		},
+		{
+			name:     "Legacy unnormalized keyword",
+			mustHave: []string{"  RTX 3080TI "},
+			want:     true,
+		},
+		{
+			name:  "Blank keyword never matches",
+			anyOf: []string{"  "},
+			want:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.Matches(corpus, tt.mustHave, tt.anyOf, tt.mustNot); got != tt.want {
+				t.Errorf("Matcher.Matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatcher_MatchesWithReason(t *testing.T) {
+	m := New()
+	corpus := "Selling my RTX 3080ti for $500 in Toronto. BNIB."
+
+	tests := []struct {
+		name string
+		rule store.AlertRule
+		want MatchResult
+	}{
+		{
+			name: "Matched",
+			rule: store.AlertRule{MustHave: []string{"3080ti"}, AnyOf: []string{"toronto", "gta"}, MustNot: []string{"broken"}},
+			want: MatchResult{Matched: true, MatchedAnyOf: "toronto"},
+		},
+		{
+			name: "Matched without AnyOf",
+			rule: store.AlertRule{MustHave: []string{"3080ti", "$500"}},
+			want: MatchResult{Matched: true},
+		},
+		{
+			name: "Later AnyOf term reported",
+			rule: store.AlertRule{AnyOf: []string{"ottawa", "bnib", "toronto"}},
+			want: MatchResult{Matched: true, MatchedAnyOf: "bnib"},
+		},
+		{
+			name: "MustNot fired",
+			rule: store.AlertRule{MustHave: []string{"3080ti"}, MustNot: []string{"broken", "bnib"}},
+			want: MatchResult{FiredMustNot: "bnib"},
+		},
+		{
+			name: "MustHave missing",
+			rule: store.AlertRule{MustHave: []string{"3080ti", "vancouver"}},
+			want: MatchResult{FailedMustHave: "vancouver"},
+		},
+		{
+			name: "No AnyOf term present",
+			rule: store.AlertRule{MustHave: []string{"3080ti"}, AnyOf: []string{"calgary", "ottawa"}},
+			want: MatchResult{},
+		},
+		{
+			name: "MustNot is checked before MustHave",
+			rule: store.AlertRule{MustHave: []string{"vancouver"}, MustNot: []string{"toronto"}},
+			want: MatchResult{FiredMustNot: "toronto"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := m.MatchesWithReason(corpus, tt.rule.MustHave, tt.rule.AnyOf, tt.rule.MustNot)
+			if got != tt.want {
+				t.Errorf("MatchesWithReason() = %+v, want %+v", got, tt.want)
+			}
+			if explained := m.Explain(corpus, tt.rule); explained != got {
+				t.Errorf("Explain() = %+v, want %+v", explained, got)
+			}
+			if matched := m.Matches(corpus, tt.rule.MustHave, tt.rule.AnyOf, tt.rule.MustNot); matched != got.Matched {
+				t.Errorf("Matches() = %v, but MatchesWithReason() reported %+v", matched, got)
+			}
+		})
+	}
+}
+
+// TestMatcher_Concurrent shares one Matcher across goroutines that keep missing the cache; run with -race.
+func TestMatcher_Concurrent(t *testing.T) {
+	m := New()
+	corpus := "Selling my RTX 3080ti for $500 in Toronto. BNIB."
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				miss := fmt.Sprintf("model%d", (g*200+i)%50)
+				if !m.Matches(corpus, []string{"3080ti", " Toronto "}, nil, []string{miss}) {
+					t.Errorf("goroutine %d: expected a match", g)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+// benchCorpus is a typical cleaned post: title, description and location joined as in processNewPost.
+const benchCorpus = "[H] EVGA RTX 3080 FTW3 Ultra 10GB, Ryzen 7 5800X [W] $650 PayPal, local cash " +
+	"Selling my RTX 3080 after upgrading. Card is in great shape, never mined on, repasted in March. " +
+	"Comes with original box and anti-sag bracket. 5800X is boxed with the stock cooler. Toronto, ON"
+
+var benchKeywords = struct {
+	mustHave, anyOf, mustNot []string
+}{
+	mustHave: []string{"rtx 3080", "$650"},
+	anyOf:    []string{"toronto", "mississauga", "ottawa", "gta"},
+	mustNot:  []string{"mining rig", "broken", "for parts", "lhr"},
+}
+
+func BenchmarkMatcher_Matches(b *testing.B) {
+	m := New()
+	b.ReportAllocs()
+	for b.Loop() {
+		m.Matches(benchCorpus, benchKeywords.mustHave, benchKeywords.anyOf, benchKeywords.mustNot)
+	}
+}
+
+// BenchmarkMatcher_PatternCache compares a warm regex cache against compiling every pattern from scratch.
+func BenchmarkMatcher_PatternCache(b *testing.B) {
+	b.Run("Warm", func(b *testing.B) {
+		m := New()
+		m.Matches(benchCorpus, benchKeywords.mustHave, benchKeywords.anyOf, benchKeywords.mustNot)
+		b.ReportAllocs()
+		for b.Loop() {
+			m.Matches(benchCorpus, benchKeywords.mustHave, benchKeywords.anyOf, benchKeywords.mustNot)
+		}
+	})
+	b.Run("Cold", func(b *testing.B) {
+		b.ReportAllocs()
+		for b.Loop() {
+			New().Matches(benchCorpus, benchKeywords.mustHave, benchKeywords.anyOf, benchKeywords.mustNot)
+		}
+	})
+}
@@ -2,6 +2,9 @@ package processor
 
 import (
 	"fmt"
+	"math"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/bwmarrin/discordgo"
@@ -19,11 +22,15 @@ func NewDealBuilder() *DealBuilder {
 
 // BuildDealEmbed crafts a rich Discord embed for a Reddit post and its AI-cleaned metadata.
 func (b *DealBuilder) BuildDealEmbed(post reddit.Post, cleaned *ai.CleanedPost) *discordgo.MessageEmbed {
+	title := classifyHardware(cleaned.Title) + " " + cleaned.Title
+	if post.HasVideo() {
+		title += " [Video]"
+	}
 	embed := &discordgo.MessageEmbed{
-		Title:       "📦 " + cleaned.Title,
+		Title:       title,
 		URL:         post.URL,
-		Description: cleaned.Description,
-		Color:       b.getColor(post.Score, post.NumComments),
+		Description: dealDescription(cleaned),
+		Color:       b.getColor(post.Score, post.NumComments, time.Since(time.Unix(int64(post.CreatedUtc), 0))),
 		Fields:      []*discordgo.MessageEmbedField{},
 		Footer: &discordgo.MessageEmbedFooter{
 			Text: fmt.Sprintf("r/CanadianHardwareSwap • 👍 %d | 💬 %d", post.Score, post.NumComments),
@@ -53,45 +60,144 @@ func (b *DealBuilder) BuildDealEmbed(post reddit.Post, cleaned *ai.CleanedPost)
 		})
 	}
 
-	if post.Thumbnail != "" && post.Thumbnail != "self" && post.Thumbnail != "default" {
-		embed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: post.Thumbnail}
+	if thumbnail := dealThumbnail(post); thumbnail != "" {
+		embed.Thumbnail = &discordgo.MessageEmbedThumbnail{URL: thumbnail}
 	}
 
 	return embed
 }
 
-// BuildDealButtons creates the action buttons (e.g., Open in Reddit, Mute) for a deal message.
-func (b *DealBuilder) BuildDealButtons(url string) []discordgo.MessageComponent {
-	return []discordgo.MessageComponent{
-		discordgo.ActionsRow{
-			Components: []discordgo.MessageComponent{
-				discordgo.Button{
-					Emoji: &discordgo.ComponentEmoji{
-						Name: "🌐",
-					},
-					Label: "Open in Reddit",
-					Style: discordgo.LinkButton,
-					URL:   url,
-				},
-				discordgo.Button{
-					Emoji: &discordgo.ComponentEmoji{
-						Name: "🔇",
-					},
-					Label:    "Mute Item",
-					Style:    discordgo.SecondaryButton,
-					CustomID: "mute_item",
-				},
+// dealThumbnail picks the image shown beside a deal: the first gallery photo, since gallery posts
+// rarely get a real thumbnail, or else Reddit's thumbnail. Reddit puts placeholders like "self",
+// "default" and "nsfw" in the thumbnail field, so only actual URLs are used. NSFW galleries get no
+// photo, matching the placeholder Reddit gives them.
+func dealThumbnail(post reddit.Post) string {
+	if !post.Over18 {
+		if image := post.FirstGalleryImage(); image != "" {
+			return image
+		}
+	}
+	if strings.HasPrefix(post.Thumbnail, "https://") || strings.HasPrefix(post.Thumbnail, "http://") {
+		return post.Thumbnail
+	}
+	return ""
+}
+
+// rawPreviewLen is how many characters of the raw Reddit post WithRawPreview shows.
+const rawPreviewLen = 200
+
+// WithRawPreview returns a copy of a deal embed with the start of the raw Reddit post added as a
+// spoilered field, so readers can check details the cleaned description dropped. The embed is returned
+// as is when the post has no usable body.
+func (b *DealBuilder) WithRawPreview(embed *discordgo.MessageEmbed, post reddit.Post) *discordgo.MessageEmbed {
+	preview := strings.Join(strings.Fields(post.SelfText), " ")
+	if preview == "" || preview == "[removed]" || preview == "[deleted]" {
+		return embed
+	}
+	if r := []rune(preview); len(r) > rawPreviewLen {
+		preview = strings.TrimSpace(string(r[:rawPreviewLen])) + "…"
+	}
+
+	withPreview := *embed
+	withPreview.Fields = append(slices.Clone(embed.Fields), &discordgo.MessageEmbedField{
+		Name:  "📝 Original Post",
+		Value: "||" + escapeMarkdown(preview) + "||",
+	})
+	return &withPreview
+}
+
+// dealDescription puts the one-line summary, bolded, above the longer description so the feed can be
+// scanned at a glance. Posts cleaned before summaries existed just show their description.
+func dealDescription(cleaned *ai.CleanedPost) string {
+	summary := strings.Join(strings.Fields(cleaned.Summary), " ")
+	if summary == "" {
+		return cleaned.Description
+	}
+	line := "**" + escapeMarkdown(summary) + "**"
+	if cleaned.Description == "" {
+		return line
+	}
+	return line + "\n" + cleaned.Description
+}
+
+// markdownEscaper backslash-escapes the characters Discord treats as formatting, so model output can't
+// break out of the bold summary line or inject links and headings.
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, `*`, `\*`, `_`, `\_`, `~`, `\~`, "`", "\\`", `|`, `\|`,
+	`>`, `\>`, `#`, `\#`, `[`, `\[`, `]`, `\]`,
+)
+
+// escapeMarkdown makes s render literally in a Discord message.
+func escapeMarkdown(s string) string {
+	return markdownEscaper.Replace(s)
+}
+
+// redditMessageURL opens Reddit's private message form addressed to the username appended to it.
+const redditMessageURL = "https://www.reddit.com/message/compose/?to="
+
+// BuildDealButtons creates the action buttons (e.g., Open in Reddit, Mute, Bad Summary) for a deal message.
+// The Message Seller and Mute Seller buttons are left out when the post's author is unknown or deleted,
+// since there is no one to contact or mute.
+func (b *DealBuilder) BuildDealButtons(redditID, url, author string) []discordgo.MessageComponent {
+	buttons := []discordgo.MessageComponent{
+		discordgo.Button{
+			Emoji: &discordgo.ComponentEmoji{
+				Name: "🌐",
 			},
+			Label: "Open in Reddit",
+			Style: discordgo.LinkButton,
+			URL:   url,
 		},
+		discordgo.Button{
+			Emoji: &discordgo.ComponentEmoji{
+				Name: "🔇",
+			},
+			Label:    "Mute Item",
+			Style:    discordgo.SecondaryButton,
+			CustomID: "mute_item",
+		},
+		discordgo.Button{
+			Emoji: &discordgo.ComponentEmoji{
+				Name: "⚠️",
+			},
+			Label:    "Bad Summary",
+			Style:    discordgo.SecondaryButton,
+			CustomID: "flag_parse|" + redditID,
+		},
+	}
+	if knownAuthor(author) {
+		buttons = append(buttons, discordgo.Button{
+			Emoji: &discordgo.ComponentEmoji{
+				Name: "✉️",
+			},
+			Label: "Message Seller",
+			Style: discordgo.LinkButton,
+			URL:   redditMessageURL + author,
+		}, discordgo.Button{
+			Emoji: &discordgo.ComponentEmoji{
+				Name: "🚫",
+			},
+			Label:    "Mute Seller",
+			Style:    discordgo.SecondaryButton,
+			CustomID: "mute_author|" + author,
+		})
+	}
+	return []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: buttons},
 	}
 }
 
-// BuildClosedEmbed creates a greyed-out version of an embed for sold/closed listings.
-func (b *DealBuilder) BuildClosedEmbed(originalTitle, url, status string) *discordgo.MessageEmbed {
+// BuildClosedEmbed creates a greyed-out version of an embed for sold/closed listings. price is the final
+// price from the closed post, or empty if it doesn't state one.
+func (b *DealBuilder) BuildClosedEmbed(originalTitle, url, status, price string) *discordgo.MessageEmbed {
+	desc := fmt.Sprintf("This deal has been marked as **%s** on Reddit.", status)
+	if price != "" {
+		desc += fmt.Sprintf("\nFinal: **%s**", price)
+	}
 	return &discordgo.MessageEmbed{
 		Title:       "~~" + originalTitle + "~~",
 		URL:         url,
-		Description: fmt.Sprintf("This deal has been marked as **%s** on Reddit.", status),
+		Description: desc,
 		Color:       0x2C2F33, // Discord Darker Grey
 		Footer: &discordgo.MessageEmbedFooter{
 			Text: "Deal Closed",
@@ -99,9 +205,31 @@ func (b *DealBuilder) BuildClosedEmbed(originalTitle, url, status string) *disco
 	}
 }
 
-// getColor returns a Discord hex color based on engagement heuristics.
-func (b *DealBuilder) getColor(score, comments int) int {
-	interactions := score + comments
+// BuildArchiveEntry renders the one-line archive channel entry for a sold deal. price is the sale price
+// parsed from the post, or empty when the seller didn't give one.
+func (b *DealBuilder) BuildArchiveEntry(title, url, price string) string {
+	sold := "Sold"
+	if price != "" {
+		sold = "Sold for " + price
+	}
+	// Angle brackets keep Discord from unfurling a preview for every archived deal.
+	return fmt.Sprintf("🏷️ **%s**: %s <%s>", sold, title, url)
+}
+
+// Fresh posts have had little time to collect votes, so their engagement is boosted by up to
+// 1+colorFreshBoost, with the boost halving every colorBoostHalfLife; after a few hours only raw
+// engagement counts. The boost stays under 3x so a brand-new post's automatic self-upvote alone is grey.
+const (
+	colorFreshBoost    = 1.5
+	colorBoostHalfLife = time.Hour
+)
+
+// getColor returns a Discord hex color based on engagement heuristics. age is how long ago the post
+// was made, so the same engagement reads hotter on a post a few minutes old than on one from hours ago.
+func (b *DealBuilder) getColor(score, comments int, age time.Duration) int {
+	age = max(age, 0) // Reddit and local clocks can disagree by a few seconds.
+	boost := 1 + colorFreshBoost*math.Exp2(-age.Hours()/colorBoostHalfLife.Hours())
+	interactions := float64(score+comments) * boost
 	switch {
 	case interactions >= 16:
 		return 0xFF0000 // Lava Red
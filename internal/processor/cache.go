@@ -8,10 +8,11 @@ import (
 	"github.com/pauljones0/betterHardwareSwap/internal/store"
 )
 
-// ConfigCache provides an in-memory TTL cache for server configurations.
+// ConfigCache provides an in-memory TTL cache for server configurations and user settings.
 type ConfigCache struct {
 	mu     sync.RWMutex
 	items  map[string]cacheItem
+	users  map[string]userCacheItem
 	ttl    time.Duration
 	storer Storer
 }
@@ -21,9 +22,15 @@ type cacheItem struct {
 	expiresAt time.Time
 }
 
+type userCacheItem struct {
+	settings  *store.UserSettings
+	expiresAt time.Time
+}
+
 func NewConfigCache(storer Storer, ttl time.Duration) *ConfigCache {
 	return &ConfigCache{
 		items:  make(map[string]cacheItem),
+		users:  make(map[string]userCacheItem),
 		ttl:    ttl,
 		storer: storer,
 	}
@@ -53,3 +60,27 @@ func (c *ConfigCache) GetServerConfig(ctx context.Context, serverID string) (*st
 
 	return cfg, nil
 }
+
+func (c *ConfigCache) GetUserSettings(ctx context.Context, userID string) (*store.UserSettings, error) {
+	c.mu.RLock()
+	item, ok := c.users[userID]
+	c.mu.RUnlock()
+
+	if ok && time.Now().Before(item.expiresAt) {
+		return item.settings, nil
+	}
+
+	settings, err := c.storer.GetUserSettings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.users[userID] = userCacheItem{
+		settings:  settings,
+		expiresAt: time.Now().Add(c.ttl),
+	}
+	c.mu.Unlock()
+
+	return settings, nil
+}
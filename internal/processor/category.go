@@ -0,0 +1,68 @@
+package processor
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// defaultCategoryEmoji prefixes deals whose hardware type couldn't be told from the title.
+const defaultCategoryEmoji = "📦"
+
+// categoryKeywords maps lowercase title words to the emoji of the hardware category they signal.
+var categoryKeywords = map[string]string{
+	// GPUs
+	"gpu": "🎮", "rtx": "🎮", "gtx": "🎮", "rx": "🎮", "radeon": "🎮", "geforce": "🎮", "graphics": "🎮",
+	// CPUs
+	"cpu": "🧠", "processor": "🧠", "ryzen": "🧠", "threadripper": "🧠", "xeon": "🧠",
+	"i3": "🧠", "i5": "🧠", "i7": "🧠", "i9": "🧠", "r5": "🧠", "r7": "🧠", "r9": "🧠",
+	// Memory
+	"ram": "🐏", "ddr3": "🐏", "ddr4": "🐏", "ddr5": "🐏", "dimm": "🐏", "sodimm": "🐏",
+	// Monitors
+	"monitor": "🖥️", "monitors": "🖥️", "display": "🖥️",
+	// Storage
+	"ssd": "💾", "hdd": "💾", "nvme": "💾",
+	// Motherboards
+	"motherboard": "🧩", "mobo": "🧩",
+	// Power supplies
+	"psu": "🔌",
+	// Cooling
+	"cooler": "❄️", "aio": "❄️",
+	// Peripherals
+	"keyboard": "⌨️", "mouse": "🖱️", "headset": "🎧", "headphones": "🎧",
+	// Laptops
+	"laptop": "💻", "notebook": "💻", "macbook": "💻",
+}
+
+// categoryPatterns catch bare model numbers, which sellers often list without the brand word.
+var categoryPatterns = []struct {
+	re    *regexp.Regexp
+	emoji string
+}{
+	{regexp.MustCompile(`^(rtx|gtx|rx)?[1-5]0[5-9]0(ti|s|super|fe)?$`), "🎮"}, // 3080, 4070ti, rtx4090
+	{regexp.MustCompile(`^(rx)?[5-9][5-9]00(xt|xtx)$`), "🎮"},                 // 6800xt, 7900xtx
+	{regexp.MustCompile(`^([ir][3579])?\d{4,5}(x|x3d|k|kf|ks|f)$`), "🧠"},     // 5800x3d, 13700k
+	{regexp.MustCompile(`^[abhxz][3-9][1-9]0[em]?$`), "🧩"},                   // b550, x670e, z790
+	{regexp.MustCompile(`^\d{3,4}w$`), "🔌"},                                  // 850w
+	{regexp.MustCompile(`^\d{2,3}hz$`), "🖥️"},                                // 144hz
+}
+
+// classifyHardware returns the emoji for the hardware category a cleaned deal title is about, or
+// defaultCategoryEmoji when nothing in it is recognised. Bundles take the category of the first
+// item the seller listed.
+func classifyHardware(title string) string {
+	words := strings.FieldsFunc(strings.ToLower(title), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		if emoji, ok := categoryKeywords[word]; ok {
+			return emoji
+		}
+		for _, p := range categoryPatterns {
+			if p.re.MatchString(word) {
+				return p.emoji
+			}
+		}
+	}
+	return defaultCategoryEmoji
+}
@@ -0,0 +1,37 @@
+package processor
+
+import "testing"
+
+func TestClassifyHardware(t *testing.T) {
+	tests := []struct {
+		title string
+		want  string
+	}{
+		{"[WTS] RTX 3080 FE", "🎮"},
+		{"EVGA 3080ti FTW3", "🎮"},
+		{"Sapphire 7900XTX Nitro+", "🎮"},
+		{"Ryzen 7 5800X3D", "🧠"},
+		{"i7-13700K tray", "🧠"},
+		{"Corsair Vengeance DDR5 32GB", "🐏"},
+		{"LG 27GP850 27\" 165Hz", "🖥️"},
+		{"Dell S2721DGF monitor", "🖥️"},
+		{"Samsung 990 Pro NVMe 2TB", "💾"},
+		{"MSI B550 Tomahawk", "🧩"},
+		{"Corsair RM850x PSU", "🔌"},
+		{"Seasonic Focus 750W", "🔌"},
+		{"Arctic Liquid Freezer II AIO", "❄️"},
+		{"Keychron Q1 keyboard", "⌨️"},
+		{"Logitech G Pro X Superlight mouse", "🖱️"},
+		{"ThinkPad X1 Carbon laptop", "💻"},
+		// Bundles take the category of the first item listed.
+		{"5800X3D + B550 + 32GB RAM bundle", "🧠"},
+		{"Assorted cables and stuff", defaultCategoryEmoji},
+		{"", defaultCategoryEmoji},
+	}
+
+	for _, tt := range tests {
+		if got := classifyHardware(tt.title); got != tt.want {
+			t.Errorf("classifyHardware(%q) = %s, want %s", tt.title, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package processor
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/ai"
+	"github.com/pauljones0/betterHardwareSwap/internal/logger"
+)
+
+// globalCleanCache outlives a single pipeline run so overlapping runs on a warm instance share results.
+var globalCleanCache = NewCleanCache(256, 30*time.Minute)
+
+// CleanCache is a bounded LRU of Gemini CleanRedditPost results keyed by a hash of the raw post content,
+// so a post that reappears before its record is saved isn't paid for twice.
+type CleanCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	order    *list.List // Front is most recently used
+	items    map[string]*list.Element
+}
+
+type cleanEntry struct {
+	key       string
+	cleaned   *ai.CleanedPost
+	expiresAt time.Time
+}
+
+func NewCleanCache(capacity int, ttl time.Duration) *CleanCache {
+	return &CleanCache{
+		capacity: capacity,
+		ttl:      ttl,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// cleanCacheKey hashes the raw title, body and cleaning prompt; the separators keep ("ab", "c") and ("a", "bc") distinct.
+func cleanCacheKey(rawTitle, rawBody, prompt string) string {
+	sum := sha256.Sum256([]byte(rawTitle + "\x00" + rawBody + "\x00" + prompt))
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns a cached result for the content, if present and not expired.
+func (c *CleanCache) Get(rawTitle, rawBody, prompt string) (*ai.CleanedPost, bool) {
+	key := cleanCacheKey(rawTitle, rawBody, prompt)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*cleanEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.order.Remove(el)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return entry.cleaned, true
+}
+
+// Put stores a result, evicting the least recently used entry when full.
+func (c *CleanCache) Put(rawTitle, rawBody, prompt string, cleaned *ai.CleanedPost) {
+	key := cleanCacheKey(rawTitle, rawBody, prompt)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*cleanEntry)
+		entry.cleaned = cleaned
+		entry.expiresAt = time.Now().Add(c.ttl)
+		c.order.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&cleanEntry{key: key, cleaned: cleaned, expiresAt: time.Now().Add(c.ttl)})
+	if c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cleanEntry).key)
+	}
+}
+
+// cachingAI wraps an AIService so identical posts are only cleaned once per cache window.
+type cachingAI struct {
+	AIService
+	cache *CleanCache
+}
+
+func (c *cachingAI) CleanRedditPost(ctx context.Context, rawTitle, rawBody, promptOverride string) (*ai.CleanedPost, error) {
+	if cleaned, ok := c.cache.Get(rawTitle, rawBody, promptOverride); ok {
+		logger.Debug(ctx, "Clean cache hit", "title", rawTitle)
+		return cleaned, nil
+	}
+
+	cleaned, err := c.AIService.CleanRedditPost(ctx, rawTitle, rawBody, promptOverride)
+	if err != nil {
+		return nil, err
+	}
+	c.cache.Put(rawTitle, rawBody, promptOverride, cleaned)
+	return cleaned, nil
+}
@@ -0,0 +1,57 @@
+package processor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/ai"
+	"github.com/pauljones0/betterHardwareSwap/internal/testutils"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCachingAI_SecondCleanHitsCache(t *testing.T) {
+	ctx := context.Background()
+	mockAI := new(testutils.MockAI)
+	mockAI.On("CleanRedditPost", mock.Anything, "[H] RTX 3080", "Desc", mock.Anything).Return(&ai.CleanedPost{Title: "RTX 3080"}, nil).Once()
+
+	svc := &cachingAI{AIService: mockAI, cache: NewCleanCache(10, time.Minute)}
+
+	for n := 0; n < 2; n++ {
+		cleaned, err := svc.CleanRedditPost(ctx, "[H] RTX 3080", "Desc", "")
+		if err != nil {
+			t.Fatalf("clean %d failed: %v", n+1, err)
+		}
+		if cleaned.Title != "RTX 3080" {
+			t.Errorf("clean %d returned %q", n+1, cleaned.Title)
+		}
+	}
+
+	mockAI.AssertNumberOfCalls(t, "CleanRedditPost", 1)
+}
+
+func TestCleanCache_BoundsAndTTL(t *testing.T) {
+	t.Run("Evicts least recently used", func(t *testing.T) {
+		c := NewCleanCache(2, time.Minute)
+		c.Put("a", "", "", &ai.CleanedPost{Title: "A"})
+		c.Put("b", "", "", &ai.CleanedPost{Title: "B"})
+		c.Get("a", "", "") // a is now more recent than b
+		c.Put("c", "", "", &ai.CleanedPost{Title: "C"})
+
+		if _, ok := c.Get("b", "", ""); ok {
+			t.Error("expected b to be evicted")
+		}
+		if _, ok := c.Get("a", "", ""); !ok {
+			t.Error("expected a to survive eviction")
+		}
+	})
+
+	t.Run("Expired entries miss", func(t *testing.T) {
+		c := NewCleanCache(2, -time.Second)
+		c.Put("a", "", "", &ai.CleanedPost{Title: "A"})
+
+		if _, ok := c.Get("a", "", ""); ok {
+			t.Error("expected an expired entry to miss")
+		}
+	})
+}
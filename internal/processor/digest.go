@@ -0,0 +1,115 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/logger"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+)
+
+const (
+	// digestBatchSize caps how many queued pings a single /cron/digest run delivers.
+	digestBatchSize = 100
+	// digestMaxLinks caps how many deal links one digest lists, keeping it under Discord's message limit.
+	digestMaxLinks = 15
+)
+
+// notifyUsers pings userIDs in a server's ping channel about the feed message at link. Users who are in
+// their quiet hours are left out of the ping and queued for the digest sent when their quiet hours end.
+func notifyUsers(ctx context.Context, db Storer, cache ConfigGetter, client DiscordMessenger, serverID, pingChannelID, link string, userIDs []string, headline string) {
+	now := time.Now()
+	var pingNow []string
+	for _, uid := range userIDs {
+		settings, err := cache.GetUserSettings(ctx, uid)
+		if err != nil {
+			// Not knowing someone's quiet hours shouldn't cost them the ping.
+			logger.Warn(ctx, "Failed to load user settings, pinging now", "user_id", uid, "error", err)
+			pingNow = append(pingNow, uid)
+			continue
+		}
+		until, quiet := settings.QuietUntil(now)
+		if !quiet {
+			pingNow = append(pingNow, uid)
+			continue
+		}
+		err = db.QueuePing(ctx, store.QueuedPing{
+			UserID:        uid,
+			ServerID:      serverID,
+			PingChannelID: pingChannelID,
+			MessageLink:   link,
+			DeliverAt:     until,
+		})
+		if err != nil {
+			logger.Error(ctx, "Failed to queue ping for quiet hours, pinging now", "user_id", uid, "error", err)
+			pingNow = append(pingNow, uid)
+		}
+	}
+
+	if len(pingNow) == 0 {
+		return
+	}
+	pingContent := ""
+	for _, uid := range pingNow {
+		pingContent += fmt.Sprintf("<@%s> ", uid)
+	}
+	pingContent += fmt.Sprintf("- %s <%s>", headline, link)
+	_ = client.SendMessage(pingChannelID, pingContent)
+}
+
+// DeliverDigests sends the pings held back during users' quiet hours once those hours are over, as
+// one message per user and ping channel. Pings that fail to send stay queued for the next run.
+func DeliverDigests(ctx context.Context, db Storer, client DiscordMessenger) error {
+	pings, err := db.GetDuePings(ctx, time.Now(), digestBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to load queued pings: %w", err)
+	}
+
+	type digestKey struct{ userID, channelID string }
+	var order []digestKey
+	digests := make(map[digestKey][]store.QueuedPing)
+	for _, p := range pings {
+		key := digestKey{p.UserID, p.PingChannelID}
+		if _, ok := digests[key]; !ok {
+			order = append(order, key)
+		}
+		digests[key] = append(digests[key], p)
+	}
+
+	var delivered []string
+	for _, key := range order {
+		if err := client.SendMessage(key.channelID, buildDigest(key.userID, digests[key])); err != nil {
+			logger.Warn(ctx, "Failed to send quiet hours digest", "user_id", key.userID, "channel_id", key.channelID, "error", err)
+			continue
+		}
+		for _, p := range digests[key] {
+			delivered = append(delivered, p.ID)
+		}
+	}
+
+	if err := db.DeletePings(ctx, delivered); err != nil {
+		return fmt.Errorf("failed to clear delivered pings: %w", err)
+	}
+	logger.Info(ctx, "Quiet hours digests delivered", "pending", len(pings), "delivered", len(delivered))
+	return nil
+}
+
+// buildDigest renders one user's held-back pings as a single message.
+func buildDigest(userID string, pings []store.QueuedPing) string {
+	noun := "deals"
+	if len(pings) == 1 {
+		noun = "deal"
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "<@%s> - **%d %s matched your alerts during your quiet hours:**", userID, len(pings), noun)
+	for i, p := range pings {
+		if i == digestMaxLinks {
+			fmt.Fprintf(&b, "\n*...and %d more.*", len(pings)-digestMaxLinks)
+			break
+		}
+		fmt.Fprintf(&b, "\n<%s>", p.MessageLink)
+	}
+	return b.String()
+}
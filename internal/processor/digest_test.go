@@ -0,0 +1,58 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+	"github.com/pauljones0/betterHardwareSwap/internal/testutils"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDeliverDigests(t *testing.T) {
+	ctx := context.Background()
+	pings := []store.QueuedPing{
+		{ID: "p1", UserID: "user1", PingChannelID: "ping1", MessageLink: "https://discord.com/channels/g1/f1/m1"},
+		{ID: "p2", UserID: "user2", PingChannelID: "ping1", MessageLink: "https://discord.com/channels/g1/f1/m2"},
+		{ID: "p3", UserID: "user1", PingChannelID: "ping1", MessageLink: "https://discord.com/channels/g1/f1/m3"},
+	}
+
+	mockDB := new(testutils.MockStore)
+	mockDiscord := new(testutils.MockDiscord)
+
+	mockDB.On("GetDuePings", mock.Anything, mock.Anything, digestBatchSize).Return(pings, nil)
+	mockDiscord.On("SendMessage", "ping1", mock.MatchedBy(func(content string) bool {
+		return strings.HasPrefix(content, "<@user1>") && strings.Contains(content, "2 deals") &&
+			strings.Contains(content, "/m1>") && strings.Contains(content, "/m3>")
+	})).Return(nil)
+	// user2's digest fails, so their ping stays queued for the next run.
+	mockDiscord.On("SendMessage", "ping1", mock.MatchedBy(func(content string) bool {
+		return strings.HasPrefix(content, "<@user2>")
+	})).Return(errors.New("discord down"))
+	mockDB.On("DeletePings", mock.Anything, []string{"p1", "p3"}).Return(nil)
+
+	if err := DeliverDigests(ctx, mockDB, mockDiscord); err != nil {
+		t.Fatalf("DeliverDigests failed: %v", err)
+	}
+
+	mockDB.AssertExpectations(t)
+	mockDiscord.AssertExpectations(t)
+}
+
+func TestBuildDigest_CapsLinks(t *testing.T) {
+	pings := make([]store.QueuedPing, digestMaxLinks+3)
+	for i := range pings {
+		pings[i].MessageLink = "https://discord.com/channels/g1/f1/m"
+	}
+
+	got := buildDigest("user1", pings)
+
+	if n := strings.Count(got, "<https://"); n != digestMaxLinks {
+		t.Errorf("expected %d links, got %d", digestMaxLinks, n)
+	}
+	if !strings.Contains(got, "...and 3 more.") {
+		t.Errorf("expected the overflow to be summarized, got %q", got)
+	}
+}
@@ -1,10 +1,15 @@
 package processor
 
 import (
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/bwmarrin/discordgo"
 	"github.com/pauljones0/betterHardwareSwap/internal/ai"
 	"github.com/pauljones0/betterHardwareSwap/internal/reddit"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+	"github.com/pauljones0/betterHardwareSwap/internal/testutils"
 )
 
 func TestBuildDealEmbed(t *testing.T) {
@@ -28,7 +33,7 @@ func TestBuildDealEmbed(t *testing.T) {
 				Location:    "Toronto",
 				Condition:   "Used",
 			},
-			wantTitle: "📦 RTX 3080",
+			wantTitle: "🎮 RTX 3080",
 		},
 		{
 			name: "Missing optional metadata",
@@ -39,7 +44,7 @@ func TestBuildDealEmbed(t *testing.T) {
 				Title:       "Mouse",
 				Description: "Any mouse will do",
 			},
-			wantTitle: "📦 Mouse",
+			wantTitle: "🖱️ Mouse",
 		},
 	}
 
@@ -70,3 +75,188 @@ func TestBuildDealEmbed(t *testing.T) {
 		})
 	}
 }
+
+func TestBuildDealEmbed_Summary(t *testing.T) {
+	builder := NewDealBuilder()
+
+	tests := []struct {
+		name    string
+		cleaned *ai.CleanedPost
+		want    string
+	}{
+		{
+			name:    "Summary is bolded above the description",
+			cleaned: &ai.CleanedPost{Title: "RTX 3080", Summary: "RTX 3080 FE, $500 in Toronto", Description: "Barely used."},
+			want:    "**RTX 3080 FE, $500 in Toronto**\nBarely used.",
+		},
+		{
+			name:    "Markdown in the summary is escaped",
+			cleaned: &ai.CleanedPost{Title: "RTX 3080", Summary: "**FREE** [link](https://x.io) _now_", Description: "Desc"},
+			want:    `**\*\*FREE\*\* \[link\](https://x.io) \_now\_**` + "\nDesc",
+		},
+		{
+			name:    "No summary keeps the plain description",
+			cleaned: &ai.CleanedPost{Title: "RTX 3080", Description: "Barely used."},
+			want:    "Barely used.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := builder.BuildDealEmbed(reddit.Post{}, tt.cleaned)
+			if got.Description != tt.want {
+				t.Errorf("expected description %q, got %q", tt.want, got.Description)
+			}
+		})
+	}
+}
+
+func TestBuildDealEmbed_Media(t *testing.T) {
+	tests := []struct {
+		fixture       string
+		wantTitle     string
+		wantURL       string
+		wantThumbnail string
+	}{
+		{
+			fixture:       "reddit_post_gallery.json",
+			wantTitle:     "🎮 RTX 4070 Super",
+			wantURL:       "https://www.reddit.com/gallery/24680",
+			wantThumbnail: "https://preview.redd.it/gpu01.jpg?width=4032&format=pjpg&auto=webp&s=abc123",
+		},
+		{
+			fixture:       "reddit_post_video.json",
+			wantTitle:     "🎮 RX 7900 XTX [Video]",
+			wantURL:       "https://v.redd.it/abc123xyz",
+			wantThumbnail: "https://b.thumbs.redditmedia.com/video_thumb.jpg",
+		},
+		{
+			// Reddit's "nsfw" thumbnail placeholder isn't a URL.
+			fixture:   "reddit_post_nsfw.json",
+			wantTitle: "🎮 RTX 3080 FE",
+			wantURL:   "https://reddit.com/r/hardwareswap/comments/67890",
+		},
+	}
+
+	b := NewDealBuilder()
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			var post reddit.Post
+			if err := testutils.LoadFixture(tt.fixture, &post); err != nil {
+				t.Fatalf("failed to load fixture: %v", err)
+			}
+			cleanedTitle := strings.TrimSuffix(strings.TrimPrefix(tt.wantTitle, "🎮 "), " [Video]")
+
+			embed := b.BuildDealEmbed(post, &ai.CleanedPost{Title: cleanedTitle})
+
+			if embed.Title != tt.wantTitle {
+				t.Errorf("title = %q, want %q", embed.Title, tt.wantTitle)
+			}
+			if embed.URL != tt.wantURL {
+				t.Errorf("url = %q, want %q", embed.URL, tt.wantURL)
+			}
+			gotThumbnail := ""
+			if embed.Thumbnail != nil {
+				gotThumbnail = embed.Thumbnail.URL
+			}
+			if gotThumbnail != tt.wantThumbnail {
+				t.Errorf("thumbnail = %q, want %q", gotThumbnail, tt.wantThumbnail)
+			}
+		})
+	}
+}
+
+func TestGetColor(t *testing.T) {
+	b := NewDealBuilder()
+	tests := []struct {
+		name            string
+		score, comments int
+		age             time.Duration
+		want            int
+	}{
+		{"Quiet old post", 1, 0, 24 * time.Hour, 0x808080},
+		{"Old post keeps raw thresholds", 3, 0, 24 * time.Hour, 0xFFFF00},
+		{"Busy old post", 10, 6, 24 * time.Hour, 0xFF0000},
+		{"Fresh post with the same engagement is hotter", 3, 0, 5 * time.Minute, 0xFFA500},
+		{"Engagement in minutes beats the same over hours", 3, 0, 5 * time.Hour, 0xFFFF00},
+		{"A lone vote stays grey", 1, 0, 0, 0x808080},
+		{"Future timestamps count as brand new", 2, 0, -time.Minute, 0xFFFF00},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := b.getColor(tt.score, tt.comments, tt.age); got != tt.want {
+				t.Errorf("getColor(%d, %d, %v) = %#x, want %#x", tt.score, tt.comments, tt.age, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildDealButtons_Author(t *testing.T) {
+	b := NewDealBuilder()
+
+	// labels returns the labels of the deal's buttons, plus the URL of the Message Seller button if any.
+	labels := func(components []discordgo.MessageComponent) (map[string]bool, string) {
+		got := make(map[string]bool)
+		contact := ""
+		for _, c := range components[0].(discordgo.ActionsRow).Components {
+			btn := c.(discordgo.Button)
+			got[btn.Label] = true
+			if btn.Label == "Message Seller" {
+				contact = btn.URL
+			}
+		}
+		return got, contact
+	}
+
+	got, contact := labels(b.BuildDealButtons("abc123", "https://reddit.com/abc123", "seller_1"))
+	if !got["Message Seller"] || !got["Mute Seller"] {
+		t.Errorf("expected seller buttons for a known author, got %v", got)
+	}
+	if contact != "https://www.reddit.com/message/compose/?to=seller_1" {
+		t.Errorf("unexpected contact link %q", contact)
+	}
+
+	for _, author := range []string{"[deleted]", ""} {
+		got, _ := labels(b.BuildDealButtons("abc123", "https://reddit.com/abc123", author))
+		if got["Message Seller"] || got["Mute Seller"] {
+			t.Errorf("expected no seller buttons for author %q, got %v", author, got)
+		}
+		if !got["Open in Reddit"] {
+			t.Errorf("expected the deal to still link to Reddit for author %q, got %v", author, got)
+		}
+	}
+}
+
+func TestServerDealEmbed_RawPreview(t *testing.T) {
+	post := reddit.Post{
+		ID:       "abc123",
+		SelfText: "Selling my **3080**.\n\nComes with the original box, " + strings.Repeat("and more ", 40),
+	}
+	embed := NewDealBuilder().BuildDealEmbed(post, &ai.CleanedPost{Title: "RTX 3080", Price: "$500"})
+
+	got := serverDealEmbed(&store.ServerConfig{ShowRawPreview: true}, embed, post)
+	if len(got.Fields) != len(embed.Fields)+1 {
+		t.Fatalf("expected the raw preview field to be added, got %+v", got.Fields)
+	}
+	field := got.Fields[len(got.Fields)-1]
+	if field.Name != "📝 Original Post" {
+		t.Errorf("unexpected preview field name %q", field.Name)
+	}
+	if !strings.HasPrefix(field.Value, "||Selling my \\*\\*3080\\*\\*. Comes with") || !strings.HasSuffix(field.Value, "…||") {
+		t.Errorf("expected a flattened, escaped, truncated and spoilered preview, got %q", field.Value)
+	}
+	if len(embed.Fields) != 1 {
+		t.Errorf("expected the shared embed to be left untouched, got %+v", embed.Fields)
+	}
+
+	if got := serverDealEmbed(&store.ServerConfig{}, embed, post); got != embed {
+		t.Error("expected servers without the setting to get the shared embed")
+	}
+	if got := serverDealEmbed(nil, embed, post); got != embed {
+		t.Error("expected DM scopes to get the shared embed")
+	}
+	if got := serverDealEmbed(&store.ServerConfig{ShowRawPreview: true}, embed, reddit.Post{SelfText: "[removed]"}); got != embed {
+		t.Error("expected removed posts to get no preview")
+	}
+}
@@ -0,0 +1,89 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/logger"
+	"github.com/pauljones0/betterHardwareSwap/internal/reddit"
+)
+
+// failureNotifyInterval is the minimum time between two pipeline failure DMs, so an outage that fails
+// every scrape sends one message instead of one a minute.
+const failureNotifyInterval = time.Hour
+
+// maxFailureDetailLen caps how much of the error text goes into the DM.
+const maxFailureDetailLen = 500
+
+var (
+	errFetchReddit = errors.New("failed to fetch reddit")
+	errLoadAlerts  = errors.New("failed to load alerts")
+)
+
+// failureNotifier DMs the bot owner when the scrape pipeline fails, at most once per failureNotifyInterval.
+// The debounce is kept in memory, so each Cloud Run instance can send its own message.
+type failureNotifier struct {
+	mu       sync.Mutex
+	lastSent time.Time
+}
+
+// notify DMs adminID a summary of err unless a failure was already reported within the last hour.
+// It does nothing if adminID is empty, and reports whether a message was sent.
+func (n *failureNotifier) notify(ctx context.Context, client DiscordMessenger, adminID string, err error, now time.Time) bool {
+	if adminID == "" {
+		return false
+	}
+
+	n.mu.Lock()
+	if !n.lastSent.IsZero() && now.Sub(n.lastSent) < failureNotifyInterval {
+		n.mu.Unlock()
+		logger.Info(ctx, "Pipeline failure already reported recently, not notifying admin")
+		return false
+	}
+	n.lastSent = now
+	n.mu.Unlock()
+
+	channelID, dmErr := client.CreateDM(adminID)
+	if dmErr == nil {
+		dmErr = client.SendMessage(channelID, failureMessage(err))
+	}
+	if dmErr != nil {
+		// Let the next failure try again rather than staying silent for an hour.
+		n.mu.Lock()
+		n.lastSent = time.Time{}
+		n.mu.Unlock()
+		logger.Error(ctx, "Failed to notify admin of pipeline failure", "error", dmErr)
+		return false
+	}
+	return true
+}
+
+// failureMessage describes a pipeline error for the bot owner, with what probably went wrong and what to do about it.
+func failureMessage(err error) string {
+	kind, action := classifyFailure(err)
+	detail := err.Error()
+	if r := []rune(detail); len(r) > maxFailureDetailLen {
+		detail = string(r[:maxFailureDetailLen]) + "…"
+	}
+	return fmt.Sprintf("🚨 **Scrape pipeline failed: %s**\n```%s```\n**Suggested action:** %s\n-# Further failures in the next hour won't be reported.",
+		kind, detail, action)
+}
+
+// classifyFailure names the kind of pipeline error and suggests a fix for it.
+func classifyFailure(err error) (kind, action string) {
+	switch {
+	case errors.Is(err, reddit.ErrAccessDenied):
+		return "Reddit denied access", "Check the User-Agent and whether Reddit has banned the Cloud Run IP."
+	case errors.Is(err, context.DeadlineExceeded):
+		return "Timed out", "Check whether Reddit or Firestore is slow, or raise the Cloud Run request timeout."
+	case errors.Is(err, errFetchReddit):
+		return "Reddit unreachable", "Reddit is probably down. Nothing to do unless it keeps failing after Reddit recovers."
+	case errors.Is(err, errLoadAlerts):
+		return "Firestore error", "Check Firestore quotas and the service account's permissions."
+	default:
+		return "Unexpected error", "Check the Cloud Run logs for this run."
+	}
+}
@@ -0,0 +1,63 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/reddit"
+	"github.com/pauljones0/betterHardwareSwap/internal/testutils"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestClassifyFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"Access denied", fmt.Errorf("%w: %w", errFetchReddit, reddit.ErrAccessDenied), "Reddit denied access"},
+		{"Timeout", fmt.Errorf("%w: %w", errFetchReddit, context.DeadlineExceeded), "Timed out"},
+		{"Reddit down", fmt.Errorf("%w: reddit returned 503", errFetchReddit), "Reddit unreachable"},
+		{"Firestore", fmt.Errorf("%w: unavailable", errLoadAlerts), "Firestore error"},
+		{"Other", errors.New("boom"), "Unexpected error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if kind, _ := classifyFailure(tt.err); kind != tt.want {
+				t.Errorf("classifyFailure(%v) = %q, want %q", tt.err, kind, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailureNotifier_Debounce(t *testing.T) {
+	ctx := context.Background()
+	mockDiscord := new(testutils.MockDiscord)
+	mockDiscord.On("CreateDM", "owner1").Return("dm_owner", nil)
+	mockDiscord.On("SendMessage", "dm_owner", mock.Anything).Return(errors.New("discord down")).Once()
+	mockDiscord.On("SendMessage", "dm_owner", mock.Anything).Return(nil)
+
+	var n failureNotifier
+	start := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	err := errors.New("boom")
+
+	if n.notify(ctx, mockDiscord, "", err, start) {
+		t.Error("expected no DM without an admin")
+	}
+	if n.notify(ctx, mockDiscord, "owner1", err, start) {
+		t.Error("expected a failed DM not to count as sent")
+	}
+	if !n.notify(ctx, mockDiscord, "owner1", err, start.Add(time.Minute)) {
+		t.Error("expected a retry after a failed DM")
+	}
+	if n.notify(ctx, mockDiscord, "owner1", err, start.Add(30*time.Minute)) {
+		t.Error("expected a second failure within the hour to be debounced")
+	}
+	if !n.notify(ctx, mockDiscord, "owner1", err, start.Add(61*time.Minute+time.Second)) {
+		t.Error("expected a failure after the hour to be reported")
+	}
+}
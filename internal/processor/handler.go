@@ -3,50 +3,78 @@ package processor
 import (
 	"fmt"
 	"net/http"
-	"os"
 	"time"
 
-	"github.com/pauljones0/betterHardwareSwap/internal/ai"
-	"github.com/pauljones0/betterHardwareSwap/internal/discord"
 	"github.com/pauljones0/betterHardwareSwap/internal/logger"
-	"github.com/pauljones0/betterHardwareSwap/internal/reddit"
-	"github.com/pauljones0/betterHardwareSwap/internal/store"
 )
 
+// Handler serves the Cloud Scheduler cron endpoints. Its clients are created once at startup and
+// shared by every run instead of being dialed per request.
+type Handler struct {
+	db         Storer
+	ai         AIService
+	scraper    Scraper
+	client     DiscordMessenger
+	adminID    string
+	pingMaxAge time.Duration // How long after listing a newly seen post may still ping; see RunPipeline
+	failures   failureNotifier
+}
+
+// NewHandler returns a Handler that runs the pipeline with the given long-lived clients.
+// Pipeline failures are DMed to adminID; an empty adminID only logs them.
+func NewHandler(db Storer, aiSvc AIService, scraper Scraper, client DiscordMessenger, adminID string, pingMaxAge time.Duration) *Handler {
+	return &Handler{db: db, ai: aiSvc, scraper: scraper, client: client, adminID: adminID, pingMaxAge: pingMaxAge}
+}
+
 // HandleCronScrape is the HTTP handler invoked by Cloud Scheduler.
-func HandleCronScrape(w http.ResponseWriter, r *http.Request) {
+func (h *Handler) HandleCronScrape(w http.ResponseWriter, r *http.Request) {
 	// Generate a simple request ID for the cron run
 	requestID := fmt.Sprintf("cron-%d", time.Now().UnixNano())
 	ctx := logger.WithRequestID(r.Context(), requestID)
 
 	logger.Info(ctx, "Starting cron scrape pipeline")
 
-	projectID := os.Getenv("GCP_PROJECT_ID")
-	db, err := store.NewStore(ctx, projectID)
-	if err != nil {
-		logger.Error(ctx, "Failed to init db", "error", err)
-		http.Error(w, "Failed to init db", http.StatusInternalServerError)
+	if err := RunPipeline(ctx, h.db, h.ai, h.scraper, h.client, h.pingMaxAge); err != nil {
+		logger.Error(ctx, "Pipeline failed", "error", err)
+		h.failures.notify(ctx, h.client, h.adminID, err, time.Now())
+		http.Error(w, "Pipeline failed", http.StatusInternalServerError)
 		return
 	}
-	defer db.Close()
 
-	aiSvc, err := ai.NewAIClient(ctx, os.Getenv("GEMINI_API_KEY"))
-	if err != nil {
-		logger.Error(ctx, "Failed to init ai", "error", err)
-		http.Error(w, "Failed to init ai", http.StatusInternalServerError)
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("✅ Pipeline complete."))
+}
+
+// HandleCronRetry is the HTTP handler that re-attempts dead-lettered feed posts.
+func (h *Handler) HandleCronRetry(w http.ResponseWriter, r *http.Request) {
+	requestID := fmt.Sprintf("retry-%d", time.Now().UnixNano())
+	ctx := logger.WithRequestID(r.Context(), requestID)
+
+	logger.Info(ctx, "Starting dead-letter retry")
+
+	if err := RetryFailedDispatches(ctx, h.db, h.client); err != nil {
+		logger.Error(ctx, "Dead-letter retry failed", "error", err)
+		http.Error(w, "Retry failed", http.StatusInternalServerError)
 		return
 	}
-	defer aiSvc.Close()
 
-	scraper := reddit.NewScraper()
-	discordClient := discord.NewClient(os.Getenv("DISCORD_BOT_TOKEN"))
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("✅ Retry complete."))
+}
 
-	if err := RunPipeline(ctx, db, aiSvc, scraper, discordClient); err != nil {
-		logger.Error(ctx, "Pipeline failed", "error", err)
-		http.Error(w, "Pipeline failed", http.StatusInternalServerError)
+// HandleCronDigest is the HTTP handler that delivers pings held back during users' quiet hours.
+func (h *Handler) HandleCronDigest(w http.ResponseWriter, r *http.Request) {
+	requestID := fmt.Sprintf("digest-%d", time.Now().UnixNano())
+	ctx := logger.WithRequestID(r.Context(), requestID)
+
+	logger.Info(ctx, "Starting quiet hours digest delivery")
+
+	if err := DeliverDigests(ctx, h.db, h.client); err != nil {
+		logger.Error(ctx, "Digest delivery failed", "error", err)
+		http.Error(w, "Digest failed", http.StatusInternalServerError)
 		return
 	}
 
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("✅ Pipeline complete."))
+	w.Write([]byte("✅ Digests delivered."))
 }
@@ -0,0 +1,94 @@
+package processor
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/reddit"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+	"github.com/pauljones0/betterHardwareSwap/internal/testutils"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandler_HandleCronScrape(t *testing.T) {
+	t.Run("Runs the pipeline with the injected clients", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+		mockScraper := new(testutils.MockScraper)
+
+		mockScraper.On("FetchNewestPosts", mock.Anything, reddit.SortNew).Return([]reddit.Post{}, nil)
+		mockDB.On("GetAllAlerts", mock.Anything).Return([]store.AlertRule{}, nil)
+		mockDB.On("GetAllServers", mock.Anything).Return([]store.ServerConfig{}, nil)
+		mockDB.On("GetScammers", mock.Anything).Return([]store.Scammer{}, nil)
+		mockDB.On("GetSystemPrompt", mock.Anything, "clean_prompt").Return("", nil)
+		mockDB.On("TrimOldPosts", mock.Anything).Return(nil)
+		mockDB.On("TrimOldPipelineRuns", mock.Anything).Return(nil)
+		mockDB.On("SavePipelineRun", mock.Anything, mock.Anything).Return(nil)
+
+		h := NewHandler(mockDB, new(testutils.MockAI), mockScraper, new(testutils.MockDiscord), "", 0)
+		rr := httptest.NewRecorder()
+		h.HandleCronScrape(rr, httptest.NewRequest("POST", "/cron/scrape", nil))
+
+		if rr.Code != http.StatusOK {
+			t.Errorf("expected status 200, got %d", rr.Code)
+		}
+		mockScraper.AssertExpectations(t)
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("Scraper failure is a server error", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+		mockScraper := new(testutils.MockScraper)
+		mockScraper.On("FetchNewestPosts", mock.Anything, reddit.SortNew).Return(nil, errors.New("reddit down"))
+		mockDB.On("SavePipelineRun", mock.Anything, mock.Anything).Return(nil)
+
+		h := NewHandler(mockDB, new(testutils.MockAI), mockScraper, new(testutils.MockDiscord), "", 0)
+		rr := httptest.NewRecorder()
+		h.HandleCronScrape(rr, httptest.NewRequest("POST", "/cron/scrape", nil))
+
+		if rr.Code != http.StatusInternalServerError {
+			t.Errorf("expected status 500, got %d", rr.Code)
+		}
+	})
+
+	t.Run("Scraper failure DMs the admin once", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+		mockScraper := new(testutils.MockScraper)
+		mockDiscord := new(testutils.MockDiscord)
+		mockDB.On("SavePipelineRun", mock.Anything, mock.Anything).Return(nil)
+		mockScraper.On("FetchNewestPosts", mock.Anything, reddit.SortNew).Return(nil, fmt.Errorf("%w: reddit returned 403", reddit.ErrAccessDenied))
+		mockDiscord.On("CreateDM", "owner1").Return("dm_owner", nil)
+		mockDiscord.On("SendMessage", "dm_owner", mock.MatchedBy(func(content string) bool {
+			return strings.Contains(content, "Reddit denied access") && strings.Contains(content, "User-Agent")
+		})).Return(nil)
+
+		h := NewHandler(mockDB, new(testutils.MockAI), mockScraper, mockDiscord, "owner1", 0)
+		for n := 0; n < 3; n++ {
+			rr := httptest.NewRecorder()
+			h.HandleCronScrape(rr, httptest.NewRequest("POST", "/cron/scrape", nil))
+			if rr.Code != http.StatusInternalServerError {
+				t.Errorf("expected status 500, got %d", rr.Code)
+			}
+		}
+
+		mockDiscord.AssertExpectations(t)
+		mockDiscord.AssertNumberOfCalls(t, "SendMessage", 1)
+	})
+}
+
+func TestHandler_HandleCronRetry(t *testing.T) {
+	mockDB := new(testutils.MockStore)
+	mockDB.On("GetFailedDispatches", mock.Anything, retryBatchSize).Return([]store.FailedDispatch{}, nil)
+
+	h := NewHandler(mockDB, new(testutils.MockAI), new(testutils.MockScraper), new(testutils.MockDiscord), "", 0)
+	rr := httptest.NewRecorder()
+	h.HandleCronRetry(rr, httptest.NewRequest("POST", "/cron/retry", nil))
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("expected status 200, got %d", rr.Code)
+	}
+	mockDB.AssertExpectations(t)
+}
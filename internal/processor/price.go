@@ -0,0 +1,65 @@
+package processor
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/ai"
+	"github.com/pauljones0/betterHardwareSwap/internal/reddit"
+)
+
+// pricePattern matches a dollar amount written either way Canadians do: "$450", "$1,200.50" or "450$".
+var pricePattern = regexp.MustCompile(`\$\s?(\d[\d,]*(?:\.\d{1,2})?)|(\d[\d,]*(?:\.\d{1,2})?)\s?\$`)
+
+// parsePrice returns the first dollar amount in text normalized to "$N", or "" if there isn't one.
+func parsePrice(text string) string {
+	m := pricePattern.FindStringSubmatch(text)
+	if m == nil {
+		return ""
+	}
+	amount := m[1]
+	if amount == "" {
+		amount = m[2]
+	}
+	return "$" + strings.TrimRight(amount, ",")
+}
+
+// finalPrice returns the price a closed post went for. Sellers usually update the title (or flair) to the
+// final price when they close a deal, so those are read from the post's current state.
+func finalPrice(post reddit.Post) string {
+	if price := parsePrice(post.Title); price != "" {
+		return price
+	}
+	return parsePrice(post.LinkFlairText)
+}
+
+// askingPrice returns the price a post is listed at: Gemini's cleaned price, or the first dollar amount
+// in the title when the clean didn't find one.
+func askingPrice(post reddit.Post, cleaned *ai.CleanedPost) string {
+	if cleaned.Price != "" {
+		return cleaned.Price
+	}
+	return parsePrice(post.Title)
+}
+
+// priceAmount returns the first dollar amount in text as a number.
+func priceAmount(text string) (float64, bool) {
+	price := parsePrice(text)
+	if price == "" {
+		return 0, false
+	}
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimPrefix(price, "$"), ",", ""), 64)
+	return amount, err == nil
+}
+
+// priceDropped reports whether newPrice is a lower dollar amount than oldPrice. Either side
+// without a readable amount never counts as a drop.
+func priceDropped(oldPrice, newPrice string) bool {
+	oldAmount, ok := priceAmount(oldPrice)
+	if !ok {
+		return false
+	}
+	newAmount, ok := priceAmount(newPrice)
+	return ok && newAmount < oldAmount
+}
@@ -0,0 +1,66 @@
+package processor
+
+import (
+	"testing"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/reddit"
+)
+
+func TestParsePrice(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"[H] RTX 3080 FE [W] $500 Local Cash", "$500"},
+		{"[H] RTX 4090 [W] $1,850 or trade", "$1,850"},
+		{"[H] SSD [W] 45.50$ etrade", "$45.50"},
+		{"Sold for $ 300, thanks all", "$300"},
+		{"[H] Keyboard [W] Cash", ""},
+	}
+
+	for _, tt := range tests {
+		if got := parsePrice(tt.text); got != tt.want {
+			t.Errorf("parsePrice(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestFinalPrice(t *testing.T) {
+	tests := []struct {
+		name string
+		post reddit.Post
+		want string
+	}{
+		{"Title", reddit.Post{Title: "[SOLD] RTX 3080 - $420", LinkFlairText: "Sold"}, "$420"},
+		{"Flair", reddit.Post{Title: "[H] RTX 3080 [W] Cash", LinkFlairText: "Sold $400"}, "$400"},
+		{"Body is ignored", reddit.Post{Title: "[H] RTX 3080 [W] Cash", LinkFlairText: "Sold", SelfText: "Asking $500"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := finalPrice(tt.post); got != tt.want {
+				t.Errorf("finalPrice() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPriceDropped(t *testing.T) {
+	tests := []struct {
+		oldPrice, newPrice string
+		want               bool
+	}{
+		{"$500", "$450", true},
+		{"$1,200 OBO", "$1,150", true},
+		{"$450", "$500", false},
+		{"$450", "$450", false},
+		{"", "$450", false},
+		{"$500", "Best offer", false},
+	}
+
+	for _, tt := range tests {
+		if got := priceDropped(tt.oldPrice, tt.newPrice); got != tt.want {
+			t.Errorf("priceDropped(%q, %q) = %v, want %v", tt.oldPrice, tt.newPrice, got, tt.want)
+		}
+	}
+}
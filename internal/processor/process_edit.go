@@ -0,0 +1,111 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/discord"
+	"github.com/pauljones0/betterHardwareSwap/internal/logger"
+	"github.com/pauljones0/betterHardwareSwap/internal/reddit"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+)
+
+// handleEditedPost re-cleans a post the seller edited (usually a price change), refreshes its feed
+// messages, and pings users whose alerts match the new version but didn't match the old one. If the
+// asking price dropped, users who already matched are pinged again with the old and new price.
+func handleEditedPost(ctx context.Context, db Storer, cache ConfigGetter, aiSvc AIService, client DiscordMessenger, post reddit.Post, record *store.PostRecord, alerts []store.AlertRule, cleanPrompt string) error {
+	logger.Info(ctx, "Detected EDITED post, re-cleaning", "reddit_id", post.ID, "edited_at", post.Edited.Time())
+
+	cleaned, err := aiSvc.CleanRedditPost(ctx, post.Title, post.SelfText, cleanPrompt)
+	if err != nil {
+		return fmt.Errorf("failed to re-clean edited post: %w", err)
+	}
+	cleaned, ok := validateCleanedPost(ctx, post, cleaned)
+	if !ok {
+		return nil
+	}
+
+	corpus := cleaned.Title + " " + cleaned.Description + " " + cleaned.Location
+	embeds := localizedEmbeds(ctx, cache, aiSvc, post, cleanPrompt, globalBuilder.BuildDealEmbed(post, cleaned), slices.Collect(maps.Keys(record.ServerMsgs)))
+
+	// Records saved before corpora were stored can't tell us who already matched, so skip re-pinging them.
+	canRePing := record.Corpus != ""
+	// The old body isn't stored, so both passes use the current one; only changes to the cleaned text re-ping.
+	now := time.Now()
+	oldMatches := findMatches(ctx, alerts, record.Corpus, post.SelfText, cleaned.IsBundle, now)
+	newMatches := findMatches(ctx, alerts, corpus, post.SelfText, cleaned.IsBundle, now)
+	dropMutedAuthor(ctx, cache, newMatches, post.Author)
+	price := askingPrice(post, cleaned)
+	dropped := priceDropped(record.Price, price)
+
+	for serverID, msgID := range record.ServerMsgs {
+		channelID, cfg, err := resolveFeedChannel(ctx, cache, client, serverID)
+		if err != nil {
+			logger.Warn(ctx, "Could not resolve feed channel during edit", "server_id", serverID, "error", err)
+			continue
+		}
+
+		if err := editFeedMessage(client, channelID, cfg, record.ServerWebhooks[serverID], msgID, serverDealEmbed(cfg, embeds[store.ServerLanguage(cfg)], post)); err != nil {
+			logger.Error(ctx, "Failed to edit message", "server_id", serverID, "msg_id", msgID, "error", err)
+			continue
+		}
+
+		// DM scopes only hold a message if their user already matched, so there is no one new to ping.
+		if !canRePing || cfg == nil {
+			continue
+		}
+		link := discord.BuildMessageLink(serverID, cfg.FeedChannelID, msgID)
+		if userIDs := newlyMatchedUsers(oldMatches[serverID], newMatches[serverID]); len(userIDs) > 0 {
+			notifyUsers(ctx, db, cache, client, serverID, cfg.PingChannelID, link, userIDs, "**An updated deal now matches your alert!**")
+		}
+		if dropped {
+			if userIDs := stillMatchedUsers(oldMatches[serverID], newMatches[serverID]); len(userIDs) > 0 {
+				headline := fmt.Sprintf("**📉 Price drop: %s → %s on a deal matching your alert!**", record.Price, price)
+				notifyUsers(ctx, db, cache, client, serverID, cfg.PingChannelID, link, userIDs, headline)
+			}
+		}
+	}
+
+	// Always record the refresh, even if some edits failed, so the post isn't re-cleaned every run.
+	if err := db.UpdatePostContent(ctx, post.ID, cleaned.Title, corpus, rawPostText(post), price); err != nil {
+		return fmt.Errorf("failed to save edited post content: %w", err)
+	}
+	return nil
+}
+
+// newlyMatchedUsers returns the users in after that weren't already in before.
+func newlyMatchedUsers(before, after []string) []string {
+	seen := make(map[string]bool, len(before))
+	for _, uid := range before {
+		seen[uid] = true
+	}
+
+	var added []string
+	for _, uid := range after {
+		if !seen[uid] {
+			seen[uid] = true
+			added = append(added, uid)
+		}
+	}
+	return added
+}
+
+// stillMatchedUsers returns the users in after that were already in before, i.e. who saw the original deal.
+func stillMatchedUsers(before, after []string) []string {
+	seen := make(map[string]bool, len(before))
+	for _, uid := range before {
+		seen[uid] = true
+	}
+
+	var kept []string
+	for _, uid := range after {
+		if seen[uid] {
+			delete(seen, uid)
+			kept = append(kept, uid)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,243 @@
+package processor
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pauljones0/betterHardwareSwap/internal/ai"
+	"github.com/pauljones0/betterHardwareSwap/internal/reddit"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+	"github.com/pauljones0/betterHardwareSwap/internal/testutils"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandleExistingPostStatus_Edited(t *testing.T) {
+	ctx := context.Background()
+
+	var post reddit.Post
+	if err := testutils.LoadFixture("reddit_post_edited.json", &post); err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+
+	alerts := []store.AlertRule{
+		{ServerID: "guild1", UserID: "already_pinged", MustHave: []string{"3080"}},
+		{ServerID: "guild1", UserID: "price_watcher", MustHave: []string{"3080", "$450"}},
+	}
+	cfg := &store.ServerConfig{FeedChannelID: "feed1", PingChannelID: "ping1"}
+	cleaned := &ai.CleanedPost{Title: "RTX 3080 FE", Description: "Now $450", Location: "Toronto", Price: "$450"}
+
+	t.Run("Edited after last clean is refreshed and re-pings new matches", func(t *testing.T) {
+		record := &store.PostRecord{
+			RedditID:     post.ID,
+			CleanedTitle: "RTX 3080 FE",
+			Corpus:       "RTX 3080 FE $500 Toronto",
+			ServerMsgs:   map[string]string{"guild1": "msg1"},
+			NumComments:  post.NumComments,
+			PostedAt:     post.Edited.Time().Add(-time.Hour),
+		}
+
+		mockDB := new(testutils.MockStore)
+		mockAI := new(testutils.MockAI)
+		mockDiscord := new(testutils.MockDiscord)
+
+		mockAI.On("CleanRedditPost", mock.Anything, post.Title, post.SelfText, mock.Anything).Return(cleaned, nil)
+		mockDB.On("GetServerConfig", mock.Anything, "guild1").Return(cfg, nil)
+		mockDiscord.On("EditEmbed", "feed1", "msg1", "", mock.Anything).Return(nil)
+		mockDB.On("GetUserSettings", mock.Anything, mock.Anything).Return(&store.UserSettings{}, nil)
+		mockDiscord.On("SendMessage", "ping1", mock.MatchedBy(func(content string) bool {
+			return strings.Contains(content, "<@price_watcher>") && !strings.Contains(content, "already_pinged") &&
+				strings.Contains(content, "https://discord.com/channels/guild1/feed1/msg1")
+		})).Return(nil)
+		mockDB.On("UpdatePostContent", mock.Anything, post.ID, "RTX 3080 FE", "RTX 3080 FE Now $450 Toronto", mock.Anything, "$450").Return(nil)
+
+		if err := handleExistingPostStatus(ctx, mockDB, mockDB, mockAI, mockDiscord, post, record, alerts, ""); err != nil {
+			t.Fatalf("handleExistingPostStatus failed: %v", err)
+		}
+
+		mockAI.AssertExpectations(t)
+		mockDB.AssertExpectations(t)
+		mockDiscord.AssertExpectations(t)
+	})
+
+	for _, tt := range []struct {
+		name     string
+		oldPrice string
+		wantDrop bool
+	}{
+		{"Price cut re-pings users who already matched", "$500", true},
+		{"Price increase does not re-ping", "$400", false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			record := &store.PostRecord{
+				RedditID:     post.ID,
+				CleanedTitle: "RTX 3080 FE",
+				Corpus:       "RTX 3080 FE $500 Toronto",
+				Price:        tt.oldPrice,
+				ServerMsgs:   map[string]string{"guild1": "msg1"},
+				NumComments:  post.NumComments,
+				PostedAt:     post.Edited.Time().Add(-time.Hour),
+			}
+
+			mockDB := new(testutils.MockStore)
+			mockAI := new(testutils.MockAI)
+			mockDiscord := new(testutils.MockDiscord)
+
+			var pings []string
+			mockAI.On("CleanRedditPost", mock.Anything, post.Title, post.SelfText, mock.Anything).Return(cleaned, nil)
+			mockDB.On("GetServerConfig", mock.Anything, "guild1").Return(cfg, nil)
+			mockDiscord.On("EditEmbed", "feed1", "msg1", "", mock.Anything).Return(nil)
+			mockDB.On("GetUserSettings", mock.Anything, mock.Anything).Return(&store.UserSettings{}, nil)
+			mockDiscord.On("SendMessage", "ping1", mock.Anything).Run(func(args mock.Arguments) {
+				pings = append(pings, args.String(1))
+			}).Return(nil)
+			mockDB.On("UpdatePostContent", mock.Anything, post.ID, "RTX 3080 FE", mock.Anything, mock.Anything, "$450").Return(nil)
+
+			if err := handleExistingPostStatus(ctx, mockDB, mockDB, mockAI, mockDiscord, post, record, alerts, ""); err != nil {
+				t.Fatalf("handleExistingPostStatus failed: %v", err)
+			}
+
+			var dropPing string
+			for _, p := range pings {
+				if strings.Contains(p, "Price drop") {
+					dropPing = p
+				}
+			}
+			if !tt.wantDrop {
+				if dropPing != "" {
+					t.Errorf("expected no price drop ping, got %q", dropPing)
+				}
+				return
+			}
+			if !strings.Contains(dropPing, "$500 → $450") || !strings.Contains(dropPing, "<@already_pinged>") || strings.Contains(dropPing, "price_watcher") {
+				t.Errorf("expected a $500 → $450 ping for the user who already matched, got %q", dropPing)
+			}
+		})
+	}
+
+	t.Run("Edit already processed is ignored", func(t *testing.T) {
+		record := &store.PostRecord{
+			RedditID:    post.ID,
+			ServerMsgs:  map[string]string{"guild1": "msg1"},
+			NumComments: post.NumComments,
+			PostedAt:    post.Edited.Time().Add(-time.Hour),
+			UpdatedAt:   post.Edited.Time().Add(time.Minute),
+		}
+
+		mockDB := new(testutils.MockStore)
+		mockAI := new(testutils.MockAI)
+		mockDiscord := new(testutils.MockDiscord)
+
+		if err := handleExistingPostStatus(ctx, mockDB, mockDB, mockAI, mockDiscord, post, record, alerts, ""); err != nil {
+			t.Fatalf("handleExistingPostStatus failed: %v", err)
+		}
+
+		mockAI.AssertNotCalled(t, "CleanRedditPost", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockDiscord.AssertNotCalled(t, "EditEmbed", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestHandleExistingPostStatus_Sold(t *testing.T) {
+	ctx := context.Background()
+	post := reddit.Post{ID: "t3_sold", Title: "[H] RTX 3080 FE [W] $450 Local Cash", URL: "https://reddit.com/sold", LinkFlairText: "Sold"}
+	cfg := &store.ServerConfig{FeedChannelID: "feed1", PingChannelID: "ping1", ArchiveChannelID: "archive1"}
+
+	t.Run("Sold transition strikes the deal and archives its price", func(t *testing.T) {
+		record := &store.PostRecord{RedditID: post.ID, CleanedTitle: "RTX 3080 FE", ServerMsgs: map[string]string{"guild1": "msg1"}}
+
+		mockDB := new(testutils.MockStore)
+		mockDiscord := new(testutils.MockDiscord)
+
+		mockDB.On("GetServerConfig", mock.Anything, "guild1").Return(cfg, nil)
+		mockDiscord.On("EditEmbed", "feed1", "msg1", "", mock.Anything).Return(nil)
+		mockDiscord.On("SendMessage", "archive1", mock.MatchedBy(func(content string) bool {
+			return strings.Contains(content, "Sold for $450") && strings.Contains(content, "RTX 3080 FE") && strings.Contains(content, "<https://reddit.com/sold>")
+		})).Return(nil)
+		mockDB.On("MarkPostClosed", mock.Anything, post.ID).Return(nil)
+
+		if err := handleExistingPostStatus(ctx, mockDB, mockDB, new(testutils.MockAI), mockDiscord, post, record, nil, ""); err != nil {
+			t.Fatalf("handleExistingPostStatus failed: %v", err)
+		}
+
+		mockDB.AssertExpectations(t)
+		mockDiscord.AssertExpectations(t)
+	})
+
+	t.Run("Updated title gives the closed embed a final price", func(t *testing.T) {
+		updated := post
+		updated.Title = "[SOLD] RTX 3080 FE - went for $420"
+		record := &store.PostRecord{RedditID: post.ID, CleanedTitle: "RTX 3080 FE", ServerMsgs: map[string]string{"guild2": "msg2"}}
+
+		mockDB := new(testutils.MockStore)
+		mockDiscord := new(testutils.MockDiscord)
+
+		mockDB.On("GetServerConfig", mock.Anything, "guild2").Return(&store.ServerConfig{FeedChannelID: "feed2"}, nil)
+		mockDiscord.On("EditEmbed", "feed2", "msg2", "", mock.MatchedBy(func(embed *discordgo.MessageEmbed) bool {
+			return strings.Contains(embed.Description, "Final: **$420**") && embed.Title == "~~RTX 3080 FE~~"
+		})).Return(nil)
+		mockDB.On("MarkPostClosed", mock.Anything, post.ID).Return(nil)
+
+		if err := handleExistingPostStatus(ctx, mockDB, mockDB, new(testutils.MockAI), mockDiscord, updated, record, nil, ""); err != nil {
+			t.Fatalf("handleExistingPostStatus failed: %v", err)
+		}
+
+		mockDB.AssertExpectations(t)
+		mockDiscord.AssertExpectations(t)
+	})
+
+	t.Run("Already closed post is left alone", func(t *testing.T) {
+		record := &store.PostRecord{RedditID: post.ID, ServerMsgs: map[string]string{"guild1": "msg1"}, ClosedAt: time.Now()}
+
+		mockDB := new(testutils.MockStore)
+		mockDiscord := new(testutils.MockDiscord)
+
+		if err := handleExistingPostStatus(ctx, mockDB, mockDB, new(testutils.MockAI), mockDiscord, post, record, nil, ""); err != nil {
+			t.Fatalf("handleExistingPostStatus failed: %v", err)
+		}
+
+		mockDiscord.AssertNotCalled(t, "EditEmbed", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockDiscord.AssertNotCalled(t, "SendMessage", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Sold deal delivered by DM is struck out in the DM", func(t *testing.T) {
+		record := &store.PostRecord{RedditID: post.ID, CleanedTitle: "RTX 3080 FE", ServerMsgs: map[string]string{store.DMScope("user1"): "msg1"}}
+
+		mockDB := new(testutils.MockStore)
+		mockDiscord := new(testutils.MockDiscord)
+
+		mockDiscord.On("CreateDM", "user1").Return("dm_chan1", nil)
+		mockDiscord.On("EditEmbed", "dm_chan1", "msg1", "", mock.Anything).Return(nil)
+		mockDB.On("MarkPostClosed", mock.Anything, post.ID).Return(nil)
+
+		if err := handleExistingPostStatus(ctx, mockDB, mockDB, new(testutils.MockAI), mockDiscord, post, record, nil, ""); err != nil {
+			t.Fatalf("handleExistingPostStatus failed: %v", err)
+		}
+
+		mockDB.AssertExpectations(t)
+		mockDB.AssertNotCalled(t, "GetServerConfig", mock.Anything, mock.Anything)
+		mockDiscord.AssertExpectations(t)
+		mockDiscord.AssertNotCalled(t, "SendMessage", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Closed without a sale is not archived", func(t *testing.T) {
+		closed := post
+		closed.LinkFlairText = "Closed"
+		record := &store.PostRecord{RedditID: post.ID, CleanedTitle: "RTX 3080 FE", ServerMsgs: map[string]string{"guild1": "msg1"}}
+
+		mockDB := new(testutils.MockStore)
+		mockDiscord := new(testutils.MockDiscord)
+
+		mockDB.On("GetServerConfig", mock.Anything, "guild1").Return(cfg, nil)
+		mockDiscord.On("EditEmbed", "feed1", "msg1", "", mock.Anything).Return(nil)
+		mockDB.On("MarkPostClosed", mock.Anything, post.ID).Return(nil)
+
+		if err := handleExistingPostStatus(ctx, mockDB, mockDB, new(testutils.MockAI), mockDiscord, closed, record, nil, ""); err != nil {
+			t.Fatalf("handleExistingPostStatus failed: %v", err)
+		}
+
+		mockDB.AssertExpectations(t)
+		mockDiscord.AssertNotCalled(t, "SendMessage", mock.Anything, mock.Anything)
+	})
+}
@@ -3,20 +3,30 @@ package processor
 import (
 	"context"
 	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/pauljones0/betterHardwareSwap/internal/ai"
+	"github.com/pauljones0/betterHardwareSwap/internal/discord"
 	"github.com/pauljones0/betterHardwareSwap/internal/logger"
+	"github.com/pauljones0/betterHardwareSwap/internal/matcher"
 	"github.com/pauljones0/betterHardwareSwap/internal/reddit"
 	"github.com/pauljones0/betterHardwareSwap/internal/store"
 )
 
 var (
-	globalMatcher = NewMatcher()
+	globalMatcher = matcher.New()
 	globalBuilder = NewDealBuilder()
 )
 
 // processNewPost handles sending the post to Gemini, matching against alerts, and dispatching.
-func processNewPost(ctx context.Context, db Storer, cache ServerConfigGetter, aiSvc AIService, client DiscordMessenger, post reddit.Post, alerts []store.AlertRule) {
+// Posts by an author in scammers (lowercased usernames) are dropped before any of that. Posts
+// listed more than pingMaxAge ago (0 disables the limit) are posted to feeds without pings.
+// It returns how many users the post matched, and an error if it couldn't be cleaned or saved.
+func processNewPost(ctx context.Context, db Storer, cache ConfigGetter, aiSvc AIService, client DiscordMessenger, post reddit.Post, alerts []store.AlertRule, servers []store.ServerConfig, scammers map[string]bool, pingMaxAge time.Duration, cleanPrompt string) (int, error) {
 	logger.Info(ctx, "Processing NEW post",
 		"reddit_id", post.ID,
 		"title", post.Title,
@@ -24,39 +34,141 @@ func processNewPost(ctx context.Context, db Storer, cache ServerConfigGetter, ai
 		"subreddit", post.Subreddit,
 	)
 
+	// A deleted author can't be on the blocklist in any meaningful way, so never match "[deleted]" against it.
+	if knownAuthor(post.Author) && scammers[strings.ToLower(post.Author)] {
+		logger.Info(ctx, "Dropping post from blocklisted author", "reddit_id", post.ID, "author", post.Author)
+		return 0, nil
+	}
+
 	// 1. Give Gemini the messy post to clean up
-	cleaned, err := aiSvc.CleanRedditPost(ctx, post.Title, post.SelfText)
+	cleaned, err := aiSvc.CleanRedditPost(ctx, post.Title, post.SelfText, cleanPrompt)
 	if err != nil {
 		logger.Error(ctx, "Gemini failed to clean post", "reddit_id", post.ID, "error", err)
-		return
+		return 0, err
+	}
+	cleaned, ok := validateCleanedPost(ctx, post, cleaned)
+	if !ok {
+		return 0, nil
 	}
 
 	// 2. Build the searchable corpus.
 	corpus := cleaned.Title + " " + cleaned.Description + " " + cleaned.Location
 
 	// 3. Match against alerts mapping ServerID -> matched users
-	matches := findMatches(ctx, alerts, corpus)
+	matched := matchingAlerts(ctx, alerts, corpus, post.SelfText, cleaned.IsBundle, time.Now())
+	matches := groupByServer(ctx, matched)
+	dropMutedAuthor(ctx, cache, matches, post.Author)
+	addAllDealsServers(matches, servers)
+	dropLowScoreServers(ctx, cache, matches, post.Score)
+	dropBlockedServers(ctx, cache, matches, corpus)
+	if post.Over18 {
+		dropNSFWServers(ctx, cache, matches)
+	}
+	countAlertMatches(ctx, db, matched, matches)
 
-	// 4. Create the beautiful Dispatch Embed
+	// 4. Create the beautiful Dispatch Embed, plus a translated one for each other language a receiving server reads.
 	embed := globalBuilder.BuildDealEmbed(post, cleaned)
+	embeds := localizedEmbeds(ctx, cache, aiSvc, post, cleanPrompt, embed, slices.Collect(maps.Keys(matches)))
 
-	// 5. Dispatch!
-	serverMsgs := dispatchToServers(ctx, cache, client, post, embed, matches)
+	// 5. Dispatch! A post we only now see but that was listed long ago (e.g. backfilled after downtime)
+	// still goes to the feeds, just without pings.
+	recipients := matches
+	if postTooOldToPing(post, pingMaxAge, time.Now()) {
+		logger.Info(ctx, "Post is too old to ping, sending to feeds only", "reddit_id", post.ID, "created_utc", post.CreatedUtc)
+		recipients = withoutPings(matches)
+	}
+	serverMsgs, serverWebhooks := dispatchToServers(ctx, db, cache, client, post, cleaned.Title, embeds, recipients)
+	welcomeFirstMatches(ctx, db, cache, client, matched, recipients, serverMsgs)
 
-	// 6. Batch save all server message IDs
-	if len(serverMsgs) > 0 {
-		if err := db.SavePostRecords(ctx, post.ID, cleaned.Title, serverMsgs); err != nil {
+	// 6. Batch save all server message IDs. The record is saved even if every feed post failed
+	// (those are dead-lettered) so the next run doesn't treat the post as new and re-clean it.
+	if len(matches) > 0 {
+		if err := db.SavePostRecords(ctx, post.ID, cleaned.Title, corpus, rawPostText(post), post.URL, askingPrice(post, cleaned), post.NumComments, serverMsgs, serverWebhooks); err != nil {
 			logger.Error(ctx, "Failed to batch save post records", "reddit_id", post.ID, "error", err)
+			return countUsers(matches), err
 		}
 	}
+	return countUsers(matches), nil
+}
+
+// postTooOldToPing reports whether post was listed more than maxAge before now. Posts without a
+// creation time, and a zero maxAge, never count as too old.
+func postTooOldToPing(post reddit.Post, maxAge time.Duration, now time.Time) bool {
+	if maxAge <= 0 || post.CreatedUtc <= 0 {
+		return false
+	}
+	return now.Sub(time.Unix(int64(post.CreatedUtc), 0)) > maxAge
 }
 
-func findMatches(ctx context.Context, alerts []store.AlertRule, corpus string) map[string][]string {
-	matches := make(map[string][]string) // ServerID -> array of UserIDs
+// withoutPings keeps every server in matches but clears its users, so the post is still sent to the
+// feed but no one is pinged. DM scopes are dropped, since their feed post is itself the ping.
+func withoutPings(matches map[string][]string) map[string][]string {
+	feedOnly := make(map[string][]string, len(matches))
+	for serverID := range matches {
+		if _, ok := store.DMScopeUser(serverID); ok {
+			continue
+		}
+		feedOnly[serverID] = nil
+	}
+	return feedOnly
+}
+
+// countUsers returns how many users are pinged across every server in matches.
+func countUsers(matches map[string][]string) int {
+	n := 0
+	for _, userIDs := range matches {
+		n += len(userIDs)
+	}
+	return n
+}
+
+// findMatches returns the users whose alerts match the cleaned corpus. Alerts with SearchBody set
+// are matched against the corpus plus the (truncated) raw Reddit body instead.
+func findMatches(ctx context.Context, alerts []store.AlertRule, corpus, rawBody string, isBundle bool, now time.Time) map[string][]string {
+	return groupByServer(ctx, matchingAlerts(ctx, alerts, corpus, rawBody, isBundle, now))
+}
+
+// matchingAlerts returns the alerts that match the cleaned corpus (plus the raw body for SearchBody alerts).
+// Alerts paused or snoozed at now and trending alerts are skipped, as are ExcludeBundles alerts when the
+// post is a bundle. Alerts that only missed because of a MustNot term are logged at debug level.
+func matchingAlerts(ctx context.Context, alerts []store.AlertRule, corpus, rawBody string, isBundle bool, now time.Time) []store.AlertRule {
+	var matched []store.AlertRule
+	bodyCorpus := corpus + " " + truncateBody(rawBody)
 	for _, alert := range alerts {
-		if globalMatcher.Matches(corpus, alert.MustHave, alert.AnyOf, alert.MustNot) {
-			matches[alert.ServerID] = append(matches[alert.ServerID], alert.UserID)
+		if !alert.Active(now) || alert.TrendingComments > 0 {
+			continue // Trending alerts fire on comment counts instead; see trendingMatches.
+		}
+		if isBundle && alert.ExcludeBundles {
+			continue
+		}
+		searched := corpus
+		if alert.SearchBody {
+			searched = bodyCorpus
+		}
+		result := globalMatcher.MatchesWithReason(searched, alert.MustHave, alert.AnyOf, alert.MustNot)
+		if result.Matched {
+			matched = append(matched, alert)
+		} else if result.FiredMustNot != "" {
+			logger.Debug(ctx, "Alert excluded by keyword", "alert_id", alert.ID, "term", result.FiredMustNot)
+		}
+	}
+	return matched
+}
+
+// groupByServer maps each server to the users whose alerts matched there. A user with several
+// matching alerts on one server is listed once, so they get a single ping per post.
+func groupByServer(ctx context.Context, matched []store.AlertRule) map[string][]string {
+	matches := make(map[string][]string)     // ServerID -> array of UserIDs
+	seen := make(map[string]map[string]bool) // ServerID -> set of UserIDs already listed
+	for _, alert := range matched {
+		if seen[alert.ServerID] == nil {
+			seen[alert.ServerID] = make(map[string]bool)
 		}
+		if seen[alert.ServerID][alert.UserID] {
+			continue
+		}
+		seen[alert.ServerID][alert.UserID] = true
+		matches[alert.ServerID] = append(matches[alert.ServerID], alert.UserID)
 	}
 
 	if len(matches) > 0 {
@@ -66,41 +178,286 @@ func findMatches(ctx context.Context, alerts []store.AlertRule, corpus string) m
 	return matches
 }
 
-func dispatchToServers(ctx context.Context, cache ServerConfigGetter, client DiscordMessenger, post reddit.Post, embed *discordgo.MessageEmbed, matches map[string][]string) map[string]string {
-	serverMsgs := make(map[string]string)
+// countAlertMatches bumps the match counters behind /stats for alerts whose user is still notified about the post.
+func countAlertMatches(ctx context.Context, db Storer, matched []store.AlertRule, matches map[string][]string) {
+	var ids []string
+	for _, alert := range matched {
+		if userIDs, ok := matches[alert.ServerID]; ok && slices.Contains(userIDs, alert.UserID) && alert.ID != "" {
+			ids = append(ids, alert.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	if err := db.IncrementAlertMatches(ctx, ids); err != nil {
+		logger.Warn(ctx, "Failed to update alert match counts", "alert_count", len(ids), "error", err)
+	}
+}
 
+// welcomeFirstMatches DMs each user notified about the post whose alerts have never matched a deal before,
+// so new users know their alert works. DM-scoped alerts are skipped, since their deal already arrived by DM,
+// and users in quiet hours are left for a later match. Users whose alert already counted matches before
+// the flag existed are marked without a DM.
+func welcomeFirstMatches(ctx context.Context, db Storer, cache ConfigGetter, client DiscordMessenger, matched []store.AlertRule, recipients map[string][]string, serverMsgs map[string]string) {
+	now := time.Now()
+	seen := make(map[string]bool)
+	for _, alert := range matched {
+		if seen[alert.UserID] || !slices.Contains(recipients[alert.ServerID], alert.UserID) {
+			continue
+		}
+		if _, sent := serverMsgs[alert.ServerID]; !sent {
+			continue
+		}
+		if _, ok := store.DMScopeUser(alert.ServerID); ok {
+			continue
+		}
+		settings, err := cache.GetUserSettings(ctx, alert.UserID)
+		if err != nil || settings.HasMatched {
+			continue
+		}
+		if _, quiet := settings.QuietUntil(now); quiet {
+			continue
+		}
+		seen[alert.UserID] = true
+
+		first, err := db.MarkUserMatched(ctx, alert.UserID)
+		if err != nil {
+			logger.Warn(ctx, "Failed to record user's first match", "user_id", alert.UserID, "error", err)
+			continue
+		}
+		if !first || alert.MatchCount > 0 {
+			continue
+		}
+		channelID, err := client.CreateDM(alert.UserID)
+		if err != nil {
+			logger.Warn(ctx, "Failed to open DM for first match welcome", "user_id", alert.UserID, "error", err)
+			continue
+		}
+		msg := fmt.Sprintf("🎉 Your alert \"%s\" just matched its first deal! You'll be pinged like this whenever a new post matches. "+
+			"Manage your alerts with `/alert list`.", alert.RawQuery)
+		if err := client.SendMessage(channelID, msg); err != nil {
+			logger.Warn(ctx, "Failed to send first match welcome", "user_id", alert.UserID, "error", err)
+		}
+	}
+}
+
+// maxBodySearchLen caps how much of the raw Reddit body body-searching alerts look at,
+// since long posts are mostly noise (shipping terms, timestamps, heatware links).
+const maxBodySearchLen = 2000
+
+func truncateBody(body string) string {
+	if r := []rune(body); len(r) > maxBodySearchLen {
+		return string(r[:maxBodySearchLen])
+	}
+	return body
+}
+
+// rawPostText is the post as the seller wrote it, saved with its record for "Bad Summary" reports. The body
+// is truncated like the searched body so the compaction prompt stays small.
+func rawPostText(post reddit.Post) string {
+	return post.Title + "\n\n" + truncateBody(post.SelfText)
+}
+
+// addAllDealsServers ensures every server in all-deals mode receives the post in its feed,
+// even when none of its alerts matched (an empty user list posts without pinging).
+func addAllDealsServers(matches map[string][]string, servers []store.ServerConfig) {
+	for _, cfg := range servers {
+		if cfg.FeedMode != store.FeedModeAllDeals {
+			continue
+		}
+		if _, ok := matches[cfg.ServerID]; !ok {
+			matches[cfg.ServerID] = nil
+		}
+	}
+}
+
+// dropLowScoreServers removes servers whose MinScore the post doesn't reach, unless one of their
+// alerts matched it: the threshold trims noise from the feed, it never silences someone's alert.
+func dropLowScoreServers(ctx context.Context, cache ConfigGetter, matches map[string][]string, score int) {
 	for serverID, userIDs := range matches {
+		if len(userIDs) > 0 {
+			continue
+		}
 		cfg, err := cache.GetServerConfig(ctx, serverID)
 		if err != nil {
-			logger.Error(ctx, "Could not get config for server", "server_id", serverID, "error", err)
 			continue
 		}
+		if score < cfg.MinScore {
+			logger.Debug(ctx, "Post below server minimum score", "server_id", serverID, "score", score, "min_score", cfg.MinScore)
+			delete(matches, serverID)
+		}
+	}
+}
 
-		// Send to Feed Channel
-		msgID, err := client.SendEmbedWithComponents(cfg.FeedChannelID, "", embed, globalBuilder.BuildDealButtons(post.URL))
-		if err == nil {
-			_ = client.AddReaction(cfg.FeedChannelID, msgID, "%F0%9F%91%8D") // Thumbs up
-			_ = client.AddReaction(cfg.FeedChannelID, msgID, "%F0%9F%91%8E") // Thumbs down
-			serverMsgs[serverID] = msgID
-		} else {
-			logger.Error(ctx, "Failed to post feed to server", "server_id", serverID, "error", err)
+// dropBlockedServers removes servers whose blocklist hits the corpus, so neither the feed post nor any ping is sent there.
+func dropBlockedServers(ctx context.Context, cache ConfigGetter, matches map[string][]string, corpus string) {
+	for serverID := range matches {
+		if _, ok := store.DMScopeUser(serverID); ok {
+			continue // Blocklists are per-server; DM-scoped alerts have none.
+		}
+		cfg, err := cache.GetServerConfig(ctx, serverID)
+		if err != nil {
+			// Dispatch logs the missing config; nothing to filter against here.
 			continue
 		}
+		for _, term := range cfg.GlobalMustNot {
+			if safeContains(corpus, term) {
+				logger.Debug(ctx, "Post blocked by server blocklist", "server_id", serverID, "term", term)
+				delete(matches, serverID)
+				break
+			}
+		}
+	}
+}
 
-		// Send deduped Ping to Ping Channel
-		if len(userIDs) > 0 {
-			pingContent := ""
-			for _, uid := range userIDs {
-				pingContent += fmt.Sprintf("<@%s> ", uid)
+// knownAuthor reports whether author names an actual Reddit user, rather than being missing or deleted.
+func knownAuthor(author string) bool {
+	return author != "" && author != "[deleted]"
+}
+
+// dropMutedAuthor removes users who muted the post's seller from matches, along with any server left
+// with no one to notify. Not knowing someone's settings keeps them in.
+func dropMutedAuthor(ctx context.Context, cache ConfigGetter, matches map[string][]string, author string) {
+	if !knownAuthor(author) {
+		return
+	}
+	for serverID, userIDs := range matches {
+		if len(userIDs) == 0 {
+			continue
+		}
+		var kept []string
+		for _, uid := range userIDs {
+			settings, err := cache.GetUserSettings(ctx, uid)
+			if err == nil && settings.HasMutedAuthor(author) {
+				logger.Debug(ctx, "Post author muted by user", "user_id", uid, "author", author)
+				continue
 			}
-			pingContent += fmt.Sprintf("- **Match Found in the Deal Feed!** <https://discord.com/channels/%s/%s/%s>", serverID, cfg.FeedChannelID, msgID)
+			kept = append(kept, uid)
+		}
+		if len(kept) == 0 {
+			delete(matches, serverID)
+			continue
+		}
+		matches[serverID] = kept
+	}
+}
 
-			_ = client.SendMessage(cfg.PingChannelID, pingContent)
+// dropNSFWServers removes servers that haven't opted in to NSFW posts. DM-scoped alerts have no
+// server config to opt in with, so they never receive them.
+func dropNSFWServers(ctx context.Context, cache ConfigGetter, matches map[string][]string) {
+	for serverID := range matches {
+		if _, ok := store.DMScopeUser(serverID); !ok {
+			if cfg, err := cache.GetServerConfig(ctx, serverID); err == nil && cfg.AllowNSFW {
+				continue
+			}
 		}
+		logger.Debug(ctx, "NSFW post suppressed", "server_id", serverID)
+		delete(matches, serverID)
 	}
-	return serverMsgs
+}
+
+// localizedEmbeds maps each language read by the given servers to its deal embed. English uses embed, which
+// matching already cleaned; other languages re-clean the post with a translated prompt and fall back to
+// the English embed if that fails.
+func localizedEmbeds(ctx context.Context, cache ConfigGetter, aiSvc AIService, post reddit.Post, cleanPrompt string, embed *discordgo.MessageEmbed, serverIDs []string) map[store.Language]*discordgo.MessageEmbed {
+	embeds := map[store.Language]*discordgo.MessageEmbed{store.LanguageEnglish: embed}
+	for _, serverID := range serverIDs {
+		if _, ok := store.DMScopeUser(serverID); ok {
+			continue
+		}
+		cfg, err := cache.GetServerConfig(ctx, serverID)
+		if err != nil {
+			continue
+		}
+		lang := store.ServerLanguage(cfg)
+		if _, ok := embeds[lang]; ok {
+			continue
+		}
+
+		embeds[lang] = embed
+		cleaned, err := aiSvc.CleanRedditPost(ctx, post.Title, post.SelfText, ai.CleanPromptForLanguage(cleanPrompt, lang))
+		if err != nil {
+			logger.Warn(ctx, "Failed to clean post for server language, using English", "reddit_id", post.ID, "language", lang, "error", err)
+			continue
+		}
+		if cleaned, ok := validateCleanedPost(ctx, post, cleaned); ok {
+			embeds[lang] = globalBuilder.BuildDealEmbed(post, cleaned)
+		}
+	}
+	return embeds
+}
+
+// dispatchToServers posts the deal to each server's feed and pings its matched users. It returns the feed
+// message ID per server, and the ID of the webhook each message went through for servers that use one.
+func dispatchToServers(ctx context.Context, db Storer, cache ConfigGetter, client DiscordMessenger, post reddit.Post, cleanedTitle string, embeds map[store.Language]*discordgo.MessageEmbed, matches map[string][]string) (map[string]string, map[string]string) {
+	serverMsgs := make(map[string]string)
+	serverWebhooks := make(map[string]string)
+
+	for serverID, userIDs := range matches {
+		channelID, cfg, err := resolveFeedChannel(ctx, cache, client, serverID)
+		if err != nil {
+			logger.Error(ctx, "Could not resolve feed channel", "server_id", serverID, "error", err)
+			continue
+		}
+		embed := serverDealEmbed(cfg, embeds[store.ServerLanguage(cfg)], post)
+
+		// Send to Feed Channel
+		msgID, err := sendFeedWithRetry(ctx, client, channelID, feedWebhookURL(cfg), embed, globalBuilder.BuildDealButtons(post.ID, post.URL, post.Author))
+		if err != nil {
+			logger.Error(ctx, "Failed to post feed to server, dead-lettering", "server_id", serverID, "error", err)
+			recordFailedDispatch(ctx, db, post, serverID, cleanedTitle, embed, userIDs, err)
+			continue
+		}
+		serverMsgs[serverID] = msgID
+		if webhookID := feedWebhookID(cfg); webhookID != "" {
+			serverWebhooks[serverID] = webhookID
+		}
+
+		// A DM is already a direct notification, so only server feeds get reactions and pings.
+		if cfg != nil {
+			announceDeal(ctx, db, cache, client, serverID, cfg, msgID, userIDs)
+		}
+	}
+	return serverMsgs, serverWebhooks
+}
+
+// serverDealEmbed returns the deal embed as a server should see it, adding the raw post preview for
+// servers that turned it on. DM scopes (nil cfg) get the embed unchanged.
+func serverDealEmbed(cfg *store.ServerConfig, embed *discordgo.MessageEmbed, post reddit.Post) *discordgo.MessageEmbed {
+	if cfg != nil && cfg.ShowRawPreview {
+		return globalBuilder.WithRawPreview(embed, post)
+	}
+	return embed
+}
+
+// resolveFeedChannel returns the channel a scope's deals are posted to. Servers use their configured
+// feed channel; DM scopes (see store.DMScope) post straight to the user's DM channel and return a nil config.
+func resolveFeedChannel(ctx context.Context, cache ConfigGetter, client DiscordMessenger, serverID string) (string, *store.ServerConfig, error) {
+	if userID, ok := store.DMScopeUser(serverID); ok {
+		channelID, err := client.CreateDM(userID)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to open DM channel: %w", err)
+		}
+		return channelID, nil, nil
+	}
+
+	cfg, err := cache.GetServerConfig(ctx, serverID)
+	if err != nil {
+		return "", nil, err
+	}
+	return cfg.FeedChannelID, cfg, nil
+}
+
+// announceDeal adds the voting reactions to a freshly posted feed message and pings the matched users.
+func announceDeal(ctx context.Context, db Storer, cache ConfigGetter, client DiscordMessenger, serverID string, cfg *store.ServerConfig, msgID string, userIDs []string) {
+	addReaction(ctx, client, cfg.FeedChannelID, msgID, "%F0%9F%91%8D") // Thumbs up
+	addReaction(ctx, client, cfg.FeedChannelID, msgID, "%F0%9F%91%8E") // Thumbs down
+
+	// Send deduped Ping to Ping Channel
+	link := discord.BuildMessageLink(serverID, cfg.FeedChannelID, msgID)
+	notifyUsers(ctx, db, cache, client, serverID, cfg.PingChannelID, link, userIDs, "**Match Found in the Deal Feed!**")
 }
 
 func safeContains(corpus, substring string) bool {
-	return globalMatcher.containsWord(corpus, substring)
+	return globalMatcher.ContainsWord(corpus, substring)
 }
@@ -2,8 +2,12 @@ package processor
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/bwmarrin/discordgo"
 	"github.com/pauljones0/betterHardwareSwap/internal/ai"
 	"github.com/pauljones0/betterHardwareSwap/internal/reddit"
 	"github.com/pauljones0/betterHardwareSwap/internal/store"
@@ -35,12 +39,13 @@ func TestProcessNewPost_TableDriven(t *testing.T) {
 			serverConfig: &store.ServerConfig{FeedChannelID: "feed1", PingChannelID: "ping1"},
 			expectMatch:  true,
 			setupMocks: func(mDB *testutils.MockStore, mAI *testutils.MockAI, mD *testutils.MockDiscord) {
-				mAI.On("CleanRedditPost", mock.Anything, "[H] RTX 3080 [W] $500", "Desc").Return(&ai.CleanedPost{Title: "RTX 3080"}, nil)
+				mAI.On("CleanRedditPost", mock.Anything, "[H] RTX 3080 [W] $500", "Desc", mock.Anything).Return(&ai.CleanedPost{Title: "RTX 3080"}, nil)
 				mDB.On("GetServerConfig", mock.Anything, "guild1").Return(&store.ServerConfig{FeedChannelID: "feed1", PingChannelID: "ping1"}, nil)
 				mD.On("SendEmbedWithComponents", "feed1", "", mock.Anything, mock.Anything).Return("msg123", nil)
 				mD.On("AddReaction", "feed1", "msg123", mock.Anything).Return(nil).Times(2)
+				mDB.On("GetUserSettings", mock.Anything, mock.Anything).Return(&store.UserSettings{HasMatched: true}, nil)
 				mD.On("SendMessage", "ping1", mock.Anything).Return(nil)
-				mDB.On("SavePostRecords", mock.Anything, "t3_match", "RTX 3080", map[string]string{"guild1": "msg123"}).Return(nil)
+				mDB.On("SavePostRecords", mock.Anything, "t3_match", "RTX 3080", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, map[string]string{"guild1": "msg123"}, mock.Anything).Return(nil)
 			},
 		},
 		{
@@ -54,7 +59,7 @@ func TestProcessNewPost_TableDriven(t *testing.T) {
 			},
 			expectMatch: false,
 			setupMocks: func(mDB *testutils.MockStore, mAI *testutils.MockAI, mD *testutils.MockDiscord) {
-				mAI.On("CleanRedditPost", mock.Anything, "Something else", "Desc").Return(&ai.CleanedPost{Title: "Something else"}, nil)
+				mAI.On("CleanRedditPost", mock.Anything, "Something else", "Desc", mock.Anything).Return(&ai.CleanedPost{Title: "Something else"}, nil)
 				// AssertNotCalled expectations are handled at the end
 			},
 		},
@@ -70,7 +75,7 @@ func TestProcessNewPost_TableDriven(t *testing.T) {
 				tt.setupMocks(mockDB, mockAI, mockDiscord)
 			}
 
-			processNewPost(ctx, mockDB, mockDB, mockAI, mockDiscord, tt.post, tt.alerts)
+			processNewPost(ctx, mockDB, mockDB, mockAI, mockDiscord, tt.post, tt.alerts, nil, nil, 0, "")
 
 			mockAI.AssertExpectations(t)
 			mockDB.AssertExpectations(t)
@@ -78,8 +83,570 @@ func TestProcessNewPost_TableDriven(t *testing.T) {
 
 			if !tt.expectMatch {
 				mockDiscord.AssertNotCalled(t, "SendEmbedWithComponents", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
-				mockDB.AssertNotCalled(t, "SavePostRecords", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+				mockDB.AssertNotCalled(t, "SavePostRecords", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
 			}
 		})
 	}
 }
+
+func TestProcessNewPost_FeedMode(t *testing.T) {
+	ctx := context.Background()
+	post := reddit.Post{ID: "t3_unmatched", Title: "[H] Mechanical Keyboard [W] $80", SelfText: "Desc"}
+	alerts := []store.AlertRule{
+		{ServerID: "guild1", UserID: "user1", MustHave: []string{"3080"}},
+	}
+
+	t.Run("AllDeals posts unmatched deals without pinging", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+		mockAI := new(testutils.MockAI)
+		mockDiscord := new(testutils.MockDiscord)
+
+		servers := []store.ServerConfig{{ServerID: "guild1", FeedChannelID: "feed1", PingChannelID: "ping1", FeedMode: store.FeedModeAllDeals}}
+
+		mockAI.On("CleanRedditPost", mock.Anything, post.Title, post.SelfText, mock.Anything).Return(&ai.CleanedPost{Title: "Mechanical Keyboard"}, nil)
+		mockDB.On("GetServerConfig", mock.Anything, "guild1").Return(&servers[0], nil)
+		mockDiscord.On("SendEmbedWithComponents", "feed1", "", mock.Anything, mock.Anything).Return("msg1", nil)
+		mockDiscord.On("AddReaction", "feed1", "msg1", mock.Anything).Return(nil).Times(2)
+		mockDB.On("SavePostRecords", mock.Anything, "t3_unmatched", "Mechanical Keyboard", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, map[string]string{"guild1": "msg1"}, mock.Anything).Return(nil)
+
+		processNewPost(ctx, mockDB, mockDB, mockAI, mockDiscord, post, alerts, servers, nil, 0, "")
+
+		mockDiscord.AssertExpectations(t)
+		mockDB.AssertExpectations(t)
+		mockDiscord.AssertNotCalled(t, "SendMessage", mock.Anything, mock.Anything)
+	})
+
+	t.Run("AlertsOnly skips unmatched deals", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+		mockAI := new(testutils.MockAI)
+		mockDiscord := new(testutils.MockDiscord)
+
+		servers := []store.ServerConfig{{ServerID: "guild1", FeedChannelID: "feed1", PingChannelID: "ping1", FeedMode: store.FeedModeAlertsOnly}}
+
+		mockAI.On("CleanRedditPost", mock.Anything, post.Title, post.SelfText, mock.Anything).Return(&ai.CleanedPost{Title: "Mechanical Keyboard"}, nil)
+
+		processNewPost(ctx, mockDB, mockDB, mockAI, mockDiscord, post, alerts, servers, nil, 0, "")
+
+		mockDiscord.AssertNotCalled(t, "SendEmbedWithComponents", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockDB.AssertNotCalled(t, "SavePostRecords", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestProcessNewPost_FrenchServer(t *testing.T) {
+	ctx := context.Background()
+	post := reddit.Post{ID: "t3_fr", Title: "[H] RTX 3080 [W] $500", SelfText: "Desc"}
+	servers := []store.ServerConfig{
+		{ServerID: "guild_en", FeedChannelID: "feed_en", PingChannelID: "ping_en", FeedMode: store.FeedModeAllDeals},
+		{ServerID: "guild_fr", FeedChannelID: "feed_fr", PingChannelID: "ping_fr", FeedMode: store.FeedModeAllDeals, Language: store.LanguageFrench},
+	}
+
+	mockDB := new(testutils.MockStore)
+	mockAI := new(testutils.MockAI)
+	mockDiscord := new(testutils.MockDiscord)
+
+	frenchPrompt := ai.CleanPromptForLanguage("", store.LanguageFrench)
+	mockAI.On("CleanRedditPost", mock.Anything, post.Title, post.SelfText, "").Return(&ai.CleanedPost{Title: "RTX 3080"}, nil)
+	mockAI.On("CleanRedditPost", mock.Anything, post.Title, post.SelfText, frenchPrompt).Return(&ai.CleanedPost{Title: "RTX 3080 (français)"}, nil).Once()
+	mockDB.On("GetServerConfig", mock.Anything, "guild_en").Return(&servers[0], nil)
+	mockDB.On("GetServerConfig", mock.Anything, "guild_fr").Return(&servers[1], nil)
+	mockDiscord.On("SendEmbedWithComponents", "feed_en", "", mock.MatchedBy(func(e *discordgo.MessageEmbed) bool {
+		return e.Title == "🎮 RTX 3080"
+	}), mock.Anything).Return("msg_en", nil)
+	mockDiscord.On("SendEmbedWithComponents", "feed_fr", "", mock.MatchedBy(func(e *discordgo.MessageEmbed) bool {
+		return e.Title == "🎮 RTX 3080 (français)"
+	}), mock.Anything).Return("msg_fr", nil)
+	mockDiscord.On("AddReaction", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	mockDB.On("SavePostRecords", mock.Anything, "t3_fr", "RTX 3080", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, map[string]string{"guild_en": "msg_en", "guild_fr": "msg_fr"}, mock.Anything).Return(nil)
+
+	processNewPost(ctx, mockDB, mockDB, mockAI, mockDiscord, post, nil, servers, nil, 0, "")
+
+	mockAI.AssertExpectations(t)
+	mockDiscord.AssertExpectations(t)
+	mockDB.AssertExpectations(t)
+}
+
+func TestProcessNewPost_Blocklist(t *testing.T) {
+	ctx := context.Background()
+	post := reddit.Post{ID: "t3_spam", Title: "[H] RTX 3080 Crypto Mining Rig [W] $900", SelfText: "Desc"}
+	alerts := []store.AlertRule{
+		{ID: "alert1", ServerID: "guild1", UserID: "user1", MustHave: []string{"3080"}},
+		{ID: "alert2", ServerID: "guild2", UserID: "user2", MustHave: []string{"3080"}},
+	}
+
+	mockDB := new(testutils.MockStore)
+	mockAI := new(testutils.MockAI)
+	mockDiscord := new(testutils.MockDiscord)
+
+	blocked := &store.ServerConfig{ServerID: "guild1", FeedChannelID: "feed1", PingChannelID: "ping1", GlobalMustNot: []string{"mining rig"}}
+	open := &store.ServerConfig{ServerID: "guild2", FeedChannelID: "feed2", PingChannelID: "ping2"}
+
+	mockAI.On("CleanRedditPost", mock.Anything, post.Title, post.SelfText, mock.Anything).Return(&ai.CleanedPost{Title: "RTX 3080 Crypto Mining Rig"}, nil)
+	mockDB.On("GetServerConfig", mock.Anything, "guild1").Return(blocked, nil)
+	mockDB.On("GetServerConfig", mock.Anything, "guild2").Return(open, nil)
+	mockDiscord.On("SendEmbedWithComponents", "feed2", "", mock.Anything, mock.Anything).Return("msg2", nil)
+	mockDiscord.On("AddReaction", "feed2", "msg2", mock.Anything).Return(nil).Times(2)
+	mockDB.On("GetUserSettings", mock.Anything, mock.Anything).Return(&store.UserSettings{HasMatched: true}, nil)
+	mockDiscord.On("SendMessage", "ping2", mock.Anything).Return(nil)
+	// Only the alert whose server received the post counts as a match.
+	mockDB.On("IncrementAlertMatches", mock.Anything, []string{"alert2"}).Return(nil)
+	mockDB.On("SavePostRecords", mock.Anything, "t3_spam", "RTX 3080 Crypto Mining Rig", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, map[string]string{"guild2": "msg2"}, mock.Anything).Return(nil)
+
+	processNewPost(ctx, mockDB, mockDB, mockAI, mockDiscord, post, alerts, nil, nil, 0, "")
+
+	mockDiscord.AssertExpectations(t)
+	mockDB.AssertExpectations(t)
+	mockDiscord.AssertNotCalled(t, "SendEmbedWithComponents", "feed1", mock.Anything, mock.Anything, mock.Anything)
+	mockDiscord.AssertNotCalled(t, "SendMessage", "ping1", mock.Anything)
+}
+
+func TestProcessNewPost_MutedAuthor(t *testing.T) {
+	ctx := context.Background()
+	post := reddit.Post{ID: "t3_muted", Title: "[H] RTX 3080 [W] $500", SelfText: "Desc", Author: "Spammer99"}
+	alerts := []store.AlertRule{
+		{ID: "alert1", ServerID: "guild1", UserID: "user1", MustHave: []string{"3080"}},
+		{ID: "alert2", ServerID: "guild1", UserID: "user2", MustHave: []string{"3080"}},
+		{ID: "alert3", ServerID: "guild2", UserID: "user1", MustHave: []string{"3080"}},
+	}
+
+	mockDB := new(testutils.MockStore)
+	mockAI := new(testutils.MockAI)
+	mockDiscord := new(testutils.MockDiscord)
+
+	mockAI.On("CleanRedditPost", mock.Anything, post.Title, post.SelfText, mock.Anything).Return(&ai.CleanedPost{Title: "RTX 3080"}, nil)
+	mockDB.On("GetUserSettings", mock.Anything, "user1").Return(&store.UserSettings{MutedAuthors: []string{"spammer99"}}, nil)
+	mockDB.On("GetUserSettings", mock.Anything, "user2").Return(&store.UserSettings{HasMatched: true}, nil)
+	mockDB.On("GetServerConfig", mock.Anything, "guild1").Return(&store.ServerConfig{FeedChannelID: "feed1", PingChannelID: "ping1"}, nil)
+	mockDiscord.On("SendEmbedWithComponents", "feed1", "", mock.Anything, mock.Anything).Return("msg1", nil)
+	mockDiscord.On("AddReaction", "feed1", "msg1", mock.Anything).Return(nil).Times(2)
+	mockDiscord.On("SendMessage", "ping1", mock.MatchedBy(func(content string) bool {
+		return strings.Contains(content, "<@user2>") && !strings.Contains(content, "<@user1>")
+	})).Return(nil)
+	// Only user2's alert counts as a match; guild2 had no one else to notify, so it gets nothing.
+	mockDB.On("IncrementAlertMatches", mock.Anything, []string{"alert2"}).Return(nil)
+	mockDB.On("SavePostRecords", mock.Anything, "t3_muted", "RTX 3080", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, map[string]string{"guild1": "msg1"}, mock.Anything).Return(nil)
+
+	matched, err := processNewPost(ctx, mockDB, mockDB, mockAI, mockDiscord, post, alerts, nil, nil, 0, "")
+
+	if err != nil || matched != 1 {
+		t.Errorf("expected one matched user, got %d, %v", matched, err)
+	}
+	mockDiscord.AssertExpectations(t)
+	mockDB.AssertExpectations(t)
+	mockDB.AssertNotCalled(t, "GetServerConfig", mock.Anything, "guild2")
+}
+
+func TestProcessNewPost_MinScore(t *testing.T) {
+	ctx := context.Background()
+	post := reddit.Post{ID: "t3_fresh", Title: "[H] RTX 3080 [W] $500", SelfText: "Desc", Score: 1}
+	alerts := []store.AlertRule{
+		{ID: "alert1", ServerID: "guild1", UserID: "user1", MustHave: []string{"4090"}},
+		{ID: "alert2", ServerID: "guild2", UserID: "user2", MustHave: []string{"3080"}},
+	}
+	servers := []store.ServerConfig{
+		{ServerID: "guild1", FeedChannelID: "feed1", PingChannelID: "ping1", FeedMode: store.FeedModeAllDeals, MinScore: 5},
+		{ServerID: "guild2", FeedChannelID: "feed2", PingChannelID: "ping2", FeedMode: store.FeedModeAllDeals, MinScore: 5},
+	}
+
+	mockDB := new(testutils.MockStore)
+	mockAI := new(testutils.MockAI)
+	mockDiscord := new(testutils.MockDiscord)
+
+	mockAI.On("CleanRedditPost", mock.Anything, post.Title, post.SelfText, mock.Anything).Return(&ai.CleanedPost{Title: "RTX 3080"}, nil)
+	mockDB.On("GetServerConfig", mock.Anything, "guild1").Return(&servers[0], nil)
+	mockDB.On("GetServerConfig", mock.Anything, "guild2").Return(&servers[1], nil)
+	// guild2's alert matched, so the low score doesn't stop the post or the ping there.
+	mockDiscord.On("SendEmbedWithComponents", "feed2", "", mock.Anything, mock.Anything).Return("msg2", nil)
+	mockDiscord.On("AddReaction", "feed2", "msg2", mock.Anything).Return(nil).Times(2)
+	mockDB.On("GetUserSettings", mock.Anything, mock.Anything).Return(&store.UserSettings{HasMatched: true}, nil)
+	mockDiscord.On("SendMessage", "ping2", mock.Anything).Return(nil)
+	mockDB.On("IncrementAlertMatches", mock.Anything, []string{"alert2"}).Return(nil)
+	mockDB.On("SavePostRecords", mock.Anything, "t3_fresh", "RTX 3080", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, map[string]string{"guild2": "msg2"}, mock.Anything).Return(nil)
+
+	processNewPost(ctx, mockDB, mockDB, mockAI, mockDiscord, post, alerts, servers, nil, 0, "")
+
+	mockDiscord.AssertExpectations(t)
+	mockDB.AssertExpectations(t)
+	mockDiscord.AssertNotCalled(t, "SendEmbedWithComponents", "feed1", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestProcessNewPost_NSFW(t *testing.T) {
+	ctx := context.Background()
+	var post reddit.Post
+	if err := testutils.LoadFixture("reddit_post_nsfw.json", &post); err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+	if !post.Over18 {
+		t.Fatal("expected the fixture to parse as over_18")
+	}
+	alerts := []store.AlertRule{
+		{ID: "alert1", ServerID: "guild1", UserID: "user1", MustHave: []string{"3080"}},
+		{ID: "alert2", ServerID: "guild2", UserID: "user2", MustHave: []string{"3080"}},
+		{ID: "alert3", ServerID: store.DMScope("user3"), UserID: "user3", MustHave: []string{"3080"}},
+	}
+
+	mockDB := new(testutils.MockStore)
+	mockAI := new(testutils.MockAI)
+	mockDiscord := new(testutils.MockDiscord)
+
+	suppressed := &store.ServerConfig{ServerID: "guild1", FeedChannelID: "feed1", PingChannelID: "ping1"}
+	optedIn := &store.ServerConfig{ServerID: "guild2", FeedChannelID: "feed2", PingChannelID: "ping2", AllowNSFW: true}
+
+	mockAI.On("CleanRedditPost", mock.Anything, post.Title, post.SelfText, mock.Anything).Return(&ai.CleanedPost{Title: "RTX 3080 FE"}, nil)
+	mockDB.On("GetServerConfig", mock.Anything, "guild1").Return(suppressed, nil)
+	mockDB.On("GetServerConfig", mock.Anything, "guild2").Return(optedIn, nil)
+	mockDiscord.On("SendEmbedWithComponents", "feed2", "", mock.Anything, mock.Anything).Return("msg2", nil)
+	mockDiscord.On("AddReaction", "feed2", "msg2", mock.Anything).Return(nil).Times(2)
+	mockDB.On("GetUserSettings", mock.Anything, mock.Anything).Return(&store.UserSettings{HasMatched: true}, nil)
+	mockDiscord.On("SendMessage", "ping2", mock.Anything).Return(nil)
+	mockDB.On("IncrementAlertMatches", mock.Anything, []string{"alert2"}).Return(nil)
+	mockDB.On("SavePostRecords", mock.Anything, post.ID, "RTX 3080 FE", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, map[string]string{"guild2": "msg2"}, mock.Anything).Return(nil)
+
+	processNewPost(ctx, mockDB, mockDB, mockAI, mockDiscord, post, alerts, nil, nil, 0, "")
+
+	mockDiscord.AssertExpectations(t)
+	mockDB.AssertExpectations(t)
+	mockDiscord.AssertNotCalled(t, "SendEmbedWithComponents", "feed1", mock.Anything, mock.Anything, mock.Anything)
+	mockDiscord.AssertNotCalled(t, "CreateDM", mock.Anything)
+}
+
+func TestProcessNewPost_OverlappingAlertsPingOnce(t *testing.T) {
+	ctx := context.Background()
+	post := reddit.Post{ID: "t3_overlap", Title: "[H] RTX 3080 [W] $500", SelfText: "Desc"}
+	alerts := []store.AlertRule{
+		{ID: "alert1", ServerID: "guild1", UserID: "user1", MustHave: []string{"3080"}},
+		{ID: "alert2", ServerID: "guild1", UserID: "user1", MustHave: []string{"rtx"}},
+	}
+	cfg := &store.ServerConfig{ServerID: "guild1", FeedChannelID: "feed1", PingChannelID: "ping1"}
+
+	mockDB := new(testutils.MockStore)
+	mockAI := new(testutils.MockAI)
+	mockDiscord := new(testutils.MockDiscord)
+
+	mockAI.On("CleanRedditPost", mock.Anything, post.Title, post.SelfText, mock.Anything).Return(&ai.CleanedPost{Title: "RTX 3080"}, nil)
+	mockDB.On("GetServerConfig", mock.Anything, "guild1").Return(cfg, nil)
+	mockDiscord.On("SendEmbedWithComponents", "feed1", "", mock.Anything, mock.Anything).Return("msg1", nil)
+	mockDiscord.On("AddReaction", "feed1", "msg1", mock.Anything).Return(nil).Times(2)
+	mockDB.On("GetUserSettings", mock.Anything, "user1").Return(&store.UserSettings{UserID: "user1", HasMatched: true}, nil)
+	mockDiscord.On("SendMessage", "ping1", mock.MatchedBy(func(content string) bool {
+		return strings.Count(content, "<@user1>") == 1
+	})).Return(nil).Once()
+	// Both alerts still count towards /stats.
+	mockDB.On("IncrementAlertMatches", mock.Anything, []string{"alert1", "alert2"}).Return(nil)
+	mockDB.On("SavePostRecords", mock.Anything, "t3_overlap", "RTX 3080", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, map[string]string{"guild1": "msg1"}, mock.Anything).Return(nil)
+
+	processNewPost(ctx, mockDB, mockDB, mockAI, mockDiscord, post, alerts, nil, nil, 0, "")
+
+	mockDiscord.AssertExpectations(t)
+	mockDB.AssertExpectations(t)
+}
+
+func TestProcessNewPost_OldPostFeedsWithoutPinging(t *testing.T) {
+	ctx := context.Background()
+	listed := time.Now().Add(-5 * time.Hour)
+	post := reddit.Post{ID: "t3_backfill", Title: "[H] RTX 3080 [W] $500", SelfText: "Desc", CreatedUtc: float64(listed.Unix())}
+	alerts := []store.AlertRule{
+		{ID: "alert1", ServerID: "guild1", UserID: "user1", MustHave: []string{"3080"}},
+		{ID: "alert2", ServerID: store.DMScope("user2"), UserID: "user2", MustHave: []string{"3080"}},
+	}
+	cfg := &store.ServerConfig{ServerID: "guild1", FeedChannelID: "feed1", PingChannelID: "ping1"}
+
+	mockDB := new(testutils.MockStore)
+	mockAI := new(testutils.MockAI)
+	mockDiscord := new(testutils.MockDiscord)
+
+	mockAI.On("CleanRedditPost", mock.Anything, post.Title, post.SelfText, mock.Anything).Return(&ai.CleanedPost{Title: "RTX 3080"}, nil)
+	mockDB.On("GetServerConfig", mock.Anything, "guild1").Return(cfg, nil)
+	mockDiscord.On("SendEmbedWithComponents", "feed1", "", mock.Anything, mock.Anything).Return("msg1", nil)
+	mockDiscord.On("AddReaction", "feed1", "msg1", mock.Anything).Return(nil).Times(2)
+	mockDB.On("IncrementAlertMatches", mock.Anything, mock.Anything).Return(nil)
+	mockDB.On("SavePostRecords", mock.Anything, "t3_backfill", "RTX 3080", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, map[string]string{"guild1": "msg1"}, mock.Anything).Return(nil)
+
+	processNewPost(ctx, mockDB, mockDB, mockAI, mockDiscord, post, alerts, nil, nil, 3*time.Hour, "")
+
+	mockDiscord.AssertExpectations(t)
+	mockDB.AssertExpectations(t)
+	mockDiscord.AssertNotCalled(t, "SendMessage", mock.Anything, mock.Anything)
+	mockDiscord.AssertNotCalled(t, "CreateDM", mock.Anything)
+}
+
+func TestPostTooOldToPing(t *testing.T) {
+	now := time.Now()
+	hourOld := reddit.Post{CreatedUtc: float64(now.Add(-time.Hour).Unix())}
+
+	if postTooOldToPing(hourOld, 3*time.Hour, now) {
+		t.Error("expected a post inside the limit to ping")
+	}
+	if !postTooOldToPing(hourOld, 30*time.Minute, now) {
+		t.Error("expected a post past the limit not to ping")
+	}
+	if postTooOldToPing(hourOld, 0, now) {
+		t.Error("expected a zero limit to disable the check")
+	}
+	if postTooOldToPing(reddit.Post{}, time.Minute, now) {
+		t.Error("expected a post without a creation time to ping")
+	}
+}
+
+func TestProcessNewPost_QuietHours(t *testing.T) {
+	ctx := context.Background()
+	post := reddit.Post{ID: "t3_night", Title: "[H] RTX 3080 [W] $500", SelfText: "Desc"}
+	alerts := []store.AlertRule{
+		{ID: "alert1", ServerID: "guild1", UserID: "sleeper", MustHave: []string{"3080"}},
+		{ID: "alert2", ServerID: "guild1", UserID: "night_owl", MustHave: []string{"3080"}},
+	}
+	cfg := &store.ServerConfig{ServerID: "guild1", FeedChannelID: "feed1", PingChannelID: "ping1"}
+
+	// A window around the current hour, so the test is always inside it.
+	hour := time.Now().UTC().Hour()
+	sleeping := &store.UserSettings{UserID: "sleeper", QuietEnabled: true, QuietStart: hour, QuietEnd: (hour + 2) % 24}
+
+	mockDB := new(testutils.MockStore)
+	mockAI := new(testutils.MockAI)
+	mockDiscord := new(testutils.MockDiscord)
+
+	mockAI.On("CleanRedditPost", mock.Anything, post.Title, post.SelfText, mock.Anything).Return(&ai.CleanedPost{Title: "RTX 3080"}, nil)
+	mockDB.On("GetServerConfig", mock.Anything, "guild1").Return(cfg, nil)
+	mockDiscord.On("SendEmbedWithComponents", "feed1", "", mock.Anything, mock.Anything).Return("msg1", nil)
+	mockDiscord.On("AddReaction", "feed1", "msg1", mock.Anything).Return(nil).Times(2)
+	mockDB.On("GetUserSettings", mock.Anything, "sleeper").Return(sleeping, nil)
+	mockDB.On("GetUserSettings", mock.Anything, "night_owl").Return(&store.UserSettings{UserID: "night_owl", HasMatched: true}, nil)
+	mockDB.On("QueuePing", mock.Anything, mock.MatchedBy(func(p store.QueuedPing) bool {
+		return p.UserID == "sleeper" && p.PingChannelID == "ping1" &&
+			p.MessageLink == "https://discord.com/channels/guild1/feed1/msg1" && p.DeliverAt.After(time.Now())
+	})).Return(nil)
+	mockDiscord.On("SendMessage", "ping1", mock.MatchedBy(func(content string) bool {
+		return strings.Contains(content, "<@night_owl>") && !strings.Contains(content, "sleeper")
+	})).Return(nil)
+	mockDB.On("IncrementAlertMatches", mock.Anything, []string{"alert1", "alert2"}).Return(nil)
+	mockDB.On("SavePostRecords", mock.Anything, "t3_night", "RTX 3080", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, map[string]string{"guild1": "msg1"}, mock.Anything).Return(nil)
+
+	processNewPost(ctx, mockDB, mockDB, mockAI, mockDiscord, post, alerts, nil, nil, 0, "")
+
+	mockDiscord.AssertExpectations(t)
+	mockDB.AssertExpectations(t)
+}
+
+func TestFindMatches_SearchBody(t *testing.T) {
+	ctx := context.Background()
+	corpus := "RTX 3080 FE Toronto"
+	body := "Comes bundled with an EKWB waterblock, serial ABC123."
+
+	alerts := []store.AlertRule{
+		{ServerID: "guild1", UserID: "summary_only", MustHave: []string{"waterblock"}},
+		{ServerID: "guild1", UserID: "full_post", MustHave: []string{"waterblock"}, SearchBody: true},
+	}
+
+	matches := findMatches(ctx, alerts, corpus, body, false, time.Now())
+
+	if len(matches["guild1"]) != 1 || matches["guild1"][0] != "full_post" {
+		t.Errorf("expected only the body-searching alert to match, got %v", matches["guild1"])
+	}
+}
+
+func TestFindMatches_Snoozed(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	alerts := []store.AlertRule{
+		{ServerID: "guild1", UserID: "user1", MustHave: []string{"3080"}, SnoozeUntil: now.Add(7 * 24 * time.Hour)},
+	}
+
+	if matches := findMatches(ctx, alerts, "RTX 3080 FE", "", false, now); len(matches) != 0 {
+		t.Errorf("expected a snoozed alert not to match, got %v", matches)
+	}
+	if matches := findMatches(ctx, alerts, "RTX 3080 FE", "", false, now.Add(7*24*time.Hour)); len(matches["guild1"]) != 1 {
+		t.Errorf("expected the alert to match once the snooze expired, got %v", matches)
+	}
+}
+
+func TestFindMatches_Paused(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	alerts := []store.AlertRule{
+		{ServerID: "guild1", UserID: "user1", MustHave: []string{"3080"}, Paused: true},
+		{ServerID: "guild1", UserID: "user1", AnyOf: []string{"rtx"}, Paused: true},
+		{ServerID: "guild1", UserID: "user2", MustHave: []string{"3080"}},
+	}
+
+	matches := findMatches(ctx, alerts, "RTX 3080 FE", "", false, now)
+	if got := matches["guild1"]; len(got) != 1 || got[0] != "user2" {
+		t.Errorf("expected only the unpaused user to match, got %v", matches)
+	}
+}
+
+func TestFindMatches_ExcludeBundles(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	alerts := []store.AlertRule{
+		{ServerID: "guild1", UserID: "user1", MustHave: []string{"3080"}, ExcludeBundles: true},
+		{ServerID: "guild1", UserID: "user2", MustHave: []string{"3080"}},
+	}
+
+	matches := findMatches(ctx, alerts, "RTX 3080 + full PC bundle", "", true, now)
+	if got := matches["guild1"]; len(got) != 1 || got[0] != "user2" {
+		t.Errorf("expected only the alert that allows bundles to match a bundle, got %v", matches)
+	}
+	if matches := findMatches(ctx, alerts, "RTX 3080 FE", "", false, now); len(matches["guild1"]) != 2 {
+		t.Errorf("expected both alerts to match a single item, got %v", matches)
+	}
+}
+
+// BenchmarkProcessNewPost_Matching measures the alert matching step of processNewPost as the alert count grows.
+func BenchmarkProcessNewPost_Matching(b *testing.B) {
+	ctx := context.Background()
+	corpus := "EVGA RTX 3080 FTW3 Ultra 10GB Card is in great shape, never mined on. Toronto, ON"
+	body := "Selling my RTX 3080 after upgrading. Comes with original box and anti-sag bracket."
+
+	models := []string{"3060", "3070", "3080", "3090", "4070", "4080", "4090", "6800 xt", "7900 xtx", "a770"}
+	cities := []string{"toronto", "ottawa", "vancouver", "calgary", "montreal"}
+
+	for _, n := range []int{100, 1000, 10000} {
+		alerts := make([]store.AlertRule, n)
+		for i := range alerts {
+			alerts[i] = store.AlertRule{
+				ServerID:   fmt.Sprintf("guild%d", i%50),
+				UserID:     fmt.Sprintf("user%d", i),
+				MustHave:   []string{models[i%len(models)]},
+				AnyOf:      []string{cities[i%len(cities)], "gta"},
+				MustNot:    []string{"broken"},
+				SearchBody: i%4 == 0,
+			}
+		}
+
+		b.Run(fmt.Sprintf("Alerts=%d", n), func(b *testing.B) {
+			b.ReportAllocs()
+			for b.Loop() {
+				findMatches(ctx, alerts, corpus, body, false, time.Now())
+			}
+		})
+	}
+}
+
+func TestProcessNewPost_DMScope(t *testing.T) {
+	ctx := context.Background()
+	post := reddit.Post{ID: "t3_dm", Title: "[H] RTX 3080 [W] $500", SelfText: "Desc"}
+	alerts := []store.AlertRule{
+		{ServerID: store.DMScope("user1"), UserID: "user1", MustHave: []string{"3080"}},
+	}
+
+	mockDB := new(testutils.MockStore)
+	mockAI := new(testutils.MockAI)
+	mockDiscord := new(testutils.MockDiscord)
+
+	mockAI.On("CleanRedditPost", mock.Anything, post.Title, post.SelfText, mock.Anything).Return(&ai.CleanedPost{Title: "RTX 3080"}, nil)
+	mockDiscord.On("CreateDM", "user1").Return("dmchan1", nil)
+	mockDiscord.On("SendEmbedWithComponents", "dmchan1", "", mock.Anything, mock.Anything).Return("msg1", nil)
+	mockDB.On("SavePostRecords", mock.Anything, "t3_dm", "RTX 3080", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, map[string]string{"dm:user1": "msg1"}, mock.Anything).Return(nil)
+
+	processNewPost(ctx, mockDB, mockDB, mockAI, mockDiscord, post, alerts, nil, nil, 0, "")
+
+	mockDiscord.AssertExpectations(t)
+	mockDB.AssertExpectations(t)
+	mockDB.AssertNotCalled(t, "GetServerConfig", mock.Anything, mock.Anything)
+	mockDiscord.AssertNotCalled(t, "AddReaction", mock.Anything, mock.Anything, mock.Anything)
+	mockDiscord.AssertNotCalled(t, "SendMessage", mock.Anything, mock.Anything)
+}
+
+func TestProcessNewPost_FirstMatchWelcome(t *testing.T) {
+	ctx := context.Background()
+	alerts := []store.AlertRule{
+		{ID: "alert1", ServerID: "guild1", UserID: "user1", RawQuery: "rtx 3080", MustHave: []string{"3080"}},
+	}
+	cfg := &store.ServerConfig{ServerID: "guild1", FeedChannelID: "feed1", PingChannelID: "ping1"}
+
+	mockDB := new(testutils.MockStore)
+	mockAI := new(testutils.MockAI)
+	mockDiscord := new(testutils.MockDiscord)
+
+	mockAI.On("CleanRedditPost", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(&ai.CleanedPost{Title: "RTX 3080"}, nil)
+	mockDB.On("GetServerConfig", mock.Anything, "guild1").Return(cfg, nil)
+	mockDiscord.On("SendEmbedWithComponents", "feed1", "", mock.Anything, mock.Anything).Return("msg1", nil)
+	mockDiscord.On("AddReaction", "feed1", "msg1", mock.Anything).Return(nil)
+	// The cached settings still predate the first match when the second post arrives.
+	mockDB.On("GetUserSettings", mock.Anything, "user1").Return(&store.UserSettings{UserID: "user1"}, nil)
+	mockDiscord.On("SendMessage", "ping1", mock.Anything).Return(nil)
+	mockDB.On("IncrementAlertMatches", mock.Anything, []string{"alert1"}).Return(nil)
+	mockDB.On("SavePostRecords", mock.Anything, mock.Anything, "RTX 3080", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, map[string]string{"guild1": "msg1"}, mock.Anything).Return(nil)
+	mockDB.On("MarkUserMatched", mock.Anything, "user1").Return(true, nil).Once()
+	mockDB.On("MarkUserMatched", mock.Anything, "user1").Return(false, nil)
+	mockDiscord.On("CreateDM", "user1").Return("dm1", nil).Once()
+	mockDiscord.On("SendMessage", "dm1", "🎉 Your alert \"rtx 3080\" just matched its first deal! You'll be pinged like this whenever a new post matches. "+
+		"Manage your alerts with `/alert list`.").Return(nil).Once()
+
+	for _, id := range []string{"t3_first", "t3_second", "t3_third"} {
+		post := reddit.Post{ID: id, Title: "[H] RTX 3080 [W] $500", SelfText: "Desc"}
+		processNewPost(ctx, mockDB, mockDB, mockAI, mockDiscord, post, alerts, nil, nil, 0, "")
+	}
+
+	mockDiscord.AssertExpectations(t)
+	mockDB.AssertExpectations(t)
+	mockDiscord.AssertNumberOfCalls(t, "CreateDM", 1)
+}
+
+func TestWelcomeFirstMatches_Skips(t *testing.T) {
+	ctx := context.Background()
+	hour := time.Now().UTC().Hour()
+	settings := map[string]*store.UserSettings{
+		"welcomed": {UserID: "welcomed", HasMatched: true},
+		"sleeper":  {UserID: "sleeper", QuietEnabled: true, QuietStart: hour, QuietEnd: (hour + 2) % 24},
+		"veteran":  {UserID: "veteran"},
+	}
+	matched := []store.AlertRule{
+		{ServerID: "guild1", UserID: "welcomed"},
+		{ServerID: "guild1", UserID: "sleeper"},
+		{ServerID: "guild1", UserID: "veteran", MatchCount: 12},
+		{ServerID: store.DMScope("dm_user"), UserID: "dm_user"},
+		{ServerID: "guild2", UserID: "unsent"},
+	}
+	recipients := map[string][]string{
+		"guild1":                 {"welcomed", "sleeper", "veteran"},
+		store.DMScope("dm_user"): {"dm_user"},
+		"guild2":                 {"unsent"},
+	}
+	// guild2's feed post failed, so unsent wasn't notified.
+	serverMsgs := map[string]string{"guild1": "msg1", store.DMScope("dm_user"): "msg2"}
+
+	mockDB := new(testutils.MockStore)
+	mockDiscord := new(testutils.MockDiscord)
+	for uid, s := range settings {
+		mockDB.On("GetUserSettings", mock.Anything, uid).Return(s, nil)
+	}
+	// A user whose alert matched before the flag existed is marked, but not welcomed.
+	mockDB.On("MarkUserMatched", mock.Anything, "veteran").Return(true, nil)
+
+	welcomeFirstMatches(ctx, mockDB, mockDB, mockDiscord, matched, recipients, serverMsgs)
+
+	mockDB.AssertExpectations(t)
+	mockDB.AssertNumberOfCalls(t, "MarkUserMatched", 1)
+	mockDiscord.AssertNotCalled(t, "CreateDM", mock.Anything)
+	mockDiscord.AssertNotCalled(t, "SendMessage", mock.Anything, mock.Anything)
+}
+
+func TestRunPipeline_DropsBlocklistedAuthor(t *testing.T) {
+	ctx := context.Background()
+	post := reddit.Post{ID: "t3_scam", Title: "[H] RTX 4090 scam [W] $300", SelfText: "Desc", Author: "Scammer_Joe"}
+	alerts := []store.AlertRule{{ServerID: "guild1", UserID: "user1", MustHave: []string{"4090"}}}
+
+	mockDB := new(testutils.MockStore)
+	mockAI := new(testutils.MockAI)
+	mockScraper := new(testutils.MockScraper)
+	mockDiscord := new(testutils.MockDiscord)
+
+	mockScraper.On("FetchNewestPosts", mock.Anything, reddit.SortNew).Return([]reddit.Post{post}, nil)
+	mockDB.On("GetAllAlerts", mock.Anything).Return(alerts, nil)
+	mockDB.On("GetAllServers", mock.Anything).Return([]store.ServerConfig{{ServerID: "guild2", FeedChannelID: "feed2", FeedMode: store.FeedModeAllDeals}}, nil)
+	mockDB.On("GetScammers", mock.Anything).Return([]store.Scammer{{Username: "scammer_joe"}}, nil)
+	mockDB.On("GetSystemPrompt", mock.Anything, "clean_prompt").Return("", nil)
+	mockDB.On("GetPostRecord", mock.Anything, "t3_scam").Return(nil, nil)
+	mockDB.On("TrimOldPosts", mock.Anything).Return(nil)
+	mockDB.On("TrimOldPipelineRuns", mock.Anything).Return(nil)
+	mockDB.On("SavePipelineRun", mock.Anything, mock.Anything).Return(nil)
+
+	if err := RunPipeline(ctx, mockDB, mockAI, mockScraper, mockDiscord, 0); err != nil {
+		t.Fatalf("expected the run to succeed, got %v", err)
+	}
+
+	// Neither the matching alert's server nor the all-deals server gets the post, and Gemini never sees it.
+	mockAI.AssertNotCalled(t, "CleanRedditPost", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockDiscord.AssertNotCalled(t, "SendEmbedWithComponents", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockDB.AssertNotCalled(t, "SavePostRecords", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
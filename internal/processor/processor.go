@@ -2,6 +2,7 @@ package processor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 	"time"
@@ -14,24 +15,42 @@ import (
 	"golang.org/x/sync/errgroup"
 )
 
-type ServerConfigGetter interface {
+// ConfigGetter looks up the server configs and user settings that decide where and when deals are sent.
+type ConfigGetter interface {
 	GetServerConfig(ctx context.Context, serverID string) (*store.ServerConfig, error)
+	GetUserSettings(ctx context.Context, userID string) (*store.UserSettings, error)
 }
 
 // Storer defines the database operations needed by the processor.
 type Storer interface {
 	GetAllAlerts(ctx context.Context) ([]store.AlertRule, error)
+	IncrementAlertMatches(ctx context.Context, alertIDs []string) error
 	GetPostRecord(ctx context.Context, redditID string) (*store.PostRecord, error)
-	SavePostRecord(ctx context.Context, redditID, cleanedTitle, serverID, discordMsgID string) error
-	SavePostRecords(ctx context.Context, redditID, cleanedTitle string, serverMsgs map[string]string) error
+	AddPostMessage(ctx context.Context, redditID, serverID, discordMsgID, webhookID string) error
+	SavePostRecords(ctx context.Context, redditID, cleanedTitle, corpus, rawText, postURL, price string, numComments int, serverMsgs, serverWebhooks map[string]string) error
+	SavePipelineRun(ctx context.Context, run store.PipelineRun) error
+	TrimOldPipelineRuns(ctx context.Context) error
+	UpdatePostComments(ctx context.Context, redditID string, numComments int) error
+	UpdatePostContent(ctx context.Context, redditID, cleanedTitle, corpus, rawText, price string) error
+	MarkPostClosed(ctx context.Context, redditID string) error
 	TrimOldPosts(ctx context.Context) error
 	GetServerConfig(ctx context.Context, serverID string) (*store.ServerConfig, error)
-	Close() error
+	GetAllServers(ctx context.Context) ([]store.ServerConfig, error)
+	GetSystemPrompt(ctx context.Context, key string) (string, error)
+	SaveFailedDispatch(ctx context.Context, fd store.FailedDispatch) error
+	GetFailedDispatches(ctx context.Context, limit int) ([]store.FailedDispatch, error)
+	DeleteFailedDispatch(ctx context.Context, id string) error
+	GetUserSettings(ctx context.Context, userID string) (*store.UserSettings, error)
+	MarkUserMatched(ctx context.Context, userID string) (bool, error)
+	QueuePing(ctx context.Context, p store.QueuedPing) error
+	GetDuePings(ctx context.Context, now time.Time, limit int) ([]store.QueuedPing, error)
+	DeletePings(ctx context.Context, ids []string) error
+	GetScammers(ctx context.Context) ([]store.Scammer, error)
 }
 
 // AIService defines the AI operations needed by the processor.
 type AIService interface {
-	CleanRedditPost(ctx context.Context, rawTitle, rawBody string) (*ai.CleanedPost, error)
+	CleanRedditPost(ctx context.Context, rawTitle, rawBody, promptOverride string) (*ai.CleanedPost, error)
 }
 
 // DiscordMessenger defines the Discord operations needed by the processor.
@@ -40,54 +59,106 @@ type DiscordMessenger interface {
 	AddReaction(channelID, messageID, emoji string) error
 	SendMessage(channelID, content string) error
 	EditEmbed(channelID, messageID, content string, embed *discordgo.MessageEmbed) error
+	SendEmbedViaWebhook(webhookURL string, embed *discordgo.MessageEmbed, components []discordgo.MessageComponent) (string, error)
+	EditEmbedViaWebhook(webhookURL, messageID string, embed *discordgo.MessageEmbed) error
+	CreateDM(userID string) (string, error)
 }
 
 // Scraper defines the Reddit scraping operations needed by the processor.
 type Scraper interface {
-	FetchNewestPosts(ctx context.Context) ([]reddit.Post, error)
+	FetchNewestPosts(ctx context.Context, sort reddit.Sort) ([]reddit.Post, error)
 }
 
 // RunPipeline sweeps Reddit, parses via AI, checks user alerts, and dispatches to Discord.
-func RunPipeline(ctx context.Context, db Storer, aiSvc AIService, scraper Scraper, discordClient DiscordMessenger) error {
-
-	posts, err := scraper.FetchNewestPosts(ctx)
+// New posts listed more than pingMaxAge before they were first seen still reach the feeds but
+// ping no one, so a backfill after downtime doesn't ping everyone at once; 0 disables the limit.
+// Every run, successful or not, is recorded as a store.PipelineRun.
+func RunPipeline(ctx context.Context, db Storer, aiSvc AIService, scraper Scraper, discordClient DiscordMessenger, pingMaxAge time.Duration) (err error) {
+	run := &runStats{started: time.Now()}
+	defer func() { recordRun(ctx, db, run, err) }()
+
+	posts, err := scraper.FetchNewestPosts(ctx, reddit.SortNew)
+	if errors.Is(err, reddit.ErrAccessDenied) {
+		logger.Error(ctx, "Reddit is refusing the bot (check the User-Agent or whether the IP is banned)", "error", err)
+	}
 	if err != nil {
-		// If Reddit is down, we could DM the admin here. For simplicity in V1, we just return the error.
-		return fmt.Errorf("failed to fetch reddit: %w", err)
+		return fmt.Errorf("%w: %w", errFetchReddit, err)
 	}
+	run.postsFetched = len(posts)
 
 	// 1. Fetch all user keywords in one shot
 	alerts, err := db.GetAllAlerts(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to load alerts: %w", err)
+		return fmt.Errorf("%w: %w", errLoadAlerts, err)
 	}
 
 	// 2. Fetch server routing configs (using a TTL cache)
 	cache := NewConfigCache(db, 5*time.Minute)
 
-	g, ctx := errgroup.WithContext(ctx)
+	// Reuse recent Gemini results for identical content across overlapping runs.
+	aiSvc = &cachingAI{AIService: aiSvc, cache: globalCleanCache}
+
+	// Servers in all-deals mode receive every post, not just matches. Failing to load them
+	// only degrades those servers to alerts-only for this run.
+	servers, err := db.GetAllServers(ctx)
+	if err != nil {
+		logger.Warn(ctx, "Non-fatal: failed to load server configs, feeds fall back to alerts-only", "error", err)
+	}
+
+	// Posts by users on the global scammer blocklist are dropped before cleaning. Failing to load it
+	// only lets their posts through for this run.
+	scammers := make(map[string]bool)
+	if list, err := db.GetScammers(ctx); err != nil {
+		logger.Warn(ctx, "Non-fatal: failed to load scammer blocklist", "error", err)
+	} else {
+		for _, sc := range list {
+			scammers[sc.Username] = true
+		}
+	}
+
+	// An approved clean_prompt replaces the built-in cleaning instruction. None being stored is the
+	// normal state; failing to load it just means this run uses the default.
+	cleanPrompt, err := db.GetSystemPrompt(ctx, "clean_prompt")
+	if err != nil && !errors.Is(err, store.ErrNotFound) {
+		logger.Warn(ctx, "Non-fatal: failed to load clean prompt, using default", "error", err)
+	}
+
+	// The group's context is cancelled once Wait returns, so keep ctx for the cleanup after it.
+	g, gctx := errgroup.WithContext(ctx)
 	g.SetLimit(10) // Process max 10 posts concurrently to stay within API quotas
 
 	for _, p := range posts {
 		post := p // closure capture
 		g.Go(func() error {
 			// Check if we've seen this post
-			record, err := db.GetPostRecord(ctx, post.ID)
+			record, err := db.GetPostRecord(gctx, post.ID)
+			if err != nil && !errors.Is(err, store.ErrNotFound) {
+				// Treating a post we couldn't look up as new could post it twice; the next run retries it.
+				logger.Warn(gctx, "Failed to look up post record, skipping", "reddit_id", post.ID, "error", err)
+				run.errors.Add(1)
+				return nil
+			}
 
-			isNew := (record == nil || err != nil)
+			isNew := record == nil
 
 			// If it's closed/sold or deleted, handle updates.
 			if !isNew {
-				err = handleExistingPostStatus(ctx, cache, discordClient, post, record)
+				err = handleExistingPostStatus(gctx, db, cache, aiSvc, discordClient, post, record, alerts, cleanPrompt)
 				if err != nil {
-					logger.Warn(ctx, "Failed to update status", "reddit_id", post.ID, "error", err)
+					logger.Warn(gctx, "Failed to update status", "reddit_id", post.ID, "error", err)
+					run.errors.Add(1)
 				}
 				return nil
 			}
 
 			// Only process NEW posts that are not deleted/removed instantly
 			if isNew && post.RemovedByByCategory == "" && !strings.EqualFold(post.LinkFlairText, "Sold") && !strings.EqualFold(post.LinkFlairText, "Closed") {
-				processNewPost(ctx, db, cache, aiSvc, discordClient, post, alerts)
+				run.newPosts.Add(1)
+				matched, err := processNewPost(gctx, db, cache, aiSvc, discordClient, post, alerts, servers, scammers, pingMaxAge, cleanPrompt)
+				run.matches.Add(int64(matched))
+				if err != nil {
+					run.errors.Add(1)
+				}
 			}
 			return nil
 		})
@@ -101,36 +172,66 @@ func RunPipeline(ctx context.Context, db Storer, aiSvc AIService, scraper Scrape
 	if err := db.TrimOldPosts(ctx); err != nil {
 		logger.Warn(ctx, "Non-fatal: failed to trim old posts", "error", err)
 	}
+	if err := db.TrimOldPipelineRuns(ctx); err != nil {
+		logger.Warn(ctx, "Non-fatal: failed to trim old pipeline runs", "error", err)
+	}
 
 	logger.Info(ctx, "Pipeline finished successfully")
 	return nil
 }
 
-func handleExistingPostStatus(ctx context.Context, cache ServerConfigGetter, client DiscordMessenger, post reddit.Post, record *store.PostRecord) error {
-	// If the post was sold or closed
-	if strings.EqualFold(post.LinkFlairText, "Sold") || strings.EqualFold(post.LinkFlairText, "Closed") {
+func handleExistingPostStatus(ctx context.Context, db Storer, cache ConfigGetter, aiSvc AIService, client DiscordMessenger, post reddit.Post, record *store.PostRecord, alerts []store.AlertRule, cleanPrompt string) error {
+	// If the post was sold or closed. A closed post stays in /new for a while, so only act on the transition.
+	if (strings.EqualFold(post.LinkFlairText, "Sold") || strings.EqualFold(post.LinkFlairText, "Closed")) && record.ClosedAt.IsZero() {
 		logger.Info(ctx, "Detected SOLD/CLOSED post, updating messages", "reddit_id", post.ID, "count", len(record.ServerMsgs))
 
+		price := finalPrice(post)
+
 		for serverID, msgID := range record.ServerMsgs {
-			cfg, err := cache.GetServerConfig(ctx, serverID)
+			channelID, cfg, err := resolveFeedChannel(ctx, cache, client, serverID)
 			if err != nil {
-				logger.Warn(ctx, "Could not get config for server during update", "server_id", serverID, "error", err)
+				logger.Warn(ctx, "Could not resolve feed channel during update", "server_id", serverID, "error", err)
 				continue
 			}
 
 			// Construct a greyed out, struck-through version of the original deal
-			embed := globalBuilder.BuildClosedEmbed(record.CleanedTitle, post.URL, post.LinkFlairText)
+			embed := globalBuilder.BuildClosedEmbed(record.CleanedTitle, post.URL, post.LinkFlairText, price)
 
-			err = client.EditEmbed(cfg.FeedChannelID, msgID, "", embed)
+			err = editFeedMessage(client, channelID, cfg, record.ServerWebhooks[serverID], msgID, embed)
 			if err != nil {
 				logger.Error(ctx, "Failed to edit message", "server_id", serverID, "msg_id", msgID, "error", err)
 			}
+
+			// DM scopes have no config and so no archive channel.
+			if cfg != nil && cfg.ArchiveChannelID != "" && strings.EqualFold(post.LinkFlairText, "Sold") {
+				entry := globalBuilder.BuildArchiveEntry(record.CleanedTitle, post.URL, price)
+				if err := client.SendMessage(cfg.ArchiveChannelID, entry); err != nil {
+					logger.Error(ctx, "Failed to post to archive channel", "server_id", serverID, "error", err)
+				}
+			}
+		}
+
+		if err := db.MarkPostClosed(ctx, post.ID); err != nil {
+			logger.Warn(ctx, "Failed to mark post closed", "reddit_id", post.ID, "error", err)
 		}
 	}
 
 	// If the post was deleted by user/mods
 	if post.RemovedByByCategory != "" {
 		logger.Info(ctx, "Detected DELETED post", "reddit_id", post.ID, "category", post.RemovedByByCategory)
+		return nil
+	}
+
+	isClosed := strings.EqualFold(post.LinkFlairText, "Sold") || strings.EqualFold(post.LinkFlairText, "Closed")
+
+	// If the post picked up comments since the last scrape, it may have crossed a trending alert's threshold
+	if !isClosed && post.NumComments != record.NumComments {
+		notifyTrending(ctx, db, cache, client, post, record, alerts)
+	}
+
+	// If the seller edited the post (usually a price update) since we last cleaned it
+	if !isClosed && post.Edited.Time().After(record.LastProcessed()) {
+		return handleEditedPost(ctx, db, cache, aiSvc, client, post, record, alerts, cleanPrompt)
 	}
 
 	return nil
@@ -0,0 +1,92 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/discord"
+	"github.com/pauljones0/betterHardwareSwap/internal/logger"
+)
+
+const (
+	// reactionAttempts is how many times a reaction is tried when Discord rate limits it.
+	reactionAttempts = 3
+	// maxReactionRetryAfter caps how long we honour a 429's retry_after so one reaction can't stall a run.
+	maxReactionRetryAfter = 5 * time.Second
+)
+
+var (
+	// reactionInterval spaces out reaction calls; Discord allows roughly one reaction per 250ms. Overridden in tests.
+	reactionInterval = 250 * time.Millisecond
+	// reactionRetryFallback is the wait used when a 429 doesn't say how long to back off. Overridden in tests.
+	reactionRetryFallback = time.Second
+
+	globalReactionLimiter = &reactionLimiter{}
+)
+
+// reactionLimiter paces reaction calls across every server so a busy run doesn't trip Discord's reaction rate limit.
+type reactionLimiter struct {
+	mu   sync.Mutex
+	next time.Time
+}
+
+// Wait blocks until the next reaction slot is free.
+func (rl *reactionLimiter) Wait(ctx context.Context) error {
+	rl.mu.Lock()
+	now := time.Now()
+	slot := rl.next
+	if slot.Before(now) {
+		slot = now
+	}
+	rl.next = slot.Add(reactionInterval)
+	rl.mu.Unlock()
+
+	wait := time.Until(slot)
+	if wait <= 0 {
+		return nil
+	}
+	select {
+	case <-time.After(wait):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// addReaction adds an emoji to a message through the shared limiter, retrying when Discord answers 429.
+// Reactions are cosmetic, so failures are logged at debug level rather than returned.
+func addReaction(ctx context.Context, client DiscordMessenger, channelID, msgID, emoji string) {
+	for attempt := 1; attempt <= reactionAttempts; attempt++ {
+		if err := globalReactionLimiter.Wait(ctx); err != nil {
+			return
+		}
+
+		err := client.AddReaction(channelID, msgID, emoji)
+		if err == nil {
+			return
+		}
+
+		var apiErr *discord.APIError
+		if !errors.As(err, &apiErr) || !apiErr.IsRateLimited() || attempt == reactionAttempts {
+			logger.Debug(ctx, "Failed to add reaction", "channel_id", channelID, "message_id", msgID, "attempt", attempt, "error", err)
+			return
+		}
+
+		wait := apiErr.RetryAfter
+		if wait <= 0 {
+			wait = reactionRetryFallback
+		}
+		if wait > maxReactionRetryAfter {
+			wait = maxReactionRetryAfter
+		}
+		logger.Debug(ctx, "Reaction rate limited, retrying", "channel_id", channelID, "retry_after", wait)
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/discord"
+	"github.com/pauljones0/betterHardwareSwap/internal/testutils"
+)
+
+func TestAddReaction(t *testing.T) {
+	ctx := context.Background()
+	reactionInterval = 0
+	reactionRetryFallback = time.Millisecond
+	defer func() {
+		reactionInterval = 250 * time.Millisecond
+		reactionRetryFallback = time.Second
+	}()
+
+	rateLimited := &discord.APIError{StatusCode: 429, Body: `{"retry_after": 0.001}`, RetryAfter: time.Millisecond}
+
+	t.Run("429 is retried", func(t *testing.T) {
+		mockDiscord := new(testutils.MockDiscord)
+		mockDiscord.On("AddReaction", "feed1", "msg1", "%F0%9F%91%8D").Return(rateLimited).Once()
+		mockDiscord.On("AddReaction", "feed1", "msg1", "%F0%9F%91%8D").Return(nil).Once()
+
+		addReaction(ctx, mockDiscord, "feed1", "msg1", "%F0%9F%91%8D")
+
+		mockDiscord.AssertExpectations(t)
+		mockDiscord.AssertNumberOfCalls(t, "AddReaction", 2)
+	})
+
+	t.Run("Persistent 429 gives up after max attempts", func(t *testing.T) {
+		mockDiscord := new(testutils.MockDiscord)
+		mockDiscord.On("AddReaction", "feed1", "msg1", "%F0%9F%91%8D").Return(rateLimited)
+
+		addReaction(ctx, mockDiscord, "feed1", "msg1", "%F0%9F%91%8D")
+
+		mockDiscord.AssertNumberOfCalls(t, "AddReaction", reactionAttempts)
+	})
+
+	t.Run("Other errors are not retried", func(t *testing.T) {
+		mockDiscord := new(testutils.MockDiscord)
+		mockDiscord.On("AddReaction", "feed1", "msg1", "%F0%9F%91%8D").Return(errors.New("discord API error 403: Missing Permissions"))
+
+		addReaction(ctx, mockDiscord, "feed1", "msg1", "%F0%9F%91%8D")
+
+		mockDiscord.AssertNumberOfCalls(t, "AddReaction", 1)
+	})
+}
+
+func TestReactionLimiter_Paces(t *testing.T) {
+	reactionInterval = 20 * time.Millisecond
+	defer func() { reactionInterval = 250 * time.Millisecond }()
+
+	rl := &reactionLimiter{}
+	start := time.Now()
+	for n := 0; n < 3; n++ {
+		if err := rl.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait failed: %v", err)
+		}
+	}
+
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Errorf("expected three calls to be spaced at least 40ms apart, took %v", elapsed)
+	}
+}
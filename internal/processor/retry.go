@@ -0,0 +1,172 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pauljones0/betterHardwareSwap/internal/discord"
+	"github.com/pauljones0/betterHardwareSwap/internal/logger"
+	"github.com/pauljones0/betterHardwareSwap/internal/reddit"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+)
+
+const (
+	// feedSendAttempts is how many times a feed post is tried inline before it is dead-lettered.
+	feedSendAttempts = 3
+	// maxDispatchAttempts is how many /cron/retry passes a dead-letter entry gets before it is abandoned.
+	maxDispatchAttempts = 5
+	// retryBatchSize caps how many dead-letter entries a single /cron/retry run re-attempts.
+	retryBatchSize = 50
+)
+
+// dispatchRetryBackoff is the initial wait between inline feed send attempts. Overridden in tests.
+var dispatchRetryBackoff = 500 * time.Millisecond
+
+// feedWebhookURL returns the webhook a scope's feed posts go through, or "" to post as the bot.
+// DM scopes have no config and always post as the bot.
+func feedWebhookURL(cfg *store.ServerConfig) string {
+	if cfg == nil {
+		return ""
+	}
+	return cfg.FeedWebhookURL
+}
+
+// sendFeed posts a deal embed through webhookURL when one is configured, otherwise as the bot to channelID.
+func sendFeed(client DiscordMessenger, channelID, webhookURL string, embed *discordgo.MessageEmbed, components []discordgo.MessageComponent) (string, error) {
+	if webhookURL != "" {
+		return client.SendEmbedViaWebhook(webhookURL, embed, components)
+	}
+	return client.SendEmbedWithComponents(channelID, "", embed, components)
+}
+
+// feedWebhookID returns the ID of the webhook feed posts are sent through for cfg, or "" when the bot
+// posts them. It is recorded with each message so later edits go the same way.
+func feedWebhookID(cfg *store.ServerConfig) string {
+	if webhookURL := feedWebhookURL(cfg); webhookURL != "" {
+		return discord.WebhookID(webhookURL)
+	}
+	return ""
+}
+
+// editFeedMessage updates a feed post the same way it was sent: through the webhook with ID webhookID, or
+// as the bot when webhookID is empty. Webhook messages can only be edited through the webhook that created
+// them, so once a server replaces or removes that webhook its earlier messages can't be edited.
+func editFeedMessage(client DiscordMessenger, channelID string, cfg *store.ServerConfig, webhookID, msgID string, embed *discordgo.MessageEmbed) error {
+	if webhookID == "" {
+		return client.EditEmbed(channelID, msgID, "", embed)
+	}
+	if feedWebhookID(cfg) != webhookID {
+		return fmt.Errorf("message was sent through webhook %s, which is no longer the server's feed webhook", webhookID)
+	}
+	return client.EditEmbedViaWebhook(feedWebhookURL(cfg), msgID, embed)
+}
+
+// sendFeedWithRetry posts a deal embed to a feed channel, retrying transient failures with exponential backoff.
+func sendFeedWithRetry(ctx context.Context, client DiscordMessenger, channelID, webhookURL string, embed *discordgo.MessageEmbed, components []discordgo.MessageComponent) (string, error) {
+	var lastErr error
+	backoff := dispatchRetryBackoff
+
+	for i := 0; i < feedSendAttempts; i++ {
+		msgID, err := sendFeed(client, channelID, webhookURL, embed, components)
+		if err == nil {
+			return msgID, nil
+		}
+		lastErr = err
+
+		if i == feedSendAttempts-1 {
+			break
+		}
+		logger.Warn(ctx, "Feed post failed, retrying", "channel_id", channelID, "retry", i+1, "backoff", backoff, "error", err)
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+
+	return "", fmt.Errorf("feed post failed after %d attempts: %w", feedSendAttempts, lastErr)
+}
+
+// recordFailedDispatch dead-letters a feed post so /cron/retry can deliver it later.
+func recordFailedDispatch(ctx context.Context, db Storer, post reddit.Post, serverID, cleanedTitle string, embed *discordgo.MessageEmbed, userIDs []string, sendErr error) {
+	embedJSON, err := json.Marshal(embed)
+	if err != nil {
+		logger.Error(ctx, "Failed to serialize embed for dead-letter", "reddit_id", post.ID, "error", err)
+		return
+	}
+
+	fd := store.FailedDispatch{
+		RedditID:     post.ID,
+		ServerID:     serverID,
+		CleanedTitle: cleanedTitle,
+		PostURL:      post.URL,
+		Author:       post.Author,
+		EmbedJSON:    string(embedJSON),
+		UserIDs:      userIDs,
+		Attempts:     1,
+		LastError:    sendErr.Error(),
+	}
+	if err := db.SaveFailedDispatch(ctx, fd); err != nil {
+		logger.Error(ctx, "Failed to save dead-letter dispatch", "reddit_id", post.ID, "server_id", serverID, "error", err)
+	}
+}
+
+// RetryFailedDispatches re-attempts dead-lettered feed posts. Delivered entries are merged into the
+// post record and removed; entries that keep failing are abandoned after maxDispatchAttempts.
+func RetryFailedDispatches(ctx context.Context, db Storer, client DiscordMessenger) error {
+	dispatches, err := db.GetFailedDispatches(ctx, retryBatchSize)
+	if err != nil {
+		return fmt.Errorf("failed to load failed dispatches: %w", err)
+	}
+
+	delivered := 0
+	for _, fd := range dispatches {
+		var embed discordgo.MessageEmbed
+		if err := json.Unmarshal([]byte(fd.EmbedJSON), &embed); err != nil {
+			logger.Error(ctx, "Dropping unreadable dead-letter dispatch", "id", fd.ID, "error", err)
+			_ = db.DeleteFailedDispatch(ctx, fd.ID)
+			continue
+		}
+
+		channelID, cfg, err := resolveFeedChannel(ctx, db, client, fd.ServerID)
+		if err != nil {
+			logger.Warn(ctx, "Could not resolve feed channel for dead-letter server", "server_id", fd.ServerID, "error", err)
+			continue
+		}
+
+		msgID, err := sendFeed(client, channelID, feedWebhookURL(cfg), &embed, globalBuilder.BuildDealButtons(fd.RedditID, fd.PostURL, fd.Author))
+		if err != nil {
+			fd.Attempts++
+			fd.LastError = err.Error()
+			if fd.Attempts >= maxDispatchAttempts {
+				logger.Error(ctx, "Abandoning dead-letter dispatch", "id", fd.ID, "attempts", fd.Attempts, "error", err)
+				_ = db.DeleteFailedDispatch(ctx, fd.ID)
+				continue
+			}
+			if err := db.SaveFailedDispatch(ctx, fd); err != nil {
+				logger.Error(ctx, "Failed to update dead-letter dispatch", "id", fd.ID, "error", err)
+			}
+			continue
+		}
+
+		if cfg != nil {
+			announceDeal(ctx, db, db, client, fd.ServerID, cfg, msgID, fd.UserIDs)
+		}
+
+		if err := db.AddPostMessage(ctx, fd.RedditID, fd.ServerID, msgID, feedWebhookID(cfg)); err != nil {
+			logger.Error(ctx, "Failed to save post record for retried dispatch", "reddit_id", fd.RedditID, "error", err)
+		}
+		if err := db.DeleteFailedDispatch(ctx, fd.ID); err != nil {
+			logger.Error(ctx, "Failed to delete delivered dead-letter dispatch", "id", fd.ID, "error", err)
+		}
+		delivered++
+	}
+
+	logger.Info(ctx, "Dead-letter retry finished", "pending", len(dispatches), "delivered", delivered)
+	return nil
+}
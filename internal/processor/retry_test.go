@@ -0,0 +1,194 @@
+package processor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/pauljones0/betterHardwareSwap/internal/ai"
+	"github.com/pauljones0/betterHardwareSwap/internal/reddit"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+	"github.com/pauljones0/betterHardwareSwap/internal/testutils"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestProcessNewPost_DispatchRetry(t *testing.T) {
+	ctx := context.Background()
+	dispatchRetryBackoff = time.Millisecond
+	defer func() { dispatchRetryBackoff = 500 * time.Millisecond }()
+
+	post := reddit.Post{ID: "t3_retry", Title: "[H] RTX 3080 [W] $500", SelfText: "Desc", URL: "https://reddit.com/retry"}
+	alerts := []store.AlertRule{{ServerID: "guild1", UserID: "user1", MustHave: []string{"3080"}}}
+	cfg := &store.ServerConfig{FeedChannelID: "feed1", PingChannelID: "ping1"}
+
+	t.Run("Transient failure is retried", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+		mockAI := new(testutils.MockAI)
+		mockDiscord := new(testutils.MockDiscord)
+
+		mockAI.On("CleanRedditPost", mock.Anything, post.Title, post.SelfText, mock.Anything).Return(&ai.CleanedPost{Title: "RTX 3080"}, nil)
+		mockDB.On("GetServerConfig", mock.Anything, "guild1").Return(cfg, nil)
+		mockDiscord.On("SendEmbedWithComponents", "feed1", "", mock.Anything, mock.Anything).Return("", errors.New("discord API error 500")).Once()
+		mockDiscord.On("SendEmbedWithComponents", "feed1", "", mock.Anything, mock.Anything).Return("msg123", nil).Once()
+		mockDiscord.On("AddReaction", "feed1", "msg123", mock.Anything).Return(nil).Times(2)
+		mockDB.On("GetUserSettings", mock.Anything, mock.Anything).Return(&store.UserSettings{HasMatched: true}, nil)
+		mockDiscord.On("SendMessage", "ping1", mock.Anything).Return(nil)
+		mockDB.On("SavePostRecords", mock.Anything, "t3_retry", "RTX 3080", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, map[string]string{"guild1": "msg123"}, mock.Anything).Return(nil)
+
+		processNewPost(ctx, mockDB, mockDB, mockAI, mockDiscord, post, alerts, nil, nil, 0, "")
+
+		mockDiscord.AssertExpectations(t)
+		mockDB.AssertExpectations(t)
+		mockDB.AssertNotCalled(t, "SaveFailedDispatch", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Persistent failure is dead-lettered and record still saved", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+		mockAI := new(testutils.MockAI)
+		mockDiscord := new(testutils.MockDiscord)
+
+		mockAI.On("CleanRedditPost", mock.Anything, post.Title, post.SelfText, mock.Anything).Return(&ai.CleanedPost{Title: "RTX 3080"}, nil)
+		mockDB.On("GetServerConfig", mock.Anything, "guild1").Return(cfg, nil)
+		mockDiscord.On("SendEmbedWithComponents", "feed1", "", mock.Anything, mock.Anything).Return("", errors.New("discord API error 500")).Times(feedSendAttempts)
+		mockDB.On("SaveFailedDispatch", mock.Anything, mock.MatchedBy(func(fd store.FailedDispatch) bool {
+			return fd.RedditID == "t3_retry" && fd.ServerID == "guild1" && len(fd.UserIDs) == 1 && fd.EmbedJSON != ""
+		})).Return(nil)
+		mockDB.On("SavePostRecords", mock.Anything, "t3_retry", "RTX 3080", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, map[string]string{}, mock.Anything).Return(nil)
+
+		processNewPost(ctx, mockDB, mockDB, mockAI, mockDiscord, post, alerts, nil, nil, 0, "")
+
+		mockDiscord.AssertExpectations(t)
+		mockDB.AssertExpectations(t)
+		mockDiscord.AssertNotCalled(t, "SendMessage", mock.Anything, mock.Anything)
+	})
+}
+
+func TestProcessNewPost_FeedWebhook(t *testing.T) {
+	ctx := context.Background()
+	post := reddit.Post{ID: "t3_hook", Title: "[H] RTX 3080 [W] $500", SelfText: "Desc", URL: "https://reddit.com/hook"}
+	alerts := []store.AlertRule{{ServerID: "guild1", UserID: "user1", MustHave: []string{"3080"}}}
+	hookURL := "https://discord.com/api/webhooks/1/tok"
+	cfg := &store.ServerConfig{FeedChannelID: "feed1", PingChannelID: "ping1", FeedWebhookURL: hookURL}
+
+	mockDB := new(testutils.MockStore)
+	mockAI := new(testutils.MockAI)
+	mockDiscord := new(testutils.MockDiscord)
+
+	mockAI.On("CleanRedditPost", mock.Anything, post.Title, post.SelfText, mock.Anything).Return(&ai.CleanedPost{Title: "RTX 3080"}, nil)
+	mockDB.On("GetServerConfig", mock.Anything, "guild1").Return(cfg, nil)
+	mockDiscord.On("SendEmbedViaWebhook", hookURL, mock.Anything, mock.Anything).Return("msg123", nil).Once()
+	mockDiscord.On("AddReaction", "feed1", "msg123", mock.Anything).Return(nil).Times(2)
+	mockDB.On("GetUserSettings", mock.Anything, mock.Anything).Return(&store.UserSettings{HasMatched: true}, nil)
+	mockDiscord.On("SendMessage", "ping1", mock.Anything).Return(nil)
+	mockDB.On("SavePostRecords", mock.Anything, "t3_hook", "RTX 3080", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, map[string]string{"guild1": "msg123"}, map[string]string{"guild1": "1"}).Return(nil)
+
+	processNewPost(ctx, mockDB, mockDB, mockAI, mockDiscord, post, alerts, nil, nil, 0, "")
+
+	mockDiscord.AssertExpectations(t)
+	mockDB.AssertExpectations(t)
+	mockDiscord.AssertNotCalled(t, "SendEmbedWithComponents", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestEditFeedMessage(t *testing.T) {
+	embed := &discordgo.MessageEmbed{Title: "Sold"}
+	hook := func(url string) *store.ServerConfig {
+		return &store.ServerConfig{FeedChannelID: "feed1", FeedWebhookURL: url}
+	}
+
+	t.Run("Bot message stays with the bot after a webhook is added", func(t *testing.T) {
+		mockDiscord := new(testutils.MockDiscord)
+		mockDiscord.On("EditEmbed", "feed1", "msg1", "", embed).Return(nil)
+
+		if err := editFeedMessage(mockDiscord, "feed1", hook("https://discord.com/api/webhooks/1/tok"), "", "msg1", embed); err != nil {
+			t.Fatalf("editFeedMessage failed: %v", err)
+		}
+		mockDiscord.AssertExpectations(t)
+	})
+
+	t.Run("Webhook message goes through its webhook", func(t *testing.T) {
+		mockDiscord := new(testutils.MockDiscord)
+		mockDiscord.On("EditEmbedViaWebhook", "https://discord.com/api/webhooks/1/tok", "msg1", embed).Return(nil)
+
+		if err := editFeedMessage(mockDiscord, "feed1", hook("https://discord.com/api/webhooks/1/tok"), "1", "msg1", embed); err != nil {
+			t.Fatalf("editFeedMessage failed: %v", err)
+		}
+		mockDiscord.AssertExpectations(t)
+	})
+
+	for name, cfg := range map[string]*store.ServerConfig{
+		"Webhook replaced": hook("https://discord.com/api/webhooks/2/tok"),
+		"Webhook removed":  hook(""),
+	} {
+		t.Run(name, func(t *testing.T) {
+			mockDiscord := new(testutils.MockDiscord)
+
+			if err := editFeedMessage(mockDiscord, "feed1", cfg, "1", "msg1", embed); err == nil {
+				t.Error("expected an error for a message whose webhook is gone")
+			}
+			mockDiscord.AssertNotCalled(t, "EditEmbed", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			mockDiscord.AssertNotCalled(t, "EditEmbedViaWebhook", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestRetryFailedDispatches(t *testing.T) {
+	ctx := context.Background()
+
+	embedJSON, _ := json.Marshal(&discordgo.MessageEmbed{Title: "📦 RTX 3080"})
+	fd := store.FailedDispatch{
+		ID:           "t3_retry_guild1",
+		RedditID:     "t3_retry",
+		ServerID:     "guild1",
+		CleanedTitle: "RTX 3080",
+		PostURL:      "https://reddit.com/retry",
+		EmbedJSON:    string(embedJSON),
+		UserIDs:      []string{"user1"},
+		Attempts:     1,
+	}
+	cfg := &store.ServerConfig{FeedChannelID: "feed1", PingChannelID: "ping1"}
+
+	t.Run("Delivered entry is recorded and removed", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+		mockDiscord := new(testutils.MockDiscord)
+
+		mockDB.On("GetFailedDispatches", mock.Anything, retryBatchSize).Return([]store.FailedDispatch{fd}, nil)
+		mockDB.On("GetServerConfig", mock.Anything, "guild1").Return(cfg, nil)
+		mockDiscord.On("SendEmbedWithComponents", "feed1", "", mock.Anything, mock.Anything).Return("msg999", nil)
+		mockDiscord.On("AddReaction", "feed1", "msg999", mock.Anything).Return(nil).Times(2)
+		mockDB.On("GetUserSettings", mock.Anything, mock.Anything).Return(&store.UserSettings{HasMatched: true}, nil)
+		mockDiscord.On("SendMessage", "ping1", mock.Anything).Return(nil)
+		mockDB.On("AddPostMessage", mock.Anything, "t3_retry", "guild1", "msg999", "").Return(nil)
+		mockDB.On("DeleteFailedDispatch", mock.Anything, "t3_retry_guild1").Return(nil)
+
+		if err := RetryFailedDispatches(ctx, mockDB, mockDiscord); err != nil {
+			t.Fatalf("RetryFailedDispatches failed: %v", err)
+		}
+
+		mockDB.AssertExpectations(t)
+		mockDiscord.AssertExpectations(t)
+		// Rewriting the whole record would move posted_at and hide seller edits made before the retry.
+		mockDB.AssertNotCalled(t, "SavePostRecords", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Failed entry has its attempt count bumped", func(t *testing.T) {
+		mockDB := new(testutils.MockStore)
+		mockDiscord := new(testutils.MockDiscord)
+
+		mockDB.On("GetFailedDispatches", mock.Anything, retryBatchSize).Return([]store.FailedDispatch{fd}, nil)
+		mockDB.On("GetServerConfig", mock.Anything, "guild1").Return(cfg, nil)
+		mockDiscord.On("SendEmbedWithComponents", "feed1", "", mock.Anything, mock.Anything).Return("", errors.New("still down"))
+		mockDB.On("SaveFailedDispatch", mock.Anything, mock.MatchedBy(func(got store.FailedDispatch) bool {
+			return got.Attempts == 2 && got.LastError == "still down"
+		})).Return(nil)
+
+		if err := RetryFailedDispatches(ctx, mockDB, mockDiscord); err != nil {
+			t.Fatalf("RetryFailedDispatches failed: %v", err)
+		}
+
+		mockDB.AssertExpectations(t)
+		mockDB.AssertNotCalled(t, "DeleteFailedDispatch", mock.Anything, mock.Anything)
+	})
+}
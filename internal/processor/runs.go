@@ -0,0 +1,38 @@
+package processor
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/logger"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+)
+
+// runStats counts what a pipeline run did. The counters are updated from the per-post goroutines.
+type runStats struct {
+	started      time.Time
+	postsFetched int
+	newPosts     atomic.Int64
+	matches      atomic.Int64
+	errors       atomic.Int64
+}
+
+// recordRun saves the run's counts and outcome as a store.PipelineRun. A run that failed outright
+// (runErr) still gets a record. Failing to save it only logs, since it must not fail the run.
+func recordRun(ctx context.Context, db Storer, run *runStats, runErr error) {
+	record := store.PipelineRun{
+		StartedAt:    run.started,
+		Duration:     time.Since(run.started),
+		PostsFetched: run.postsFetched,
+		NewPosts:     int(run.newPosts.Load()),
+		Matches:      int(run.matches.Load()),
+		Errors:       int(run.errors.Load()),
+	}
+	if runErr != nil {
+		record.Error = runErr.Error()
+	}
+	if err := db.SavePipelineRun(ctx, record); err != nil {
+		logger.Warn(ctx, "Non-fatal: failed to save pipeline run record", "error", err)
+	}
+}
@@ -0,0 +1,111 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/ai"
+	"github.com/pauljones0/betterHardwareSwap/internal/reddit"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+	"github.com/pauljones0/betterHardwareSwap/internal/testutils"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRunPipeline_RecordsRun(t *testing.T) {
+	ctx := context.Background()
+	matched := reddit.Post{ID: "run_match", Title: "[H] RTX 4070 run record [W] $600", SelfText: "Desc"}
+	failed := reddit.Post{ID: "run_fail", Title: "[H] Broken run record post [W] $1", SelfText: "Desc"}
+	seen := reddit.Post{ID: "run_seen", Title: "[H] Already posted [W] $50"}
+	alerts := []store.AlertRule{
+		{ServerID: "guild1", UserID: "user1", MustHave: []string{"4070"}},
+		{ServerID: "guild1", UserID: "user2", AnyOf: []string{"rtx"}},
+	}
+
+	mockDB := new(testutils.MockStore)
+	mockAI := new(testutils.MockAI)
+	mockScraper := new(testutils.MockScraper)
+	mockDiscord := new(testutils.MockDiscord)
+
+	mockScraper.On("FetchNewestPosts", mock.Anything, reddit.SortNew).Return([]reddit.Post{matched, failed, seen}, nil)
+	mockDB.On("GetAllAlerts", mock.Anything).Return(alerts, nil)
+	mockDB.On("GetAllServers", mock.Anything).Return([]store.ServerConfig{}, nil)
+	mockDB.On("GetScammers", mock.Anything).Return([]store.Scammer{}, nil)
+	mockDB.On("GetSystemPrompt", mock.Anything, "clean_prompt").Return("", nil)
+	mockDB.On("GetPostRecord", mock.Anything, "run_match").Return(nil, nil)
+	mockDB.On("GetPostRecord", mock.Anything, "run_fail").Return(nil, nil)
+	mockDB.On("GetPostRecord", mock.Anything, "run_seen").Return(&store.PostRecord{RedditID: "run_seen", PostedAt: time.Now()}, nil)
+
+	mockAI.On("CleanRedditPost", mock.Anything, matched.Title, matched.SelfText, mock.Anything).Return(&ai.CleanedPost{Title: "RTX 4070"}, nil)
+	mockAI.On("CleanRedditPost", mock.Anything, failed.Title, failed.SelfText, mock.Anything).Return(nil, errors.New("gemini down"))
+	mockDB.On("GetServerConfig", mock.Anything, "guild1").Return(&store.ServerConfig{FeedChannelID: "feed1", PingChannelID: "ping1"}, nil)
+	mockDB.On("GetUserSettings", mock.Anything, mock.Anything).Return(&store.UserSettings{HasMatched: true}, nil)
+	mockDiscord.On("SendEmbedWithComponents", "feed1", "", mock.Anything, mock.Anything).Return("msg1", nil)
+	mockDiscord.On("AddReaction", "feed1", "msg1", mock.Anything).Return(nil)
+	mockDiscord.On("SendMessage", "ping1", mock.Anything).Return(nil)
+	mockDB.On("SavePostRecords", mock.Anything, "run_match", "RTX 4070", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	mockDB.On("TrimOldPosts", mock.Anything).Return(nil)
+	mockDB.On("TrimOldPipelineRuns", mock.Anything).Return(nil)
+	var run store.PipelineRun
+	mockDB.On("SavePipelineRun", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		run = args.Get(1).(store.PipelineRun)
+	}).Return(nil)
+
+	if err := RunPipeline(ctx, mockDB, mockAI, mockScraper, mockDiscord, 0); err != nil {
+		t.Fatalf("expected the run to succeed, got %v", err)
+	}
+
+	mockDB.AssertExpectations(t)
+	if run.PostsFetched != 3 || run.NewPosts != 2 || run.Matches != 2 || run.Errors != 1 || run.Error != "" {
+		t.Errorf("unexpected run record %+v", run)
+	}
+	if run.StartedAt.IsZero() || run.Duration <= 0 {
+		t.Errorf("expected the run to be timed, got %+v", run)
+	}
+}
+
+func TestRunPipeline_SkipsPostsItCantLookUp(t *testing.T) {
+	post := reddit.Post{ID: "run_lookup_fail", Title: "[H] RTX 4070 [W] $600", SelfText: "Desc"}
+
+	mockDB := new(testutils.MockStore)
+	mockAI := new(testutils.MockAI)
+	mockScraper := new(testutils.MockScraper)
+
+	mockScraper.On("FetchNewestPosts", mock.Anything, reddit.SortNew).Return([]reddit.Post{post}, nil)
+	mockDB.On("GetAllAlerts", mock.Anything).Return([]store.AlertRule{}, nil)
+	mockDB.On("GetAllServers", mock.Anything).Return([]store.ServerConfig{}, nil)
+	mockDB.On("GetScammers", mock.Anything).Return([]store.Scammer{}, nil)
+	mockDB.On("GetSystemPrompt", mock.Anything, "clean_prompt").Return("", nil)
+	mockDB.On("GetPostRecord", mock.Anything, post.ID).Return(nil, errors.New("unavailable"))
+	mockDB.On("TrimOldPosts", mock.Anything).Return(nil)
+	mockDB.On("TrimOldPipelineRuns", mock.Anything).Return(nil)
+	var run store.PipelineRun
+	mockDB.On("SavePipelineRun", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		run = args.Get(1).(store.PipelineRun)
+	}).Return(nil)
+
+	if err := RunPipeline(context.Background(), mockDB, mockAI, mockScraper, new(testutils.MockDiscord), 0); err != nil {
+		t.Fatalf("expected the run to succeed, got %v", err)
+	}
+
+	mockAI.AssertNotCalled(t, "CleanRedditPost", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	if run.NewPosts != 0 || run.Errors != 1 {
+		t.Errorf("expected the post to be skipped and counted as an error, got %+v", run)
+	}
+}
+
+func TestRunPipeline_RecordsFailedRun(t *testing.T) {
+	mockDB := new(testutils.MockStore)
+	mockScraper := new(testutils.MockScraper)
+	mockScraper.On("FetchNewestPosts", mock.Anything, reddit.SortNew).Return(nil, errors.New("reddit down"))
+	mockDB.On("SavePipelineRun", mock.Anything, mock.MatchedBy(func(run store.PipelineRun) bool {
+		return run.PostsFetched == 0 && run.Error == "failed to fetch reddit: reddit down"
+	})).Return(nil)
+
+	if err := RunPipeline(context.Background(), mockDB, new(testutils.MockAI), mockScraper, new(testutils.MockDiscord), 0); err == nil {
+		t.Fatal("expected the run to fail")
+	}
+	mockDB.AssertExpectations(t)
+}
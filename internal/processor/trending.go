@@ -0,0 +1,80 @@
+package processor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/discord"
+	"github.com/pauljones0/betterHardwareSwap/internal/logger"
+	"github.com/pauljones0/betterHardwareSwap/internal/reddit"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+)
+
+// notifyTrending pings the owners of trending alerts whose comment threshold the post crossed since the
+// last scrape, then records the new comment count so each threshold fires only once per post.
+func notifyTrending(ctx context.Context, db Storer, cache ConfigGetter, client DiscordMessenger, post reddit.Post, record *store.PostRecord, alerts []store.AlertRule) {
+	matches := trendingMatches(ctx, alerts, record, post.NumComments, time.Now())
+	dropMutedAuthor(ctx, cache, matches, post.Author)
+	dropBlockedServers(ctx, cache, matches, record.Corpus)
+	if post.Over18 {
+		dropNSFWServers(ctx, cache, matches)
+	}
+
+	headline := fmt.Sprintf("🔥 **A deal is heating up!** (%d comments)", post.NumComments)
+	for serverID, userIDs := range matches {
+		pingChannelID, link, err := resolveTrendingTarget(ctx, cache, client, serverID, post, record)
+		if err != nil {
+			logger.Warn(ctx, "Could not resolve where to send trending ping", "server_id", serverID, "error", err)
+			continue
+		}
+		notifyUsers(ctx, db, cache, client, serverID, pingChannelID, link, userIDs, headline)
+	}
+
+	if err := db.UpdatePostComments(ctx, post.ID, post.NumComments); err != nil {
+		logger.Warn(ctx, "Failed to record comment count", "reddit_id", post.ID, "error", err)
+	}
+}
+
+// trendingMatches returns, per server, the users whose trending alerts were crossed by the post going from
+// record.NumComments to numComments. Keyword lists on a trending alert must also match the stored corpus.
+func trendingMatches(ctx context.Context, alerts []store.AlertRule, record *store.PostRecord, numComments int, now time.Time) map[string][]string {
+	var matched []store.AlertRule
+	for _, alert := range alerts {
+		threshold := alert.TrendingComments
+		if threshold <= 0 || !alert.Active(now) {
+			continue
+		}
+		if record.NumComments >= threshold || numComments < threshold {
+			continue
+		}
+		if !globalMatcher.Matches(record.Corpus, alert.MustHave, alert.AnyOf, alert.MustNot) {
+			continue
+		}
+		matched = append(matched, alert)
+	}
+	return groupByServer(ctx, matched)
+}
+
+// resolveTrendingTarget returns the channel to ping in and the link to ping with. The link is the server's
+// feed message when the post was sent there, and the Reddit thread otherwise.
+func resolveTrendingTarget(ctx context.Context, cache ConfigGetter, client DiscordMessenger, serverID string, post reddit.Post, record *store.PostRecord) (string, string, error) {
+	link := "https://www.reddit.com" + post.Permalink
+
+	if userID, ok := store.DMScopeUser(serverID); ok {
+		channelID, err := client.CreateDM(userID)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to open DM channel: %w", err)
+		}
+		return channelID, link, nil
+	}
+
+	cfg, err := cache.GetServerConfig(ctx, serverID)
+	if err != nil {
+		return "", "", err
+	}
+	if msgID, ok := record.ServerMsgs[serverID]; ok {
+		link = discord.BuildMessageLink(serverID, cfg.FeedChannelID, msgID)
+	}
+	return cfg.PingChannelID, link, nil
+}
@@ -0,0 +1,71 @@
+package processor
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/reddit"
+	"github.com/pauljones0/betterHardwareSwap/internal/store"
+	"github.com/pauljones0/betterHardwareSwap/internal/testutils"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandleExistingPostStatus_TrendingCrossing(t *testing.T) {
+	ctx := context.Background()
+	post := reddit.Post{ID: "t3_hot", Title: "[H] RTX 3080 FE [W] $450", Permalink: "/r/CanadianHardwareSwap/comments/hot/", NumComments: 25}
+	alerts := []store.AlertRule{
+		{ServerID: "guild1", UserID: "hot_user", TrendingComments: 20},
+		{ServerID: "guild1", UserID: "gpu_hot_user", TrendingComments: 20, AnyOf: []string{"3080"}},
+		{ServerID: "guild1", UserID: "cpu_hot_user", TrendingComments: 20, AnyOf: []string{"5800x3d"}},
+		{ServerID: "guild1", UserID: "already_crossed", TrendingComments: 5},
+		{ServerID: "guild1", UserID: "not_yet", TrendingComments: 50},
+		{ServerID: "guild1", UserID: "keyword_user", MustHave: []string{"3080"}},
+		{ServerID: "guild2", UserID: "other_server", TrendingComments: 10},
+	}
+	cfg := &store.ServerConfig{FeedChannelID: "feed1", PingChannelID: "ping1"}
+
+	t.Run("Crossing the threshold pings once", func(t *testing.T) {
+		record := &store.PostRecord{RedditID: post.ID, Corpus: "RTX 3080 FE Toronto", ServerMsgs: map[string]string{"guild1": "msg1"}, NumComments: 8}
+
+		mockDB := new(testutils.MockStore)
+		mockDiscord := new(testutils.MockDiscord)
+
+		mockDB.On("GetServerConfig", mock.Anything, "guild1").Return(cfg, nil)
+		mockDB.On("GetServerConfig", mock.Anything, "guild2").Return(&store.ServerConfig{FeedChannelID: "feed2", PingChannelID: "ping2"}, nil)
+		mockDB.On("GetUserSettings", mock.Anything, mock.Anything).Return(&store.UserSettings{}, nil)
+		mockDiscord.On("SendMessage", "ping1", mock.MatchedBy(func(content string) bool {
+			return strings.Contains(content, "<@hot_user>") && strings.Contains(content, "<@gpu_hot_user>") &&
+				!strings.Contains(content, "cpu_hot_user") && !strings.Contains(content, "already_crossed") &&
+				!strings.Contains(content, "not_yet") && !strings.Contains(content, "keyword_user") &&
+				strings.Contains(content, "25 comments") && strings.Contains(content, "https://discord.com/channels/guild1/feed1/msg1")
+		})).Return(nil).Once()
+		// guild2 never got the post in its feed, so its users are sent to the Reddit thread.
+		mockDiscord.On("SendMessage", "ping2", mock.MatchedBy(func(content string) bool {
+			return strings.Contains(content, "<@other_server>") && strings.Contains(content, "https://www.reddit.com/r/CanadianHardwareSwap/comments/hot/")
+		})).Return(nil).Once()
+		mockDB.On("UpdatePostComments", mock.Anything, post.ID, 25).Return(nil)
+
+		if err := handleExistingPostStatus(ctx, mockDB, mockDB, new(testutils.MockAI), mockDiscord, post, record, alerts, ""); err != nil {
+			t.Fatalf("handleExistingPostStatus failed: %v", err)
+		}
+
+		mockDB.AssertExpectations(t)
+		mockDiscord.AssertExpectations(t)
+	})
+
+	t.Run("Already past the threshold does not ping again", func(t *testing.T) {
+		record := &store.PostRecord{RedditID: post.ID, Corpus: "RTX 3080 FE Toronto", ServerMsgs: map[string]string{"guild1": "msg1"}, NumComments: 22}
+
+		mockDB := new(testutils.MockStore)
+		mockDiscord := new(testutils.MockDiscord)
+		mockDB.On("UpdatePostComments", mock.Anything, post.ID, 25).Return(nil)
+
+		if err := handleExistingPostStatus(ctx, mockDB, mockDB, new(testutils.MockAI), mockDiscord, post, record, alerts, ""); err != nil {
+			t.Fatalf("handleExistingPostStatus failed: %v", err)
+		}
+
+		mockDB.AssertExpectations(t)
+		mockDiscord.AssertNotCalled(t, "SendMessage", mock.Anything, mock.Anything)
+	})
+}
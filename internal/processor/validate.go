@@ -0,0 +1,57 @@
+package processor
+
+import (
+	"context"
+	"strings"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/ai"
+	"github.com/pauljones0/betterHardwareSwap/internal/logger"
+	"github.com/pauljones0/betterHardwareSwap/internal/reddit"
+)
+
+// Length limits derived from Discord's embed limits, leaving room for the category emoji title prefix
+// and the escaped, bolded summary line that is rendered above the description.
+const (
+	maxCleanedTitleLen       = 250
+	maxCleanedSummaryLen     = 80
+	maxCleanedDescriptionLen = 3900
+	maxCleanedFieldLen       = 1024
+)
+
+// validateCleanedPost repairs a Gemini result before it is matched and dispatched: an empty title falls
+// back to the raw Reddit title and over-long fields are truncated to what an embed can hold. Repairs are
+// logged so prompt quality can be monitored. It returns false if the post has no usable title at all.
+// The input is never mutated since it may be shared through the clean cache.
+func validateCleanedPost(ctx context.Context, post reddit.Post, cleaned *ai.CleanedPost) (*ai.CleanedPost, bool) {
+	fixed := *cleaned
+	var repairs []string
+
+	fixed.Title = strings.TrimSpace(fixed.Title)
+	if fixed.Title == "" {
+		fixed.Title = strings.TrimSpace(post.Title)
+		repairs = append(repairs, "empty_title")
+	}
+	if fixed.Title == "" {
+		logger.Warn(ctx, "Skipping post with no usable title", "reddit_id", post.ID)
+		return nil, false
+	}
+
+	truncate := func(name string, s *string, max int) {
+		if r := []rune(*s); len(r) > max {
+			*s = string(r[:max-1]) + "…"
+			repairs = append(repairs, name+"_too_long")
+		}
+	}
+	truncate("title", &fixed.Title, maxCleanedTitleLen)
+	fixed.Summary = strings.Join(strings.Fields(fixed.Summary), " ")
+	truncate("summary", &fixed.Summary, maxCleanedSummaryLen)
+	truncate("description", &fixed.Description, maxCleanedDescriptionLen)
+	truncate("price", &fixed.Price, maxCleanedFieldLen)
+	truncate("location", &fixed.Location, maxCleanedFieldLen)
+	truncate("condition", &fixed.Condition, maxCleanedFieldLen)
+
+	if len(repairs) > 0 {
+		logger.Warn(ctx, "Repaired malformed cleaned post", "reddit_id", post.ID, "repairs", repairs)
+	}
+	return &fixed, true
+}
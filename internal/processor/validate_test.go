@@ -0,0 +1,65 @@
+package processor
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/ai"
+	"github.com/pauljones0/betterHardwareSwap/internal/reddit"
+)
+
+func TestValidateCleanedPost(t *testing.T) {
+	ctx := context.Background()
+	post := reddit.Post{ID: "t3_val", Title: "  [H] RTX 3080 [W] $500  "}
+
+	t.Run("Empty title falls back to raw title", func(t *testing.T) {
+		cleaned := &ai.CleanedPost{Title: "   ", Description: "Desc"}
+
+		got, ok := validateCleanedPost(ctx, post, cleaned)
+		if !ok {
+			t.Fatal("expected the post to be repaired, not skipped")
+		}
+		if got.Title != "[H] RTX 3080 [W] $500" {
+			t.Errorf("expected trimmed raw title fallback, got %q", got.Title)
+		}
+		if cleaned.Title != "   " {
+			t.Error("expected the original cleaned post to be left untouched")
+		}
+	})
+
+	t.Run("Over-long fields are truncated", func(t *testing.T) {
+		cleaned := &ai.CleanedPost{Title: strings.Repeat("é", 300), Price: strings.Repeat("$", 2000)}
+
+		got, ok := validateCleanedPost(ctx, post, cleaned)
+		if !ok {
+			t.Fatal("expected the post to be repaired, not skipped")
+		}
+		if n := utf8.RuneCountInString(got.Title); n != maxCleanedTitleLen {
+			t.Errorf("expected title truncated to %d runes, got %d", maxCleanedTitleLen, n)
+		}
+		if n := utf8.RuneCountInString(got.Price); n != maxCleanedFieldLen {
+			t.Errorf("expected price truncated to %d runes, got %d", maxCleanedFieldLen, n)
+		}
+	})
+
+	t.Run("Summary is flattened to one line and truncated", func(t *testing.T) {
+		cleaned := &ai.CleanedPost{Title: "RTX 3080", Summary: "RTX 3080\n  mint " + strings.Repeat("x", 100)}
+
+		got, _ := validateCleanedPost(ctx, post, cleaned)
+		if strings.Contains(got.Summary, "\n") || !strings.HasPrefix(got.Summary, "RTX 3080 mint ") {
+			t.Errorf("expected a single-line summary, got %q", got.Summary)
+		}
+		if n := utf8.RuneCountInString(got.Summary); n != maxCleanedSummaryLen {
+			t.Errorf("expected summary truncated to %d runes, got %d", maxCleanedSummaryLen, n)
+		}
+	})
+
+	t.Run("No usable title is skipped", func(t *testing.T) {
+		_, ok := validateCleanedPost(ctx, reddit.Post{ID: "t3_blank"}, &ai.CleanedPost{})
+		if ok {
+			t.Error("expected a post with no title anywhere to be skipped")
+		}
+	})
+}
@@ -0,0 +1,317 @@
+// Package query parses manually typed Boolean alert queries such as `rtx AND (4080 OR 4090) NOT broken`
+// into an alert's keyword lists without calling the AI.
+package query
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ErrSyntax is returned for queries that are malformed, such as unbalanced parentheses or an operator
+// with nothing after it.
+var ErrSyntax = errors.New("query syntax error")
+
+// ErrUnsupported is returned for queries that parse but can't be read with certainty or can't be
+// stored as one alert, such as two separate OR groups or a lowercase "or" that may be part of a name.
+var ErrUnsupported = errors.New("query not supported")
+
+// Query is a parsed alert query, in the shape alerts are stored: every MustHave term, at least one
+// AnyOf term if there are any, and none of the MustNot terms. Terms are lowercase.
+type Query struct {
+	MustHave []string
+	AnyOf    []string
+	MustNot  []string
+}
+
+// Parse reads a Boolean query. Operators are the uppercase words AND, OR and NOT, and parentheses
+// group. Adjacent words form one phrase ("ryzen 7"), as does text in double quotes, and terms next to
+// each other without an operator are ANDed. The error wraps ErrSyntax or ErrUnsupported.
+func Parse(input string) (*Query, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, fmt.Errorf("%w: unexpected %s", ErrSyntax, tok)
+	}
+
+	q := &Query{}
+	if err := q.add(n); err != nil {
+		return nil, err
+	}
+	if len(q.MustHave) == 0 && len(q.AnyOf) == 0 {
+		return nil, fmt.Errorf("%w: nothing to look for, only words to exclude", ErrUnsupported)
+	}
+	return q, nil
+}
+
+// add merges one ANDed part of the query into q.
+func (q *Query) add(n *node) error {
+	switch n.kind {
+	case nodeTerm:
+		q.MustHave = append(q.MustHave, n.term)
+	case nodeAnd:
+		for _, kid := range n.kids {
+			if err := q.add(kid); err != nil {
+				return err
+			}
+		}
+	case nodeOr:
+		if q.AnyOf != nil {
+			return fmt.Errorf("%w: only one OR group is allowed", ErrUnsupported)
+		}
+		terms, ok := orTerms(n)
+		if !ok {
+			return fmt.Errorf("%w: OR can only join keywords", ErrUnsupported)
+		}
+		q.AnyOf = terms
+	case nodeNot:
+		// NOT (NOT a) is just a.
+		if n.kids[0].kind == nodeNot {
+			return q.add(n.kids[0].kids[0])
+		}
+		// NOT (a OR b) is NOT a AND NOT b, so it excludes both a and b. NOT (a AND b) would only exclude
+		// posts mentioning both, which MustNot can't express.
+		terms, ok := orTerms(n.kids[0])
+		if !ok {
+			return fmt.Errorf("%w: NOT can only exclude keywords", ErrUnsupported)
+		}
+		q.MustNot = append(q.MustNot, terms...)
+	}
+	return nil
+}
+
+// orTerms returns the terms of n if it is a term or an OR of terms.
+func orTerms(n *node) ([]string, bool) {
+	switch n.kind {
+	case nodeTerm:
+		return []string{n.term}, true
+	case nodeOr:
+		var terms []string
+		for _, kid := range n.kids {
+			kidTerms, ok := orTerms(kid)
+			if !ok {
+				return nil, false
+			}
+			terms = append(terms, kidTerms...)
+		}
+		return terms, true
+	}
+	return nil, false
+}
+
+type nodeKind int
+
+const (
+	nodeTerm nodeKind = iota
+	nodeAnd
+	nodeOr
+	nodeNot
+)
+
+type node struct {
+	kind nodeKind
+	term string  // nodeTerm only
+	kids []*node // Two or more for nodeAnd and nodeOr, one for nodeNot
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenOpen
+	tokenClose
+)
+
+type token struct {
+	kind tokenKind
+	text string // tokenWord only
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of query"
+	case tokenWord:
+		return fmt.Sprintf("%q", t.text)
+	case tokenAnd:
+		return "AND"
+	case tokenOr:
+		return "OR"
+	case tokenNot:
+		return "NOT"
+	case tokenOpen:
+		return `"("`
+	default:
+		return `")"`
+	}
+}
+
+// isWordRune reports whether r can be part of a keyword. Anything else, such as && or |, is left for
+// the AI to interpret.
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("-_.+/'#", r)
+}
+
+func lex(input string) ([]token, error) {
+	var tokens []token
+	runes := []rune(input)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenOpen})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenClose})
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("%w: unclosed quote", ErrSyntax)
+			}
+			phrase := strings.Join(strings.Fields(string(runes[i+1:end])), " ")
+			if phrase == "" {
+				return nil, fmt.Errorf("%w: empty quotes", ErrSyntax)
+			}
+			tokens = append(tokens, token{kind: tokenWord, text: strings.ToLower(phrase)})
+			i = end + 1
+		case isWordRune(r):
+			end := i
+			for end < len(runes) && isWordRune(runes[end]) {
+				end++
+			}
+			word := string(runes[i:end])
+			switch word {
+			case "AND":
+				tokens = append(tokens, token{kind: tokenAnd})
+			case "OR":
+				tokens = append(tokens, token{kind: tokenOr})
+			case "NOT":
+				tokens = append(tokens, token{kind: tokenNot})
+			default:
+				switch strings.ToLower(word) {
+				case "and", "or", "not":
+					return nil, fmt.Errorf("%w: %q could be an operator or part of a keyword", ErrUnsupported, word)
+				}
+				tokens = append(tokens, token{kind: tokenWord, text: strings.ToLower(word)})
+			}
+			i = end
+		default:
+			return nil, fmt.Errorf("%w: unexpected character %q", ErrUnsupported, r)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF}), nil
+}
+
+// parser is a recursive-descent parser over the grammar
+//
+//	or    = and { "OR" and }
+//	and   = unary { ["AND"] unary }
+//	unary = "NOT" unary | "(" or ")" | word { word }
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) parseOr() (*node, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	kids := []*node{first}
+	for p.peek().kind == tokenOr {
+		p.next()
+		n, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		kids = append(kids, n)
+	}
+	if len(kids) == 1 {
+		return first, nil
+	}
+	return &node{kind: nodeOr, kids: kids}, nil
+}
+
+func (p *parser) parseAnd() (*node, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	kids := []*node{first}
+	for {
+		switch p.peek().kind {
+		case tokenAnd:
+			p.next()
+		case tokenNot, tokenOpen, tokenWord:
+			// Implicit AND, e.g. "4090 NOT broken".
+		default:
+			if len(kids) == 1 {
+				return first, nil
+			}
+			return &node{kind: nodeAnd, kids: kids}, nil
+		}
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		kids = append(kids, n)
+	}
+}
+
+func (p *parser) parseUnary() (*node, error) {
+	switch tok := p.next(); tok.kind {
+	case tokenNot:
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &node{kind: nodeNot, kids: []*node{n}}, nil
+	case tokenOpen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenClose {
+			return nil, fmt.Errorf("%w: missing \")\"", ErrSyntax)
+		}
+		return n, nil
+	case tokenWord:
+		words := []string{tok.text}
+		for p.peek().kind == tokenWord {
+			words = append(words, p.next().text)
+		}
+		return &node{kind: nodeTerm, term: strings.Join(words, " ")}, nil
+	default:
+		return nil, fmt.Errorf("%w: expected a keyword but found %s", ErrSyntax, tok)
+	}
+}
@@ -0,0 +1,144 @@
+package query
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/matcher"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  Query
+	}{
+		{
+			name:  "Single term",
+			input: "4090",
+			want:  Query{MustHave: []string{"4090"}},
+		},
+		{
+			name:  "AND",
+			input: "rtx AND 4090",
+			want:  Query{MustHave: []string{"rtx", "4090"}},
+		},
+		{
+			name:  "Adjacent words form a phrase",
+			input: "Ryzen 7 AND 5800x3d",
+			want:  Query{MustHave: []string{"ryzen 7", "5800x3d"}},
+		},
+		{
+			name:  "Quoted phrase",
+			input: `"red  devil" AND 7900xtx`,
+			want:  Query{MustHave: []string{"red devil", "7900xtx"}},
+		},
+		{
+			name:  "OR group",
+			input: "3080 OR 3090",
+			want:  Query{AnyOf: []string{"3080", "3090"}},
+		},
+		{
+			name:  "Grouped OR with AND and NOT",
+			input: "rtx AND (4080 OR 4090) NOT broken",
+			want:  Query{MustHave: []string{"rtx"}, AnyOf: []string{"4080", "4090"}, MustNot: []string{"broken"}},
+		},
+		{
+			name:  "Parenthesised phrase with implicit AND NOT",
+			input: "(ryzen 7) NOT (broken)",
+			want:  Query{MustHave: []string{"ryzen 7"}, MustNot: []string{"broken"}},
+		},
+		{
+			name:  "NOT of an OR excludes each term",
+			input: "gpu AND NOT (mining OR broken)",
+			want:  Query{MustHave: []string{"gpu"}, MustNot: []string{"mining", "broken"}},
+		},
+		{
+			name:  "Double negation",
+			input: "gpu NOT (NOT 4090)",
+			want:  Query{MustHave: []string{"gpu", "4090"}},
+		},
+		{
+			name:  "Nested OR",
+			input: "(3080 OR (3090 OR 4090)) AND fe",
+			want:  Query{MustHave: []string{"fe"}, AnyOf: []string{"3080", "3090", "4090"}},
+		},
+		{
+			name:  "Model number punctuation",
+			input: "rx-7900 AND 32gb.ddr5",
+			want:  Query{MustHave: []string{"rx-7900", "32gb.ddr5"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", tt.input, err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.input, *got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  error
+	}{
+		{name: "Empty", input: "  ", want: ErrSyntax},
+		{name: "Unclosed parenthesis", input: "(rtx AND 4090", want: ErrSyntax},
+		{name: "Unopened parenthesis", input: "rtx AND 4090)", want: ErrSyntax},
+		{name: "Empty parentheses", input: "rtx ()", want: ErrSyntax},
+		{name: "Trailing operator", input: "rtx AND", want: ErrSyntax},
+		{name: "Leading operator", input: "OR 4090", want: ErrSyntax},
+		{name: "Operators together", input: "rtx AND OR 4090", want: ErrSyntax},
+		{name: "Unclosed quote", input: `"red devil AND 7900xtx`, want: ErrSyntax},
+		{name: "Lowercase operator", input: "3080 or 3090", want: ErrUnsupported},
+		{name: "Symbol operator", input: "3080 || 3090", want: ErrUnsupported},
+		{name: "Two OR groups", input: "(3080 OR 3090) AND (toronto OR ottawa)", want: ErrUnsupported},
+		{name: "OR of ANDs", input: "(rtx AND 3080) OR 6800xt", want: ErrUnsupported},
+		{name: "NOT of an AND", input: "gpu NOT (mining AND broken)", want: ErrUnsupported},
+		{name: "NOT of an OR holding an AND", input: "gpu NOT (mining OR (broken AND cracked))", want: ErrUnsupported},
+		{name: "Only exclusions", input: "NOT broken", want: ErrUnsupported},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.input)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Parse(%q) = %+v, %v; want error %v", tt.input, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse_NotGroupExcludesEachTerm(t *testing.T) {
+	grouped, err := Parse("3080 AND NOT (broken OR waterblocked)")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	separate, err := Parse("3080 NOT broken NOT waterblocked")
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if !reflect.DeepEqual(grouped, separate) {
+		t.Errorf("NOT (a OR b) = %+v, want the same as NOT a NOT b = %+v", grouped, separate)
+	}
+
+	m := matcher.New()
+	for corpus, want := range map[string]bool{
+		"evga 3080 ftw3":                    true,
+		"evga 3080 ftw3, broken fan":        false,
+		"evga 3080 ftw3 waterblocked":       false,
+		"3080 waterblocked, broken bracket": false,
+	} {
+		if got := m.Matches(corpus, grouped.MustHave, grouped.AnyOf, grouped.MustNot); got != want {
+			t.Errorf("Matches(%q) = %v, want %v", corpus, got, want)
+		}
+	}
+}
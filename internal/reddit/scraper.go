@@ -3,17 +3,24 @@ package reddit
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/pauljones0/betterHardwareSwap/internal/clock"
 	"github.com/pauljones0/betterHardwareSwap/internal/logger"
 )
 
 // Reddit struct maps the nested structure of Reddit's .json feed.
 type Feed struct {
 	Data struct {
+		After    string `json:"after"` // Cursor for the next page, empty on the last one
 		Children []struct {
 			Data Post `json:"data"`
 		} `json:"children"`
@@ -35,15 +42,125 @@ type Post struct {
 	LinkFlairText       string  `json:"link_flair_text"`     // "Closed", "Selling", etc
 	RemovedByByCategory string  `json:"removed_by_category"` // "moderator", "deleted"
 	Thumbnail           string  `json:"thumbnail"`
+	Edited              Edited  `json:"edited"`   // Zero if never edited
+	Over18              bool    `json:"over_18"`  // Marked NSFW
+	Stickied            bool    `json:"stickied"` // Pinned mod threads (confirmed trades, price checks, rules)
+
+	// Media. Gallery posts link to a reddit.com/gallery page and often have a "default" thumbnail, so
+	// their images come from GalleryData (display order) and MediaMetadata (URLs by media ID).
+	PostHint      string                   `json:"post_hint"` // "image", "hosted:video", "rich:video", "link", "self"
+	IsVideo       bool                     `json:"is_video"`  // Reddit-hosted video
+	IsGallery     bool                     `json:"is_gallery"`
+	GalleryData   *GalleryData             `json:"gallery_data"`
+	MediaMetadata map[string]MediaMetadata `json:"media_metadata"`
+}
+
+// GalleryData lists a gallery post's images in the order the seller arranged them.
+type GalleryData struct {
+	Items []struct {
+		MediaID string `json:"media_id"`
+	} `json:"items"`
+}
+
+// MediaMetadata describes one image uploaded with a gallery post.
+type MediaMetadata struct {
+	Status string `json:"status"` // "valid" once Reddit has processed the upload
+	Source struct {
+		URL string `json:"u"` // HTML-escaped, as Reddit sends it
+	} `json:"s"`
+}
+
+// HasVideo reports whether the post is a video, whether hosted on Reddit or embedded from elsewhere.
+func (p Post) HasVideo() bool {
+	return p.IsVideo || strings.HasSuffix(p.PostHint, ":video")
+}
+
+// FirstGalleryImage returns the URL of the first processed image in a gallery post, or "" if the post
+// isn't a gallery or none of its images are ready.
+func (p Post) FirstGalleryImage() string {
+	if !p.IsGallery || p.GalleryData == nil {
+		return ""
+	}
+	for _, item := range p.GalleryData.Items {
+		media, ok := p.MediaMetadata[item.MediaID]
+		if ok && media.Status == "valid" && media.Source.URL != "" {
+			return html.UnescapeString(media.Source.URL)
+		}
+	}
+	return ""
+}
+
+// Edited is Reddit's `edited` field, which is `false` for unedited posts and a Unix timestamp otherwise.
+type Edited float64
+
+// UnmarshalJSON accepts both the boolean and numeric forms Reddit sends.
+func (e *Edited) UnmarshalJSON(b []byte) error {
+	var ts float64
+	if err := json.Unmarshal(b, &ts); err == nil {
+		*e = Edited(ts)
+		return nil
+	}
+	var flag bool
+	if err := json.Unmarshal(b, &flag); err != nil {
+		return fmt.Errorf("unexpected edited value %s: %w", string(b), err)
+	}
+	*e = 0
+	return nil
+}
+
+// Time returns when the post was last edited, or the zero time if it never was.
+func (e Edited) Time() time.Time {
+	if e <= 0 {
+		return time.Time{}
+	}
+	sec := int64(e)
+	return time.Unix(sec, int64((float64(e)-float64(sec))*1e9))
 }
 
-// Scraper handles talking to Reddit.
+// ErrAccessDenied means Reddit rejected the bot outright (401/403), usually a bad User-Agent or an IP ban.
+// Retrying won't help; an admin needs to look.
+var ErrAccessDenied = errors.New("reddit denied access")
+
+// Sort is a Reddit listing order.
+type Sort string
+
+// Listing orders FetchNewestPosts can read the subreddit in.
+const (
+	// SortNew lists posts newest first: the firehose every deal feed is built from.
+	SortNew Sort = "new"
+	// SortRising lists recent posts that are quickly gaining votes and comments, for trending feeds.
+	SortRising Sort = "rising"
+	// SortHot lists Reddit's usual front-page ranking.
+	SortHot Sort = "hot"
+)
+
+// Valid reports whether s is a listing order Reddit serves.
+func (s Sort) Valid() bool {
+	switch s {
+	case SortNew, SortRising, SortHot:
+		return true
+	}
+	return false
+}
+
+// MaxPageSize is the most posts Reddit returns for a single listing request.
+const MaxPageSize = 100
+
+// Scraper handles talking to Reddit. It keeps no state between fetches, so one Scraper can serve
+// concurrent fetches (e.g. several listing orders at once) as long as its fields aren't changed meanwhile.
 type Scraper struct {
 	httpClient   *http.Client
 	BaseURL      string
 	RetryBackoff time.Duration
+	Clock        clock.Clock // Times retry backoffs; tests swap in a clock.Fake to skip the waits
+	Limit        int         // Newest posts to fetch per run; above MaxPageSize the feed is paged
+	UserAgent    string      // Sent with every request; DefaultUserAgent when empty
 }
 
+// DefaultUserAgent identifies this project's bot to Reddit. Reddit asks every client to send its own
+// descriptive User-Agent, so forks and self-hosted deployments should set REDDIT_USER_AGENT instead.
+const DefaultUserAgent = "script:canadianhardwareswapbot:v2.0 (by u/pauljones0)"
+
 // NewScraper returns an initialized Scraper.
 func NewScraper() *Scraper {
 	return &Scraper{
@@ -52,11 +169,18 @@ func NewScraper() *Scraper {
 		},
 		BaseURL:      "https://www.reddit.com",
 		RetryBackoff: 2 * time.Second,
+		Clock:        clock.Real{},
+		Limit:        MaxPageSize,
+		UserAgent:    DefaultUserAgent,
 	}
 }
 
-// FetchNewestPosts hits the .json endpoint of r/CanadianHardwareSwap.
-func (s *Scraper) FetchNewestPosts(ctx context.Context) ([]Post, error) {
+// FetchNewestPosts hits the .json endpoint of r/CanadianHardwareSwap for the given listing order.
+func (s *Scraper) FetchNewestPosts(ctx context.Context, sort Sort) ([]Post, error) {
+	if !sort.Valid() {
+		return nil, fmt.Errorf("unknown reddit sort %q", sort)
+	}
+
 	// =========================================================================
 	// TEMPORARY: Reddit fetching is disabled.
 	//
@@ -70,29 +194,68 @@ func (s *Scraper) FetchNewestPosts(ctx context.Context) ([]Post, error) {
 	// =========================================================================
 	logger.Warn(ctx, "Reddit fetching is temporarily disabled — returning empty feed")
 	return []Post{}, nil
+}
 
-	// ---- Original implementation below (unreachable until stub is removed) --
+// fetchLive is the real Reddit fetch that FetchNewestPosts delegates to once the stub above is removed.
+// It pages through the feed, MaxPageSize posts at a time, until s.Limit posts have been listed or the
+// feed runs out.
+func (s *Scraper) fetchLive(ctx context.Context, sort Sort) ([]Post, error) {
+	remaining := s.Limit
+	if remaining <= 0 {
+		remaining = MaxPageSize
+	}
 
+	var posts []Post
+	after := ""
+	for remaining > 0 {
+		pageSize := min(remaining, MaxPageSize)
+		page, listed, next, err := s.fetchPage(ctx, sort, pageSize, after)
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, page...)
+		remaining -= listed
+		// A short page means the feed has nothing older to give.
+		if next == "" || listed < pageSize {
+			break
+		}
+		after = next
+	}
+	return posts, nil
+}
+
+// fetchPage fetches one page of up to limit posts in the sort's listing after the given cursor, returning the posts kept,
+// how many Reddit listed (stickies included) and the cursor for the next page.
+// 429s and 5xx responses are retried with backoff (honouring Retry-After on 429s); 401/403 mean the bot
+// is blocked or misconfigured, so they fail fast with ErrAccessDenied instead of hammering Reddit.
+func (s *Scraper) fetchPage(ctx context.Context, sort Sort, limit int, after string) ([]Post, int, string, error) {
 	// maxRetries capped at 3 (down from 8) to fail fast and stay within the
 	// Cloud Run timeout. Worst-case total wait: 2s + 4s + 8s = 14s.
 	maxRetries := 3
 	backoff := s.RetryBackoff
 	maxBackoff := 10 * time.Second
-	var lastErr error
 	var respStatusCode int
 
 	for i := 0; i < maxRetries; i++ {
-		req, err := http.NewRequestWithContext(ctx, "GET", s.BaseURL+"/r/CanadianHardwareSwap/.json?sort=new&limit=100", nil)
+		query := url.Values{"limit": {strconv.Itoa(limit)}}
+		if after != "" {
+			query.Set("after", after)
+		}
+		req, err := http.NewRequestWithContext(ctx, "GET", s.BaseURL+"/r/CanadianHardwareSwap/"+string(sort)+".json?"+query.Encode(), nil)
 		if err != nil {
-			return nil, err
+			return nil, 0, "", err
 		}
 
 		// Reddit explicitly requires a custom User-Agent to avoid IP bans.
-		req.Header.Set("User-Agent", "script:canadianhardwareswapbot:v2.0 (by u/pauljones0)")
+		userAgent := s.UserAgent
+		if userAgent == "" {
+			userAgent = DefaultUserAgent
+		}
+		req.Header.Set("User-Agent", userAgent)
 
 		resp, err := s.httpClient.Do(req)
 		if err != nil {
-			return nil, err
+			return nil, 0, "", err
 		}
 
 		respStatusCode = resp.StatusCode
@@ -101,49 +264,70 @@ func (s *Scraper) FetchNewestPosts(ctx context.Context) ([]Post, error) {
 			body, err := io.ReadAll(resp.Body)
 			resp.Body.Close() // Close explicitly — defer inside a loop is a leak.
 			if err != nil {
-				return nil, fmt.Errorf("failed to read reddit response body: %w", err)
+				return nil, 0, "", fmt.Errorf("failed to read reddit response body: %w", err)
 			}
 
 			var feed Feed
 			if err := json.Unmarshal(body, &feed); err != nil {
-				return nil, fmt.Errorf("failed to decode reddit json: %w", err)
+				return nil, 0, "", fmt.Errorf("failed to decode reddit json: %w", err)
 			}
 
 			var posts []Post
 			for _, child := range feed.Data.Children {
 				// Only track actual posts, not stickies/announcements
-				if child.Data.Author != "AutoModerator" {
+				if child.Data.Author != "AutoModerator" && !child.Data.Stickied {
 					posts = append(posts, child.Data)
 				}
 			}
 
-			return posts, nil
+			return posts, len(feed.Data.Children), feed.Data.After, nil
+		}
+
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+			return nil, 0, "", fmt.Errorf("%w: reddit returned %d: %s", ErrAccessDenied, resp.StatusCode, string(body))
 		}
 
-		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusForbidden || resp.StatusCode >= 500 {
-			resp.Body.Close()
-			logger.Warn(ctx, "Reddit request failed, retrying", "status", resp.StatusCode, "retry", i+1, "backoff", backoff)
+		if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode < 500 {
+			return nil, 0, "", fmt.Errorf("reddit returned %d: %s", resp.StatusCode, string(body))
+		}
 
-			select {
-			case <-time.After(backoff):
-				backoff *= 2
-				if backoff > maxBackoff {
-					backoff = maxBackoff
-				}
-				continue
-			case <-ctx.Done():
-				return nil, ctx.Err()
+		if i == maxRetries-1 {
+			break
+		}
+
+		wait := backoff
+		if resp.StatusCode == http.StatusTooManyRequests {
+			if ra := retryAfter(resp); ra > 0 {
+				wait = ra
 			}
 		}
+		if wait > maxBackoff {
+			wait = maxBackoff
+		}
+		logger.Warn(ctx, "Reddit request failed, retrying", "status", resp.StatusCode, "retry", i+1, "backoff", wait)
 
-		body, _ := io.ReadAll(resp.Body)
-		resp.Body.Close()
-		lastErr = fmt.Errorf("reddit returned %d: %s", respStatusCode, string(body))
-		break // Not a retryable status, stop immediately.
+		select {
+		case <-s.Clock.After(wait):
+			backoff *= 2
+			if backoff > maxBackoff {
+				backoff = maxBackoff
+			}
+		case <-ctx.Done():
+			return nil, 0, "", ctx.Err()
+		}
 	}
 
-	if lastErr != nil {
-		return nil, lastErr
+	return nil, 0, "", fmt.Errorf("max retries exceeded, last status: %d", respStatusCode)
+}
+
+// retryAfter parses the Retry-After header (in seconds) Reddit sends with 429s.
+func retryAfter(resp *http.Response) time.Duration {
+	secs, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || secs <= 0 {
+		return 0
 	}
-	return nil, fmt.Errorf("max retries exceeded, last status: %d", respStatusCode)
+	return time.Duration(secs) * time.Second
 }
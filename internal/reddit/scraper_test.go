@@ -3,9 +3,16 @@ package reddit
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -38,11 +45,204 @@ func TestParseSample(t *testing.T) {
 	}
 }
 
-func TestFetchWithRetries(t *testing.T) {
-	// TEMPORARY: Skipped while the Reddit stub is active in FetchNewestPosts.
-	// Re-enable once the stub is removed and live fetching is restored.
-	t.Skip("Reddit fetching is temporarily disabled — see scraper.go stub")
+func TestFetchSkipsStickiedPosts(t *testing.T) {
+	sample, err := os.ReadFile("sample.json")
+	if err != nil {
+		t.Fatalf("failed to read sample.json: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(sample)
+	}))
+	defer server.Close()
+
+	s := NewScraper()
+	s.BaseURL = server.URL
+
+	posts, err := s.fetchLive(context.Background(), SortNew)
+	if err != nil {
+		t.Fatalf("fetchLive failed: %v", err)
+	}
+
+	// The sample leads with the pinned confirmed-trade and price-check threads.
+	if len(posts) != 23 {
+		t.Errorf("expected the 2 stickied threads to be dropped from 25 posts, got %d", len(posts))
+	}
+	for _, p := range posts {
+		if p.Stickied || p.ID == "1r4zyeg" || p.ID == "1qsilcl" {
+			t.Errorf("stickied post %s (%q) was not filtered", p.ID, p.Title)
+		}
+	}
+}
+
+func TestFetchLimit(t *testing.T) {
+	// page builds a listing of n non-stickied posts followed by the given cursor.
+	page := func(n int, after string) Feed {
+		var feed Feed
+		feed.Data.After = after
+		for i := 0; i < n; i++ {
+			var child struct {
+				Data Post `json:"data"`
+			}
+			child.Data = Post{ID: fmt.Sprintf("%s%d", after, i), Author: "seller"}
+			feed.Data.Children = append(feed.Data.Children, child)
+		}
+		return feed
+	}
+
+	tests := []struct {
+		name      string
+		limit     int
+		wantQuery []string
+		wantPosts int
+	}{
+		{name: "Small limit", limit: 25, wantQuery: []string{"limit=25"}, wantPosts: 25},
+		{name: "Capped at one page", limit: 100, wantQuery: []string{"limit=100"}, wantPosts: 100},
+		{name: "Paged", limit: 250, wantQuery: []string{
+			"limit=100",
+			"after=t3_p1&limit=100",
+			"after=t3_p2&limit=50",
+		}, wantPosts: 250},
+		{name: "Unset defaults to one page", limit: 0, wantQuery: []string{"limit=100"}, wantPosts: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var queries []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				queries = append(queries, r.URL.RawQuery)
+				limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+				json.NewEncoder(w).Encode(page(limit, fmt.Sprintf("t3_p%d", len(queries))))
+			}))
+			defer server.Close()
+
+			s := NewScraper()
+			s.BaseURL = server.URL
+			s.Limit = tt.limit
+
+			posts, err := s.fetchLive(context.Background(), SortNew)
+			if err != nil {
+				t.Fatalf("fetchLive failed: %v", err)
+			}
+			if !reflect.DeepEqual(queries, tt.wantQuery) {
+				t.Errorf("got queries %q, want %q", queries, tt.wantQuery)
+			}
+			if len(posts) != tt.wantPosts {
+				t.Errorf("expected %d posts, got %d", tt.wantPosts, len(posts))
+			}
+		})
+	}
+}
+
+func TestFetchSortEndpoint(t *testing.T) {
+	for _, sort := range []Sort{SortNew, SortRising, SortHot} {
+		t.Run(string(sort), func(t *testing.T) {
+			var path string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				json.NewEncoder(w).Encode(Feed{})
+			}))
+			defer server.Close()
+
+			s := NewScraper()
+			s.BaseURL = server.URL
+
+			if _, err := s.fetchLive(context.Background(), sort); err != nil {
+				t.Fatalf("fetchLive failed: %v", err)
+			}
+			if want := "/r/CanadianHardwareSwap/" + string(sort) + ".json"; path != want {
+				t.Errorf("got path %q, want %q", path, want)
+			}
+		})
+	}
+
+	if _, err := NewScraper().FetchNewestPosts(context.Background(), Sort("top")); err == nil {
+		t.Error("expected an unknown sort to be rejected")
+	}
+}
+
+func TestFetchUserAgent(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		want      string
+	}{
+		{"Default", DefaultUserAgent, DefaultUserAgent},
+		{"Configured", "script:mydealbot:v1.0 (by u/someone)", "script:mydealbot:v1.0 (by u/someone)"},
+		{"Empty falls back to the default", "", DefaultUserAgent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.Header.Get("User-Agent")
+				json.NewEncoder(w).Encode(Feed{})
+			}))
+			defer server.Close()
+
+			s := NewScraper()
+			s.BaseURL = server.URL
+			s.UserAgent = tt.userAgent
+
+			if _, err := s.fetchLive(context.Background(), SortNew); err != nil {
+				t.Fatalf("fetchLive failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("sent User-Agent %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
 
+func TestFetchConcurrent(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		var feed Feed
+		feed.Data.Children = make([]struct {
+			Data Post `json:"data"`
+		}, 1)
+		feed.Data.Children[0].Data = Post{ID: strings.TrimSuffix(r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:], ".json")}
+		json.NewEncoder(w).Encode(feed)
+	}))
+	defer server.Close()
+
+	s := NewScraper()
+	s.BaseURL = server.URL
+
+	// Run with -race: every fetch shares the Scraper and its HTTP client.
+	sorts := []Sort{SortNew, SortRising, SortHot, SortNew, SortRising, SortHot}
+	var wg sync.WaitGroup
+	errs := make([]error, len(sorts))
+	ids := make([]string, len(sorts))
+	for n, sort := range sorts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			posts, err := s.fetchLive(context.Background(), sort)
+			errs[n] = err
+			if len(posts) == 1 {
+				ids[n] = posts[0].ID
+			}
+		}()
+	}
+	wg.Wait()
+
+	for n, sort := range sorts {
+		if errs[n] != nil {
+			t.Errorf("fetch %d (%s) failed: %v", n, sort, errs[n])
+		}
+		if ids[n] != string(sort) {
+			t.Errorf("fetch %d got post %q, want the %s listing", n, ids[n], sort)
+		}
+	}
+	if got := requests.Load(); got != int32(len(sorts)) {
+		t.Errorf("expected %d requests, got %d", len(sorts), got)
+	}
+}
+
+func TestFetchWithRetries(t *testing.T) {
 	ctx := context.Background()
 	callCount := 0
 
@@ -62,7 +262,8 @@ func TestFetchWithRetries(t *testing.T) {
 	s.BaseURL = server.URL
 	s.RetryBackoff = 1 * time.Millisecond // Fast retries for testing
 
-	_, err := s.FetchNewestPosts(ctx)
+	// FetchNewestPosts is stubbed while Reddit blocks Cloud Run, so exercise the live path directly.
+	_, err := s.fetchLive(ctx, SortNew)
 	if err != nil {
 		t.Errorf("expected success after retries, got error: %v", err)
 	}
@@ -71,3 +272,124 @@ func TestFetchWithRetries(t *testing.T) {
 		t.Errorf("expected 3 calls, got %d", callCount)
 	}
 }
+
+func TestFetchRetryClassification(t *testing.T) {
+	tests := []struct {
+		name          string
+		status        int
+		retryAfter    string
+		wantCalls     int
+		wantDenied    bool
+		wantMinElapse time.Duration
+	}{
+		{name: "429 retries and honours Retry-After", status: http.StatusTooManyRequests, retryAfter: "1", wantCalls: 2, wantMinElapse: time.Second},
+		{name: "500 retries", status: http.StatusInternalServerError, wantCalls: 2},
+		{name: "503 retries", status: http.StatusServiceUnavailable, wantCalls: 2},
+		{name: "403 fails fast", status: http.StatusForbidden, wantCalls: 1, wantDenied: true},
+		{name: "401 fails fast", status: http.StatusUnauthorized, wantCalls: 1, wantDenied: true},
+		{name: "404 fails fast", status: http.StatusNotFound, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls == 1 {
+					if tt.retryAfter != "" {
+						w.Header().Set("Retry-After", tt.retryAfter)
+					}
+					w.WriteHeader(tt.status)
+					return
+				}
+				json.NewEncoder(w).Encode(Feed{})
+			}))
+			defer server.Close()
+
+			s := NewScraper()
+			s.BaseURL = server.URL
+			s.RetryBackoff = time.Millisecond
+
+			start := time.Now()
+			_, err := s.fetchLive(context.Background(), SortNew)
+			elapsed := time.Since(start)
+
+			if calls != tt.wantCalls {
+				t.Errorf("expected %d calls, got %d", tt.wantCalls, calls)
+			}
+			if tt.wantCalls > 1 && err != nil {
+				t.Errorf("expected retry to succeed, got %v", err)
+			}
+			if tt.wantCalls == 1 && err == nil {
+				t.Error("expected an error")
+			}
+			if got := errors.Is(err, ErrAccessDenied); got != tt.wantDenied {
+				t.Errorf("errors.Is(err, ErrAccessDenied) = %v, want %v (err: %v)", got, tt.wantDenied, err)
+			}
+			if elapsed < tt.wantMinElapse {
+				t.Errorf("expected to wait at least %v, waited %v", tt.wantMinElapse, elapsed)
+			}
+		})
+	}
+}
+
+func TestEditedUnmarshal(t *testing.T) {
+	tests := []struct {
+		name string
+		json string
+		want Edited
+	}{
+		{"Never edited", `{"id": "a", "edited": false}`, 0},
+		{"Edited timestamp", `{"id": "a", "edited": 1672534800.0}`, 1672534800},
+		{"Field missing", `{"id": "a"}`, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var post Post
+			if err := json.Unmarshal([]byte(tt.json), &post); err != nil {
+				t.Fatalf("failed to unmarshal: %v", err)
+			}
+			if post.Edited != tt.want {
+				t.Errorf("Edited = %v, want %v", post.Edited, tt.want)
+			}
+		})
+	}
+
+	if !Edited(0).Time().IsZero() {
+		t.Error("expected an unedited post to report the zero time")
+	}
+	if got := Edited(1672534800).Time(); !got.Equal(time.Unix(1672534800, 0)) {
+		t.Errorf("unexpected edit time %v", got)
+	}
+}
+
+func TestPostMedia(t *testing.T) {
+	var gallery Post
+	if err := json.Unmarshal([]byte(`{
+		"is_gallery": true,
+		"gallery_data": {"items": [{"media_id": "a"}, {"media_id": "b"}]},
+		"media_metadata": {
+			"a": {"status": "failed"},
+			"b": {"status": "valid", "s": {"u": "https://preview.redd.it/b.jpg?width=640&amp;s=x"}}
+		}
+	}`), &gallery); err != nil {
+		t.Fatalf("failed to parse gallery post: %v", err)
+	}
+	if got, want := gallery.FirstGalleryImage(), "https://preview.redd.it/b.jpg?width=640&s=x"; got != want {
+		t.Errorf("FirstGalleryImage() = %q, want %q", got, want)
+	}
+	if gallery.HasVideo() {
+		t.Error("a gallery is not a video")
+	}
+
+	if got := (Post{Thumbnail: "https://b.thumbs.redditmedia.com/x.jpg"}).FirstGalleryImage(); got != "" {
+		t.Errorf("expected no gallery image for a plain post, got %q", got)
+	}
+	if !(Post{IsVideo: true}).HasVideo() || !(Post{PostHint: "rich:video"}).HasVideo() {
+		t.Error("expected hosted and embedded videos to be recognised")
+	}
+	if (Post{PostHint: "image"}).HasVideo() {
+		t.Error("an image post is not a video")
+	}
+}
@@ -0,0 +1,24 @@
+package store
+
+import (
+	"context"
+
+	"cloud.google.com/go/firestore"
+)
+
+// IncrementCounter atomically adds delta to a numeric field of collection/docID, creating the document or
+// field if it doesn't exist yet. The addition runs server-side as an Increment transform, so concurrent
+// pipeline goroutines and instances never overwrite each other's counts the way a read-modify-write would.
+func (s *Store) IncrementCounter(ctx context.Context, collection, docID, field string, delta int64) error {
+	ref := s.client.Collection(collection).Doc(docID)
+	_, err := ref.Set(ctx, map[string]interface{}{field: firestore.Increment(delta)}, firestore.Merge([]string{field}))
+	return wrapErr(err)
+}
+
+// IncrementExistingCounter is IncrementCounter for counters kept on documents that may be deleted, like
+// alerts: it returns an error matching ErrNotFound instead of recreating a missing document.
+func (s *Store) IncrementExistingCounter(ctx context.Context, collection, docID, field string, delta int64) error {
+	ref := s.client.Collection(collection).Doc(docID)
+	_, err := ref.Update(ctx, []firestore.Update{{FieldPath: firestore.FieldPath{field}, Value: firestore.Increment(delta)}})
+	return wrapErr(err)
+}
@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newEmulatorStore connects to the Firestore emulator, e.g. `gcloud emulators firestore start
+// --host-port=localhost:8681` with FIRESTORE_EMULATOR_HOST=localhost:8681, and skips the test otherwise.
+func newEmulatorStore(t *testing.T) *Store {
+	t.Helper()
+	if os.Getenv("FIRESTORE_EMULATOR_HOST") == "" {
+		t.Skip("FIRESTORE_EMULATOR_HOST not set")
+	}
+	s, err := NewStore(context.Background(), "test-project")
+	if err != nil {
+		t.Fatalf("NewStore failed: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestIncrementCounter_Emulator(t *testing.T) {
+	ctx := context.Background()
+	s := newEmulatorStore(t)
+	docID := "counter-" + time.Now().Format("150405.000000000")
+
+	// Concurrent increments on a document that doesn't exist yet all land.
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := s.IncrementCounter(ctx, "counters_test", docID, "hits", 2); err != nil {
+				t.Errorf("IncrementCounter failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if err := s.IncrementCounter(ctx, "counters_test", docID, "misses", 1); err != nil {
+		t.Fatalf("IncrementCounter failed: %v", err)
+	}
+
+	doc, err := s.client.Collection("counters_test").Doc(docID).Get(ctx)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if hits, _ := doc.DataAt("hits"); hits != int64(40) {
+		t.Errorf("expected 20 increments of 2 to sum to 40, got %v", hits)
+	}
+	if misses, _ := doc.DataAt("misses"); misses != int64(1) {
+		t.Errorf("expected a second field to be added alongside the first, got %v", misses)
+	}
+}
+
+func TestIncrementExistingCounter_Emulator(t *testing.T) {
+	ctx := context.Background()
+	s := newEmulatorStore(t)
+	docID := "existing-" + time.Now().Format("150405.000000000")
+
+	err := s.IncrementExistingCounter(ctx, "counters_test", docID, "hits", 1)
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected ErrNotFound for a missing document, got %v", err)
+	}
+	if doc, _ := s.client.Collection("counters_test").Doc(docID).Get(ctx); doc != nil && doc.Exists() {
+		t.Fatal("expected the missing document not to be created")
+	}
+
+	if _, err := s.client.Collection("counters_test").Doc(docID).Set(ctx, map[string]interface{}{"hits": 1}); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := s.IncrementExistingCounter(ctx, "counters_test", docID, "hits", 2); err != nil {
+		t.Fatalf("IncrementExistingCounter failed: %v", err)
+	}
+	doc, err := s.client.Collection("counters_test").Doc(docID).Get(ctx)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if hits, _ := doc.DataAt("hits"); hits != int64(3) {
+		t.Errorf("expected 1+2 to sum to 3, got %v", hits)
+	}
+}
@@ -0,0 +1,36 @@
+package store
+
+import (
+	"context"
+	"sync"
+)
+
+// The process-wide Store returned by Default. The Firestore client multiplexes requests over its own
+// gRPC connections and is safe for concurrent use, so one per process is all we need.
+var (
+	defaultOnce  sync.Once
+	defaultStore *Store
+	defaultErr   error
+)
+
+// Default returns the process-wide Store, dialing Firestore for projectID on the first call. Every later
+// call returns the same Store (projectID is then ignored), or the same error if that first dial failed.
+// Only ctx's values reach the client; its deadline and cancellation are dropped, so a request context
+// that happened to make the first call neither bounds the dial nor closes the shared client when it
+// ends. Call CloseDefault on shutdown.
+func Default(ctx context.Context, projectID string) (*Store, error) {
+	defaultOnce.Do(func() {
+		defaultStore, defaultErr = NewStore(context.WithoutCancel(ctx), projectID)
+	})
+	return defaultStore, defaultErr
+}
+
+// CloseDefault closes the Store returned by Default, if one was created. The Store must not be used
+// afterwards.
+func CloseDefault() error {
+	defaultOnce.Do(func() {}) // Make sure a Default racing with shutdown can't dial after this.
+	if defaultStore == nil {
+		return nil
+	}
+	return defaultStore.Close()
+}
@@ -0,0 +1,57 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"testing"
+)
+
+// resetDefault forgets the process-wide Store so each test dials its own.
+func resetDefault(t *testing.T) {
+	t.Helper()
+	defaultOnce = sync.Once{}
+	defaultStore, defaultErr = nil, nil
+}
+
+func TestDefault_ReturnsSameStore(t *testing.T) {
+	// The emulator host makes the client skip credentials; nothing is dialed until a request is made.
+	t.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:8681")
+	resetDefault(t)
+	t.Cleanup(func() { resetDefault(t) })
+
+	const callers = 10
+	stores := make([]*Store, callers)
+	var wg sync.WaitGroup
+	for n := range callers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s, err := Default(context.Background(), "test-project")
+			if err != nil {
+				t.Errorf("Default failed: %v", err)
+				return
+			}
+			stores[n] = s
+		}()
+	}
+	wg.Wait()
+
+	for n, s := range stores {
+		if s == nil || s != stores[0] {
+			t.Fatalf("call %d returned a different Store: %p vs %p", n, s, stores[0])
+		}
+	}
+
+	if err := CloseDefault(); err != nil {
+		t.Errorf("CloseDefault failed: %v", err)
+	}
+}
+
+func TestCloseDefault_NeverOpened(t *testing.T) {
+	resetDefault(t)
+	t.Cleanup(func() { resetDefault(t) })
+
+	if err := CloseDefault(); err != nil {
+		t.Errorf("expected closing an unopened default store to be a no-op, got %v", err)
+	}
+}
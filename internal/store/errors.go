@@ -0,0 +1,35 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Errors store methods wrap Firestore failures in, so callers can tell a missing document or a
+// credentials problem from a network error with errors.Is, without importing gRPC status codes. The
+// original Firestore error stays in the chain.
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrPermission = errors.New("permission denied")
+
+	// ErrAlertNotFound is returned for an alert that has been deleted. It matches ErrNotFound too.
+	ErrAlertNotFound = fmt.Errorf("alert %w", ErrNotFound)
+)
+
+// wrapErr maps err onto ErrNotFound or ErrPermission by its gRPC code. Other errors, errors that are
+// already wrapped and nil are returned unchanged.
+func wrapErr(err error) error {
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrPermission) {
+		return err
+	}
+	switch status.Code(err) {
+	case codes.NotFound:
+		return fmt.Errorf("%w: %w", ErrNotFound, err)
+	case codes.PermissionDenied, codes.Unauthenticated:
+		return fmt.Errorf("%w: %w", ErrPermission, err)
+	}
+	return err
+}
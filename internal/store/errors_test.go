@@ -0,0 +1,61 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestWrapErr(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error // Sentinel the result must match; nil means none
+	}{
+		{"Not found", status.Error(codes.NotFound, "no such document"), ErrNotFound},
+		{"Permission denied", status.Error(codes.PermissionDenied, "missing IAM role"), ErrPermission},
+		{"Unauthenticated", status.Error(codes.Unauthenticated, "expired credentials"), ErrPermission},
+		{"Unavailable", status.Error(codes.Unavailable, "connection reset"), nil},
+		{"Plain error", errors.New("bad data"), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := wrapErr(tt.err)
+			if !errors.Is(got, tt.err) {
+				t.Errorf("expected the original error to stay in the chain, got %v", got)
+			}
+			for _, sentinel := range []error{ErrNotFound, ErrPermission} {
+				if want := sentinel == tt.want; errors.Is(got, sentinel) != want {
+					t.Errorf("errors.Is(%v, %v) = %v, want %v", got, sentinel, !want, want)
+				}
+			}
+			if status.Code(got) != status.Code(tt.err) {
+				t.Errorf("expected the gRPC code %v to survive wrapping, got %v", status.Code(tt.err), status.Code(got))
+			}
+		})
+	}
+
+	if wrapErr(nil) != nil {
+		t.Error("expected nil to stay nil")
+	}
+	once := wrapErr(status.Error(codes.NotFound, "gone"))
+	if twice := wrapErr(once); twice != once {
+		t.Errorf("expected an already wrapped error to be returned unchanged, got %v", twice)
+	}
+	if !errors.Is(ErrAlertNotFound, ErrNotFound) {
+		t.Error("expected ErrAlertNotFound to be an ErrNotFound")
+	}
+}
+
+func TestGetServerConfig_NotFound_Emulator(t *testing.T) {
+	s := newEmulatorStore(t)
+
+	_, err := s.GetServerConfig(context.Background(), "never-set-up")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a server that was never set up, got %v", err)
+	}
+}
@@ -2,72 +2,207 @@ package store
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"sort"
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"cloud.google.com/go/firestore/apiv1/firestorepb"
+	"github.com/pauljones0/betterHardwareSwap/internal/clock"
 	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
-// Store represents a connection to the Firestore database.
+// Store represents a connection to the Firestore database. Its methods wrap missing documents and
+// permission failures in ErrNotFound and ErrPermission.
 type Store struct {
 	client *firestore.Client
+	clock  clock.Clock // Stamps created and updated times; clock.Real outside tests
+}
+
+// FeedMode controls which deals are posted to a server's feed channel.
+type FeedMode string
+
+const (
+	// FeedModeAlertsOnly posts only deals that match at least one alert on the server. This is the default.
+	FeedModeAlertsOnly FeedMode = "alerts_only"
+	// FeedModeAllDeals posts every new deal, pinging users only when their alerts match.
+	FeedModeAllDeals FeedMode = "all_deals"
+)
+
+// Language is the language a server reads its deal feed and help text in.
+type Language string
+
+const (
+	// LanguageEnglish is the default.
+	LanguageEnglish Language = "en"
+	// LanguageFrench cleans deals into French and shows the help and setup messages in French.
+	LanguageFrench Language = "fr"
+)
+
+// ServerLanguage returns the language configured for a server, defaulting to English for servers
+// that never chose one and for DM scopes, which have no config (nil).
+func ServerLanguage(cfg *ServerConfig) Language {
+	if cfg == nil || cfg.Language != LanguageFrench {
+		return LanguageEnglish
+	}
+	return LanguageFrench
 }
 
 // ServerConfig stores Discord server configuration.
 type ServerConfig struct {
-	FeedChannelID string    `firestore:"feed_channel_id"`
-	PingChannelID string    `firestore:"ping_channel_id"`
-	UpdatedAt     time.Time `firestore:"updated_at"`
+	ServerID         string    `firestore:"-"`
+	FeedChannelID    string    `firestore:"feed_channel_id"`
+	PingChannelID    string    `firestore:"ping_channel_id"`
+	ArchiveChannelID string    `firestore:"archive_channel_id,omitempty"` // Optional channel that logs what sold deals went for
+	FeedMode         FeedMode  `firestore:"feed_mode,omitempty"`          // Empty means FeedModeAlertsOnly
+	GlobalMustNot    []string  `firestore:"global_must_not,omitempty"`    // Server-wide blocklist applied to every feed post
+	AllowNSFW        bool      `firestore:"allow_nsfw,omitempty"`         // NSFW (over_18) posts are suppressed unless set
+	MinScore         int       `firestore:"min_score,omitempty"`          // Unmatched posts below this Reddit score are left out of the feed
+	Language         Language  `firestore:"language,omitempty"`           // Empty means LanguageEnglish
+	FeedWebhookURL   string    `firestore:"feed_webhook_url,omitempty"`   // Feed posts go through this webhook instead of the bot when set
+	ShowRawPreview   bool      `firestore:"show_raw_preview,omitempty"`   // Deal embeds also show the start of the raw Reddit post
+	UpdatedAt        time.Time `firestore:"updated_at"`
 }
 
 // AlertRule represents a single user's keyword alert.
 type AlertRule struct {
-	ID        string    `firestore:"-"`
-	UserID    string    `firestore:"user_id"`
-	ServerID  string    `firestore:"server_id"`
-	MustHave  []string  `firestore:"must_have"` // AND
-	AnyOf     []string  `firestore:"any_of"`    // OR
-	MustNot   []string  `firestore:"must_not"`  // NOT
-	RawQuery  string    `firestore:"raw_query"` // What the user originally typed
-	CreatedAt time.Time `firestore:"created_at"`
+	ID          string    `firestore:"-"`
+	UserID      string    `firestore:"user_id"`
+	ServerID    string    `firestore:"server_id"`              // Guild ID, or DMScope(UserID) for alerts managed from DMs
+	MustHave    []string  `firestore:"must_have"`              // AND
+	AnyOf       []string  `firestore:"any_of"`                 // OR
+	MustNot     []string  `firestore:"must_not"`               // NOT
+	RawQuery    string    `firestore:"raw_query"`              // What the user originally typed
+	SearchBody  bool      `firestore:"search_body,omitempty"`  // Also match against the raw Reddit body
+	MatchCount  int64     `firestore:"match_count,omitempty"`  // Posts this alert has matched, for /stats
+	SnoozeUntil time.Time `firestore:"snooze_until,omitempty"` // Alert is skipped by matching until this time
+	Paused      bool      `firestore:"paused,omitempty"`       // Set by /alert pause-all; skipped by matching until resumed
+	// ExcludeBundles stops the alert matching posts the cleaner flagged as bundles, so a "3080" alert
+	// isn't set off by a full PC that happens to contain one. Set by /alert bundles.
+	ExcludeBundles bool `firestore:"exclude_bundles,omitempty"`
+	// TrendingComments makes this a trending alert: instead of matching new posts, it pings once when a
+	// post already in the feed reaches this many comments. Keyword lists, if any, must match its corpus.
+	TrendingComments int       `firestore:"trending_comments,omitempty"`
+	CreatedAt        time.Time `firestore:"created_at"`
+}
+
+// Snoozed reports whether the alert is still snoozed at now. A snooze clears itself once it passes.
+func (a AlertRule) Snoozed(now time.Time) bool {
+	return now.Before(a.SnoozeUntil)
+}
+
+// Active reports whether the alert should be matched at now: it is neither paused nor snoozed.
+func (a AlertRule) Active(now time.Time) bool {
+	return !a.Paused && !a.Snoozed(now)
 }
 
 // PostRecord maps a Reddit post ID to a Discord message ID to allow updating/striking-through.
 type PostRecord struct {
 	RedditID     string            `firestore:"reddit_id"`
 	CleanedTitle string            `firestore:"cleaned_title"`
-	ServerMsgs   map[string]string `firestore:"server_msgs"` // ServerID -> MessageID mapping
+	ServerMsgs   map[string]string `firestore:"server_msgs"`            // ServerID -> MessageID mapping
+	Corpus       string            `firestore:"corpus,omitempty"`       // Cleaned title, description and location, searched by /find
+	NumComments  int               `firestore:"num_comments,omitempty"` // Comment count at the last scrape, for trending alerts
+	URL          string            `firestore:"url,omitempty"`
+	Price        string            `firestore:"price,omitempty"` // Asking price at the last clean, for price-drop pings
 	PostedAt     time.Time         `firestore:"posted_at"`
+	UpdatedAt    time.Time         `firestore:"updated_at,omitempty"` // Last time an edited post was re-cleaned
+	ClosedAt     time.Time         `firestore:"closed_at,omitempty"`  // When the feed messages were struck as sold/closed
+	// ServerWebhooks maps ServerID -> ID of the webhook that posted that server's message. Servers missing
+	// from it had the message posted by the bot. Edits have to go the same way the message was sent.
+	ServerWebhooks map[string]string `firestore:"server_webhooks,omitempty"`
+	// RawText is the Reddit title and (truncated) body the cleaned fields were produced from, so a
+	// "Bad Summary" report can show the compaction prompt what the cleaner was given.
+	RawText string `firestore:"raw_text,omitempty"`
+}
+
+// LastProcessed returns when the record's content was last refreshed from Reddit.
+func (pr PostRecord) LastProcessed() time.Time {
+	if pr.UpdatedAt.After(pr.PostedAt) {
+		return pr.UpdatedAt
+	}
+	return pr.PostedAt
 }
 
 // AnalyticsRecord stores information about how an alert was created to evaluate AI effectiveness.
 type AnalyticsRecord struct {
 	ID                 string    `firestore:"-"`
-	FlowType           string    `firestore:"flow_type"` // "wizard" or "manual"
+	FlowType           string    `firestore:"flow_type"` // "wizard", "manual" or "clean"
 	OriginalUserPrompt string    `firestore:"original_user_prompt,omitempty"`
 	AISuggestedQuery   string    `firestore:"ai_suggested_query,omitempty"`
 	FinalSavedQuery    string    `firestore:"final_saved_query,omitempty"`
 	Outcome            string    `firestore:"outcome"` // e.g., Accepted_As_Is, Edited, Cancelled, Manual_Entry_Success
 	EditCount          int       `firestore:"edit_count"`
+	AlertID            string    `firestore:"alert_id,omitempty"`          // The alert a confirmed flow produced
+	RedditID           string    `firestore:"reddit_id,omitempty"`         // The post a "clean" flow record reports
+	UserID             string    `firestore:"user_id,omitempty"`           // Who reported it
+	StrippedKeywords   []string  `firestore:"stripped_keywords,omitempty"` // Banned generic terms removed from the wizard's rule
+	RawPost            string    `firestore:"raw_post,omitempty"`          // The Reddit post a "clean" flow record reports, as written
+	CleanedTitle       string    `firestore:"cleaned_title,omitempty"`     // The title the post was cleaned into
+	CleanedText        string    `firestore:"cleaned_text,omitempty"`      // The cleaned text alerts matched against
 	CreatedAt          time.Time `firestore:"created_at"`
 }
 
+// FailedDispatch is a dead-letter entry for a deal that could not be posted to a server's feed channel.
+// The embed is stored pre-rendered so a retry doesn't need to re-run the AI cleaning.
+type FailedDispatch struct {
+	ID           string    `firestore:"-"`
+	RedditID     string    `firestore:"reddit_id"`
+	ServerID     string    `firestore:"server_id"`
+	CleanedTitle string    `firestore:"cleaned_title"`
+	PostURL      string    `firestore:"post_url"`
+	Author       string    `firestore:"author,omitempty"` // Reddit seller, for the Mute Seller button
+	EmbedJSON    string    `firestore:"embed_json"`
+	UserIDs      []string  `firestore:"user_ids"` // Users to ping once the feed post succeeds
+	Attempts     int       `firestore:"attempts"`
+	LastError    string    `firestore:"last_error"`
+	CreatedAt    time.Time `firestore:"created_at"`
+}
+
+// QueuedPing is an alert ping held back because it landed in the user's quiet hours. Pings that are due
+// are delivered together as one digest per user and channel.
+type QueuedPing struct {
+	ID            string    `firestore:"-"`
+	UserID        string    `firestore:"user_id"`
+	ServerID      string    `firestore:"server_id"`
+	PingChannelID string    `firestore:"ping_channel_id"`
+	MessageLink   string    `firestore:"message_link"` // The feed message the ping points at
+	DeliverAt     time.Time `firestore:"deliver_at"`   // When the user's quiet hours end
+	CreatedAt     time.Time `firestore:"created_at"`
+}
+
 // SystemPrompt stores the dynamically updated system instructions for the AI model.
 type SystemPrompt struct {
 	PromptText string    `firestore:"prompt_text"`
 	UpdatedAt  time.Time `firestore:"updated_at"`
 }
 
+// Actions recorded in the prompt history.
+const (
+	PromptActionApproved = "approved" // The admin approved a compacted prompt
+	PromptActionReset    = "reset"    // The admin put the flow back on its built-in default
+)
+
+// PromptHistoryEntry records one change to a system prompt, so admins can see what was live when.
+type PromptHistoryEntry struct {
+	ID         string    `firestore:"-"`
+	PromptKey  string    `firestore:"prompt_key"`
+	Action     string    `firestore:"action"`
+	PromptText string    `firestore:"prompt_text"`
+	ChangedAt  time.Time `firestore:"changed_at"`
+}
+
 // NewStore initializes a new Firestore client using application default credentials.
 func NewStore(ctx context.Context, projectID string) (*Store, error) {
 	client, err := firestore.NewClient(ctx, projectID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create firestore client: %v", err)
 	}
-	return &Store{client: client}, nil
+	return &Store{client: client, clock: clock.Real{}}, nil
 }
 
 // Close closes the Firestore client.
@@ -79,31 +214,94 @@ func (s *Store) Close() error {
 
 // SaveServerConfig saves or updates the feed and ping channels for a given Discord server.
 func (s *Store) SaveServerConfig(ctx context.Context, serverID string, cfg ServerConfig) error {
-	cfg.UpdatedAt = time.Now()
+	cfg.UpdatedAt = s.clock.Now()
 	_, err := s.client.Collection("servers").Doc(serverID).Set(ctx, cfg)
-	return err
+	return wrapErr(err)
 }
 
 // GetServerConfig retrieves the server config for a given Discord server ID.
 func (s *Store) GetServerConfig(ctx context.Context, serverID string) (*ServerConfig, error) {
 	doc, err := s.client.Collection("servers").Doc(serverID).Get(ctx)
 	if err != nil {
-		return nil, err
+		return nil, wrapErr(err)
 	}
 	var cfg ServerConfig
 	if err := doc.DataTo(&cfg); err != nil {
-		return nil, err
+		return nil, wrapErr(err)
 	}
+	cfg.ServerID = doc.Ref.ID
 	return &cfg, nil
 }
 
+// AddBlocklistTerm adds a term to a server's blocklist. Adding an existing term is a no-op.
+func (s *Store) AddBlocklistTerm(ctx context.Context, serverID, term string) error {
+	_, err := s.client.Collection("servers").Doc(serverID).Update(ctx, []firestore.Update{
+		{Path: "global_must_not", Value: firestore.ArrayUnion(term)},
+	})
+	return wrapErr(err)
+}
+
+// RemoveBlocklistTerm removes a term from a server's blocklist.
+func (s *Store) RemoveBlocklistTerm(ctx context.Context, serverID, term string) error {
+	_, err := s.client.Collection("servers").Doc(serverID).Update(ctx, []firestore.Update{
+		{Path: "global_must_not", Value: firestore.ArrayRemove(term)},
+	})
+	return wrapErr(err)
+}
+
+// GetAllServers retrieves every configured server, sorted by server ID for stable pagination.
+func (s *Store) GetAllServers(ctx context.Context) ([]ServerConfig, error) {
+	var servers []ServerConfig
+	iter := s.client.Collection("servers").Documents(ctx)
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, wrapErr(err)
+		}
+		var cfg ServerConfig
+		if err := doc.DataTo(&cfg); err != nil {
+			return nil, wrapErr(err)
+		}
+		cfg.ServerID = doc.Ref.ID
+		servers = append(servers, cfg)
+	}
+
+	sort.Slice(servers, func(i, j int) bool {
+		return servers[i].ServerID < servers[j].ServerID
+	})
+
+	return servers, nil
+}
+
 // --- Alerts ---
 
-// AddAlert adds a new alert rule for a user on a specific server.
+// AddAlert adds a new alert rule for a user on a specific server. Keywords are normalized before saving.
 func (s *Store) AddAlert(ctx context.Context, rule AlertRule) error {
-	rule.CreatedAt = time.Now()
+	rule = NormalizeAlertRule(rule)
+	rule.CreatedAt = s.clock.Now()
 	_, _, err := s.client.Collection("alerts").Add(ctx, rule)
-	return err
+	return wrapErr(err)
+}
+
+// GetAlert returns a single alert by document ID, or ErrAlertNotFound if it has been deleted.
+func (s *Store) GetAlert(ctx context.Context, docID string) (*AlertRule, error) {
+	doc, err := s.client.Collection("alerts").Doc(docID).Get(ctx)
+	if err = wrapErr(err); errors.Is(err, ErrNotFound) {
+		return nil, fmt.Errorf("%w: %w", ErrAlertNotFound, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var alert AlertRule
+	if err := doc.DataTo(&alert); err != nil {
+		return nil, wrapErr(err)
+	}
+	alert.ID = doc.Ref.ID
+	return &alert, nil
 }
 
 // GetUserAlerts retrieves all alerts for a specific user on a specific server.
@@ -120,11 +318,11 @@ func (s *Store) GetUserAlerts(ctx context.Context, serverID, userID string) ([]A
 			break
 		}
 		if err != nil {
-			return nil, err
+			return nil, wrapErr(err)
 		}
 		var alert AlertRule
 		if err := doc.DataTo(&alert); err != nil {
-			return nil, err
+			return nil, wrapErr(err)
 		}
 		alert.ID = doc.Ref.ID
 		alerts = append(alerts, alert)
@@ -141,27 +339,139 @@ func (s *Store) GetUserAlerts(ctx context.Context, serverID, userID string) ([]A
 // DeleteAlert removes an alert rule by its Firestore document ID (not the Discord interaction ID).
 func (s *Store) DeleteAlert(ctx context.Context, docID string) error {
 	_, err := s.client.Collection("alerts").Doc(docID).Delete(ctx)
-	return err
+	return wrapErr(err)
+}
+
+// SetAlertSearchBody toggles whether an alert also matches against the raw Reddit post body.
+func (s *Store) SetAlertSearchBody(ctx context.Context, docID string, enabled bool) error {
+	_, err := s.client.Collection("alerts").Doc(docID).Update(ctx, []firestore.Update{
+		{Path: "search_body", Value: enabled},
+	})
+	return wrapErr(err)
+}
+
+// SnoozeAlert stops an alert matching until the given time. A zero time wakes it immediately.
+func (s *Store) SnoozeAlert(ctx context.Context, docID string, until time.Time) error {
+	var value interface{} = until
+	if until.IsZero() {
+		value = firestore.Delete
+	}
+	_, err := s.client.Collection("alerts").Doc(docID).Update(ctx, []firestore.Update{
+		{Path: "snooze_until", Value: value},
+	})
+	return wrapErr(err)
+}
+
+// maxBatchWrites is the most operations Firestore accepts in one WriteBatch.
+const maxBatchWrites = 500
+
+// commitChunked queues one write per ref and commits them in batches of maxBatchWrites. It returns how
+// many writes were committed, which on error counts the batches that landed before the failing one.
+func (s *Store) commitChunked(ctx context.Context, refs []*firestore.DocumentRef, write func(*firestore.WriteBatch, *firestore.DocumentRef)) (int, error) {
+	committed := 0
+	for start := 0; start < len(refs); start += maxBatchWrites {
+		end := min(start+maxBatchWrites, len(refs))
+		batch := s.client.Batch()
+		for _, ref := range refs[start:end] {
+			write(batch, ref)
+		}
+		if _, err := batch.Commit(ctx); err != nil {
+			return committed, wrapErr(err)
+		}
+		committed = end
+	}
+	return committed, nil
+}
+
+// SetAllAlertsEnabled pauses (enabled=false) or resumes every alert a user has in a scope and returns how
+// many alerts actually changed state. If a batch fails, the count covers the batches already committed.
+func (s *Store) SetAllAlertsEnabled(ctx context.Context, serverID, userID string, enabled bool) (int, error) {
+	alerts, err := s.GetUserAlerts(ctx, serverID, userID)
+	if err != nil {
+		return 0, wrapErr(err)
+	}
+
+	var refs []*firestore.DocumentRef
+	for _, alert := range alerts {
+		if alert.Paused != !enabled {
+			refs = append(refs, s.client.Collection("alerts").Doc(alert.ID))
+		}
+	}
+	return s.commitChunked(ctx, refs, func(b *firestore.WriteBatch, ref *firestore.DocumentRef) {
+		b.Update(ref, []firestore.Update{{Path: "paused", Value: !enabled}})
+	})
+}
+
+// SetAllAlertsExcludeBundles sets whether every alert a user has in a scope skips bundle listings and
+// returns how many alerts actually changed. If a batch fails, the count covers the batches already committed.
+func (s *Store) SetAllAlertsExcludeBundles(ctx context.Context, serverID, userID string, exclude bool) (int, error) {
+	alerts, err := s.GetUserAlerts(ctx, serverID, userID)
+	if err != nil {
+		return 0, wrapErr(err)
+	}
+
+	var refs []*firestore.DocumentRef
+	for _, alert := range alerts {
+		if alert.ExcludeBundles != exclude {
+			refs = append(refs, s.client.Collection("alerts").Doc(alert.ID))
+		}
+	}
+	return s.commitChunked(ctx, refs, func(b *firestore.WriteBatch, ref *firestore.DocumentRef) {
+		b.Update(ref, []firestore.Update{{Path: "exclude_bundles", Value: exclude}})
+	})
 }
 
 // DeleteAllUserAlerts removes every alert a specific user has registered on a given server.
 func (s *Store) DeleteAllUserAlerts(ctx context.Context, serverID, userID string) error {
 	alerts, err := s.GetUserAlerts(ctx, serverID, userID)
 	if err != nil {
-		return err
+		return wrapErr(err)
 	}
 
-	batch := s.client.Batch()
-	for _, alert := range alerts {
-		ref := s.client.Collection("alerts").Doc(alert.ID)
-		batch.Delete(ref)
+	refs := make([]*firestore.DocumentRef, len(alerts))
+	for i, alert := range alerts {
+		refs[i] = s.client.Collection("alerts").Doc(alert.ID)
 	}
+	_, err = s.commitChunked(ctx, refs, deleteWrite)
+	return err
+}
 
-	if len(alerts) > 0 {
-		_, err = batch.Commit(ctx)
-		return err
+// deleteWrite queues the deletion of ref, for commitChunked.
+func deleteWrite(b *firestore.WriteBatch, ref *firestore.DocumentRef) {
+	b.Delete(ref)
+}
+
+// CountServerAlerts returns the number of alerts registered on a server using a server-side count aggregation.
+func (s *Store) CountServerAlerts(ctx context.Context, serverID string) (int64, error) {
+	q := s.client.Collection("alerts").Where("server_id", "==", serverID)
+	res, err := q.NewAggregationQuery().WithCount("count").Get(ctx)
+	if err != nil {
+		return 0, wrapErr(err)
 	}
-	return nil
+
+	v, ok := res["count"].(*firestorepb.Value)
+	if !ok {
+		return 0, fmt.Errorf("unexpected count aggregation result: %T", res["count"])
+	}
+	return v.GetIntegerValue(), nil
+}
+
+// IncrementAlertMatches bumps the match counter of every alert that fired for a post. Each alert is
+// updated on its own, so an alert deleted mid-run is logged and skipped rather than recreated or
+// failing the counts of the others.
+func (s *Store) IncrementAlertMatches(ctx context.Context, alertIDs []string) error {
+	var firstErr error
+	for _, id := range alertIDs {
+		err := s.IncrementExistingCounter(ctx, "alerts", id, "match_count", 1)
+		if errors.Is(err, ErrNotFound) {
+			log.Printf("Skipping match count for alert %s: it no longer exists", id)
+			continue
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
 }
 
 // GetAllAlerts retrieves all alerts across all servers. Used heavily by the scraper deduplication logic.
@@ -175,11 +485,11 @@ func (s *Store) GetAllAlerts(ctx context.Context) ([]AlertRule, error) {
 			break
 		}
 		if err != nil {
-			return nil, err
+			return nil, wrapErr(err)
 		}
 		var alert AlertRule
 		if err := doc.DataTo(&alert); err != nil {
-			return nil, err
+			return nil, wrapErr(err)
 		}
 		alert.ID = doc.Ref.ID
 		alerts = append(alerts, alert)
@@ -189,62 +499,132 @@ func (s *Store) GetAllAlerts(ctx context.Context) ([]AlertRule, error) {
 
 // --- Posts ---
 
-// SavePostRecord stores the mapping between a Reddit Post ID and the Discord Message ID it generated for a specific server.
-func (s *Store) SavePostRecord(ctx context.Context, redditID, cleanedTitle, serverID, discordMsgID string) error {
-	doc := s.client.Collection("posts").Doc(redditID)
-
-	data := map[string]interface{}{
-		"reddit_id":     redditID,
-		"cleaned_title": cleanedTitle,
-		"posted_at":     time.Now(),
-		"server_msgs": map[string]string{
-			serverID: discordMsgID,
-		},
+// AddPostMessage records the Discord message a post was sent as on one server, for a feed post delivered
+// after the post record was saved. Only that server's entries change, so the record keeps its posted_at
+// and any cleaned title an edit has since refreshed. webhookID is the ID of the webhook the message was
+// posted through, or "" if the bot posted it.
+func (s *Store) AddPostMessage(ctx context.Context, redditID, serverID, discordMsgID, webhookID string) error {
+	updates := []firestore.Update{
+		{FieldPath: firestore.FieldPath{"server_msgs", serverID}, Value: discordMsgID},
 	}
-
-	_, err := doc.Set(ctx, data, firestore.MergeAll)
-	return err
+	if webhookID != "" {
+		updates = append(updates, firestore.Update{FieldPath: firestore.FieldPath{"server_webhooks", serverID}, Value: webhookID})
+	}
+	_, err := s.client.Collection("posts").Doc(redditID).Update(ctx, updates)
+	return wrapErr(err)
 }
 
-// SavePostRecords stores mappings for multiple servers in a single post record.
-func (s *Store) SavePostRecords(ctx context.Context, redditID, cleanedTitle string, serverMsgs map[string]string) error {
+// SavePostRecords stores mappings for multiple servers in a single post record, along with the
+// searchable corpus and link so the post can be found later via /find, and the asking price and
+// comment count that price-drop and trending alerts compare against. rawText is the post as written
+// (see PostRecord.RawText). serverWebhooks lists the servers whose message was posted through a
+// webhook (see PostRecord.ServerWebhooks).
+func (s *Store) SavePostRecords(ctx context.Context, redditID, cleanedTitle, corpus, rawText, postURL, price string, numComments int, serverMsgs, serverWebhooks map[string]string) error {
 	doc := s.client.Collection("posts").Doc(redditID)
 
 	data := map[string]interface{}{
 		"reddit_id":     redditID,
 		"cleaned_title": cleanedTitle,
-		"posted_at":     time.Now(),
+		"corpus":        corpus,
+		"raw_text":      rawText,
+		"url":           postURL,
+		"price":         price,
+		"num_comments":  numComments,
+		"posted_at":     s.clock.Now(),
 		"server_msgs":   serverMsgs,
 	}
+	if len(serverWebhooks) > 0 {
+		data["server_webhooks"] = serverWebhooks
+	}
 
 	_, err := doc.Set(ctx, data, firestore.MergeAll)
-	return err
+	return wrapErr(err)
+}
+
+// UpdatePostContent refreshes the cleaned title, corpus, raw text and price of an edited post without touching its message mappings.
+func (s *Store) UpdatePostContent(ctx context.Context, redditID, cleanedTitle, corpus, rawText, price string) error {
+	_, err := s.client.Collection("posts").Doc(redditID).Update(ctx, []firestore.Update{
+		{Path: "cleaned_title", Value: cleanedTitle},
+		{Path: "corpus", Value: corpus},
+		{Path: "raw_text", Value: rawText},
+		{Path: "price", Value: price},
+		{Path: "updated_at", Value: s.clock.Now()},
+	})
+	return wrapErr(err)
+}
+
+// UpdatePostComments records the comment count seen on the latest scrape of a post.
+func (s *Store) UpdatePostComments(ctx context.Context, redditID string, numComments int) error {
+	_, err := s.client.Collection("posts").Doc(redditID).Update(ctx, []firestore.Update{
+		{Path: "num_comments", Value: numComments},
+	})
+	return wrapErr(err)
+}
+
+// MarkPostClosed records that a post's feed messages were struck as sold/closed so later runs don't redo it.
+func (s *Store) MarkPostClosed(ctx context.Context, redditID string) error {
+	_, err := s.client.Collection("posts").Doc(redditID).Update(ctx, []firestore.Update{
+		{Path: "closed_at", Value: s.clock.Now()},
+	})
+	return wrapErr(err)
 }
 
 // GetPostRecord retrieves a post record to find the matching Discord Message ID.
 func (s *Store) GetPostRecord(ctx context.Context, redditID string) (*PostRecord, error) {
 	doc, err := s.client.Collection("posts").Doc(redditID).Get(ctx)
 	if err != nil {
-		return nil, err
+		return nil, wrapErr(err)
 	}
 	var pr PostRecord
 	if err := doc.DataTo(&pr); err != nil {
-		return nil, err
+		return nil, wrapErr(err)
 	}
 	return &pr, nil
 }
 
-// TrimOldPosts hard-deletes posts older than the 500 most recent ones to keep the database exceptionally lean.
-func (s *Store) TrimOldPosts(ctx context.Context) error {
-	// 1. Get all post documents, ordered by creation time descending.
+// GetRecentPosts returns up to limit post records, newest first.
+func (s *Store) GetRecentPosts(ctx context.Context, limit int) ([]PostRecord, error) {
 	iter := s.client.Collection("posts").
 		OrderBy("posted_at", firestore.Desc).
+		Limit(limit).
 		Documents(ctx)
+	defer iter.Stop()
 
-	count := 0
-	batch := s.client.Batch()
-	docsToDelete := 0
+	var posts []PostRecord
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, wrapErr(err)
+		}
+		var pr PostRecord
+		if err := doc.DataTo(&pr); err != nil {
+			continue
+		}
+		posts = append(posts, pr)
+	}
+	return posts, nil
+}
 
+// maxStoredPosts is how many of the most recent posts TrimOldPosts keeps.
+const maxStoredPosts = 500
+
+// TrimOldPosts hard-deletes posts older than the 500 most recent ones to keep the database exceptionally lean.
+func (s *Store) TrimOldPosts(ctx context.Context) error {
+	return s.trimCollection(ctx, "posts", "posted_at", maxStoredPosts)
+}
+
+// trimCollection hard-deletes every document in collection past the keep most recent, ordered by orderField.
+func (s *Store) trimCollection(ctx context.Context, collection, orderField string, keep int) error {
+	// 1. Get all documents, ordered by creation time descending.
+	iter := s.client.Collection(collection).
+		OrderBy(orderField, firestore.Desc).
+		Documents(ctx)
+
+	var refs []*firestore.DocumentRef
+	count := 0
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
@@ -253,66 +633,205 @@ func (s *Store) TrimOldPosts(ctx context.Context) error {
 		if err != nil {
 			// If we fail here, we just log and return.
 			// Trimming isn't critical, it'll just try again next time.
-			log.Printf("Error iterating posts during trim: %v", err)
-			return err
+			log.Printf("Error iterating %s during trim: %v", collection, err)
+			return wrapErr(err)
 		}
 
 		count++
-		// If we've seen more than 500, queue this document for deletion.
-		if count > 500 {
-			batch.Delete(doc.Ref)
-			docsToDelete++
-
-			// Firestore batches are limited to 500 operations.
-			// If we hit it, commit and start a new batch.
-			if docsToDelete == 500 {
-				if _, err := batch.Commit(ctx); err != nil {
-					log.Printf("Error committing chunked batch delete during trim: %v", err)
-					return err
-				}
-				batch = s.client.Batch()
-				docsToDelete = 0
-			}
+		// If we've seen more than keep, queue this document for deletion.
+		if count > keep {
+			refs = append(refs, doc.Ref)
 		}
 	}
 
-	// Commit any remaining deletions.
-	if docsToDelete > 0 {
-		if _, err := batch.Commit(ctx); err != nil {
-			log.Printf("Error committing final batch delete during trim: %v", err)
-			return err
-		}
-		log.Printf("Trimmed %d old posts from Firestore.", docsToDelete)
+	trimmed, err := s.commitChunked(ctx, refs, deleteWrite)
+	if trimmed > 0 {
+		log.Printf("Trimmed %d old %s from Firestore.", trimmed, collection)
+	}
+	if err != nil {
+		log.Printf("Error committing batch delete during %s trim: %v", collection, err)
+		return err
 	}
 
 	return nil
 }
 
+// --- Failed Dispatches ---
+
+// SaveFailedDispatch creates or updates a dead-letter entry. Entries are keyed by post and server so
+// repeated failures for the same dispatch overwrite rather than duplicate.
+func (s *Store) SaveFailedDispatch(ctx context.Context, fd FailedDispatch) error {
+	if fd.ID == "" {
+		fd.ID = fd.RedditID + "_" + fd.ServerID
+	}
+	if fd.CreatedAt.IsZero() {
+		fd.CreatedAt = s.clock.Now()
+	}
+	_, err := s.client.Collection("failed_dispatches").Doc(fd.ID).Set(ctx, fd)
+	return wrapErr(err)
+}
+
+// GetFailedDispatches retrieves up to `limit` dead-letter entries, oldest first.
+func (s *Store) GetFailedDispatches(ctx context.Context, limit int) ([]FailedDispatch, error) {
+	var dispatches []FailedDispatch
+	iter := s.client.Collection("failed_dispatches").
+		OrderBy("created_at", firestore.Asc).
+		Limit(limit).
+		Documents(ctx)
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, wrapErr(err)
+		}
+		var fd FailedDispatch
+		if err := doc.DataTo(&fd); err != nil {
+			continue // skip malformed
+		}
+		fd.ID = doc.Ref.ID
+		dispatches = append(dispatches, fd)
+	}
+
+	return dispatches, nil
+}
+
+// DeleteFailedDispatch removes a dead-letter entry once it has been delivered or abandoned.
+func (s *Store) DeleteFailedDispatch(ctx context.Context, id string) error {
+	_, err := s.client.Collection("failed_dispatches").Doc(id).Delete(ctx)
+	return wrapErr(err)
+}
+
+// --- Queued Pings ---
+
+// QueuePing stores a ping to be delivered once the user's quiet hours end.
+func (s *Store) QueuePing(ctx context.Context, p QueuedPing) error {
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = s.clock.Now()
+	}
+	_, _, err := s.client.Collection("queued_pings").Add(ctx, p)
+	return wrapErr(err)
+}
+
+// GetDuePings retrieves up to `limit` queued pings whose delivery time is at or before `now`, oldest first.
+func (s *Store) GetDuePings(ctx context.Context, now time.Time, limit int) ([]QueuedPing, error) {
+	var pings []QueuedPing
+	iter := s.client.Collection("queued_pings").
+		Where("deliver_at", "<=", now).
+		OrderBy("deliver_at", firestore.Asc).
+		Limit(limit).
+		Documents(ctx)
+	defer iter.Stop()
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, wrapErr(err)
+		}
+		var p QueuedPing
+		if err := doc.DataTo(&p); err != nil {
+			continue // skip malformed
+		}
+		p.ID = doc.Ref.ID
+		pings = append(pings, p)
+	}
+
+	return pings, nil
+}
+
+// DeletePings removes delivered queued pings.
+func (s *Store) DeletePings(ctx context.Context, ids []string) error {
+	refs := make([]*firestore.DocumentRef, len(ids))
+	for i, id := range ids {
+		refs[i] = s.client.Collection("queued_pings").Doc(id)
+	}
+	_, err := s.commitChunked(ctx, refs, deleteWrite)
+	return err
+}
+
 // --- Analytics ---
 
 // SaveAnalytics saves an interaction record for AI query generation analytics.
 func (s *Store) SaveAnalytics(ctx context.Context, record AnalyticsRecord) error {
-	record.CreatedAt = time.Now()
+	record.CreatedAt = s.clock.Now()
 	_, _, err := s.client.Collection("ai_query_analytics").Add(ctx, record)
-	return err
+	return wrapErr(err)
+}
+
+// SaveParseFlag saves a user's "Bad Summary" report, keyed by record.RedditID and record.UserID so each
+// user counts once per post. It returns false, without error, when that user already reported the post.
+func (s *Store) SaveParseFlag(ctx context.Context, record AnalyticsRecord) (bool, error) {
+	record.CreatedAt = s.clock.Now()
+	_, err := s.client.Collection("ai_query_analytics").Doc("flag_"+record.RedditID+"_"+record.UserID).Create(ctx, record)
+	if status.Code(err) == codes.AlreadyExists {
+		return false, nil
+	}
+	if err != nil {
+		return false, wrapErr(err)
+	}
+	return true, nil
 }
 
-// GetUnprocessedAnalyticsByFlow grabs up to `limit` records from the analytics collection for a specific AI module.
+// GetUnprocessedAnalyticsByFlow grabs up to `limit` records from the analytics collection for a specific AI module,
+// oldest first. The query needs a composite index on (flow_type, created_at); until it exists the records are
+// sorted in memory instead.
 func (s *Store) GetUnprocessedAnalyticsByFlow(ctx context.Context, flowType string, limit int) ([]AnalyticsRecord, error) {
+	byFlow := s.client.Collection("ai_query_analytics").Where("flow_type", "==", flowType)
+	return queryWithIndexFallback("GetUnprocessedAnalyticsByFlow", limit,
+		func() ([]AnalyticsRecord, error) {
+			return readAnalytics(byFlow.OrderBy("created_at", firestore.Asc).Limit(limit).Documents(ctx))
+		},
+		func() ([]AnalyticsRecord, error) {
+			return readAnalytics(byFlow.Documents(ctx))
+		},
+		func(a, b AnalyticsRecord) int {
+			return a.CreatedAt.Compare(b.CreatedAt)
+		},
+	)
+}
+
+// readAnalytics drains an analytics query, skipping malformed records.
+func readAnalytics(iter *firestore.DocumentIterator) ([]AnalyticsRecord, error) {
+	defer iter.Stop()
+	var records []AnalyticsRecord
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, wrapErr(err)
+		}
+		var rec AnalyticsRecord
+		if err := doc.DataTo(&rec); err != nil {
+			continue // skip malformed
+		}
+		rec.ID = doc.Ref.ID
+		records = append(records, rec)
+	}
+
+	return records, nil
+}
+
+// GetAlertPerformance joins the analytics records of confirmed alerts with those alerts' match counts,
+// summarized per flow. Only records still in the collection count, since approved compactions clear them.
+func (s *Store) GetAlertPerformance(ctx context.Context) ([]AlertPerformance, error) {
 	var records []AnalyticsRecord
 	iter := s.client.Collection("ai_query_analytics").
-		Where("flow_type", "==", flowType).
-		OrderBy("created_at", firestore.Asc).
-		Limit(limit).
+		Where("alert_id", ">", "").
 		Documents(ctx)
-
 	for {
 		doc, err := iter.Next()
 		if err == iterator.Done {
 			break
 		}
 		if err != nil {
-			return nil, err
+			return nil, wrapErr(err)
 		}
 		var rec AnalyticsRecord
 		if err := doc.DataTo(&rec); err != nil {
@@ -322,21 +841,39 @@ func (s *Store) GetUnprocessedAnalyticsByFlow(ctx context.Context, flowType stri
 		records = append(records, rec)
 	}
 
-	return records, nil
+	alerts := make(map[string]AlertRule)
+	if len(records) > 0 {
+		refs := make([]*firestore.DocumentRef, 0, len(records))
+		for _, rec := range records {
+			refs = append(refs, s.client.Collection("alerts").Doc(rec.AlertID))
+		}
+		docs, err := s.client.GetAll(ctx, refs)
+		if err != nil {
+			return nil, wrapErr(err)
+		}
+		for _, doc := range docs {
+			if !doc.Exists() {
+				continue
+			}
+			var a AlertRule
+			if err := doc.DataTo(&a); err != nil {
+				continue
+			}
+			a.ID = doc.Ref.ID
+			alerts[a.ID] = a
+		}
+	}
+
+	return summarizeAlertPerformance(records, alerts), nil
 }
 
 // DeleteAnalyticsChunk deletes a specific set of analytics records by their document IDs.
 func (s *Store) DeleteAnalyticsChunk(ctx context.Context, ids []string) error {
-	if len(ids) == 0 {
-		return nil
-	}
-
-	batch := s.client.Batch()
-	for _, id := range ids {
-		ref := s.client.Collection("ai_query_analytics").Doc(id)
-		batch.Delete(ref)
+	refs := make([]*firestore.DocumentRef, len(ids))
+	for i, id := range ids {
+		refs[i] = s.client.Collection("ai_query_analytics").Doc(id)
 	}
-	_, err := batch.Commit(ctx)
+	_, err := s.commitChunked(ctx, refs, deleteWrite)
 	return err
 }
 
@@ -346,21 +883,47 @@ func (s *Store) DeleteAnalyticsChunk(ctx context.Context, ids []string) error {
 func (s *Store) GetSystemPrompt(ctx context.Context, key string) (string, error) {
 	doc, err := s.client.Collection("system_prompts").Doc(key).Get(ctx)
 	if err != nil {
-		return "", err
+		return "", wrapErr(err)
 	}
 	var sp SystemPrompt
 	if err := doc.DataTo(&sp); err != nil {
-		return "", err
+		return "", wrapErr(err)
 	}
 	return sp.PromptText, nil
 }
 
+// GetSystemPromptRecord returns the stored System Prompt along with when it was last updated, or nil
+// if none has been approved yet and the built-in default is in use.
+func (s *Store) GetSystemPromptRecord(ctx context.Context, key string) (*SystemPrompt, error) {
+	doc, err := s.client.Collection("system_prompts").Doc(key).Get(ctx)
+	if doc != nil && !doc.Exists() {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	var sp SystemPrompt
+	if err := doc.DataTo(&sp); err != nil {
+		return nil, wrapErr(err)
+	}
+	return &sp, nil
+}
+
+// AddPromptHistory appends an entry to the prompt history. ChangedAt defaults to now.
+func (s *Store) AddPromptHistory(ctx context.Context, entry PromptHistoryEntry) error {
+	if entry.ChangedAt.IsZero() {
+		entry.ChangedAt = s.clock.Now()
+	}
+	_, _, err := s.client.Collection("prompt_history").Add(ctx, entry)
+	return wrapErr(err)
+}
+
 // SetSystemPrompt saves a new System Prompt definition.
 func (s *Store) SetSystemPrompt(ctx context.Context, key, promptText string) error {
 	sp := SystemPrompt{
 		PromptText: promptText,
-		UpdatedAt:  time.Now(),
+		UpdatedAt:  s.clock.Now(),
 	}
 	_, err := s.client.Collection("system_prompts").Doc(key).Set(ctx, sp)
-	return err
+	return wrapErr(err)
 }
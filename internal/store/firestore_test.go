@@ -0,0 +1,211 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAlertRule_Active(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name string
+		rule AlertRule
+		want bool
+	}{
+		{"Plain alert", AlertRule{}, true},
+		{"Paused", AlertRule{Paused: true}, false},
+		{"Snoozed", AlertRule{SnoozeUntil: now.Add(time.Hour)}, false},
+		{"Snooze expired", AlertRule{SnoozeUntil: now.Add(-time.Hour)}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.rule.Active(now); got != tt.want {
+			t.Errorf("%s: Active() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSetAllAlertsEnabled_Emulator(t *testing.T) {
+	ctx := context.Background()
+	s := newEmulatorStore(t)
+	serverID := "pause-" + time.Now().Format("150405.000000000")
+
+	for _, term := range []string{"3080", "5800x3d", "ddr5"} {
+		if err := s.AddAlert(ctx, AlertRule{UserID: "user1", ServerID: serverID, MustHave: []string{term}}); err != nil {
+			t.Fatalf("AddAlert failed: %v", err)
+		}
+	}
+	if err := s.AddAlert(ctx, AlertRule{UserID: "user2", ServerID: serverID, MustHave: []string{"3080"}}); err != nil {
+		t.Fatalf("AddAlert failed: %v", err)
+	}
+
+	changed, err := s.SetAllAlertsEnabled(ctx, serverID, "user1", false)
+	if err != nil || changed != 3 {
+		t.Fatalf("expected 3 alerts paused, got %d, %v", changed, err)
+	}
+	alerts, _ := s.GetUserAlerts(ctx, serverID, "user1")
+	for _, a := range alerts {
+		if !a.Paused {
+			t.Errorf("expected alert %s to be paused", a.ID)
+		}
+	}
+	if others, _ := s.GetUserAlerts(ctx, serverID, "user2"); len(others) != 1 || others[0].Paused {
+		t.Errorf("expected another user's alert to be left alone, got %+v", others)
+	}
+
+	// Pausing again changes nothing; resuming flips them all back.
+	if changed, _ := s.SetAllAlertsEnabled(ctx, serverID, "user1", false); changed != 0 {
+		t.Errorf("expected no alerts to change on a second pause, got %d", changed)
+	}
+	if changed, _ := s.SetAllAlertsEnabled(ctx, serverID, "user1", true); changed != 3 {
+		t.Errorf("expected 3 alerts resumed, got %d", changed)
+	}
+}
+
+func TestBulkAlertWrites_OverBatchLimit_Emulator(t *testing.T) {
+	ctx := context.Background()
+	s := newEmulatorStore(t)
+	serverID := "bulk-" + time.Now().Format("150405.000000000")
+
+	const n = maxBatchWrites + 1
+	for range n {
+		if err := s.AddAlert(ctx, AlertRule{UserID: "user1", ServerID: serverID, MustHave: []string{"3080"}}); err != nil {
+			t.Fatalf("AddAlert failed: %v", err)
+		}
+	}
+
+	if changed, err := s.SetAllAlertsEnabled(ctx, serverID, "user1", false); err != nil || changed != n {
+		t.Fatalf("expected %d alerts paused, got %d, %v", n, changed, err)
+	}
+	if changed, err := s.SetAllAlertsExcludeBundles(ctx, serverID, "user1", true); err != nil || changed != n {
+		t.Fatalf("expected %d alerts to skip bundles, got %d, %v", n, changed, err)
+	}
+	if err := s.DeleteAllUserAlerts(ctx, serverID, "user1"); err != nil {
+		t.Fatalf("DeleteAllUserAlerts failed: %v", err)
+	}
+	if alerts, _ := s.GetUserAlerts(ctx, serverID, "user1"); len(alerts) != 0 {
+		t.Errorf("expected every alert deleted, %d left", len(alerts))
+	}
+}
+
+func TestSetAllAlertsExcludeBundles_Emulator(t *testing.T) {
+	ctx := context.Background()
+	s := newEmulatorStore(t)
+	serverID := "bundles-" + time.Now().Format("150405.000000000")
+
+	if err := s.AddAlert(ctx, AlertRule{UserID: "user1", ServerID: serverID, MustHave: []string{"3080"}}); err != nil {
+		t.Fatalf("AddAlert failed: %v", err)
+	}
+	if err := s.AddAlert(ctx, AlertRule{UserID: "user1", ServerID: serverID, MustHave: []string{"ddr5"}, ExcludeBundles: true}); err != nil {
+		t.Fatalf("AddAlert failed: %v", err)
+	}
+
+	changed, err := s.SetAllAlertsExcludeBundles(ctx, serverID, "user1", true)
+	if err != nil || changed != 1 {
+		t.Fatalf("expected 1 alert changed, got %d, %v", changed, err)
+	}
+	alerts, _ := s.GetUserAlerts(ctx, serverID, "user1")
+	for _, a := range alerts {
+		if !a.ExcludeBundles {
+			t.Errorf("expected alert %s to skip bundles", a.ID)
+		}
+	}
+}
+
+func TestGetAlert_Emulator(t *testing.T) {
+	ctx := context.Background()
+	s := newEmulatorStore(t)
+	serverID := "get-alert-" + time.Now().Format("150405.000000000")
+
+	rule := AlertRule{
+		UserID:         "user1",
+		ServerID:       serverID,
+		MustHave:       []string{"3090"},
+		AnyOf:          []string{"toronto", "gta"},
+		MustNot:        []string{"broken"},
+		RawQuery:       "3090 in the gta",
+		SearchBody:     true,
+		ExcludeBundles: true,
+	}
+	if err := s.AddAlert(ctx, rule); err != nil {
+		t.Fatalf("AddAlert failed: %v", err)
+	}
+	alerts, _ := s.GetUserAlerts(ctx, serverID, "user1")
+	if len(alerts) != 1 {
+		t.Fatalf("expected 1 alert, got %d", len(alerts))
+	}
+
+	got, err := s.GetAlert(ctx, alerts[0].ID)
+	if err != nil {
+		t.Fatalf("GetAlert failed: %v", err)
+	}
+	// GetAlert and GetUserAlerts read the same document, so everything, ID included, must agree.
+	if !reflect.DeepEqual(*got, alerts[0]) {
+		t.Errorf("GetAlert = %+v, want %+v", *got, alerts[0])
+	}
+	if got.ID == "" || got.RawQuery != rule.RawQuery || !reflect.DeepEqual(got.AnyOf, rule.AnyOf) || !got.SearchBody || !got.ExcludeBundles {
+		t.Errorf("GetAlert did not round-trip the saved rule: %+v", *got)
+	}
+
+	if err := s.DeleteAlert(ctx, alerts[0].ID); err != nil {
+		t.Fatalf("DeleteAlert failed: %v", err)
+	}
+	if _, err := s.GetAlert(ctx, alerts[0].ID); !errors.Is(err, ErrAlertNotFound) {
+		t.Errorf("expected ErrAlertNotFound for a deleted alert, got %v", err)
+	}
+}
+
+func TestSaveParseFlag_Emulator(t *testing.T) {
+	ctx := context.Background()
+	s := newEmulatorStore(t)
+	redditID := "flag-" + time.Now().Format("150405.000000000")
+
+	record := AnalyticsRecord{FlowType: "clean", Outcome: "Flagged_Bad_Parse", RedditID: redditID, UserID: "user1"}
+	for n, want := range []bool{true, false} {
+		created, err := s.SaveParseFlag(ctx, record)
+		if err != nil {
+			t.Fatalf("SaveParseFlag #%d failed: %v", n+1, err)
+		}
+		if created != want {
+			t.Errorf("SaveParseFlag #%d = %v, want %v", n+1, created, want)
+		}
+	}
+
+	record.UserID = "user2"
+	if created, err := s.SaveParseFlag(ctx, record); err != nil || !created {
+		t.Errorf("expected another user's report to be saved, got %v, %v", created, err)
+	}
+}
+
+func TestAddPostMessage_Emulator(t *testing.T) {
+	ctx := context.Background()
+	s := newEmulatorStore(t)
+	redditID := "post-" + time.Now().Format("150405.000000000")
+	t.Cleanup(func() { _, _ = s.client.Collection("posts").Doc(redditID).Delete(ctx) })
+
+	if err := s.SavePostRecords(ctx, redditID, "RTX 3080", "rtx 3080", "", "", "", 0, map[string]string{"guild1": "msg1"}, nil); err != nil {
+		t.Fatalf("SavePostRecords failed: %v", err)
+	}
+	before, err := s.GetPostRecord(ctx, redditID)
+	if err != nil {
+		t.Fatalf("GetPostRecord failed: %v", err)
+	}
+
+	if err := s.AddPostMessage(ctx, redditID, "guild2", "msg2", "hook2"); err != nil {
+		t.Fatalf("AddPostMessage failed: %v", err)
+	}
+	after, err := s.GetPostRecord(ctx, redditID)
+	if err != nil {
+		t.Fatalf("GetPostRecord failed: %v", err)
+	}
+
+	if !after.PostedAt.Equal(before.PostedAt) || after.CleanedTitle != "RTX 3080" {
+		t.Errorf("expected posted_at and the title to be unchanged, got %v and %q", after.PostedAt, after.CleanedTitle)
+	}
+	wantMsgs := map[string]string{"guild1": "msg1", "guild2": "msg2"}
+	if !reflect.DeepEqual(after.ServerMsgs, wantMsgs) || after.ServerWebhooks["guild2"] != "hook2" {
+		t.Errorf("expected guild2's message to be added, got %v and %v", after.ServerMsgs, after.ServerWebhooks)
+	}
+}
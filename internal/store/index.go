@@ -0,0 +1,54 @@
+package store
+
+import (
+	"log"
+	"regexp"
+	"slices"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// indexURLPattern finds the console link Firestore includes when a query needs a composite index.
+var indexURLPattern = regexp.MustCompile(`https://console\.firebase\.google\.com/\S+`)
+
+// isIndexRequired reports whether err is Firestore rejecting a query because its composite index
+// hasn't been created, and returns the console URL for creating it when Firestore provided one.
+func isIndexRequired(err error) (string, bool) {
+	if err == nil || status.Code(err) != codes.FailedPrecondition {
+		return "", false
+	}
+	msg := status.Convert(err).Message()
+	if !strings.Contains(msg, "index") {
+		return "", false
+	}
+	return indexURLPattern.FindString(msg), true
+}
+
+// queryWithIndexFallback runs ordered and, if Firestore rejects it for a missing composite index, logs
+// the link for creating the index and runs unordered instead. The unordered results are sorted with cmp
+// and trimmed to limit in memory, so callers get the same answer, just more slowly, until the index exists.
+func queryWithIndexFallback[T any](name string, limit int, ordered, unordered func() ([]T, error), cmp func(a, b T) int) ([]T, error) {
+	results, err := ordered()
+	url, missing := isIndexRequired(err)
+	if !missing {
+		return results, err
+	}
+
+	if url != "" {
+		log.Printf("Firestore query %s needs a composite index, falling back to sorting in memory. Create it at: %s", name, url)
+	} else {
+		log.Printf("Firestore query %s needs a composite index, falling back to sorting in memory: %v", name, err)
+	}
+
+	results, err = unordered()
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(results, cmp)
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results, nil
+}
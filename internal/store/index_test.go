@@ -0,0 +1,64 @@
+package store
+
+import (
+	"cmp"
+	"errors"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const indexConsoleURL = "https://console.firebase.google.com/v1/r/project/bhs/firestore/indexes?create_composite=abc123"
+
+func TestIsIndexRequired(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantMissing bool
+		wantURL     string
+	}{
+		{"Index error with link", status.Error(codes.FailedPrecondition, "The query requires an index. You can create it here: "+indexConsoleURL), true, indexConsoleURL},
+		{"Index error without link", status.Error(codes.FailedPrecondition, "The query requires an index."), true, ""},
+		{"Other precondition", status.Error(codes.FailedPrecondition, "transaction aborted"), false, ""},
+		{"Other error", errors.New("network down"), false, ""},
+		{"No error", nil, false, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			url, missing := isIndexRequired(tt.err)
+			if missing != tt.wantMissing || url != tt.wantURL {
+				t.Errorf("isIndexRequired() = (%q, %v), want (%q, %v)", url, missing, tt.wantURL, tt.wantMissing)
+			}
+		})
+	}
+}
+
+func TestQueryWithIndexFallback(t *testing.T) {
+	ordered := func() ([]int, error) {
+		return nil, status.Error(codes.FailedPrecondition, "The query requires an index. You can create it here: "+indexConsoleURL)
+	}
+	unordered := func() ([]int, error) {
+		return []int{5, 1, 4, 2, 3}, nil
+	}
+
+	got, err := queryWithIndexFallback("test", 3, ordered, unordered, cmp.Compare[int])
+	if err != nil {
+		t.Fatalf("expected the fallback to succeed, got %v", err)
+	}
+	if len(got) != 3 || got[0] != 1 || got[1] != 2 || got[2] != 3 {
+		t.Errorf("expected the three smallest results in order, got %v", got)
+	}
+
+	t.Run("Other errors are returned", func(t *testing.T) {
+		called := false
+		_, err := queryWithIndexFallback("test", 3,
+			func() ([]int, error) { return nil, errors.New("network down") },
+			func() ([]int, error) { called = true; return nil, nil },
+			cmp.Compare[int])
+		if err == nil || called {
+			t.Errorf("expected the error without a fallback, got err=%v fallback=%v", err, called)
+		}
+	})
+}
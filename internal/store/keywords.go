@@ -0,0 +1,52 @@
+package store
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+// DefaultBannedKeywords are used until the bot owner stores their own list. Every post on the subreddit
+// is already computer hardware, so a rule built on one of these matches nearly everything.
+var DefaultBannedKeywords = []string{
+	"gpu", "computer", "computer parts", "pc", "pc parts", "hardware", "gaming", "electronics", "buy", "sell", "wts", "wtb",
+}
+
+// BannedKeywords is the settings/banned_keywords document. The bot owner edits it in the Firestore
+// console; there is no command for it.
+type BannedKeywords struct {
+	Terms []string `firestore:"terms"`
+}
+
+// GetBannedKeywords returns the generic terms stripped from keyword wizard rules, or
+// DefaultBannedKeywords if none have been stored. A stored empty list bans nothing.
+func (s *Store) GetBannedKeywords(ctx context.Context) ([]string, error) {
+	doc, err := s.client.Collection("settings").Doc("banned_keywords").Get(ctx)
+	if doc != nil && !doc.Exists() {
+		return slices.Clone(DefaultBannedKeywords), nil
+	}
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	var bk BannedKeywords
+	if err := doc.DataTo(&bk); err != nil {
+		return nil, wrapErr(err)
+	}
+	return bk.Terms, nil
+}
+
+// RecordStrippedKeywords counts one more hit for each banned term the wizard filter removed, as a field
+// per term on the stats/banned_keywords document. The counts show which rules of the wizard prompt the
+// model breaks most.
+func (s *Store) RecordStrippedKeywords(ctx context.Context, terms []string) error {
+	hits := make(map[string]int64)
+	for _, term := range terms {
+		hits[strings.ToLower(strings.TrimSpace(term))]++
+	}
+	for term, n := range hits {
+		if err := s.IncrementCounter(ctx, "stats", "banned_keywords", term, n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
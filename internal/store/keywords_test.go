@@ -0,0 +1,60 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestGetBannedKeywords_Emulator(t *testing.T) {
+	ctx := context.Background()
+	s := newEmulatorStore(t)
+	ref := s.client.Collection("settings").Doc("banned_keywords")
+	_, _ = ref.Delete(ctx)
+	t.Cleanup(func() { _, _ = ref.Delete(ctx) })
+
+	got, err := s.GetBannedKeywords(ctx)
+	if err != nil {
+		t.Fatalf("GetBannedKeywords failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, DefaultBannedKeywords) {
+		t.Errorf("expected the defaults before a list is stored, got %v", got)
+	}
+
+	if _, err := ref.Set(ctx, BannedKeywords{Terms: []string{"gpu", "mouse"}}); err != nil {
+		t.Fatalf("storing the list failed: %v", err)
+	}
+	got, err = s.GetBannedKeywords(ctx)
+	if err != nil {
+		t.Fatalf("GetBannedKeywords failed: %v", err)
+	}
+	if !reflect.DeepEqual(got, []string{"gpu", "mouse"}) {
+		t.Errorf("expected the stored list, got %v", got)
+	}
+}
+
+func TestRecordStrippedKeywords_Emulator(t *testing.T) {
+	ctx := context.Background()
+	s := newEmulatorStore(t)
+	ref := s.client.Collection("stats").Doc("banned_keywords")
+	_, _ = ref.Delete(ctx)
+	t.Cleanup(func() { _, _ = ref.Delete(ctx) })
+
+	if err := s.RecordStrippedKeywords(ctx, []string{"gpu", "GPU", "pc parts"}); err != nil {
+		t.Fatalf("RecordStrippedKeywords failed: %v", err)
+	}
+	if err := s.RecordStrippedKeywords(ctx, []string{"gpu"}); err != nil {
+		t.Fatalf("RecordStrippedKeywords failed: %v", err)
+	}
+
+	doc, err := ref.Get(ctx)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if gpu, _ := doc.DataAt("gpu"); gpu != int64(3) {
+		t.Errorf("expected 3 gpu hits, got %v", gpu)
+	}
+	if pcParts, _ := doc.DataAtPath([]string{"pc parts"}); pcParts != int64(1) {
+		t.Errorf("expected 1 pc parts hit, got %v", pcParts)
+	}
+}
@@ -0,0 +1,60 @@
+package store
+
+import "sort"
+
+// AlertPerformance summarizes how the alerts confirmed through one creation flow have performed.
+type AlertPerformance struct {
+	FlowType     string
+	Alerts       int   // Confirmed alerts that still exist
+	FiringAlerts int   // Of those, alerts that have matched at least one post
+	Matches      int64 // Posts matched across all of them
+	Deleted      int   // Confirmed alerts the user has since deleted
+}
+
+// MatchesPerAlert returns the average number of posts each surviving alert has matched.
+func (p AlertPerformance) MatchesPerAlert() float64 {
+	if p.Alerts == 0 {
+		return 0
+	}
+	return float64(p.Matches) / float64(p.Alerts)
+}
+
+// summarizeAlertPerformance groups analytics records linked to an alert by flow and totals the
+// match counts of the alerts that still exist. Results are sorted by flow type.
+func summarizeAlertPerformance(records []AnalyticsRecord, alerts map[string]AlertRule) []AlertPerformance {
+	byFlow := make(map[string]*AlertPerformance)
+	seen := make(map[string]bool)
+
+	for _, rec := range records {
+		if rec.AlertID == "" || seen[rec.AlertID] {
+			continue
+		}
+		seen[rec.AlertID] = true
+
+		perf, ok := byFlow[rec.FlowType]
+		if !ok {
+			perf = &AlertPerformance{FlowType: rec.FlowType}
+			byFlow[rec.FlowType] = perf
+		}
+
+		alert, ok := alerts[rec.AlertID]
+		if !ok {
+			perf.Deleted++
+			continue
+		}
+		perf.Alerts++
+		perf.Matches += alert.MatchCount
+		if alert.MatchCount > 0 {
+			perf.FiringAlerts++
+		}
+	}
+
+	out := make([]AlertPerformance, 0, len(byFlow))
+	for _, perf := range byFlow {
+		out = append(out, *perf)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].FlowType < out[j].FlowType
+	})
+	return out
+}
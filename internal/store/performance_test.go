@@ -0,0 +1,35 @@
+package store
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSummarizeAlertPerformance(t *testing.T) {
+	records := []AnalyticsRecord{
+		{FlowType: "wizard", AlertID: "a1"},
+		{FlowType: "wizard", AlertID: "a2"},
+		{FlowType: "wizard", AlertID: "a2"}, // Duplicate confirm click
+		{FlowType: "manual", AlertID: "m1"},
+		{FlowType: "manual", AlertID: "gone"},
+		{FlowType: "manual"}, // Not linked to an alert
+	}
+	alerts := map[string]AlertRule{
+		"a1": {ID: "a1", MatchCount: 4},
+		"a2": {ID: "a2"},
+		"m1": {ID: "m1", MatchCount: 1},
+	}
+
+	got := summarizeAlertPerformance(records, alerts)
+
+	want := []AlertPerformance{
+		{FlowType: "manual", Alerts: 1, FiringAlerts: 1, Matches: 1, Deleted: 1},
+		{FlowType: "wizard", Alerts: 2, FiringAlerts: 1, Matches: 4},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("summarizeAlertPerformance() = %+v, want %+v", got, want)
+	}
+	if avg := got[1].MatchesPerAlert(); avg != 2 {
+		t.Errorf("expected 2 matches per wizard alert, got %v", avg)
+	}
+}
@@ -0,0 +1,55 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// rateLimitRecord is a user's entry in the rate_limits collection.
+type rateLimitRecord struct {
+	LastSeen time.Time `firestore:"last_seen"`
+}
+
+// TryRateLimit checks and records a user's interaction against the rate limit shared by every instance.
+// It reports false if the user's last allowed interaction was less than interval before now. The read and
+// write run in one transaction, so two instances racing on the same user can't both let them through.
+func (s *Store) TryRateLimit(ctx context.Context, userID string, interval time.Duration, now time.Time) (bool, error) {
+	ref := s.client.Collection("rate_limits").Doc(userID)
+	allowed := false
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		allowed = false // The function reruns on contention.
+
+		var last *rateLimitRecord
+		doc, err := tx.Get(ref)
+		switch {
+		case status.Code(err) == codes.NotFound:
+		case err != nil:
+			return wrapErr(err)
+		default:
+			var rec rateLimitRecord
+			if err := doc.DataTo(&rec); err == nil {
+				last = &rec
+			}
+		}
+
+		if !rateLimitAllows(last, interval, now) {
+			return nil
+		}
+		allowed = true
+		return tx.Set(ref, rateLimitRecord{LastSeen: now})
+	})
+	if err != nil {
+		return false, wrapErr(err)
+	}
+	return allowed, nil
+}
+
+// rateLimitAllows reports whether a user whose last allowed interaction is last (nil if none is recorded)
+// may make another at now.
+func rateLimitAllows(last *rateLimitRecord, interval time.Duration, now time.Time) bool {
+	return last == nil || now.Sub(last.LastSeen) >= interval
+}
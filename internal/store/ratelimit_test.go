@@ -0,0 +1,57 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimitAllows(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	if !rateLimitAllows(nil, 2*time.Second, now) {
+		t.Error("expected a user with no record to be allowed")
+	}
+	if rateLimitAllows(&rateLimitRecord{LastSeen: now.Add(-time.Second)}, 2*time.Second, now) {
+		t.Error("expected a user seen 1s ago to be throttled")
+	}
+	if !rateLimitAllows(&rateLimitRecord{LastSeen: now.Add(-2 * time.Second)}, 2*time.Second, now) {
+		t.Error("expected a user seen 2s ago to be allowed")
+	}
+}
+
+// TestTryRateLimit_Emulator runs the transactional path against the Firestore emulator.
+func TestTryRateLimit_Emulator(t *testing.T) {
+	ctx := context.Background()
+	s := newEmulatorStore(t)
+
+	userID := "ratelimit-" + time.Now().Format("150405.000000000")
+	now := time.Now()
+
+	// Several instances racing on the same user let exactly one request through.
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := s.TryRateLimit(ctx, userID, 2*time.Second, now)
+			if err != nil {
+				t.Errorf("TryRateLimit failed: %v", err)
+			}
+			if ok {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if allowed.Load() != 1 {
+		t.Errorf("expected exactly one request to be allowed, got %d", allowed.Load())
+	}
+
+	if ok, err := s.TryRateLimit(ctx, userID, 2*time.Second, now.Add(3*time.Second)); err != nil || !ok {
+		t.Errorf("expected the user to be allowed once the interval passed, got %v, %v", ok, err)
+	}
+}
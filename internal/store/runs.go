@@ -0,0 +1,62 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// maxStoredRuns is how many of the most recent pipeline runs TrimOldPipelineRuns keeps.
+const maxStoredRuns = 500
+
+// PipelineRun records one scrape of Reddit, so operators can review recent runs with /runs.
+type PipelineRun struct {
+	ID           string        `firestore:"-"`
+	StartedAt    time.Time     `firestore:"started_at"`
+	Duration     time.Duration `firestore:"duration"`
+	PostsFetched int           `firestore:"posts_fetched"`   // Posts returned by Reddit
+	NewPosts     int           `firestore:"new_posts"`       // Posts not seen before, sent to Gemini for cleaning
+	Matches      int           `firestore:"matches"`         // Users matched across every new post
+	Errors       int           `firestore:"errors"`          // Posts that failed to clean, save or update
+	Error        string        `firestore:"error,omitempty"` // Why the run failed outright, if it did
+}
+
+// SavePipelineRun stores a finished pipeline run.
+func (s *Store) SavePipelineRun(ctx context.Context, run PipelineRun) error {
+	_, _, err := s.client.Collection("pipeline_runs").Add(ctx, run)
+	return wrapErr(err)
+}
+
+// GetRecentPipelineRuns returns the most recent pipeline runs, newest first.
+func (s *Store) GetRecentPipelineRuns(ctx context.Context, limit int) ([]PipelineRun, error) {
+	iter := s.client.Collection("pipeline_runs").
+		OrderBy("started_at", firestore.Desc).
+		Limit(limit).
+		Documents(ctx)
+	defer iter.Stop()
+
+	var runs []PipelineRun
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, wrapErr(err)
+		}
+		var run PipelineRun
+		if err := doc.DataTo(&run); err != nil {
+			continue
+		}
+		run.ID = doc.Ref.ID
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// TrimOldPipelineRuns hard-deletes pipeline runs older than the 500 most recent ones, like TrimOldPosts.
+func (s *Store) TrimOldPipelineRuns(ctx context.Context) error {
+	return s.trimCollection(ctx, "pipeline_runs", "started_at", maxStoredRuns)
+}
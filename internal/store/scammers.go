@@ -0,0 +1,74 @@
+package store
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"google.golang.org/api/iterator"
+)
+
+// Scammer is a Reddit user on the bot owner's global blocklist. Their posts are dropped before cleaning,
+// so they never reach any server's feed or anyone's alerts.
+type Scammer struct {
+	Username string    `firestore:"-"` // Lowercased, without the u/ prefix; also the document ID
+	AddedBy  string    `firestore:"added_by"`
+	AddedAt  time.Time `firestore:"added_at"`
+}
+
+// redditUsername matches the characters Reddit allows in a username.
+var redditUsername = regexp.MustCompile(`^[a-z0-9_-]{3,20}$`)
+
+// NormalizeRedditUsername lowercases a Reddit username and strips a leading u/ or /u/. It reports false
+// if what's left isn't a valid username.
+func NormalizeRedditUsername(name string) (string, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.TrimPrefix(name, "/")
+	name = strings.TrimPrefix(name, "u/")
+	return name, redditUsername.MatchString(name)
+}
+
+// AddScammer puts a normalized username on the global blocklist. Adding an existing one refreshes it.
+func (s *Store) AddScammer(ctx context.Context, username, addedBy string) error {
+	_, err := s.client.Collection("scammers").Doc(username).Set(ctx, Scammer{
+		AddedBy: addedBy,
+		AddedAt: s.clock.Now(),
+	})
+	return wrapErr(err)
+}
+
+// RemoveScammer takes a normalized username off the global blocklist.
+func (s *Store) RemoveScammer(ctx context.Context, username string) error {
+	_, err := s.client.Collection("scammers").Doc(username).Delete(ctx)
+	return wrapErr(err)
+}
+
+// GetScammers returns the global blocklist sorted by username.
+func (s *Store) GetScammers(ctx context.Context) ([]Scammer, error) {
+	var scammers []Scammer
+	iter := s.client.Collection("scammers").Documents(ctx)
+	defer iter.Stop()
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, wrapErr(err)
+		}
+		var sc Scammer
+		if err := doc.DataTo(&sc); err != nil {
+			continue
+		}
+		sc.Username = doc.Ref.ID
+		scammers = append(scammers, sc)
+	}
+
+	sort.Slice(scammers, func(i, j int) bool {
+		return scammers[i].Username < scammers[j].Username
+	})
+	return scammers, nil
+}
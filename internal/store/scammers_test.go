@@ -0,0 +1,26 @@
+package store
+
+import "testing"
+
+func TestNormalizeRedditUsername(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   string
+		wantOK bool
+	}{
+		{"Spammer99", "spammer99", true},
+		{" u/Spammer99 ", "spammer99", true},
+		{"/u/Bad_Seller-1", "bad_seller-1", true},
+		{"ab", "ab", false},
+		{"has space", "has space", false},
+		{"u/", "", false},
+		{"waytoolongusername_123456", "waytoolongusername_123456", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := NormalizeRedditUsername(tt.in)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("NormalizeRedditUsername(%q) = %q, %v, want %q, %v", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+package store
+
+import "strings"
+
+// DMScopePrefix marks alerts a user manages from DMs (user-installed app) rather than inside a server.
+// Such alerts are stored with ServerID set to DMScopePrefix + userID and are delivered by DM.
+const DMScopePrefix = "dm:"
+
+// DMScope returns the synthetic server ID used for a user's DM-managed alerts.
+func DMScope(userID string) string {
+	return DMScopePrefix + userID
+}
+
+// DMScopeUser reports whether serverID is a DM scope and, if so, which user it belongs to.
+func DMScopeUser(serverID string) (string, bool) {
+	userID, ok := strings.CutPrefix(serverID, DMScopePrefix)
+	if !ok || userID == "" {
+		return "", false
+	}
+	return userID, true
+}
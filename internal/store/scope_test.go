@@ -0,0 +1,23 @@
+package store
+
+import "testing"
+
+func TestDMScopeUser(t *testing.T) {
+	tests := []struct {
+		serverID string
+		wantUser string
+		wantOK   bool
+	}{
+		{DMScope("user1"), "user1", true},
+		{"123456789", "", false},
+		{"dm:", "", false},
+		{"", "", false},
+	}
+
+	for _, tt := range tests {
+		user, ok := DMScopeUser(tt.serverID)
+		if user != tt.wantUser || ok != tt.wantOK {
+			t.Errorf("DMScopeUser(%q) = (%q, %v), want (%q, %v)", tt.serverID, user, ok, tt.wantUser, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UserSettings holds a user's personal preferences. They follow the user across servers and DMs.
+type UserSettings struct {
+	UserID       string    `firestore:"-"`
+	Timezone     string    `firestore:"timezone,omitempty"`      // IANA name, e.g. "America/Toronto". Empty means UTC
+	QuietEnabled bool      `firestore:"quiet_enabled,omitempty"` // Whether QuietStart/QuietEnd apply
+	QuietStart   int       `firestore:"quiet_start"`             // Hour (0-23, user's timezone) quiet hours begin
+	QuietEnd     int       `firestore:"quiet_end"`               // Hour (0-23, user's timezone) quiet hours end
+	MutedAuthors []string  `firestore:"muted_authors,omitempty"` // Lowercased Reddit usernames whose posts never match the user's alerts
+	HasMatched   bool      `firestore:"has_matched,omitempty"`   // Whether one of the user's alerts has matched a deal, so the first match welcome was sent
+	UpdatedAt    time.Time `firestore:"updated_at"`
+}
+
+// HasMutedAuthor reports whether the user muted the Reddit seller author. Usernames are compared case-insensitively.
+func (u UserSettings) HasMutedAuthor(author string) bool {
+	author = strings.ToLower(author)
+	for _, muted := range u.MutedAuthors {
+		if muted == author {
+			return true
+		}
+	}
+	return false
+}
+
+// Location returns the user's timezone, falling back to UTC when it is unset or unknown.
+func (u UserSettings) Location() *time.Location {
+	if u.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// QuietUntil reports whether now falls inside the user's quiet hours and, if so, when they end.
+// Windows may wrap midnight (e.g. 22 to 7). A window whose start and end are equal is empty.
+func (u UserSettings) QuietUntil(now time.Time) (time.Time, bool) {
+	if !u.QuietEnabled || u.QuietStart == u.QuietEnd {
+		return time.Time{}, false
+	}
+
+	local := now.In(u.Location())
+	hour := local.Hour()
+	var quiet bool
+	if u.QuietStart < u.QuietEnd {
+		quiet = hour >= u.QuietStart && hour < u.QuietEnd
+	} else {
+		quiet = hour >= u.QuietStart || hour < u.QuietEnd
+	}
+	if !quiet {
+		return time.Time{}, false
+	}
+
+	end := time.Date(local.Year(), local.Month(), local.Day(), u.QuietEnd, 0, 0, 0, local.Location())
+	if !end.After(local) {
+		end = end.AddDate(0, 0, 1)
+	}
+	return end, true
+}
+
+// GetUserSettings returns a user's settings, or zero-value settings if they never saved any.
+func (s *Store) GetUserSettings(ctx context.Context, userID string) (*UserSettings, error) {
+	doc, err := s.client.Collection("users").Doc(userID).Get(ctx)
+	if doc != nil && !doc.Exists() {
+		return &UserSettings{UserID: userID}, nil
+	}
+	if err != nil {
+		return nil, wrapErr(err)
+	}
+	var settings UserSettings
+	if err := doc.DataTo(&settings); err != nil {
+		return nil, wrapErr(err)
+	}
+	settings.UserID = userID
+	return &settings, nil
+}
+
+// SetQuietHours turns on quiet hours for a user. An empty timezone keeps whatever the user already has.
+func (s *Store) SetQuietHours(ctx context.Context, userID string, start, end int, timezone string) error {
+	data := map[string]interface{}{
+		"quiet_enabled": true,
+		"quiet_start":   start,
+		"quiet_end":     end,
+		"updated_at":    s.clock.Now(),
+	}
+	if timezone != "" {
+		data["timezone"] = timezone
+	}
+	_, err := s.client.Collection("users").Doc(userID).Set(ctx, data, firestore.MergeAll)
+	return wrapErr(err)
+}
+
+// SetTimezone saves a user's IANA timezone, keeping their other settings.
+func (s *Store) SetTimezone(ctx context.Context, userID, timezone string) error {
+	_, err := s.client.Collection("users").Doc(userID).Set(ctx, map[string]interface{}{
+		"timezone":   timezone,
+		"updated_at": s.clock.Now(),
+	}, firestore.MergeAll)
+	return wrapErr(err)
+}
+
+// ClearQuietHours turns off quiet hours for a user, keeping their other settings.
+func (s *Store) ClearQuietHours(ctx context.Context, userID string) error {
+	_, err := s.client.Collection("users").Doc(userID).Set(ctx, map[string]interface{}{
+		"quiet_enabled": false,
+		"updated_at":    s.clock.Now(),
+	}, firestore.MergeAll)
+	return wrapErr(err)
+}
+
+// MuteAuthor stops posts by the Reddit user author from matching any of the user's alerts, keeping their other settings.
+func (s *Store) MuteAuthor(ctx context.Context, userID, author string) error {
+	_, err := s.client.Collection("users").Doc(userID).Set(ctx, map[string]interface{}{
+		"muted_authors": firestore.ArrayUnion(strings.ToLower(author)),
+		"updated_at":    s.clock.Now(),
+	}, firestore.MergeAll)
+	return wrapErr(err)
+}
+
+// UnmuteAuthor lets posts by author match the user's alerts again.
+func (s *Store) UnmuteAuthor(ctx context.Context, userID, author string) error {
+	_, err := s.client.Collection("users").Doc(userID).Set(ctx, map[string]interface{}{
+		"muted_authors": firestore.ArrayRemove(strings.ToLower(author)),
+		"updated_at":    s.clock.Now(),
+	}, firestore.MergeAll)
+	return wrapErr(err)
+}
+
+// MarkUserMatched records that one of the user's alerts has matched a deal, keeping their other settings.
+// It reports true only for the call that set the flag, so two instances racing on a user's first match
+// can't both welcome them.
+func (s *Store) MarkUserMatched(ctx context.Context, userID string) (bool, error) {
+	ref := s.client.Collection("users").Doc(userID)
+	first := false
+	err := s.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		first = false // The function reruns on contention.
+
+		doc, err := tx.Get(ref)
+		switch {
+		case status.Code(err) == codes.NotFound:
+		case err != nil:
+			return wrapErr(err)
+		default:
+			var settings UserSettings
+			if err := doc.DataTo(&settings); err == nil && settings.HasMatched {
+				return nil
+			}
+		}
+
+		first = true
+		return tx.Set(ref, map[string]interface{}{
+			"has_matched": true,
+			"updated_at":  s.clock.Now(),
+		}, firestore.MergeAll)
+	})
+	if err != nil {
+		return false, wrapErr(err)
+	}
+	return first, nil
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUserSettings_QuietUntil(t *testing.T) {
+	toronto, err := time.LoadLocation("America/Toronto")
+	if err != nil {
+		t.Skipf("timezone data unavailable: %v", err)
+	}
+
+	overnight := UserSettings{Timezone: "America/Toronto", QuietEnabled: true, QuietStart: 22, QuietEnd: 7}
+	tests := []struct {
+		name      string
+		settings  UserSettings
+		now       time.Time
+		wantQuiet bool
+		wantUntil time.Time
+	}{
+		{"Before midnight", overnight, time.Date(2026, 3, 2, 23, 30, 0, 0, toronto), true, time.Date(2026, 3, 3, 7, 0, 0, 0, toronto)},
+		{"After midnight", overnight, time.Date(2026, 3, 3, 3, 0, 0, 0, toronto), true, time.Date(2026, 3, 3, 7, 0, 0, 0, toronto)},
+		{"End hour is awake", overnight, time.Date(2026, 3, 3, 7, 0, 0, 0, toronto), false, time.Time{}},
+		{"Daytime", overnight, time.Date(2026, 3, 3, 12, 0, 0, 0, toronto), false, time.Time{}},
+		{"Evaluated in the user's timezone", overnight, time.Date(2026, 3, 3, 4, 0, 0, 0, time.UTC), true, time.Date(2026, 3, 3, 7, 0, 0, 0, toronto)},
+		{"Same-day window", UserSettings{QuietEnabled: true, QuietStart: 9, QuietEnd: 17}, time.Date(2026, 3, 3, 10, 0, 0, 0, time.UTC), true, time.Date(2026, 3, 3, 17, 0, 0, 0, time.UTC)},
+		{"Disabled", UserSettings{QuietStart: 0, QuietEnd: 23}, time.Date(2026, 3, 3, 10, 0, 0, 0, time.UTC), false, time.Time{}},
+		{"Empty window", UserSettings{QuietEnabled: true, QuietStart: 5, QuietEnd: 5}, time.Date(2026, 3, 3, 5, 0, 0, 0, time.UTC), false, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			until, quiet := tt.settings.QuietUntil(tt.now)
+			if quiet != tt.wantQuiet {
+				t.Fatalf("quiet = %v, want %v", quiet, tt.wantQuiet)
+			}
+			if !until.Equal(tt.wantUntil) {
+				t.Errorf("until = %v, want %v", until, tt.wantUntil)
+			}
+		})
+	}
+}
+
+func TestUserSettings_HasMutedAuthor(t *testing.T) {
+	u := UserSettings{MutedAuthors: []string{"spammer99"}}
+
+	if !u.HasMutedAuthor("spammer99") || !u.HasMutedAuthor("Spammer99") {
+		t.Error("expected the muted author to match regardless of case")
+	}
+	if u.HasMutedAuthor("honest_seller") {
+		t.Error("expected other authors not to be muted")
+	}
+	if (UserSettings{}).HasMutedAuthor("spammer99") {
+		t.Error("expected no authors to be muted by default")
+	}
+}
+
+// TestMarkUserMatched_Emulator runs the transactional path against the Firestore emulator.
+func TestMarkUserMatched_Emulator(t *testing.T) {
+	ctx := context.Background()
+	s := newEmulatorStore(t)
+
+	userID := "matched-" + time.Now().Format("150405.000000000")
+	if err := s.SetTimezone(ctx, userID, "America/Toronto"); err != nil {
+		t.Fatalf("SetTimezone failed: %v", err)
+	}
+
+	// Several instances racing on the same user's first match see it exactly once.
+	var first atomic.Int32
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ok, err := s.MarkUserMatched(ctx, userID)
+			if err != nil {
+				t.Errorf("MarkUserMatched failed: %v", err)
+				return
+			}
+			if ok {
+				first.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if got := first.Load(); got != 1 {
+		t.Errorf("first matches = %d, want 1", got)
+	}
+
+	settings, err := s.GetUserSettings(ctx, userID)
+	if err != nil {
+		t.Fatalf("GetUserSettings failed: %v", err)
+	}
+	if !settings.HasMatched || settings.Timezone != "America/Toronto" {
+		t.Errorf("settings = %+v, want HasMatched set and the timezone kept", settings)
+	}
+
+	if ok, err := s.MarkUserMatched(ctx, userID); err != nil || ok {
+		t.Errorf("MarkUserMatched again = %v, %v; want false, nil", ok, err)
+	}
+}
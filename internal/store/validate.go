@@ -0,0 +1,105 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/pauljones0/betterHardwareSwap/internal/hardware"
+)
+
+// Limits shared by every path that stages an alert (AI wizard, manual wizard, imports).
+const (
+	MaxAlertTerms      = 20  // Per keyword list
+	MaxAlertTermLength = 50  // Runes per keyword
+	MaxRawQueryLength  = 300 // Matches the AI wizard's modal limit
+)
+
+var (
+	ErrAlertNoKeywords = errors.New("alert needs at least one keyword to include")
+	ErrAlertNoOwner    = errors.New("alert is missing its user or server")
+)
+
+// NormalizeAlertRule returns a copy of the rule with every keyword trimmed and lowercased, empty
+// keywords dropped and duplicates removed. An AnyOf list that repeats a MustHave keyword is always
+// satisfied, so it is dropped. Recognized hardware models in AnyOf gain their common spellings (see
+// hardware.ExpandAliases) up to MaxAlertTerms. Alerts are stored in this form so matching can skip
+// per-call string cleanup.
+func NormalizeAlertRule(rule AlertRule) AlertRule {
+	rule.MustHave = normalizeTerms(rule.MustHave)
+	rule.AnyOf = normalizeTerms(rule.AnyOf)
+	if overlaps(rule.AnyOf, rule.MustHave) {
+		// Removing just the repeated keyword would make the rest of AnyOf required, narrowing the alert.
+		rule.AnyOf = nil
+	}
+	rule.AnyOf = hardware.ExpandAliases(rule.AnyOf, MaxAlertTerms)
+	rule.MustNot = normalizeTerms(rule.MustNot)
+	rule.RawQuery = strings.TrimSpace(rule.RawQuery)
+	return rule
+}
+
+func normalizeTerms(terms []string) []string {
+	if terms == nil {
+		return nil
+	}
+	out := make([]string, 0, len(terms))
+	seen := make(map[string]bool, len(terms))
+	for _, t := range terms {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		out = append(out, t)
+	}
+	return out
+}
+
+// overlaps reports whether any term appears in both lists.
+func overlaps(a, b []string) bool {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ValidateAlertRule checks a rule against the shared alert limits. It validates the normalized form,
+// so callers should save the result of NormalizeAlertRule.
+func ValidateAlertRule(rule AlertRule) error {
+	rule = NormalizeAlertRule(rule)
+
+	if rule.UserID == "" || rule.ServerID == "" {
+		return ErrAlertNoOwner
+	}
+	if len(rule.MustHave) == 0 && len(rule.AnyOf) == 0 && rule.TrendingComments <= 0 {
+		return ErrAlertNoKeywords
+	}
+	if utf8.RuneCountInString(rule.RawQuery) > MaxRawQueryLength {
+		return fmt.Errorf("alert query is longer than %d characters", MaxRawQueryLength)
+	}
+
+	lists := []struct {
+		name  string
+		terms []string
+	}{
+		{"must include", rule.MustHave},
+		{"match any of", rule.AnyOf},
+		{"exclude", rule.MustNot},
+	}
+	for _, l := range lists {
+		if len(l.terms) > MaxAlertTerms {
+			return fmt.Errorf("%q list has %d keywords, the limit is %d", l.name, len(l.terms), MaxAlertTerms)
+		}
+		for _, t := range l.terms {
+			if utf8.RuneCountInString(t) > MaxAlertTermLength {
+				return fmt.Errorf("keyword %q is longer than %d characters", t, MaxAlertTermLength)
+			}
+		}
+	}
+	return nil
+}
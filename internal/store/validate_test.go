@@ -0,0 +1,160 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidateAlertRule(t *testing.T) {
+	base := AlertRule{UserID: "user1", ServerID: "guild1", MustHave: []string{"3080"}}
+
+	tests := []struct {
+		name    string
+		mutate  func(r *AlertRule)
+		wantErr error
+		wantMsg string
+	}{
+		{
+			name:   "Valid rule",
+			mutate: func(r *AlertRule) {},
+		},
+		{
+			name:   "AnyOf alone is enough",
+			mutate: func(r *AlertRule) { r.MustHave = nil; r.AnyOf = []string{"3080", "3090"} },
+		},
+		{
+			name:    "No positive keywords",
+			mutate:  func(r *AlertRule) { r.MustHave = nil; r.MustNot = []string{"broken"} },
+			wantErr: ErrAlertNoKeywords,
+		},
+		{
+			name:    "Whitespace-only keywords count as empty",
+			mutate:  func(r *AlertRule) { r.MustHave = []string{"  ", ""} },
+			wantErr: ErrAlertNoKeywords,
+		},
+		{
+			name: "Duplicates collapse under the limit",
+			mutate: func(r *AlertRule) {
+				r.AnyOf = make([]string, MaxAlertTerms+1)
+				for i := range r.AnyOf {
+					r.AnyOf[i] = "Term"
+				}
+			},
+		},
+		{
+			name:    "Missing owner",
+			mutate:  func(r *AlertRule) { r.UserID = "" },
+			wantErr: ErrAlertNoOwner,
+		},
+		{
+			name:    "Keyword too long",
+			mutate:  func(r *AlertRule) { r.MustNot = []string{strings.Repeat("a", MaxAlertTermLength+1)} },
+			wantMsg: "longer than",
+		},
+		{
+			name: "Keyword at the limit after trimming",
+			mutate: func(r *AlertRule) {
+				r.MustHave = []string{"  " + strings.Repeat("a", MaxAlertTermLength) + "  "}
+			},
+		},
+		{
+			name: "Too many keywords",
+			mutate: func(r *AlertRule) {
+				r.AnyOf = make([]string, MaxAlertTerms+1)
+				for i := range r.AnyOf {
+					r.AnyOf[i] = fmt.Sprintf("term%d", i)
+				}
+			},
+			wantMsg: "the limit is",
+		},
+		{
+			name:    "Raw query too long",
+			mutate:  func(r *AlertRule) { r.RawQuery = strings.Repeat("q", MaxRawQueryLength+1) },
+			wantMsg: "query is longer than",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule := base
+			rule.MustHave = append([]string(nil), base.MustHave...)
+			tt.mutate(&rule)
+
+			err := ValidateAlertRule(rule)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("expected %v, got %v", tt.wantErr, err)
+				}
+			case tt.wantMsg != "":
+				if err == nil || !strings.Contains(err.Error(), tt.wantMsg) {
+					t.Errorf("expected an error containing %q, got %v", tt.wantMsg, err)
+				}
+			default:
+				if err != nil {
+					t.Errorf("expected a valid rule, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestNormalizeAlertRule(t *testing.T) {
+	rule := AlertRule{
+		MustHave: []string{"  RTX 3080 "},
+		AnyOf:    []string{"Toronto", "OTTAWA", " toronto", ""},
+		RawQuery: "  my alert ",
+	}
+
+	got := NormalizeAlertRule(rule)
+
+	if !reflect.DeepEqual(got.MustHave, []string{"rtx 3080"}) {
+		t.Errorf("unexpected must_have %v", got.MustHave)
+	}
+	if !reflect.DeepEqual(got.AnyOf, []string{"toronto", "ottawa"}) {
+		t.Errorf("unexpected any_of %v", got.AnyOf)
+	}
+	if got.MustNot != nil {
+		t.Errorf("expected nil must_not to stay nil, got %v", got.MustNot)
+	}
+	if got.RawQuery != "my alert" {
+		t.Errorf("unexpected raw query %q", got.RawQuery)
+	}
+	if rule.MustHave[0] != "  RTX 3080 " {
+		t.Error("NormalizeAlertRule should not modify its input")
+	}
+}
+
+func TestNormalizeAlertRule_ExpandsModelAliases(t *testing.T) {
+	got := NormalizeAlertRule(AlertRule{MustHave: []string{"3080"}, AnyOf: []string{"RTX3080", "Calgary"}})
+
+	if !reflect.DeepEqual(got.AnyOf, []string{"rtx3080", "calgary", "3080", "rtx 3080", "3080fe"}) {
+		t.Errorf("expected the 3080 spellings to be added to any_of, got %v", got.AnyOf)
+	}
+	if !reflect.DeepEqual(got.MustHave, []string{"3080"}) {
+		t.Errorf("expected must_have to be left alone, got %v", got.MustHave)
+	}
+}
+
+func TestNormalizeAlertRule_CollapsesOverlappingTerms(t *testing.T) {
+	got := NormalizeAlertRule(AlertRule{
+		MustHave: []string{"3090", "FE", "fe "},
+		AnyOf:    []string{"Toronto", "3090", "toronto"},
+	})
+
+	if !reflect.DeepEqual(got.MustHave, []string{"3090", "fe"}) {
+		t.Errorf("expected must_have to be de-duplicated, got %v", got.MustHave)
+	}
+	// Every post with the required 3090 already satisfies "3090 or toronto", so the list adds nothing.
+	if len(got.AnyOf) != 0 {
+		t.Errorf("expected any_of repeating a must_have term to be dropped, got %v", got.AnyOf)
+	}
+
+	got = NormalizeAlertRule(AlertRule{MustHave: []string{"3090"}, AnyOf: []string{"Toronto", "toronto", "GTA"}})
+	if !reflect.DeepEqual(got.AnyOf, []string{"toronto", "gta"}) {
+		t.Errorf("expected a disjoint any_of to be kept and de-duplicated, got %v", got.AnyOf)
+	}
+}
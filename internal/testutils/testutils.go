@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/pauljones0/betterHardwareSwap/internal/ai"
@@ -38,6 +39,14 @@ func (m *MockStore) AddAlert(ctx context.Context, rule store.AlertRule) error {
 	return args.Error(0)
 }
 
+func (m *MockStore) GetAlert(ctx context.Context, docID string) (*store.AlertRule, error) {
+	args := m.Called(ctx, docID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*store.AlertRule), args.Error(1)
+}
+
 func (m *MockStore) GetUserAlerts(ctx context.Context, serverID, userID string) ([]store.AlertRule, error) {
 	args := m.Called(ctx, serverID, userID)
 	if args.Get(0) == nil {
@@ -56,6 +65,19 @@ func (m *MockStore) DeleteAllUserAlerts(ctx context.Context, serverID, userID st
 	return args.Error(0)
 }
 
+func (m *MockStore) IncrementAlertMatches(ctx context.Context, alertIDs []string) error {
+	args := m.Called(ctx, alertIDs)
+	return args.Error(0)
+}
+
+func (m *MockStore) GetAlertPerformance(ctx context.Context) ([]store.AlertPerformance, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]store.AlertPerformance), args.Error(1)
+}
+
 func (m *MockStore) GetAllAlerts(ctx context.Context) ([]store.AlertRule, error) {
 	args := m.Called(ctx)
 	if args.Get(0) == nil {
@@ -72,20 +94,59 @@ func (m *MockStore) GetPostRecord(ctx context.Context, redditID string) (*store.
 	return args.Get(0).(*store.PostRecord), args.Error(1)
 }
 
-func (m *MockStore) SavePostRecord(ctx context.Context, redditID, cleanedTitle, serverID, discordMsgID string) error {
-	args := m.Called(ctx, redditID, cleanedTitle, serverID, discordMsgID)
+func (m *MockStore) AddPostMessage(ctx context.Context, redditID, serverID, discordMsgID, webhookID string) error {
+	args := m.Called(ctx, redditID, serverID, discordMsgID, webhookID)
+	return args.Error(0)
+}
+
+func (m *MockStore) SavePostRecords(ctx context.Context, redditID, cleanedTitle, corpus, rawText, postURL, price string, numComments int, serverMsgs, serverWebhooks map[string]string) error {
+	args := m.Called(ctx, redditID, cleanedTitle, corpus, rawText, postURL, price, numComments, serverMsgs, serverWebhooks)
+	return args.Error(0)
+}
+
+func (m *MockStore) UpdatePostComments(ctx context.Context, redditID string, numComments int) error {
+	args := m.Called(ctx, redditID, numComments)
 	return args.Error(0)
 }
 
-func (m *MockStore) SavePostRecords(ctx context.Context, redditID, cleanedTitle string, serverMsgs map[string]string) error {
-	args := m.Called(ctx, redditID, cleanedTitle, serverMsgs)
+func (m *MockStore) UpdatePostContent(ctx context.Context, redditID, cleanedTitle, corpus, rawText, price string) error {
+	args := m.Called(ctx, redditID, cleanedTitle, corpus, rawText, price)
+	return args.Error(0)
+}
+
+func (m *MockStore) MarkPostClosed(ctx context.Context, redditID string) error {
+	args := m.Called(ctx, redditID)
 	return args.Error(0)
 }
 
+func (m *MockStore) GetRecentPosts(ctx context.Context, limit int) ([]store.PostRecord, error) {
+	args := m.Called(ctx, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]store.PostRecord), args.Error(1)
+}
+
 func (m *MockStore) TrimOldPosts(ctx context.Context) error {
 	return m.Called(ctx).Error(0)
 }
 
+func (m *MockStore) SavePipelineRun(ctx context.Context, run store.PipelineRun) error {
+	return m.Called(ctx, run).Error(0)
+}
+
+func (m *MockStore) GetRecentPipelineRuns(ctx context.Context, limit int) ([]store.PipelineRun, error) {
+	args := m.Called(ctx, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]store.PipelineRun), args.Error(1)
+}
+
+func (m *MockStore) TrimOldPipelineRuns(ctx context.Context) error {
+	return m.Called(ctx).Error(0)
+}
+
 func (m *MockStore) GetServerConfig(ctx context.Context, serverID string) (*store.ServerConfig, error) {
 	args := m.Called(ctx, serverID)
 	if args.Get(0) == nil {
@@ -94,16 +155,170 @@ func (m *MockStore) GetServerConfig(ctx context.Context, serverID string) (*stor
 	return args.Get(0).(*store.ServerConfig), args.Error(1)
 }
 
+func (m *MockStore) SetAllAlertsEnabled(ctx context.Context, serverID, userID string, enabled bool) (int, error) {
+	args := m.Called(ctx, serverID, userID, enabled)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockStore) SetAllAlertsExcludeBundles(ctx context.Context, serverID, userID string, exclude bool) (int, error) {
+	args := m.Called(ctx, serverID, userID, exclude)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockStore) SnoozeAlert(ctx context.Context, docID string, until time.Time) error {
+	args := m.Called(ctx, docID, until)
+	return args.Error(0)
+}
+
+func (m *MockStore) SetAlertSearchBody(ctx context.Context, docID string, enabled bool) error {
+	args := m.Called(ctx, docID, enabled)
+	return args.Error(0)
+}
+
+func (m *MockStore) AddBlocklistTerm(ctx context.Context, serverID, term string) error {
+	args := m.Called(ctx, serverID, term)
+	return args.Error(0)
+}
+
+func (m *MockStore) RemoveBlocklistTerm(ctx context.Context, serverID, term string) error {
+	args := m.Called(ctx, serverID, term)
+	return args.Error(0)
+}
+
 func (m *MockStore) SaveServerConfig(ctx context.Context, serverID string, cfg store.ServerConfig) error {
 	args := m.Called(ctx, serverID, cfg)
 	return args.Error(0)
 }
 
+func (m *MockStore) GetAllServers(ctx context.Context) ([]store.ServerConfig, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]store.ServerConfig), args.Error(1)
+}
+
+func (m *MockStore) CountServerAlerts(ctx context.Context, serverID string) (int64, error) {
+	args := m.Called(ctx, serverID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockStore) SaveFailedDispatch(ctx context.Context, fd store.FailedDispatch) error {
+	args := m.Called(ctx, fd)
+	return args.Error(0)
+}
+
+func (m *MockStore) GetFailedDispatches(ctx context.Context, limit int) ([]store.FailedDispatch, error) {
+	args := m.Called(ctx, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]store.FailedDispatch), args.Error(1)
+}
+
+func (m *MockStore) DeleteFailedDispatch(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockStore) GetUserSettings(ctx context.Context, userID string) (*store.UserSettings, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*store.UserSettings), args.Error(1)
+}
+
+func (m *MockStore) SetQuietHours(ctx context.Context, userID string, start, end int, timezone string) error {
+	args := m.Called(ctx, userID, start, end, timezone)
+	return args.Error(0)
+}
+
+func (m *MockStore) ClearQuietHours(ctx context.Context, userID string) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockStore) SetTimezone(ctx context.Context, userID, timezone string) error {
+	args := m.Called(ctx, userID, timezone)
+	return args.Error(0)
+}
+
+func (m *MockStore) MuteAuthor(ctx context.Context, userID, author string) error {
+	return m.Called(ctx, userID, author).Error(0)
+}
+
+func (m *MockStore) UnmuteAuthor(ctx context.Context, userID, author string) error {
+	return m.Called(ctx, userID, author).Error(0)
+}
+
+func (m *MockStore) MarkUserMatched(ctx context.Context, userID string) (bool, error) {
+	args := m.Called(ctx, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStore) AddScammer(ctx context.Context, username, addedBy string) error {
+	return m.Called(ctx, username, addedBy).Error(0)
+}
+
+func (m *MockStore) RemoveScammer(ctx context.Context, username string) error {
+	return m.Called(ctx, username).Error(0)
+}
+
+func (m *MockStore) GetScammers(ctx context.Context) ([]store.Scammer, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]store.Scammer), args.Error(1)
+}
+
+func (m *MockStore) GetBannedKeywords(ctx context.Context) ([]string, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockStore) RecordStrippedKeywords(ctx context.Context, terms []string) error {
+	args := m.Called(ctx, terms)
+	return args.Error(0)
+}
+
+func (m *MockStore) TryRateLimit(ctx context.Context, userID string, interval time.Duration, now time.Time) (bool, error) {
+	args := m.Called(ctx, userID, interval, now)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockStore) QueuePing(ctx context.Context, p store.QueuedPing) error {
+	args := m.Called(ctx, p)
+	return args.Error(0)
+}
+
+func (m *MockStore) GetDuePings(ctx context.Context, now time.Time, limit int) ([]store.QueuedPing, error) {
+	args := m.Called(ctx, now, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]store.QueuedPing), args.Error(1)
+}
+
+func (m *MockStore) DeletePings(ctx context.Context, ids []string) error {
+	args := m.Called(ctx, ids)
+	return args.Error(0)
+}
+
 func (m *MockStore) SaveAnalytics(ctx context.Context, record store.AnalyticsRecord) error {
 	args := m.Called(ctx, record)
 	return args.Error(0)
 }
 
+func (m *MockStore) SaveParseFlag(ctx context.Context, record store.AnalyticsRecord) (bool, error) {
+	args := m.Called(ctx, record)
+	return args.Bool(0), args.Error(1)
+}
+
 func (m *MockStore) GetUnprocessedAnalyticsByFlow(ctx context.Context, flowType string, limit int) ([]store.AnalyticsRecord, error) {
 	args := m.Called(ctx, flowType, limit)
 	if args.Get(0) == nil {
@@ -122,6 +337,19 @@ func (m *MockStore) GetSystemPrompt(ctx context.Context, key string) (string, er
 	return args.String(0), args.Error(1)
 }
 
+func (m *MockStore) GetSystemPromptRecord(ctx context.Context, key string) (*store.SystemPrompt, error) {
+	args := m.Called(ctx, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*store.SystemPrompt), args.Error(1)
+}
+
+func (m *MockStore) AddPromptHistory(ctx context.Context, entry store.PromptHistoryEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
 func (m *MockStore) SetSystemPrompt(ctx context.Context, key, promptText string) error {
 	args := m.Called(ctx, key, promptText)
 	return args.Error(0)
@@ -136,8 +364,8 @@ type MockAI struct {
 	mock.Mock
 }
 
-func (m *MockAI) CleanRedditPost(ctx context.Context, rawTitle, rawBody string) (*ai.CleanedPost, error) {
-	args := m.Called(ctx, rawTitle, rawBody)
+func (m *MockAI) CleanRedditPost(ctx context.Context, rawTitle, rawBody, promptOverride string) (*ai.CleanedPost, error) {
+	args := m.Called(ctx, rawTitle, rawBody, promptOverride)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -160,6 +388,14 @@ func (m *MockAI) ValidateManualQuery(ctx context.Context, userQuery, promptOverr
 	return args.Get(0).(*ai.KeywordWizardResponse), args.Error(1)
 }
 
+func (m *MockAI) RunCompaction(ctx context.Context, records []store.AnalyticsRecord, currentPrompt, flowType string) (*ai.CompactionResult, error) {
+	args := m.Called(ctx, records, currentPrompt, flowType)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ai.CompactionResult), args.Error(1)
+}
+
 func (m *MockAI) Close() {
 	m.Called()
 }
@@ -187,6 +423,20 @@ func (m *MockDiscord) EditEmbed(channelID, messageID, content string, embed *dis
 	return m.Called(channelID, messageID, content, embed).Error(0)
 }
 
+func (m *MockDiscord) SendEmbedViaWebhook(webhookURL string, embed *discordgo.MessageEmbed, components []discordgo.MessageComponent) (string, error) {
+	args := m.Called(webhookURL, embed, components)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockDiscord) EditEmbedViaWebhook(webhookURL, messageID string, embed *discordgo.MessageEmbed) error {
+	return m.Called(webhookURL, messageID, embed).Error(0)
+}
+
+func (m *MockDiscord) GetWebhookChannel(webhookURL string) (string, error) {
+	args := m.Called(webhookURL)
+	return args.String(0), args.Error(1)
+}
+
 func (m *MockDiscord) AddReaction(channelID, messageID, emoji string) error {
 	return m.Called(channelID, messageID, emoji).Error(0)
 }
@@ -199,6 +449,14 @@ func (m *MockDiscord) SendFollowupEmbedWithComponents(i *discordgo.Interaction,
 	return m.Called(i, embed, components).Error(0)
 }
 
+func (m *MockDiscord) EditOriginalInteractionResponse(i *discordgo.Interaction, embed *discordgo.MessageEmbed, components []discordgo.MessageComponent) error {
+	return m.Called(i, embed, components).Error(0)
+}
+
+func (m *MockDiscord) SendFollowupFile(i *discordgo.Interaction, content, filename string, data []byte) error {
+	return m.Called(i, content, filename, data).Error(0)
+}
+
 func (m *MockDiscord) CreateDM(userID string) (string, error) {
 	args := m.Called(userID)
 	return args.String(0), args.Error(1)
@@ -217,8 +475,8 @@ type MockScraper struct {
 	mock.Mock
 }
 
-func (m *MockScraper) FetchNewestPosts(ctx context.Context) ([]reddit.Post, error) {
-	args := m.Called(ctx)
+func (m *MockScraper) FetchNewestPosts(ctx context.Context, sort reddit.Sort) ([]reddit.Post, error) {
+	args := m.Called(ctx, sort)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
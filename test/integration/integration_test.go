@@ -47,23 +47,33 @@ func TestRunPipeline_Integration_Success(t *testing.T) {
 	}
 
 	// 2. Setup Mock Expectations for the full flow
-	mockScraper.On("FetchNewestPosts", ctx).Return([]reddit.Post{post}, nil)
+	mockScraper.On("FetchNewestPosts", ctx, reddit.SortNew).Return([]reddit.Post{post}, nil)
 	mockDB.On("GetAllAlerts", ctx).Return(alerts, nil)
+	mockDB.On("GetAllServers", mock.Anything).Return([]store.ServerConfig{}, nil)
+	mockDB.On("GetScammers", mock.Anything).Return([]store.Scammer{}, nil)
+	mockDB.On("GetSystemPrompt", mock.Anything, "clean_prompt").Return("", nil)
 	mockDB.On("GetPostRecord", mock.Anything, "pipe_1").Return(nil, nil) // New post
 
 	// processNewPost flow
-	mockAI.On("CleanRedditPost", mock.Anything, post.Title, post.SelfText).Return(cleaned, nil)
+	mockAI.On("CleanRedditPost", mock.Anything, post.Title, post.SelfText, mock.Anything).Return(cleaned, nil)
 	mockDB.On("GetServerConfig", mock.Anything, "guild_int").Return(serverConfig, nil)
 	mockDiscord.On("SendEmbedWithComponents", "feed_int", "", mock.Anything, mock.Anything).Return("discord_msg_1", nil)
 	mockDiscord.On("AddReaction", "feed_int", "discord_msg_1", mock.Anything).Return(nil).Times(2)
+	mockDB.On("GetUserSettings", mock.Anything, "user_int").Return(&store.UserSettings{}, nil)
 	mockDiscord.On("SendMessage", "ping_int", mock.Anything).Return(nil)
-	mockDB.On("SavePostRecords", mock.Anything, "pipe_1", cleaned.Title, map[string]string{"guild_int": "discord_msg_1"}).Return(nil)
+	// user_int's first match also gets the welcome DM.
+	mockDB.On("MarkUserMatched", mock.Anything, "user_int").Return(true, nil)
+	mockDiscord.On("CreateDM", "user_int").Return("dm_int", nil)
+	mockDiscord.On("SendMessage", "dm_int", mock.Anything).Return(nil)
+	mockDB.On("SavePostRecords", mock.Anything, "pipe_1", cleaned.Title, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, map[string]string{"guild_int": "discord_msg_1"}, mock.Anything).Return(nil)
 
 	// Cleanup flow
 	mockDB.On("TrimOldPosts", mock.Anything).Return(nil)
+	mockDB.On("TrimOldPipelineRuns", mock.Anything).Return(nil)
+	mockDB.On("SavePipelineRun", mock.Anything, mock.Anything).Return(nil)
 
 	// 3. Run
-	err := processor.RunPipeline(ctx, mockDB, mockAI, mockScraper, mockDiscord)
+	err := processor.RunPipeline(ctx, mockDB, mockAI, mockScraper, mockDiscord, 0)
 
 	// 4. Assertions
 	if err != nil {
@@ -83,9 +93,12 @@ func TestRunPipeline_Integration_RedditFailure(t *testing.T) {
 	mockScraper := new(testutils.MockScraper)
 	mockDiscord := new(testutils.MockDiscord)
 
-	mockScraper.On("FetchNewestPosts", ctx).Return([]reddit.Post(nil), errors.New("reddit down"))
+	mockScraper.On("FetchNewestPosts", ctx, reddit.SortNew).Return([]reddit.Post(nil), errors.New("reddit down"))
+	mockDB.On("SavePipelineRun", mock.Anything, mock.MatchedBy(func(run store.PipelineRun) bool {
+		return run.Error != ""
+	})).Return(nil)
 
-	err := processor.RunPipeline(ctx, mockDB, mockAI, mockScraper, mockDiscord)
+	err := processor.RunPipeline(ctx, mockDB, mockAI, mockScraper, mockDiscord, 0)
 
 	if err == nil {
 		t.Error("expected error when reddit is down, got nil")
@@ -100,11 +113,16 @@ func TestRunPipeline_Integration_NoPosts(t *testing.T) {
 	mockScraper := new(testutils.MockScraper)
 	mockDiscord := new(testutils.MockDiscord)
 
-	mockScraper.On("FetchNewestPosts", ctx).Return([]reddit.Post{}, nil)
+	mockScraper.On("FetchNewestPosts", ctx, reddit.SortNew).Return([]reddit.Post{}, nil)
 	mockDB.On("GetAllAlerts", ctx).Return([]store.AlertRule{}, nil)
+	mockDB.On("GetAllServers", mock.Anything).Return([]store.ServerConfig{}, nil)
+	mockDB.On("GetScammers", mock.Anything).Return([]store.Scammer{}, nil)
+	mockDB.On("GetSystemPrompt", mock.Anything, "clean_prompt").Return("", nil)
 	mockDB.On("TrimOldPosts", mock.Anything).Return(nil)
+	mockDB.On("TrimOldPipelineRuns", mock.Anything).Return(nil)
+	mockDB.On("SavePipelineRun", mock.Anything, mock.Anything).Return(nil)
 
-	err := processor.RunPipeline(ctx, mockDB, mockAI, mockScraper, mockDiscord)
+	err := processor.RunPipeline(ctx, mockDB, mockAI, mockScraper, mockDiscord, 0)
 
 	if err != nil {
 		t.Errorf("expected no error for empty posts, got %v", err)
@@ -126,29 +144,35 @@ func TestRunPipeline_PartialFailure(t *testing.T) {
 	serverConfig := &store.ServerConfig{FeedChannelID: "f1"}
 
 	// 1. Scraper returns two posts
-	mockScraper.On("FetchNewestPosts", ctx).Return([]reddit.Post{p1, p2}, nil)
+	mockScraper.On("FetchNewestPosts", ctx, reddit.SortNew).Return([]reddit.Post{p1, p2}, nil)
 	mockDB.On("GetAllAlerts", ctx).Return(alerts, nil)
+	mockDB.On("GetAllServers", mock.Anything).Return([]store.ServerConfig{}, nil)
+	mockDB.On("GetScammers", mock.Anything).Return([]store.Scammer{}, nil)
+	mockDB.On("GetSystemPrompt", mock.Anything, "clean_prompt").Return("", nil)
 
 	// 2. Post 1 fails AI cleaning
 	mockDB.On("GetPostRecord", mock.Anything, "p1").Return(nil, nil)
-	mockAI.On("CleanRedditPost", mock.Anything, p1.Title, p1.SelfText).Return(nil, errors.New("ai error"))
+	mockAI.On("CleanRedditPost", mock.Anything, p1.Title, p1.SelfText, mock.Anything).Return(nil, errors.New("ai error"))
 
 	// 3. Post 2 succeeds
 	mockDB.On("GetPostRecord", mock.Anything, "p2").Return(nil, nil)
-	mockAI.On("CleanRedditPost", mock.Anything, p2.Title, p2.SelfText).Return(&ai.CleanedPost{Title: "Success"}, nil)
+	mockAI.On("CleanRedditPost", mock.Anything, p2.Title, p2.SelfText, mock.Anything).Return(&ai.CleanedPost{Title: "Success"}, nil)
 	mockDB.On("GetServerConfig", mock.Anything, "g1").Return(serverConfig, nil)
 	mockDiscord.On("SendEmbedWithComponents", "f1", "", mock.Anything, mock.Anything).Return("m2", nil)
 	mockDiscord.On("AddReaction", "f1", "m2", mock.Anything).Return(nil).Times(2)
+	mockDB.On("GetUserSettings", mock.Anything, mock.Anything).Return(&store.UserSettings{HasMatched: true}, nil)
 	mockDiscord.On("SendMessage", mock.Anything, mock.Anything).Return(nil)
-	mockDB.On("SavePostRecords", mock.Anything, "p2", "Success", mock.Anything).Return(nil)
+	mockDB.On("SavePostRecords", mock.Anything, "p2", "Success", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
 
 	// 4. Cleanup
 	mockDB.On("TrimOldPosts", mock.Anything).Return(nil)
+	mockDB.On("TrimOldPipelineRuns", mock.Anything).Return(nil)
+	mockDB.On("SavePipelineRun", mock.Anything, mock.Anything).Return(nil)
 
-	err := processor.RunPipeline(ctx, mockDB, mockAI, mockScraper, mockDiscord)
+	err := processor.RunPipeline(ctx, mockDB, mockAI, mockScraper, mockDiscord, 0)
 
 	// We expect NO error from RunPipeline even if a sub-task (processNewPost) failed its AI call,
-	// because processNewPost handles its own errors and logs them (void function).
+	// because per-post failures are only logged and counted in the run record.
 	if err != nil {
 		t.Errorf("expected pipeline to absorb sub-errors, got %v", err)
 	}